@@ -0,0 +1,200 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+)
+
+// Synthetic units derived by masters from health reports of all nodes of the cluster
+const (
+	mastersQuorumUnit    = "cluster-masters-quorum"
+	leaderElectedUnit    = "cluster-leader-elected"
+	agentsRegisteredUnit = "cluster-agents-registered"
+	versionSkewUnit      = "cluster-version-skew"
+)
+
+// leaderRecord is resolved to the IP of the leading Mesos master
+const leaderRecord = "leader.mesos"
+
+// lookupLeader returns IPs of the leading master
+func lookupLeader() ([]string, error) {
+	return net.LookupHost(leaderRecord)
+}
+
+// clusterState is the cluster seen by a single pull
+type clusterState struct {
+	// masters and agents are nodes discovered by the puller
+	masters []dcos.Node
+	agents  []dcos.Node
+	// responses are health reports pulled from discovered nodes
+	responses []*httpResponse
+	// leader returns IPs of the leading master
+	leader    func() ([]string, error)
+	timestamp time.Time
+}
+
+// clusterUnits returns synthetic units describing health of the cluster as a whole
+func clusterUnits(s clusterState) []dcos.Unit {
+	reported := make(map[string]*httpResponse, len(s.responses))
+	for _, r := range s.responses {
+		if r.Status == http.StatusOK {
+			reported[r.Node.IP] = r
+		}
+	}
+
+	units := []dcos.Unit{
+		s.mastersQuorum(reported),
+		s.leaderElected(),
+		s.agentsRegistered(reported),
+		s.versionSkew(reported),
+	}
+	for i := range units {
+		units[i].Timestamp = s.timestamp
+	}
+	return units
+}
+
+func (s clusterState) mastersQuorum(reported map[string]*httpResponse) dcos.Unit {
+	unit := dcos.Unit{
+		UnitName:   mastersQuorumUnit,
+		Title:      "Quorum of healthy masters",
+		PrettyName: "Masters Quorum",
+		Health:     dcos.Healthy,
+	}
+
+	quorum := len(s.masters)/2 + 1
+	var unhealthy []string
+	for _, m := range s.masters {
+		r, ok := reported[m.IP]
+		switch {
+		case !ok:
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is not reporting", m.IP))
+		case r.Node.Health != dcos.Healthy:
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is unhealthy", m.IP))
+		default:
+			unit.Nodes = append(unit.Nodes, r.Node)
+		}
+	}
+	healthy := len(s.masters) - len(unhealthy)
+
+	if len(s.masters) == 0 {
+		unit.Health = dcos.Unknown
+		unit.Output = "no masters found"
+		return unit
+	}
+	unit.Output = fmt.Sprintf("%d of %d masters healthy, %d needed for quorum", healthy, len(s.masters), quorum)
+	if healthy < quorum {
+		unit.Health = dcos.Unhealthy
+	}
+	if len(unhealthy) > 0 {
+		unit.Output += ": " + strings.Join(unhealthy, ", ")
+	}
+	return unit
+}
+
+func (s clusterState) leaderElected() dcos.Unit {
+	unit := dcos.Unit{
+		UnitName:   leaderElectedUnit,
+		Title:      "Leading Mesos master is elected",
+		PrettyName: "Leader Elected",
+		Health:     dcos.Healthy,
+	}
+	if s.leader == nil {
+		unit.Health = dcos.Unknown
+		unit.Output = "leader lookup is not configured"
+		return unit
+	}
+
+	ips, err := s.leader()
+	if err != nil {
+		unit.Health = dcos.Unhealthy
+		unit.Output = fmt.Sprintf("no leader elected, could not resolve %s: %s", leaderRecord, err)
+		return unit
+	}
+	for _, m := range s.masters {
+		for _, ip := range ips {
+			if m.IP == ip {
+				unit.Nodes = append(unit.Nodes, m)
+				unit.Output = fmt.Sprintf("%s is the leader", ip)
+				return unit
+			}
+		}
+	}
+	unit.Health = dcos.Unhealthy
+	unit.Output = fmt.Sprintf("%s resolves to %s which is not a known master", leaderRecord, strings.Join(ips, ", "))
+	return unit
+}
+
+func (s clusterState) agentsRegistered(reported map[string]*httpResponse) dcos.Unit {
+	unit := dcos.Unit{
+		UnitName:   agentsRegisteredUnit,
+		Title:      "All discovered agents report their health",
+		PrettyName: "Agents Registered",
+		Health:     dcos.Healthy,
+	}
+
+	var missing []string
+	for _, a := range s.agents {
+		if _, ok := reported[a.IP]; !ok {
+			missing = append(missing, a.IP)
+			unit.Nodes = append(unit.Nodes, a)
+		}
+	}
+	unit.Output = fmt.Sprintf("%d of %d agents reporting", len(s.agents)-len(missing), len(s.agents))
+	if len(missing) > 0 {
+		unit.Health = dcos.Unhealthy
+		unit.Output += ", not reporting: " + strings.Join(missing, ", ")
+	}
+	return unit
+}
+
+func (s clusterState) versionSkew(reported map[string]*httpResponse) dcos.Unit {
+	unit := dcos.Unit{
+		UnitName:   versionSkewUnit,
+		Title:      "All nodes run the same DC/OS and dcos-diagnostics versions",
+		PrettyName: "Version Skew",
+		Health:     dcos.Healthy,
+	}
+
+	dcosVersions := map[string][]string{}
+	diagnosticsVersions := map[string][]string{}
+	for ip, r := range reported {
+		dcosVersions[r.DcosVersion] = append(dcosVersions[r.DcosVersion], ip)
+		diagnosticsVersions[r.DiagnosticsVersion] = append(diagnosticsVersions[r.DiagnosticsVersion], ip)
+	}
+
+	var skew []string
+	if len(dcosVersions) > 1 {
+		skew = append(skew, "dcos_version differs: "+describeVersions(dcosVersions))
+	}
+	if len(diagnosticsVersions) > 1 {
+		skew = append(skew, "dcos_diagnostics_version differs: "+describeVersions(diagnosticsVersions))
+	}
+	if len(skew) > 0 {
+		unit.Health = dcos.Unhealthy
+		unit.Output = strings.Join(skew, "\n")
+		return unit
+	}
+	unit.Output = fmt.Sprintf("%d nodes run the same versions", len(reported))
+	return unit
+}
+
+// describeVersions lists versions with nodes running them, e.g. 2.1.0 on 10.0.0.1, 10.0.0.2; 2.2.0 on 10.0.0.3
+func describeVersions(versions map[string][]string) string {
+	var descriptions []string
+	for version, ips := range versions {
+		sort.Strings(ips)
+		if version == "" {
+			version = "unknown"
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s on %s", version, strings.Join(ips, ", ")))
+	}
+	sort.Strings(descriptions)
+	return strings.Join(descriptions, "; ")
+}
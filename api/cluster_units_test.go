@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reportedNode(ip, role string, health dcos.Health, version string) *httpResponse {
+	return &httpResponse{
+		Status:             http.StatusOK,
+		Node:               dcos.Node{IP: ip, Role: role, Health: health},
+		DcosVersion:        version,
+		DiagnosticsVersion: "0.4.0",
+	}
+}
+
+func clusterUnitsByName(s clusterState) map[string]dcos.Unit {
+	units := map[string]dcos.Unit{}
+	for _, u := range clusterUnits(s) {
+		units[u.UnitName] = u
+	}
+	return units
+}
+
+func TestClusterUnitsHealthyCluster(t *testing.T) {
+	now := time.Now()
+	units := clusterUnitsByName(clusterState{
+		masters: []dcos.Node{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}, {IP: "10.0.0.3"}},
+		agents:  []dcos.Node{{IP: "10.0.1.1"}},
+		responses: []*httpResponse{
+			reportedNode("10.0.0.1", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.0.2", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.0.3", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.1.1", dcos.AgentRole, dcos.Healthy, "2.1.0"),
+		},
+		leader:    func() ([]string, error) { return []string{"10.0.0.2"}, nil },
+		timestamp: now,
+	})
+
+	require.Len(t, units, 4)
+	for name, u := range units {
+		assert.Equal(t, dcos.Health(dcos.Healthy), u.Health, name)
+		assert.Equal(t, now, u.Timestamp, name)
+	}
+	assert.Equal(t, "3 of 3 masters healthy, 2 needed for quorum", units[mastersQuorumUnit].Output)
+	assert.Equal(t, "10.0.0.2 is the leader", units[leaderElectedUnit].Output)
+	assert.Equal(t, "1 of 1 agents reporting", units[agentsRegisteredUnit].Output)
+	assert.Equal(t, "4 nodes run the same versions", units[versionSkewUnit].Output)
+}
+
+func TestClusterUnitsMastersQuorum(t *testing.T) {
+	masters := []dcos.Node{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}, {IP: "10.0.0.3"}}
+
+	units := clusterUnitsByName(clusterState{
+		masters: masters,
+		responses: []*httpResponse{
+			reportedNode("10.0.0.1", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.0.2", dcos.MasterRole, dcos.Unhealthy, "2.1.0"),
+			{Status: http.StatusServiceUnavailable, Node: dcos.Node{IP: "10.0.0.3", Health: dcos.Unknown}},
+		},
+	})
+	quorum := units[mastersQuorumUnit]
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), quorum.Health)
+	assert.Equal(t, "1 of 3 masters healthy, 2 needed for quorum: 10.0.0.2 is unhealthy, 10.0.0.3 is not reporting", quorum.Output)
+	require.Len(t, quorum.Nodes, 1)
+	assert.Equal(t, "10.0.0.1", quorum.Nodes[0].IP)
+
+	units = clusterUnitsByName(clusterState{
+		masters: masters,
+		responses: []*httpResponse{
+			reportedNode("10.0.0.1", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.0.2", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+		},
+	})
+	assert.Equal(t, dcos.Health(dcos.Healthy), units[mastersQuorumUnit].Health)
+	assert.Equal(t, "2 of 3 masters healthy, 2 needed for quorum: 10.0.0.3 is not reporting", units[mastersQuorumUnit].Output)
+
+	units = clusterUnitsByName(clusterState{})
+	assert.Equal(t, dcos.Health(dcos.Unknown), units[mastersQuorumUnit].Health)
+	assert.Equal(t, "no masters found", units[mastersQuorumUnit].Output)
+}
+
+func TestClusterUnitsLeaderElected(t *testing.T) {
+	masters := []dcos.Node{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}
+
+	units := clusterUnitsByName(clusterState{
+		masters: masters,
+		leader:  func() ([]string, error) { return nil, errors.New("no such host") },
+	})
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), units[leaderElectedUnit].Health)
+	assert.Equal(t, "no leader elected, could not resolve leader.mesos: no such host", units[leaderElectedUnit].Output)
+
+	units = clusterUnitsByName(clusterState{
+		masters: masters,
+		leader:  func() ([]string, error) { return []string{"10.0.0.9"}, nil },
+	})
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), units[leaderElectedUnit].Health)
+	assert.Equal(t, "leader.mesos resolves to 10.0.0.9 which is not a known master", units[leaderElectedUnit].Output)
+
+	units = clusterUnitsByName(clusterState{masters: masters})
+	assert.Equal(t, dcos.Health(dcos.Unknown), units[leaderElectedUnit].Health)
+}
+
+func TestClusterUnitsAgentsRegistered(t *testing.T) {
+	units := clusterUnitsByName(clusterState{
+		agents: []dcos.Node{{IP: "10.0.1.1"}, {IP: "10.0.1.2"}, {IP: "10.0.1.3"}},
+		responses: []*httpResponse{
+			reportedNode("10.0.1.1", dcos.AgentRole, dcos.Healthy, "2.1.0"),
+			{Status: http.StatusNotFound, Node: dcos.Node{IP: "10.0.1.3", Health: dcos.Unknown}},
+		},
+	})
+
+	agents := units[agentsRegisteredUnit]
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), agents.Health)
+	assert.Equal(t, "1 of 3 agents reporting, not reporting: 10.0.1.2, 10.0.1.3", agents.Output)
+	assert.Len(t, agents.Nodes, 2)
+}
+
+func TestClusterUnitsVersionSkew(t *testing.T) {
+	diagnostics := reportedNode("10.0.1.2", dcos.AgentRole, dcos.Healthy, "2.1.0")
+	diagnostics.DiagnosticsVersion = "0.5.0"
+
+	units := clusterUnitsByName(clusterState{
+		responses: []*httpResponse{
+			reportedNode("10.0.0.1", dcos.MasterRole, dcos.Healthy, "2.1.0"),
+			reportedNode("10.0.1.1", dcos.AgentRole, dcos.Healthy, "2.2.0"),
+			diagnostics,
+		},
+	})
+
+	skew := units[versionSkewUnit]
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), skew.Health)
+	assert.Equal(t, "dcos_version differs: 2.1.0 on 10.0.0.1, 10.0.1.2; 2.2.0 on 10.0.1.1\n"+
+		"dcos_diagnostics_version differs: 0.4.0 on 10.0.0.1, 10.0.1.1; 0.5.0 on 10.0.1.2", skew.Output)
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,6 +10,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,8 +23,13 @@ import (
 
 	"github.com/dcos/dcos-diagnostics/fetcher"
 
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/collector"
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/integrity"
+	diagio "github.com/dcos/dcos-diagnostics/io"
+	"github.com/dcos/dcos-diagnostics/search"
 	"github.com/dcos/dcos-diagnostics/units"
 	"github.com/dcos/dcos-diagnostics/util"
 
@@ -51,10 +56,12 @@ type DiagnosticsJob struct {
 	statusMutex   sync.RWMutex
 	progressMutex sync.RWMutex
 
-	cancelFunc   context.CancelFunc
-	logProviders logProviders
-	client       *http.Client
+	cancelFunc     context.CancelFunc
+	providersMutex sync.RWMutex
+	logProviders   logProviders
+	client         *http.Client
 
+	cfgMutex  sync.RWMutex
 	Cfg       *config.Config
 	DCOSTools dcos.Tooler
 	Transport http.RoundTripper
@@ -157,11 +164,11 @@ func (j *DiagnosticsJob) run(req bundleCreateRequest) (createResponse, error) {
 	logrus.Debugf("Found requested nodes: %v", foundNodes)
 
 	// try to create directory for diagnostic bundles
-	_, err = os.Stat(j.Cfg.FlagDiagnosticsBundleDir)
+	_, err = os.Stat(j.config().FlagDiagnosticsBundleDir)
 	if os.IsNotExist(err) {
-		logrus.Infof("Directory: %s not found, attempting to create one", j.Cfg.FlagDiagnosticsBundleDir)
-		if err := os.MkdirAll(j.Cfg.FlagDiagnosticsBundleDir, os.ModePerm); err != nil {
-			e := fmt.Errorf("could not create directory: %s", j.Cfg.FlagDiagnosticsBundleDir)
+		logrus.Infof("Directory: %s not found, attempting to create one", j.config().FlagDiagnosticsBundleDir)
+		if err := os.MkdirAll(j.config().FlagDiagnosticsBundleDir, os.ModePerm); err != nil {
+			e := fmt.Errorf("could not create directory: %s", j.config().FlagDiagnosticsBundleDir)
 			j.setStatus(e.Error())
 			return prepareCreateResponseWithErr(http.StatusServiceUnavailable, e)
 		}
@@ -173,9 +180,9 @@ func (j *DiagnosticsJob) run(req bundleCreateRequest) (createResponse, error) {
 	t := time.Now()
 	bundleName := fmt.Sprintf("bundle-%d-%02d-%02d-%d.zip", t.Year(), t.Month(), t.Day(), t.Unix())
 
-	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute*time.Duration(j.Cfg.FlagDiagnosticsJobTimeoutMinutes))
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute*time.Duration(j.config().FlagDiagnosticsJobTimeoutMinutes))
 
-	j.LastBundlePath = filepath.Join(j.Cfg.FlagDiagnosticsBundleDir, bundleName)
+	j.LastBundlePath = filepath.Join(j.config().FlagDiagnosticsBundleDir, bundleName)
 	j.setStatus("Diagnostics job started, archive will be available at: " + j.LastBundlePath)
 	j.cancelFunc = cancelFunc
 	j.JobStarted = time.Now()
@@ -222,7 +229,14 @@ func (j *DiagnosticsJob) runBackgroundJob(ctx context.Context, nodes []dcos.Node
 	}
 	defer zipfile.Close()
 
-	zipWriter := zip.NewWriter(zipfile)
+	// legacy bundles are always zip archives, their names and clients expect bundle-*.zip
+	zipWriter, err := archive.NewWriter(zipfile, archive.Zip)
+	if err != nil {
+		j.setStatus(jobFailedStatus)
+		j.appendError(err)
+		logrus.Error(err)
+		return
+	}
 	defer zipWriter.Close()
 
 	// summaryReport is a log of a diagnostics job
@@ -240,8 +254,11 @@ func (j *DiagnosticsJob) runBackgroundJob(ctx context.Context, nodes []dcos.Node
 	}
 	j.setJobProgressPercentage(100)
 
+	// manifest records checksums of every file in the bundle so its integrity could be verified later
+	manifest := integrity.NewManifest()
+
 	for _, path := range zips {
-		if err = appendToZip(zipWriter, path); err != nil {
+		if err = appendToZip(zipWriter, path, manifest); err != nil {
 			j.logError(err, "Could not create a bundle", summaryErrorsReport)
 		}
 		if err = os.Remove(path); err != nil {
@@ -249,40 +266,38 @@ func (j *DiagnosticsJob) runBackgroundJob(ctx context.Context, nodes []dcos.Node
 		}
 	}
 
-	j.flushReport(zipWriter, "summaryReport.txt", summaryReport)
+	j.flushReport(zipWriter, manifest, "summaryReport.txt", summaryReport)
 	if summaryErrorsReport.Len() > 0 {
-		j.flushReport(zipWriter, "summaryErrorsReport.txt", summaryErrorsReport)
+		j.flushReport(zipWriter, manifest, "summaryErrorsReport.txt", summaryErrorsReport)
 	}
-}
 
-func appendToZip(writer *zip.Writer, path string) error {
-	r, err := zip.OpenReader(path)
+	manifestFile, err := zipWriter.Create(integrity.ManifestFileName)
 	if err != nil {
-		return fmt.Errorf("could not open %s: %s", path, err)
+		e := fmt.Errorf("could not append a %s to a zip file: %s", integrity.ManifestFileName, err)
+		logrus.Error(e)
+		j.appendError(e)
+		return
 	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		rc, err := f.Open()
-		if err != nil {
-			return fmt.Errorf("could not open %s from zip: %s", f.Name, err)
-		}
+	if _, err := manifest.WriteTo(manifestFile); err != nil {
+		logrus.Errorf("Error writing %s: %s", integrity.ManifestFileName, err)
+	}
+}
 
-		file, err := writer.Create(f.Name)
+func appendToZip(writer archive.Writer, path string, manifest *integrity.Manifest) error {
+	return archive.Walk(path, func(name string, r io.Reader) error {
+		file, err := writer.Create(name)
 		if err != nil {
-			return fmt.Errorf("could not create file %s: %s", f.Name, err)
+			return fmt.Errorf("could not create file %s: %s", name, err)
 		}
-		_, err = io.Copy(file, rc)
+		_, err = manifest.Copy(name, file, r)
 		if err != nil {
-			return fmt.Errorf("could not copy file %s to zip: %s", f.Name, err)
+			return fmt.Errorf("could not copy file %s to zip: %s", name, err)
 		}
-		rc.Close()
-	}
-
-	return nil
+		return nil
+	})
 }
 
-func (j *DiagnosticsJob) flushReport(zipWriter *zip.Writer, fileName string, report *bytes.Buffer) {
+func (j *DiagnosticsJob) flushReport(zipWriter archive.Writer, manifest *integrity.Manifest, fileName string, report *bytes.Buffer) {
 	zipFile, err := zipWriter.Create(fileName)
 	if err != nil {
 		e := fmt.Errorf("could not append a report.txt to a zip file: %s", err)
@@ -291,7 +306,7 @@ func (j *DiagnosticsJob) flushReport(zipWriter *zip.Writer, fileName string, rep
 		j.setStatus(e.Error())
 		return
 	}
-	_, err = io.Copy(zipFile, report)
+	_, err = manifest.Copy(fileName, zipFile, report)
 	if err != nil {
 		logrus.Errorf("Error writing %s: %s", fileName, err)
 	}
@@ -311,9 +326,9 @@ func (j *DiagnosticsJob) collectDataFromNodes(ctx context.Context, nodes []dcos.
 	fetchStatusUpdate := make(chan fetcher.StatusUpdate)
 	fetchResponse := make(chan fetcher.BulkResponse)
 
-	numberOfWorkers := j.Cfg.FlagDiagnosticsBundleFetchersCount
+	numberOfWorkers := j.config().FlagDiagnosticsBundleFetchersCount
 	for i := 0; i < numberOfWorkers; i++ {
-		f, err := fetcher.New(j.Cfg.FlagDiagnosticsBundleDir, j.client, fetchReq, fetchStatusUpdate, fetchResponse, j.FetchPrometheusVector)
+		f, err := fetcher.New(j.config().FlagDiagnosticsBundleDir, j.client, fetchReq, fetchStatusUpdate, fetchResponse, j.FetchPrometheusVector)
 		if err != nil {
 			return nil, fmt.Errorf("could not start fetchers: %s", err)
 		}
@@ -387,7 +402,7 @@ func (j *DiagnosticsJob) getEndpointsToFetch(ctx context.Context, nodes []dcos.N
 				return fetchRequests
 			default:
 			}
-			fullURL, err := util.UseTLSScheme("http://"+node.IP+httpEndpoint.PortAndPath, j.Cfg.FlagForceTLS)
+			fullURL, err := util.UseTLSScheme("http://"+node.IP+httpEndpoint.PortAndPath, j.config().FlagForceTLS)
 			if err != nil {
 				j.logError(fmt.Errorf("could prepare URL: %s", err), node.IP, summaryErrorsReport)
 				continue
@@ -397,6 +412,7 @@ func (j *DiagnosticsJob) getEndpointsToFetch(ctx context.Context, nodes []dcos.N
 				Node:     node,
 				FileName: fileName,
 				Optional: httpEndpoint.Optional,
+				Options:  httpEndpoint.Options.collectorOptions(),
 			})
 		}
 	}
@@ -454,7 +470,7 @@ func (j *DiagnosticsJob) getErrors() []string {
 }
 
 func (j *DiagnosticsJob) getNodeEndpoints(node dcos.Node) (endpoints map[string]endpointSpec, e error) {
-	port, err := getPullPortByRole(j.Cfg, node.Role)
+	port, err := getPullPortByRole(j.config(), node.Role)
 	if err != nil {
 		e = fmt.Errorf("used incorrect role: %s", err)
 		return nil, e
@@ -486,7 +502,7 @@ func (j *DiagnosticsJob) delete(bundleName string) (response diagnosticsReportRe
 	defer j.Unlock()
 
 	// first try to locate a bundle on a local disk.
-	bundlePath := filepath.Join(j.Cfg.FlagDiagnosticsBundleDir, bundleName)
+	bundlePath := filepath.Join(j.config().FlagDiagnosticsBundleDir, bundleName)
 	logrus.Debugf("Trying remove a bundle: %s", bundlePath)
 	_, err = os.Stat(bundlePath)
 	if err == nil {
@@ -503,7 +519,7 @@ func (j *DiagnosticsJob) delete(bundleName string) (response diagnosticsReportRe
 		return prepareResponseWithErr(http.StatusServiceUnavailable, err)
 	}
 	if ok {
-		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/delete/%s", node, j.Cfg.FlagMasterPort, baseRoute, bundleName)
+		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/delete/%s", node, j.config().FlagMasterPort, baseRoute, bundleName)
 		status := "Attempting to delete a bundle on a remote host. POST " + url
 		logrus.Debug(status)
 		j.setStatus(status)
@@ -575,7 +591,7 @@ func (j *DiagnosticsJob) getStatusAll() (map[string]bundleReportStatus, error) {
 			continue
 		}
 		var status bundleReportStatus
-		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/status", master.IP, j.Cfg.FlagMasterPort, baseRoute)
+		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/status", master.IP, j.config().FlagMasterPort, baseRoute)
 		body, code, err := j.DCOSTools.Get(url, time.Second*3)
 		if code != 200 {
 			logrus.WithField("StatusCode", code).WithField("URL", url).Error("Could not get data")
@@ -606,7 +622,7 @@ func (j *DiagnosticsJob) getStatusAll() (map[string]bundleReportStatus, error) {
 func (j *DiagnosticsJob) getBundleReportStatus() bundleReportStatus {
 	// use a temp var `used`, since disk.Usage panics if partition does not exist.
 	var used float64
-	cfg := j.Cfg
+	cfg := j.config()
 	//TODO(janisz): Inject disk.Usage to DiagnosticsJob so this could be tested.
 	usageStat, err := disk.Usage(cfg.FlagDiagnosticsBundleDir)
 	if err == nil {
@@ -706,11 +722,11 @@ func (j *DiagnosticsJob) cancel() (response diagnosticsReportResponse, err error
 		j.cancelFunc()
 		logrus.Debug("Cancelling a local job")
 	} else {
-		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/cancel", node, j.Cfg.FlagMasterPort, baseRoute)
+		url := fmt.Sprintf("http://%s:%d%s/report/diagnostics/cancel", node, j.config().FlagMasterPort, baseRoute)
 		status := "Attempting to cancel a job on a remote host. POST " + url
 		logrus.Debug(status)
 		j.setStatus(status)
-		response, _, err := j.DCOSTools.Post(url, j.Cfg.GetSingleEntryTimeout())
+		response, _, err := j.DCOSTools.Post(url, j.config().GetSingleEntryTimeout())
 		if err != nil {
 			return prepareResponseWithErr(http.StatusServiceUnavailable, err)
 		}
@@ -771,7 +787,7 @@ func (j *DiagnosticsJob) isBundleAvailable(bundleName string) (string, string, b
 	}
 	logrus.WithField("Bundle", bundleName).WithError(err).Info("Not found bundle locally")
 
-	bundles, err := listAllBundles(j.Cfg, j.DCOSTools)
+	bundles, err := listAllBundles(j.config(), j.DCOSTools)
 	if err != nil {
 		return "", "", false, err
 	}
@@ -793,7 +809,7 @@ func (j *DiagnosticsJob) isBundleAvailable(bundleName string) (string, string, b
 
 // return a a list of bundles available on a localhost.
 func (j *DiagnosticsJob) findLocalBundle() (bundles []string, err error) {
-	matches, err := filepath.Glob(j.Cfg.FlagDiagnosticsBundleDir + "/bundle-*.zip")
+	matches, err := filepath.Glob(j.config().FlagDiagnosticsBundleDir + "/bundle-*.zip")
 	if err != nil {
 		return bundles, err
 	}
@@ -862,9 +878,11 @@ func findRequestedNodes(requestedNodes []string, tools dcos.Tooler) ([]dcos.Node
 type endpointSpec struct {
 	PortAndPath string
 	Optional    bool
+	Options     CollectorOptions
 }
 
-func (j *DiagnosticsJob) getLogsEndpoints() (endpoints map[string]endpointSpec, err error) {
+func (j *DiagnosticsJob) getLogsEndpoints(ctx context.Context) (endpoints map[string]endpointSpec, err error) {
+	providers := j.getLogProviders()
 	endpoints = make(map[string]endpointSpec)
 
 	currentRole, err := j.DCOSTools.GetNodeRole()
@@ -872,13 +890,13 @@ func (j *DiagnosticsJob) getLogsEndpoints() (endpoints map[string]endpointSpec,
 		return nil, fmt.Errorf("failed to get a current role for a cfg: %s", err)
 	}
 
-	port, err := getPullPortByRole(j.Cfg, currentRole)
+	port, err := getPullPortByRole(j.config(), currentRole)
 	if err != nil {
 		return endpoints, err
 	}
 
 	// http endpoints
-	for fileName, httpEndpoint := range j.logProviders.HTTPEndpoints {
+	for fileName, httpEndpoint := range providers.HTTPEndpoints {
 		// if a role wasn't detected, consider to load all endpoints from a cfg file.
 		// if the role could not be detected or it is not set in a cfg file use the log endpoint.
 		// do not use the role only if it is set, detected and does not match the role form a cfg.
@@ -888,27 +906,45 @@ func (j *DiagnosticsJob) getLogsEndpoints() (endpoints map[string]endpointSpec,
 		endpoints[fileName] = endpointSpec{
 			PortAndPath: fmt.Sprintf(":%d%s", httpEndpoint.Port, httpEndpoint.URI),
 			Optional:    httpEndpoint.Optional,
+			Options:     httpEndpoint.CollectorOptions,
 		}
 	}
 
 	// file endpoints
-	for sanitizedLocation, file := range j.logProviders.LocalFiles {
+	for sanitizedLocation, file := range providers.LocalFiles {
 		if !roleMatched(currentRole, file.Role) {
 			continue
 		}
+		// every file selected by a glob is fetched separately, the glob itself is fetched
+		// only if files could not be selected so the error is reported
+		if glob, ok := fileCollector(file).(*collector.Glob); ok {
+			files, err := glob.Select(ctx)
+			if err == nil {
+				for _, f := range files {
+					endpoints[f.Path] = endpointSpec{
+						PortAndPath: fmt.Sprintf(":%d%s/logs/files/%s?path=%s", port, baseRoute, sanitizedLocation, url.QueryEscape(f.Rel)),
+						Options:     file.CollectorOptions,
+					}
+				}
+				continue
+			}
+			logrus.WithError(err).Warnf("Could not select files of %s", file.Location)
+		}
 		endpoints[file.Location] = endpointSpec{
 			PortAndPath: fmt.Sprintf(":%d%s/logs/files/%s", port, baseRoute, sanitizedLocation),
+			Options:     file.CollectorOptions,
 		}
 	}
 
 	// command endpoints
-	for cmdKey, c := range j.logProviders.LocalCommands {
+	for cmdKey, c := range providers.LocalCommands {
 		if !roleMatched(currentRole, c.Role) {
 			continue
 		}
 		if cmdKey != "" {
 			endpoints[cmdKey] = endpointSpec{
 				PortAndPath: fmt.Sprintf(":%d%s/logs/cmds/%s", port, baseRoute, cmdKey),
+				Options:     c.CollectorOptions,
 			}
 		}
 	}
@@ -917,45 +953,93 @@ func (j *DiagnosticsJob) getLogsEndpoints() (endpoints map[string]endpointSpec,
 
 // Init will prepare diagnostics job, read config files etc.
 func (j *DiagnosticsJob) Init() error {
-	providers, err := loadProviders(j.Cfg, j.DCOSTools)
+	providers, err := newLogProviders(j.config(), j.DCOSTools)
 	if err != nil {
 		return fmt.Errorf("could not init diagnostic job: %s", err)
 	}
 	// set JobProgressPercentage -1 means the job has never been executed
 	j.setJobProgressPercentage(-1)
-	j.logProviders = logProviders{
+	j.setLogProviders(providers)
+	j.client = util.NewHTTPClient(j.config().GetSingleEntryTimeout(), j.Transport)
+
+	return nil
+}
+
+// config returns the current config of the job. Returned config is never modified, reload replaces it.
+func (j *DiagnosticsJob) config() *config.Config {
+	j.cfgMutex.RLock()
+	defer j.cfgMutex.RUnlock()
+	return j.Cfg
+}
+
+func (j *DiagnosticsJob) setConfig(cfg *config.Config) {
+	j.cfgMutex.Lock()
+	defer j.cfgMutex.Unlock()
+	j.Cfg = cfg
+}
+
+// getLogProviders returns current log providers. Returned providers are never modified, reload replaces them.
+func (j *DiagnosticsJob) getLogProviders() logProviders {
+	j.providersMutex.RLock()
+	defer j.providersMutex.RUnlock()
+	return j.logProviders
+}
+
+func (j *DiagnosticsJob) setLogProviders(providers logProviders) {
+	j.providersMutex.Lock()
+	defer j.providersMutex.Unlock()
+	j.logProviders = providers
+}
+
+// newLogProviders reads providers from endpoints config files and names them.
+func newLogProviders(cfg *config.Config, tools dcos.Tooler) (logProviders, error) {
+	providers, err := loadProviders(cfg, tools)
+	if err != nil {
+		return logProviders{}, err
+	}
+
+	role, err := tools.GetNodeRole()
+	if err != nil {
+		return logProviders{}, fmt.Errorf("could not get role: %s", err)
+	}
+
+	named := logProviders{
 		HTTPEndpoints: make(map[string]HTTPProvider),
 		LocalFiles:    make(map[string]FileProvider),
 		LocalCommands: make(map[string]CommandProvider),
 	}
-	// set filename if not set, some endpoints might be named e.g., after corresponding unit
+
+	// Entries with the same name override earlier ones, unless they are collected on other roles
+	// and would hide the entry of this node.
 	for _, endpoint := range providers.HTTPEndpoints {
-		fileName := fmt.Sprintf("%d-%s.json", endpoint.Port, util.SanitizeString(endpoint.URI))
-		if endpoint.FileName != "" {
-			fileName = endpoint.FileName
+		fileName := httpProviderFileName(endpoint)
+		if current, ok := named.HTTPEndpoints[fileName]; !ok || !hidesOwnRole(role, current.Role, endpoint.Role) {
+			named.HTTPEndpoints[fileName] = endpoint
 		}
-		j.logProviders.HTTPEndpoints[fileName] = endpoint
 	}
 
-	// trim left "/" and replace all slashes with underscores.
 	for _, fileProvider := range providers.LocalFiles {
-		key := strings.Replace(strings.TrimLeft(fileProvider.Location, "/"), "/", "_", -1)
-		j.logProviders.LocalFiles[key] = fileProvider
+		key := fileProviderKey(fileProvider)
+		if current, ok := named.LocalFiles[key]; !ok || !hidesOwnRole(role, current.Role, fileProvider.Role) {
+			named.LocalFiles[key] = fileProvider
+		}
 	}
 
-	// sanitize command to use as filename
 	for _, commandProvider := range providers.LocalCommands {
 		if len(commandProvider.Command) > 0 {
-			cmdWithArgs := strings.Join(commandProvider.Command, "_")
-			trimmedCmdWithArgs := strings.Replace(cmdWithArgs, "/", "", -1)
-			key := fmt.Sprintf("%s.output", trimmedCmdWithArgs)
-			j.logProviders.LocalCommands[key] = commandProvider
+			key := commandProviderKey(commandProvider)
+			if current, ok := named.LocalCommands[key]; !ok || !hidesOwnRole(role, current.Role, commandProvider.Role) {
+				named.LocalCommands[key] = commandProvider
+			}
 		}
 	}
 
-	j.client = util.NewHTTPClient(j.Cfg.GetSingleEntryTimeout(), j.Transport)
+	return named, nil
+}
 
-	return nil
+// hidesOwnRole reports if an entry collected on the given roles would replace the entry of this node.
+func hidesOwnRole(myRole string, currentRoles, roles []string) bool {
+	return roleMatched(myRole, currentRoles) && !roleMatched(myRole, roles)
 }
 
 func roleMatched(myRole string, roles []string) bool {
@@ -966,14 +1050,18 @@ func roleMatched(myRole string, roles []string) bool {
 	return util.IsInList(myRole, roles)
 }
 
-func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity string) (r io.ReadCloser, err error) {
+// dispatchLogs returns logs of the entity from the given provider. Journal options are used by units provider.
+// Files are read from NumFromTail lines before the end and followed if Follow is set.
+// Output of followed commands is streamed while they run instead of being returned when they finish.
+func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity string, options units.JournalOptions) (r io.ReadCloser, err error) {
+	providers := j.getLogProviders()
 	myRole, err := j.DCOSTools.GetNodeRole()
 	if err != nil {
 		return r, fmt.Errorf("could not get a node role: %s", err)
 	}
 
 	if provider == "units" {
-		endpoint, ok := j.logProviders.HTTPEndpoints[entity]
+		endpoint, ok := providers.HTTPEndpoints[entity]
 		if !ok {
 			return r, errors.New("Not found " + entity)
 		}
@@ -982,16 +1070,19 @@ func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity stri
 			return r, errors.New("Only DC/OS systemd units are available")
 		}
 		logrus.Debugf("dispatching a Unit %s", entity)
-		duration, err := time.ParseDuration(j.Cfg.FlagDiagnosticsBundleUnitsLogsSinceString)
+		duration, err := time.ParseDuration(j.config().FlagDiagnosticsBundleUnitsLogsSinceString)
 		if err != nil {
-			return r, fmt.Errorf("error parsing '%s': %s", j.Cfg.FlagDiagnosticsBundleUnitsLogsSinceString, err.Error())
+			return r, fmt.Errorf("error parsing '%s': %s", j.config().FlagDiagnosticsBundleUnitsLogsSinceString, err.Error())
 		}
-		return units.ReadJournalOutputSince(ctx, entity, duration)
+		if !options.Follow && options.NumFromTail == 0 {
+			options.Since = duration
+		}
+		return units.ReadJournal(ctx, entity, options)
 	}
 
 	if provider == "files" {
 		logrus.Debugf("dispatching a file %s", entity)
-		fileProvider, ok := j.logProviders.LocalFiles[entity]
+		fileProvider, ok := providers.LocalFiles[entity]
 		if !ok {
 			return r, errors.New("Not found " + entity)
 		}
@@ -1001,7 +1092,16 @@ func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity stri
 		}
 		logrus.Debugf("Found a file %s", fileProvider.Location)
 
-		file, err := os.Open(fileProvider.Location)
+		// globs return the list of selected files, they are read with dispatchGlobFile
+		if glob, ok := fileCollector(fileProvider).(*collector.Glob); ok {
+			list, err := glob.Collect(ctx)
+			if err != nil && fileProvider.Optional {
+				return ioutil.NopCloser(bytes.NewReader([]byte(err.Error()))), nil
+			}
+			return list, err
+		}
+
+		file, err := diagio.TailFile(ctx, fileProvider.Location, options.NumFromTail, options.Follow)
 		if err != nil && fileProvider.Optional {
 			return ioutil.NopCloser(bytes.NewReader([]byte(err.Error()))), nil
 		}
@@ -1009,7 +1109,7 @@ func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity stri
 	}
 	if provider == "cmds" {
 		logrus.Debugf("dispatching a command %s", entity)
-		cmdProvider, ok := j.logProviders.LocalCommands[entity]
+		cmdProvider, ok := providers.LocalCommands[entity]
 		if !ok {
 			return r, errors.New("Not found " + entity)
 		}
@@ -1019,6 +1119,9 @@ func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity stri
 		}
 
 		cmd := exec.CommandContext(ctx, cmdProvider.Command[0], cmdProvider.Command[1:]...)
+		if options.Follow {
+			return streamCommand(cmd, cmdProvider.Optional)
+		}
 		output, err := cmd.CombinedOutput()
 		if err != nil && cmdProvider.Optional {
 			// combine output with error
@@ -1031,6 +1134,104 @@ func (j *DiagnosticsJob) dispatchLogs(ctx context.Context, provider, entity stri
 	return r, errors.New("Unknown provider " + provider)
 }
 
+// streamCommand starts the command and returns a reader of its combined output. An error of optional command
+// is appended to the output, otherwise it is returned from Read.
+func streamCommand(cmd *exec.Cmd, optional bool) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		if optional {
+			return ioutil.NopCloser(strings.NewReader(err.Error() + "\n")), nil
+		}
+		return nil, err
+	}
+	go func() {
+		err := cmd.Wait()
+		if err != nil && optional {
+			io.WriteString(w, err.Error()+"\n")
+			err = nil
+		}
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
+// logSearchRequest describes which lines of a DC/OS unit journal or a configured file are searched.
+type logSearchRequest struct {
+	unit  string
+	file  string
+	match search.Matcher
+	since time.Duration
+	limit int
+}
+
+// searchLogs returns lines of a DC/OS unit journal or a configured file matching the request.
+func (j *DiagnosticsJob) searchLogs(ctx context.Context, req logSearchRequest) ([]search.Result, error) {
+	providers := j.getLogProviders()
+	myRole, err := j.DCOSTools.GetNodeRole()
+	if err != nil {
+		return nil, fmt.Errorf("could not get a node role: %s", err)
+	}
+
+	if req.unit != "" {
+		endpoint, ok := providers.HTTPEndpoints[req.unit]
+		if !ok {
+			return nil, errors.New("Not found " + req.unit)
+		}
+		if !roleMatched(myRole, endpoint.Role) {
+			return nil, errors.New("Only DC/OS systemd units are available")
+		}
+		return search.Journal(ctx, req.unit, req.since, req.match, req.limit)
+	}
+
+	fileProvider, ok := providers.LocalFiles[req.file]
+	if !ok {
+		return nil, errors.New("Not found " + req.file)
+	}
+	if !roleMatched(myRole, fileProvider.Role) {
+		return nil, errors.New("Not allowed to read a file")
+	}
+	results, err := search.File(ctx, fileProvider.Location, req.since, req.match, req.limit)
+	for i := range results {
+		results[i].Source = req.file
+	}
+	return results, err
+}
+
+// dispatchGlobFile returns the file selected by the glob of the entity. The file is given with its path
+// relative to the glob root and could be read only if it is still selected by the glob.
+func (j *DiagnosticsJob) dispatchGlobFile(ctx context.Context, entity, rel string, options units.JournalOptions) (io.ReadCloser, error) {
+	providers := j.getLogProviders()
+	myRole, err := j.DCOSTools.GetNodeRole()
+	if err != nil {
+		return nil, fmt.Errorf("could not get a node role: %s", err)
+	}
+
+	fileProvider, ok := providers.LocalFiles[entity]
+	if !ok {
+		return nil, errors.New("Not found " + entity)
+	}
+	if !roleMatched(myRole, fileProvider.Role) {
+		return nil, errors.New("Not allowed to read a file")
+	}
+	glob, ok := fileCollector(fileProvider).(*collector.Glob)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a directory or a pattern", entity)
+	}
+
+	files, err := glob.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.Rel == rel {
+			return diagio.TailFile(ctx, f.Path, options.NumFromTail, options.Follow)
+		}
+	}
+	return nil, fmt.Errorf("Not found %s in %s", rel, entity)
+}
+
 // the summary report is a file added to a zip bundle file to track any errors occurred during collection logs.
 func updateSummaryReportBuffer(prefix string, err string, r *bytes.Buffer) {
 	r.WriteString(fmt.Sprintf("%s [%s] %s \n", time.Now().String(), prefix, err))
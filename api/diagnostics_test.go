@@ -2,6 +2,7 @@ package api
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/dcos/dcos-diagnostics/dcos"
 	"github.com/dcos/dcos-diagnostics/mocks"
+	"github.com/dcos/dcos-diagnostics/units"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -135,7 +137,7 @@ func TestGetLogsEndpoints(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	endpoints, err := job.getLogsEndpoints()
+	endpoints, err := job.getLogsEndpoints(context.TODO())
 	assert.NoError(t, err)
 
 	const logPath = ":1050/system/health/v1/logs/"
@@ -179,7 +181,7 @@ func TestDispatchLogsForCommand(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	r, err := job.dispatchLogs(context.TODO(), "cmds", "echo_OK.output")
+	r, err := job.dispatchLogs(context.TODO(), "cmds", "echo_OK.output", units.DefaultJournalOptions())
 	assert.NoError(t, err)
 
 	data, err := ioutil.ReadAll(r)
@@ -194,7 +196,7 @@ func TestDispatchLogsForCommandThatNotExistsButIsOptional(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	r, err := job.dispatchLogs(context.TODO(), "cmds", "does_not_exist.output")
+	r, err := job.dispatchLogs(context.TODO(), "cmds", "does_not_exist.output", units.DefaultJournalOptions())
 	require.NoError(t, err)
 
 	data, err := ioutil.ReadAll(r)
@@ -209,7 +211,7 @@ func TestDispatchLogsForCommandThatNotExistsAndIsRequired(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	_, err = job.dispatchLogs(context.TODO(), "cmds", "does_not_exist_required.output")
+	_, err = job.dispatchLogs(context.TODO(), "cmds", "does_not_exist_required.output", units.DefaultJournalOptions())
 	assert.Error(t, err)
 }
 
@@ -225,7 +227,7 @@ func TestDispatchLogsForFiles(t *testing.T) {
 
 	job.logProviders.LocalFiles = map[string]FileProvider{"ok": {Location: f.Name()}}
 
-	r, err := job.dispatchLogs(context.TODO(), "files", "ok")
+	r, err := job.dispatchLogs(context.TODO(), "files", "ok", units.DefaultJournalOptions())
 	assert.NoError(t, err)
 
 	data, err := ioutil.ReadAll(r)
@@ -233,6 +235,122 @@ func TestDispatchLogsForFiles(t *testing.T) {
 	assert.Equal(t, "OK", string(data))
 }
 
+func TestDispatchLogsForFilesTail(t *testing.T) {
+	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
+
+	f, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	_, err = f.WriteString("first\nsecond\nthird\n")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	job.logProviders.LocalFiles = map[string]FileProvider{"ok": {Location: f.Name()}}
+
+	options := units.DefaultJournalOptions()
+	options.NumFromTail = 2
+	r, err := job.dispatchLogs(context.TODO(), "files", "ok", options)
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "second\nthird\n", string(data))
+}
+
+func TestDispatchLogsForGlob(t *testing.T) {
+	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
+
+	dir, err := ioutil.TempDir("", "glob")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.log"), []byte("OK"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("not selected"), 0644))
+
+	job.logProviders.LocalFiles = map[string]FileProvider{"logs": {Location: filepath.Join(dir, "*.log")}}
+
+	endpoints, err := job.getLogsEndpoints(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]endpointSpec{
+		filepath.Join(dir, "a.log"): {PortAndPath: ":1050/system/health/v1/logs/files/logs?path=a.log"},
+	}, endpoints)
+
+	r, err := job.dispatchLogs(context.TODO(), "files", "logs", units.DefaultJournalOptions())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "a.log\t2\t")
+
+	r, err = job.dispatchGlobFile(context.TODO(), "logs", "a.log", units.DefaultJournalOptions())
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, "OK", string(data))
+
+	_, err = job.dispatchGlobFile(context.TODO(), "logs", "b.txt", units.DefaultJournalOptions())
+	assert.EqualError(t, err, "Not found b.txt in logs")
+	_, err = job.dispatchGlobFile(context.TODO(), "logs", "../a.log", units.DefaultJournalOptions())
+	assert.Error(t, err)
+}
+
+func TestDispatchLogsForFollowedFile(t *testing.T) {
+	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
+
+	f, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	_, err = f.WriteString("first\nsecond\n")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	job.logProviders.LocalFiles = map[string]FileProvider{"ok": {Location: f.Name()}}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	options := units.DefaultJournalOptions()
+	options.NumFromTail = 1
+	options.Follow = true
+	r, err := job.dispatchLogs(ctx, "files", "ok", options)
+	require.NoError(t, err)
+	defer r.Close()
+
+	lines := bufio.NewReader(r)
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", line)
+
+	_, err = f.WriteString("third\n")
+	require.NoError(t, err)
+	line, err = lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", line)
+
+	cancel()
+	_, err = lines.ReadString('\n')
+	assert.EqualError(t, err, "context canceled")
+}
+
+func TestDispatchLogsForFollowedCommand(t *testing.T) {
+	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
+	job.Cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{filepath.Join("testdata", "endpoint-config.json")}
+
+	err := job.Init()
+	require.NoError(t, err)
+
+	options := units.DefaultJournalOptions()
+	options.Follow = true
+
+	r, err := job.dispatchLogs(context.TODO(), "cmds", "echo_OK.output", options)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "OK\n", string(data))
+
+	r, err = job.dispatchLogs(context.TODO(), "cmds", "does_not_exist.output", options)
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `exec: "does": executable file not found in `)
+}
+
 func TestDispatchLogsForOptionalFileThatNotExists(t *testing.T) {
 	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
 	job.Cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{filepath.Join("testdata", "endpoint-config-2.json")}
@@ -240,7 +358,7 @@ func TestDispatchLogsForOptionalFileThatNotExists(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	r, err := job.dispatchLogs(context.TODO(), "files", "not_existing_file")
+	r, err := job.dispatchLogs(context.TODO(), "files", "not_existing_file", units.DefaultJournalOptions())
 	require.NoError(t, err)
 
 	data, err := ioutil.ReadAll(r)
@@ -259,7 +377,7 @@ func TestDispatchLogsForUnit(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	r, err := job.dispatchLogs(context.TODO(), "units", "unit_a")
+	r, err := job.dispatchLogs(context.TODO(), "units", "unit_a", units.DefaultJournalOptions())
 	assert.NoError(t, err)
 
 	data, err := ioutil.ReadAll(r)
@@ -278,7 +396,7 @@ func TestDispatchLogsForUnit_Windows(t *testing.T) {
 	err := job.Init()
 	require.NoError(t, err)
 
-	r, err := job.dispatchLogs(context.TODO(), "units", "unit_a")
+	r, err := job.dispatchLogs(context.TODO(), "units", "unit_a", units.DefaultJournalOptions())
 	assert.Nil(t, r)
 	assert.EqualError(t, err, "there is no journal on Windows")
 }
@@ -286,7 +404,7 @@ func TestDispatchLogsForUnit_Windows(t *testing.T) {
 func TestDispatchLogsWithUnknownProvider(t *testing.T) {
 	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
 
-	r, err := job.dispatchLogs(context.TODO(), "unknown", "echo_OK.output")
+	r, err := job.dispatchLogs(context.TODO(), "unknown", "echo_OK.output", units.DefaultJournalOptions())
 	assert.EqualError(t, err, "Unknown provider unknown")
 	assert.Nil(t, r)
 }
@@ -295,7 +413,7 @@ func TestDispatchLogsWithUnknownEntity(t *testing.T) {
 	job := DiagnosticsJob{Cfg: testCfg(), DCOSTools: &fakeDCOSTools{}}
 
 	for _, provider := range []string{"cmds", "files", "units"} {
-		r, err := job.dispatchLogs(context.TODO(), provider, "unknown-entity")
+		r, err := job.dispatchLogs(context.TODO(), provider, "unknown-entity", units.DefaultJournalOptions())
 		assert.EqualError(t, err, "Not found unknown-entity")
 		assert.Nil(t, r)
 	}
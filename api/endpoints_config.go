@@ -0,0 +1,461 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/jsonschema"
+	"github.com/dcos/dcos-diagnostics/util"
+)
+
+// EndpointsConfigSchema is the JSON Schema of endpoints config files
+const EndpointsConfigSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "dcos-diagnostics endpoints config",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "HTTPEndpoints": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["Port", "Uri"],
+        "properties": {
+          "Port": {"type": "integer", "minimum": 1, "maximum": 65535},
+          "Uri": {"type": "string", "pattern": "^/"},
+          "FileName": {"type": "string", "minLength": 1},
+          "Role": {"$ref": "#/definitions/role"},
+          "Optional": {"type": "boolean"},
+          "Timeout": {"$ref": "#/definitions/timeout"},
+          "MaxBytes": {"$ref": "#/definitions/maxBytes"},
+          "Retries": {"$ref": "#/definitions/retries"},
+          "TailOnly": {"$ref": "#/definitions/tailOnly"},
+          "Compression": {"$ref": "#/definitions/compression"},
+          "Tags": {"$ref": "#/definitions/tags"},
+          "Samples": {"type": "integer", "minimum": 1, "maximum": 1000, "description": "Poll the endpoint the given number of times and store the time series of its metrics"},
+          "Interval": {"$ref": "#/definitions/interval"},
+          "SampleFormat": {"type": "string", "enum": ["jsonl", "csv"], "description": "Format of the time series"},
+          "Families": {"type": "array", "items": {"type": "string", "minLength": 1}, "description": "Prometheus metric families to keep, all if empty"}
+        }
+      }
+    },
+    "LocalFiles": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["Location"],
+        "properties": {
+          "Location": {"type": "string", "minLength": 1, "description": "Absolute path of the file, the directory or a shell pattern of files"},
+          "Role": {"$ref": "#/definitions/role"},
+          "Optional": {"type": "boolean"},
+          "Timeout": {"$ref": "#/definitions/timeout"},
+          "MaxBytes": {"$ref": "#/definitions/maxBytes"},
+          "Retries": {"$ref": "#/definitions/retries"},
+          "TailOnly": {"$ref": "#/definitions/tailOnly"},
+          "Compression": {"$ref": "#/definitions/compression"},
+          "Tags": {"$ref": "#/definitions/tags"},
+          "Include": {"$ref": "#/definitions/patterns", "description": "Collect only files matching one of the patterns"},
+          "Exclude": {"$ref": "#/definitions/patterns", "description": "Skip files and directories matching one of the patterns"},
+          "MaxDepth": {"type": "integer", "minimum": 1, "description": "How deep below the directory files are collected"},
+          "MaxFiles": {"type": "integer", "minimum": 1, "description": "Maximum number of collected files"},
+          "MaxTotalBytes": {"type": "integer", "minimum": 1, "description": "Maximum total size of collected files"},
+          "Newest": {"type": "integer", "minimum": 1, "description": "Collect only the given number of most recently modified files"},
+          "Symlinks": {"type": "string", "enum": ["skip", "follow", "within-root"], "description": "Symbolic links policy"}
+        }
+      }
+    },
+    "LocalCommands": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["Command"],
+        "properties": {
+          "Command": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+          "Role": {"$ref": "#/definitions/role"},
+          "Optional": {"type": "boolean"},
+          "Timeout": {"$ref": "#/definitions/timeout"},
+          "MaxBytes": {"$ref": "#/definitions/maxBytes"},
+          "Retries": {"$ref": "#/definitions/retries"},
+          "TailOnly": {"$ref": "#/definitions/tailOnly"},
+          "Compression": {"$ref": "#/definitions/compression"},
+          "Tags": {"$ref": "#/definitions/tags"}
+        }
+      }
+    },
+    "Containers": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "Name": {"type": "string", "minLength": 1, "description": "Directory of collected files, containers if empty"},
+          "DockerSocket": {"$ref": "#/definitions/absolutePath", "description": "Docker API socket, /var/run/docker.sock if empty"},
+          "ContainerdSocket": {"$ref": "#/definitions/absolutePath", "description": "containerd socket, /run/containerd/containerd.sock if empty"},
+          "ContainerdState": {"$ref": "#/definitions/absolutePath", "description": "containerd state directory, /run/containerd if empty"},
+          "ContainerdLogDir": {"$ref": "#/definitions/absolutePath", "description": "Logs of containers created with the containerd CRI plugin, /var/log/pods if empty"},
+          "MesosRuntimeDir": {"$ref": "#/definitions/absolutePath", "description": "Mesos containerizer runtime directory, /var/run/mesos/containers if empty"},
+          "MesosWorkDir": {"$ref": "#/definitions/absolutePath", "description": "Mesos agent work directory with container sandboxes, /var/lib/mesos/slave if empty"},
+          "CgroupRoot": {"$ref": "#/definitions/absolutePath", "description": "Where cgroup hierarchies are mounted, /sys/fs/cgroup if empty"},
+          "LogLines": {"type": "integer", "minimum": 1, "description": "Number of last log lines of every container"},
+          "LogMaxBytes": {"type": "integer", "minimum": 1, "description": "Keep only the last bytes of every container log"},
+          "Role": {"$ref": "#/definitions/role"},
+          "Optional": {"type": "boolean"},
+          "Timeout": {"$ref": "#/definitions/timeout"},
+          "MaxBytes": {"$ref": "#/definitions/maxBytes"},
+          "Retries": {"$ref": "#/definitions/retries"},
+          "TailOnly": {"$ref": "#/definitions/tailOnly"},
+          "Compression": {"$ref": "#/definitions/compression"},
+          "Tags": {"$ref": "#/definitions/tags"}
+        }
+      }
+    },
+    "TLSEndpoints": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["Name", "Port"],
+        "properties": {
+          "Name": {"type": "string", "pattern": "^[A-Za-z0-9._-]+$", "description": "Identifies the endpoint, its health is reported as tls-<Name> unit"},
+          "Host": {"type": "string", "minLength": 1, "description": "Host of the endpoint, the daemon hostname if empty"},
+          "Port": {"type": "integer", "minimum": 1, "maximum": 65535},
+          "ServerName": {"type": "string", "minLength": 1, "description": "Name sent in SNI and verified against certificate SANs, Host if empty"},
+          "Role": {"$ref": "#/definitions/role"}
+        }
+      }
+    }
+  },
+  "definitions": {
+    "absolutePath": {
+      "type": "string",
+      "pattern": "^/"
+    },
+    "role": {
+      "type": "array",
+      "description": "Roles of nodes the entry is collected on, all roles if empty",
+      "items": {"type": "string", "enum": ["master", "agent", "agent_public"]}
+    },
+    "timeout": {
+      "type": "string",
+      "description": "Limits how long the collection could take e.g., 30s. The default collector timeout is used if empty",
+      "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+    },
+    "interval": {
+      "type": "string",
+      "description": "Interval between samples e.g., 10s",
+      "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+    },
+    "maxBytes": {
+      "type": "integer",
+      "description": "Collected data is truncated with a marker when it exceeds the limit",
+      "minimum": 1
+    },
+    "retries": {
+      "type": "integer",
+      "description": "How many times failed collection is retried",
+      "minimum": 0,
+      "maximum": 10
+    },
+    "tailOnly": {
+      "type": "boolean",
+      "description": "Keep the last MaxBytes of data instead of the first ones"
+    },
+    "compression": {
+      "type": "string",
+      "description": "How collected data is stored in bundles",
+      "enum": ["none", "gzip"]
+    },
+    "patterns": {
+      "type": "array",
+      "items": {"type": "string", "minLength": 1}
+    },
+    "tags": {
+      "type": "array",
+      "description": "Free form labels of the entry",
+      "items": {"type": "string", "minLength": 1}
+    }
+  }
+}
+`
+
+var endpointsConfigSchema = mustCompileSchema(EndpointsConfigSchema)
+
+func mustCompileSchema(schema string) *jsonschema.Schema {
+	s, err := jsonschema.Compile([]byte(schema))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Severity tells if a config issue prevents the config from being used.
+type Severity string
+
+// Config issue severities
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// ConfigIssue is a problem found in an endpoints config file.
+type ConfigIssue struct {
+	File     string
+	Line     int // 0 if the issue concerns the whole file
+	Column   int
+	Path     string // points to the value e.g., HTTPEndpoints[0].Port
+	Severity Severity
+	Message  string
+}
+
+func (i ConfigIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Column)
+	}
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", location, i.Severity, i.Path, i.Message)
+}
+
+// LintOptions enables checks that depend on the host the config is used on.
+type LintOptions struct {
+	// CheckCommands reports commands that could not be found on this host as warnings.
+	CheckCommands bool
+	// Role limits host checks to entries collected on nodes with the role. All entries are checked if empty.
+	Role string
+}
+
+// LintEndpointsConfigs validates the endpoints config files against the schema and reports entries that
+// would not be collected as expected: relative file paths and file names used more than once for the same role.
+// Entries of later files override entries of earlier ones with the same file name, so file names are only
+// required to be unique within a single file.
+func LintEndpointsConfigs(files []string, options LintOptions) []ConfigIssue {
+	var issues []ConfigIssue
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			issues = append(issues, ConfigIssue{File: file, Severity: SeverityError, Message: fmt.Sprintf("could not read: %s", err)})
+			continue
+		}
+		_, fileIssues := parseEndpointsConfig(file, content, options)
+		issues = append(issues, fileIssues...)
+	}
+	return issues
+}
+
+// parseEndpointsConfig validates and decodes the content of an endpoints config file.
+// Providers are returned only when no errors were found.
+func parseEndpointsConfig(file string, content []byte, options LintOptions) (LogProviders, []ConfigIssue) {
+	var providers LogProviders
+
+	issue := func(offset int64, path string, severity Severity, message string) ConfigIssue {
+		line, column := jsonschema.Position(content, offset)
+		return ConfigIssue{File: file, Line: line, Column: column, Path: path, Severity: severity, Message: message}
+	}
+
+	doc, err := jsonschema.Parse(content)
+	if err != nil {
+		if e, ok := err.(jsonschema.Error); ok {
+			return providers, []ConfigIssue{issue(e.Offset, "", SeverityError, e.Message)}
+		}
+		return providers, []ConfigIssue{issue(0, "", SeverityError, err.Error())}
+	}
+
+	var issues []ConfigIssue
+	for _, e := range endpointsConfigSchema.Validate(doc) {
+		issues = append(issues, issue(e.Offset, e.Path, SeverityError, e.Message))
+	}
+	if len(issues) > 0 {
+		// semantic checks need valid entries
+		return providers, issues
+	}
+
+	if err := json.Unmarshal(content, &providers); err != nil {
+		return providers, []ConfigIssue{issue(0, "", SeverityError, err.Error())}
+	}
+
+	names := fileNames{}
+	for i, p := range providers.HTTPEndpoints {
+		entry := doc.Member("HTTPEndpoints").Items[i]
+		entryPath := fmt.Sprintf("HTTPEndpoints[%d]", i)
+		if used := names.add("HTTPEndpoints", httpProviderFileName(p), p.Role, entryPath); used != "" {
+			issues = append(issues, issue(entry.Offset, entryPath, SeverityError, used))
+		}
+		if p.TailOnly && p.MaxBytes == 0 {
+			issues = append(issues, issue(entry.Member("TailOnly").Offset, entryPath+".TailOnly", SeverityWarning, tailOnlyWithoutMaxBytes))
+		}
+		if p.Samples == 0 {
+			for _, field := range []string{"Interval", "SampleFormat", "Families"} {
+				if v := entry.Member(field); v != nil {
+					issues = append(issues, issue(v.Offset, entryPath+"."+field, SeverityWarning, "has no effect without Samples"))
+				}
+			}
+		}
+	}
+	for i, p := range providers.LocalFiles {
+		entry := doc.Member("LocalFiles").Items[i]
+		entryPath := fmt.Sprintf("LocalFiles[%d]", i)
+		if !filepath.IsAbs(p.Location) && !path.IsAbs(filepath.ToSlash(p.Location)) {
+			issues = append(issues, issue(entry.Member("Location").Offset, entryPath+".Location", SeverityError,
+				fmt.Sprintf("%q is not an absolute path", p.Location)))
+		}
+		if _, pattern := collector.SplitGlob(p.Location); pattern != "" {
+			if _, err := path.Match(pattern, ""); err != nil {
+				issues = append(issues, issue(entry.Member("Location").Offset, entryPath+".Location", SeverityError,
+					fmt.Sprintf("invalid pattern %q: %s", pattern, err)))
+			}
+		}
+		for _, field := range []string{"Include", "Exclude"} {
+			patterns := entry.Member(field)
+			if patterns == nil {
+				continue
+			}
+			for j, pattern := range patterns.Items {
+				if _, err := path.Match(pattern.String, ""); err != nil {
+					issues = append(issues, issue(pattern.Offset, fmt.Sprintf("%s.%s[%d]", entryPath, field, j), SeverityError,
+						fmt.Sprintf("invalid pattern %q: %s", pattern.String, err)))
+				}
+			}
+		}
+		if used := names.add("LocalFiles", fileProviderKey(p), p.Role, entryPath); used != "" {
+			issues = append(issues, issue(entry.Offset, entryPath, SeverityError, used))
+		}
+		if p.TailOnly && p.MaxBytes == 0 {
+			issues = append(issues, issue(entry.Member("TailOnly").Offset, entryPath+".TailOnly", SeverityWarning, tailOnlyWithoutMaxBytes))
+		}
+	}
+	for i, p := range providers.LocalCommands {
+		entry := doc.Member("LocalCommands").Items[i]
+		entryPath := fmt.Sprintf("LocalCommands[%d]", i)
+		if used := names.add("LocalCommands", commandProviderKey(p), p.Role, entryPath); used != "" {
+			issues = append(issues, issue(entry.Offset, entryPath, SeverityError, used))
+		}
+		if p.TailOnly && p.MaxBytes == 0 {
+			issues = append(issues, issue(entry.Member("TailOnly").Offset, entryPath+".TailOnly", SeverityWarning, tailOnlyWithoutMaxBytes))
+		}
+		if options.CheckCommands && (options.Role == "" || roleMatched(options.Role, p.Role)) {
+			if _, err := exec.LookPath(p.Command[0]); err != nil {
+				issues = append(issues, issue(entry.Member("Command").Offset, entryPath+".Command", SeverityWarning,
+					fmt.Sprintf("command %q not found", p.Command[0])))
+			}
+		}
+	}
+	for i, p := range providers.Containers {
+		entry := doc.Member("Containers").Items[i]
+		entryPath := fmt.Sprintf("Containers[%d]", i)
+		if used := names.add("Containers", p.name(), p.Role, entryPath); used != "" {
+			issues = append(issues, issue(entry.Offset, entryPath, SeverityError, used))
+		}
+		if p.TailOnly && p.MaxBytes == 0 {
+			issues = append(issues, issue(entry.Member("TailOnly").Offset, entryPath+".TailOnly", SeverityWarning, tailOnlyWithoutMaxBytes))
+		}
+	}
+	for i, p := range providers.TLSEndpoints {
+		entry := doc.Member("TLSEndpoints").Items[i]
+		entryPath := fmt.Sprintf("TLSEndpoints[%d]", i)
+		if used := names.add("TLSEndpoints", p.Name, p.Role, entryPath); used != "" {
+			issues = append(issues, issue(entry.Offset, entryPath, SeverityError, used))
+		}
+	}
+
+	if hasErrors(issues) {
+		return LogProviders{}, issues
+	}
+	return providers, issues
+}
+
+// tailOnlyWithoutMaxBytes is reported for entries that ask for the tail of data but do not limit its size.
+const tailOnlyWithoutMaxBytes = "has no effect without MaxBytes"
+
+// fileNames tracks file names given to entries of every kind and roles they are collected on.
+type fileNames map[string]map[string][]fileNameUse
+
+type fileNameUse struct {
+	roles []string
+	path  string
+}
+
+// add records the file name and returns a description of the conflict if the name
+// is already used by an entry of the same kind collected on a node with the same role.
+func (n fileNames) add(kind, name string, roles []string, path string) string {
+	if n[kind] == nil {
+		n[kind] = map[string][]fileNameUse{}
+	}
+	for _, use := range n[kind][name] {
+		if role, ok := commonRole(use.roles, roles); ok {
+			return fmt.Sprintf("file name %q is already used by %s for role %s", name, use.path, role)
+		}
+	}
+	n[kind][name] = append(n[kind][name], fileNameUse{roles: roles, path: path})
+	return ""
+}
+
+// commonRole returns a role both entries are collected on. Empty roles mean all roles.
+func commonRole(a, b []string) (string, bool) {
+	all := []string{dcos.MasterRole, dcos.AgentRole, dcos.AgentPublicRole}
+	if len(a) == 0 {
+		a = all
+	}
+	if len(b) == 0 {
+		b = all
+	}
+	for _, role := range a {
+		if util.IsInList(role, b) {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+func hasErrors(issues []ConfigIssue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// issuesError joins errors from issues into a single error, nil if there are none.
+func issuesError(issues []ConfigIssue) error {
+	var errs []string
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			errs = append(errs, i.String())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// httpProviderFileName returns the name of the file the endpoint is saved to.
+func httpProviderFileName(p HTTPProvider) string {
+	if p.FileName != "" {
+		return p.FileName
+	}
+	return fmt.Sprintf("%d-%s.json", p.Port, util.SanitizeString(p.URI))
+}
+
+// fileProviderKey returns the file location with left "/" trimmed and slashes replaced with underscores.
+func fileProviderKey(p FileProvider) string {
+	return strings.Replace(strings.TrimLeft(p.Location, "/"), "/", "_", -1)
+}
+
+// commandProviderKey returns the command sanitized to be used as a file name.
+func commandProviderKey(p CommandProvider) string {
+	cmdWithArgs := strings.Join(p.Command, "_")
+	return fmt.Sprintf("%s.output", strings.Replace(cmdWithArgs, "/", "", -1))
+}
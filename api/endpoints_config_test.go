@@ -0,0 +1,211 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/collector"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func issueStrings(issues []ConfigIssue) []string {
+	var s []string
+	for _, i := range issues {
+		s = append(s, i.String())
+	}
+	return s
+}
+
+func TestLintEndpointsConfigsValid(t *testing.T) {
+	issues := LintEndpointsConfigs([]string{
+		filepath.Join("testdata", "endpoint-config.json"),
+		filepath.Join("testdata", "endpoint-config-2.json"),
+		filepath.Join("testdata", "endpoint-config-3.json"),
+	}, LintOptions{})
+
+	assert.Empty(t, issues)
+}
+
+func TestLintEndpointsConfigsSchemaErrors(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-invalid.json")
+	issues := LintEndpointsConfigs([]string{file, "not/existing/file"}, LintOptions{})
+
+	require.Len(t, issues, 5)
+	assert.Equal(t, []string{
+		file + ":5:7: error: HTTPEndpoints[0].Url: unknown field, must be one of: Compression, Families, FileName, Interval, MaxBytes, Optional, Port, Retries, Role, SampleFormat, Samples, Tags, TailOnly, Timeout, Uri",
+		file + ":3:5: error: HTTPEndpoints[0]: missing required field Uri",
+		file + `:11:16: error: HTTPEndpoints[1].Role[0]: invalid value "masters", must be one of: master, agent, agent_public`,
+		file + ":16:18: error: LocalCommands[0].Command: must have at least 1 items",
+	}, issueStrings(issues[:4]))
+	assert.Equal(t, "not/existing/file", issues[4].File)
+	assert.Equal(t, SeverityError, issues[4].Severity)
+	assert.Contains(t, issues[4].Message, "could not read: ")
+}
+
+func TestLintEndpointsConfigsSemanticErrors(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-lint.json")
+
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + `:15:5: error: HTTPEndpoints[2]: file name "state.json" is already used by HTTPEndpoints[0] for role master`,
+		file + `:23:19: error: LocalFiles[0].Location: "var/log/mesos.log" is not an absolute path`,
+	}, issueStrings(issues))
+
+	issues = LintEndpointsConfigs([]string{file}, LintOptions{CheckCommands: true, Role: "master"})
+	require.Len(t, issues, 3)
+	assert.Equal(t, file+`:32:18: warning: LocalCommands[1].Command: command "does-not-exist-on-master" not found`, issues[2].String())
+
+	issues = LintEndpointsConfigs([]string{file}, LintOptions{CheckCommands: true})
+	assert.Len(t, issues, 4)
+}
+
+func TestLintEndpointsConfigsOptions(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-options.json")
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + ":24:19: warning: LocalCommands[0].TailOnly: has no effect without MaxBytes",
+	}, issueStrings(issues))
+
+	file = filepath.Join("testdata", "endpoint-config-options-invalid.json")
+	issues = LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + `:5:18: error: LocalFiles[0].Timeout: "30 seconds" does not match ^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		file + ":6:19: error: LocalFiles[0].MaxBytes: 0 is less than 1",
+		file + ":7:18: error: LocalFiles[0].Retries: 11 is greater than 10",
+		file + `:8:22: error: LocalFiles[0].Compression: invalid value "zip", must be one of: none, gzip`,
+	}, issueStrings(issues))
+}
+
+func TestLintEndpointsConfigsGlobs(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-glob.json")
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + `:11:19: error: LocalFiles[1].Exclude[0]: invalid pattern "[secret": syntax error in pattern`,
+		file + `:16:19: error: LocalFiles[2].Location: invalid pattern "[*.json": syntax error in pattern`,
+	}, issueStrings(issues))
+}
+
+func TestLintEndpointsConfigsSamplers(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-sampler.json")
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + ":22:19: warning: HTTPEndpoints[2].Interval: has no effect without Samples",
+	}, issueStrings(issues))
+}
+
+func TestLintEndpointsConfigsContainers(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-containers.json")
+	assert.Empty(t, LintEndpointsConfigs([]string{file}, LintOptions{}))
+
+	file = filepath.Join("testdata", "endpoint-config-containers-lint.json")
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + ":11:23: error: Containers[2].DockerSocket: \"run/docker.sock\" does not match ^/",
+		file + ":12:19: error: Containers[2].LogLines: 0 is less than 1",
+	}, issueStrings(issues))
+
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	content = []byte(strings.Replace(string(content), `,
+    {
+      "DockerSocket": "run/docker.sock",
+      "LogLines": 0
+    }`, "", 1))
+	_, issues = parseEndpointsConfig(file, content, LintOptions{})
+	assert.Equal(t, []string{
+		file + `:6:5: error: Containers[1]: file name "containers" is already used by Containers[0] for role agent`,
+		file + ":8:19: warning: Containers[1].TailOnly: has no effect without MaxBytes",
+	}, issueStrings(issues))
+}
+
+func TestLintEndpointsConfigsTLS(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-tls.json")
+	assert.Empty(t, LintEndpointsConfigs([]string{file}, LintOptions{}))
+
+	file = filepath.Join("testdata", "endpoint-config-tls-lint.json")
+	issues := LintEndpointsConfigs([]string{file}, LintOptions{})
+	assert.Equal(t, []string{
+		file + ":5:14: error: TLSEndpoints[2].Name: \"zoo keeper\" does not match ^[A-Za-z0-9._-]+$",
+		file + ":5:36: error: TLSEndpoints[2].Port: 0 is less than 1",
+	}, issueStrings(issues))
+
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	content = []byte(strings.Replace(string(content), `,
+    {"Name": "zoo keeper", "Port": 0}`, "", 1))
+	_, issues = parseEndpointsConfig(file, content, LintOptions{})
+	assert.Equal(t, []string{
+		file + `:4:5: error: TLSEndpoints[1]: file name "mesos" is already used by TLSEndpoints[0] for role agent`,
+	}, issueStrings(issues))
+}
+
+func TestFileCollector(t *testing.T) {
+	c := fileCollector(FileProvider{Location: "/var/log/mesos/mesos-agent.log*", FileSelection: FileSelection{Newest: 3}})
+	require.IsType(t, &collector.Glob{}, c)
+	assert.Equal(t, "var/log/mesos", c.Name())
+
+	dir, err := ioutil.TempDir("", "glob")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c = fileCollector(FileProvider{Location: dir})
+	require.IsType(t, &collector.Glob{}, c)
+	assert.Equal(t, strings.TrimLeft(filepath.ToSlash(dir), "/"), c.Name())
+
+	c = fileCollector(FileProvider{Location: "/var/log/mesos.log"})
+	assert.IsType(t, &collector.File{}, c)
+	assert.Equal(t, "var/log/mesos.log", c.Name())
+}
+
+func TestParseEndpointsConfigOptions(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-options.json")
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+
+	providers, _ := parseEndpointsConfig(file, content, LintOptions{})
+
+	options := providers.HTTPEndpoints[0].collectorOptions()
+	assert.Equal(t, collector.Options{Timeout: 30 * time.Second, Retries: 2, Compression: collector.CompressionGzip, Tags: []string{"mesos"}}, options)
+	options = providers.LocalFiles[0].collectorOptions()
+	assert.Equal(t, collector.Options{MaxBytes: 1048576, TailOnly: true}, options)
+}
+
+func TestParseEndpointsConfigSyntaxError(t *testing.T) {
+	providers, issues := parseEndpointsConfig("config.json", []byte("{\n  \"LocalFiles\": [\n    {\"Location\": \"/a\"}\n  ]\n"), LintOptions{})
+
+	assert.Equal(t, LogProviders{}, providers)
+	assert.Equal(t, []string{"config.json:5:1: error: unexpected end of JSON input"}, issueStrings(issues))
+}
+
+func TestLoadExternalProvidersReportsIssues(t *testing.T) {
+	file := filepath.Join("testdata", "endpoint-config-invalid.json")
+	_, err := loadExternalProviders([]string{file})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not parse "+file+": "+file+":5:7: error: HTTPEndpoints[0].Url: unknown field")
+}
+
+func TestNewLogProvidersKeepsEntriesOfOwnRole(t *testing.T) {
+	f, err := ioutil.TempFile("", "endpoints-config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"HTTPEndpoints": [
+		{"Port": 5050, "Uri": "/master/state", "FileName": "state.json", "Role": ["master"]},
+		{"Port": 5051, "Uri": "/state", "FileName": "state.json", "Role": ["agent", "agent_public"]}
+	]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{f.Name()}
+	providers, err := newLogProviders(cfg, &fakeDCOSTools{})
+	require.NoError(t, err)
+
+	assert.Equal(t, HTTPProvider{Port: 5050, URI: "/master/state", FileName: "state.json", Role: []string{"master"}},
+		providers.HTTPEndpoints["state.json"])
+}
@@ -1,20 +1,30 @@
 package api
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
+	"github.com/dcos/dcos-diagnostics/search"
+	"github.com/dcos/dcos-diagnostics/silence"
+	"github.com/dcos/dcos-diagnostics/units"
+	"github.com/dcos/dcos-diagnostics/util"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -26,6 +36,8 @@ type handler struct {
 	job                *DiagnosticsJob
 	systemdUnits       *SystemdUnits
 	monitoringResponse *MonitoringResponse
+	reloader           *ConfigReloader
+	silences           *silence.Store
 }
 
 // Route handlers
@@ -36,6 +48,9 @@ func (h *handler) unitsHealthStatus(w http.ResponseWriter, _ *http.Request) {
 		httpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// fingerprint is sent with health, so masters get it without another request
+	fingerprint := h.nodeFingerprint()
+	health.Fingerprint = &fingerprint
 
 	if err := json.NewEncoder(w).Encode(health); err != nil {
 		log.Errorf("Failed to encode responses to json: %s", err)
@@ -308,9 +323,114 @@ func (h *handler) createBundleHandler(w http.ResponseWriter, r *http.Request) {
 	writeCreateResponse(w, response)
 }
 
+// A handler function returns configuration fingerprint of the node compared by masters to detect drift.
+func (h *handler) nodeFingerprintHandler(w http.ResponseWriter, _ *http.Request) {
+	if err := json.NewEncoder(w).Encode(h.nodeFingerprint()); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
+func (h *handler) nodeFingerprint() drift.Fingerprint {
+	return drift.NewSource(h.cfg.FlagDriftConfigFiles).Fingerprint(os.Getenv("DCOS_VERSION"), config.Version)
+}
+
+// A handler function returns configuration drift of cluster nodes found by the last pull.
+func (h *handler) driftHandler(w http.ResponseWriter, _ *http.Request) {
+	report, err := h.monitoringResponse.GetDrift()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
+const silencesUnavailable = "silences are available on masters started with pull"
+
+// A handler function returns silences suppressing health now.
+func (h *handler) listSilencesHandler(w http.ResponseWriter, _ *http.Request) {
+	if h.silences == nil {
+		httpError(w, silencesUnavailable, http.StatusServiceUnavailable)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.silences.Active()); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
+// A handler function creates a silence of a node, a unit or a unit on a node.
+func (h *handler) createSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.silences == nil {
+		httpError(w, silencesUnavailable, http.StatusServiceUnavailable)
+		return
+	}
+	var req silence.Silence
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, fmt.Sprintf("could not parse silence: %s", err), http.StatusBadRequest)
+		return
+	}
+	created, err := h.silences.Add(silence.Silence{
+		Node:   req.Node,
+		Unit:   req.Unit,
+		Until:  req.Until,
+		Author: req.Author,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		httpError(w, fmt.Sprintf("could not create silence: %s", err), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
+// A handler function ends the silence before its time.
+func (h *handler) deleteSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.silences == nil {
+		httpError(w, silencesUnavailable, http.StatusServiceUnavailable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	err := h.silences.Delete(id)
+	if err == silence.ErrNotFound {
+		httpError(w, fmt.Sprintf("silence %s not found", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("could not delete silence: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// A handler function returns all silences known to the master, other masters merge them with their own.
+func (h *handler) nodeSilencesHandler(w http.ResponseWriter, _ *http.Request) {
+	if h.silences == nil {
+		httpError(w, silencesUnavailable, http.StatusServiceUnavailable)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.silences.All()); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
+// A handler function returning the config version and the result of its last reload.
+func (h *handler) configHandler(w http.ResponseWriter, _ *http.Request) {
+	if h.reloader == nil {
+		httpError(w, "config reloading is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.reloader.Status()); err != nil {
+		log.Errorf("Failed to encode responses to json: %s", err)
+	}
+}
+
 // A handler function to to get a list of available logs on a node.
-func (h *handler) logsListHandler(w http.ResponseWriter, _ *http.Request) {
-	endpoints, err := h.job.getLogsEndpoints()
+func (h *handler) logsListHandler(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.job.getLogsEndpoints(r.Context())
 	if err != nil {
 		response, _ := prepareResponseWithErr(http.StatusServiceUnavailable, err)
 		writeResponse(w, response)
@@ -324,11 +444,28 @@ func (h *handler) logsListHandler(w http.ResponseWriter, _ *http.Request) {
 // return a log for past N hours for a specific systemd Unit
 func (h *handler) getUnitLogHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	timeout := time.Duration(h.cfg.FlagCommandExecTimeoutSec) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	options, err := units.ParseJournalOptions(r.URL.Query())
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusBadRequest, err)
+		writeResponse(w, response)
+		return
+	}
+
+	// followed logs are streamed until the client disconnects
+	ctx := r.Context()
+	if !options.Follow {
+		var cancel context.CancelFunc
+		timeout := time.Duration(h.cfg.FlagCommandExecTimeoutSec) * time.Second
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	unitLogOut, err := h.job.dispatchLogs(ctx, vars["provider"], vars["entity"])
+	var unitLogOut io.ReadCloser
+	if rel := r.URL.Query().Get("path"); rel != "" && vars["provider"] == "files" {
+		unitLogOut, err = h.job.dispatchGlobFile(ctx, vars["entity"], rel, options)
+	} else {
+		unitLogOut, err = h.job.dispatchLogs(ctx, vars["provider"], vars["entity"], options)
+	}
 	if err != nil {
 		response, _ := prepareResponseWithErr(http.StatusServiceUnavailable, err)
 		writeResponse(w, response)
@@ -337,8 +474,32 @@ func (h *handler) getUnitLogHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer unitLogOut.Close()
 
+	sse := acceptsEventStream(r)
+	switch {
+	case sse:
+		w.Header().Set("Content-Type", eventStreamContentType)
+		w.Header().Set("Cache-Control", "no-cache")
+	case vars["provider"] == "units":
+		w.Header().Set("Content-Type", options.Format.ContentType())
+	case options.Follow:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	var out io.Writer = w
+	if flusher, ok := w.(http.Flusher); ok && (options.Follow || sse) {
+		out = flushWriter{w: w, flusher: flusher}
+	}
+
 	log.Infof("Start read %s", vars["entity"])
-	_, err = io.Copy(w, unitLogOut)
+	if sse {
+		err = writeEvents(out, unitLogOut)
+	} else {
+		_, err = io.Copy(out, unitLogOut)
+	}
+	if err != nil && options.Follow && r.Context().Err() != nil {
+		log.Infof("Client stopped following %s", vars["entity"])
+		return
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Error writing unit log of %s: %s\n", vars["entity"], err)
 		log.Info(msg)
@@ -347,6 +508,286 @@ func (h *handler) getUnitLogHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("Done read %s", vars["entity"])
 }
 
+// A handler function proxying a logs request to the node with the given IP, host name or Mesos ID.
+// It lets masters read and follow logs of any node.
+func (h *handler) nodeLogHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	node, err := h.findNode(vars["nodeid"])
+	if err != nil {
+		httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	port, err := getPullPortByRole(h.cfg, node.Role)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	scheme := "http"
+	if h.cfg.FlagForceTLS {
+		scheme = "https"
+	}
+
+	director := func(req *http.Request) {
+		req.URL.Scheme = scheme
+		req.URL.Host = net.JoinHostPort(node.IP, strconv.Itoa(port))
+		req.URL.Path = fmt.Sprintf("%s/logs/%s/%s", baseRoute, vars["provider"], vars["entity"])
+	}
+	proxy := &httputil.ReverseProxy{
+		Director:  director,
+		Transport: h.job.Transport,
+		// flush immediately so followed logs are not delayed
+		FlushInterval: -1,
+	}
+	// content type is set by the node
+	w.Header().Del("Content-type")
+	proxy.ServeHTTP(w, r)
+}
+
+// A handler function searching logs of a unit or a file on the current node.
+func (h *handler) nodeLogSearchHandler(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parseLogSearchRequest(r.URL.Query())
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusBadRequest, err)
+		writeResponse(w, response)
+		return
+	}
+
+	timeout := time.Duration(h.cfg.FlagCommandExecTimeoutSec) * time.Second
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	results, err := h.job.searchLogs(ctx, req)
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusServiceUnavailable, err)
+		writeResponse(w, response)
+		log.WithError(err).Warnf("Could NOT search logs for %s", r.URL)
+		return
+	}
+	writeSearchResults(w, results)
+}
+
+// A handler function searching logs of a unit or a file on the requested nodes. Each node is searched
+// by its own diagnostics API and results are merged by time.
+func (h *handler) logSearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req, err := h.parseLogSearchRequest(query)
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusBadRequest, err)
+		writeResponse(w, response)
+		return
+	}
+
+	requestedNodes := []string{All}
+	if n := query.Get("nodes"); n != "" {
+		requestedNodes = strings.Split(n, ",")
+	}
+	masters, err := h.tools.GetMasterNodes()
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusServiceUnavailable, fmt.Errorf("could not get master nodes: %s", err))
+		writeResponse(w, response)
+		return
+	}
+	agents, err := h.tools.GetAgentNodes()
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusServiceUnavailable, fmt.Errorf("could not get agent nodes: %s", err))
+		writeResponse(w, response)
+		return
+	}
+	nodes, err := matchRequestedNodes(requestedNodes, masters, agents)
+	if err != nil {
+		response, _ := prepareResponseWithErr(http.StatusBadRequest, err)
+		writeResponse(w, response)
+		return
+	}
+
+	// nodes return at most as many results as could be returned in total
+	query.Del("nodes")
+	query.Set("limit", strconv.Itoa(req.limit))
+
+	timeout := time.Duration(h.cfg.FlagCommandExecTimeoutSec) * time.Second
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	client := util.NewHTTPClient(timeout, h.job.Transport)
+	fetch := func(ctx context.Context, node dcos.Node, add func(search.Result)) error {
+		return h.fetchLogSearchResults(ctx, client, node, query, add)
+	}
+
+	// results are sent as nodes respond, so truncation is known at the end and it's sent in a trailer
+	w.Header().Set("Trailer", "X-Search-Truncated")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	failed := false
+	truncated := search.Cluster(ctx, nodes, h.cfg.FlagLogsSearchConcurrency, req.limit, fetch, func(result search.Result) {
+		if failed {
+			return
+		}
+		if err := encoder.Encode(result); err != nil {
+			log.Errorf("Failed to encode search result to json: %s", err)
+			failed = true
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if truncated {
+		w.Header().Set("X-Search-Truncated", "true")
+	}
+}
+
+func (h *handler) parseLogSearchRequest(query url.Values) (logSearchRequest, error) {
+	req := logSearchRequest{
+		unit:  query.Get("unit"),
+		file:  query.Get("file"),
+		limit: h.cfg.FlagLogsSearchLimit,
+	}
+	if (req.unit == "") == (req.file == "") {
+		return req, errors.New("exactly one of unit and file must be given")
+	}
+
+	q, regex := query.Get("q"), query.Get("regex")
+	if (q == "") == (regex == "") {
+		return req, errors.New("exactly one of q and regex must be given")
+	}
+	var err error
+	if req.match, err = search.NewMatcher(q+regex, regex != ""); err != nil {
+		return req, err
+	}
+
+	since := query.Get("since")
+	if since == "" {
+		// diagnostics-units-since could be reloaded, so the config applied to the job is used
+		since = h.job.config().FlagDiagnosticsBundleUnitsLogsSinceString
+	}
+	if req.since, err = time.ParseDuration(since); err != nil {
+		return req, fmt.Errorf("invalid since %q: %s", since, err)
+	}
+
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return req, fmt.Errorf("invalid limit %q, expected a positive number", l)
+		}
+		if limit < req.limit {
+			req.limit = limit
+		}
+	}
+	return req, nil
+}
+
+// fetchLogSearchResults searches logs on the node with its diagnostics API.
+func (h *handler) fetchLogSearchResults(ctx context.Context, client *http.Client, node dcos.Node, query url.Values,
+	add func(search.Result)) error {
+	port, err := getPullPortByRole(h.cfg, node.Role)
+	if err != nil {
+		return err
+	}
+	u, err := util.UseTLSScheme(fmt.Sprintf("http://%s:%d%s?%s", node.IP, port, nodeLogsSearchEndpoint, query.Encode()), h.cfg.FlagForceTLS)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request %s: %s", u, err)
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("could not search logs: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not search logs, got %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var result search.Result
+		if err := decoder.Decode(&result); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not decode search result: %s", err)
+		}
+		add(result)
+	}
+}
+
+// writeSearchResults writes results as newline delimited JSON.
+func writeSearchResults(w io.Writer, results []search.Result) {
+	encoder := json.NewEncoder(w)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			log.Errorf("Failed to encode search result to json: %s", err)
+			return
+		}
+	}
+}
+
+func (h *handler) findNode(id string) (dcos.Node, error) {
+	masters, err := h.tools.GetMasterNodes()
+	if err != nil {
+		return dcos.Node{}, fmt.Errorf("could not get master nodes: %s", err)
+	}
+	agents, err := h.tools.GetAgentNodes()
+	if err != nil {
+		return dcos.Node{}, fmt.Errorf("could not get agent nodes: %s", err)
+	}
+	for _, node := range append(masters, agents...) {
+		if id == node.IP || id == node.MesosID || id == node.Host {
+			return node, nil
+		}
+	}
+	return dcos.Node{}, fmt.Errorf("node %s not found", id)
+}
+
+const eventStreamContentType = "text/event-stream"
+
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header["Accept"] {
+		for _, t := range strings.Split(accept, ",") {
+			if strings.TrimSpace(strings.Split(t, ";")[0]) == eventStreamContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flushWriter flushes every write so streamed data reaches the client immediately.
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.flusher.Flush()
+	return n, err
+}
+
+// writeEvents writes every line read from r as a server-sent event.
+func writeEvents(w io.Writer, r io.Reader) error {
+	lines := bufio.NewReader(r)
+	for {
+		line, err := lines.ReadString('\n')
+		if line != "" {
+			if _, werr := fmt.Fprintf(w, "data: %s\n\n", strings.TrimSuffix(line, "\n")); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func httpError(w http.ResponseWriter, msg string, code int) {
 	log.WithField("Code", code).Error(msg)
 	http.Error(w, msg, code)
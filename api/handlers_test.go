@@ -9,15 +9,21 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
+	"github.com/dcos/dcos-diagnostics/silence"
 
 	"github.com/gorilla/mux"
 	assertPackage "github.com/stretchr/testify/assert"
+	requirePackage "github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -601,6 +607,43 @@ func (s *HandlersTestSuit) TestreportHandlerFunc() {
 	s.assert.Len(response.Nodes, 1)
 }
 
+func (s *HandlersTestSuit) TestDriftHandlerFunc() {
+	// Test endpoint /system/health/v1/drift
+	response, code, err := MakeHTTPRequest(s.T(), s.router, "/system/health/v1/drift", "GET", nil)
+	s.assert.NoError(err)
+	s.assert.Equal(http.StatusNotFound, code)
+	s.assert.Equal("drift report not found\n", string(response))
+
+	report := drift.Report{Drift: true, Roles: []drift.RoleDrift{{Role: "master", Baseline: "abc"}}}
+	s.dt.MR.UpdateMonitoringResponse(&MonitoringResponse{Drift: &report})
+
+	var got drift.Report
+	s.assert.NoError(json.Unmarshal(s.get("/system/health/v1/drift"), &got))
+	s.assert.Equal(report, got)
+}
+
+func (s *HandlersTestSuit) TestNodeFingerprintHandlerFunc() {
+	// Test endpoint /system/health/v1/node/fingerprint
+	f, err := ioutil.TempFile("", "")
+	s.Require().NoError(err)
+	defer os.Remove(f.Name())
+	s.dt.Cfg.FlagDriftConfigFiles = []string{f.Name()}
+
+	var fingerprint drift.Fingerprint
+	s.assert.NoError(json.Unmarshal(s.get("/system/health/v1/node/fingerprint"), &fingerprint))
+	s.assert.Equal(config.Version, fingerprint.DiagnosticsVersion)
+	// SHA-256 of an empty file
+	s.assert.Equal(map[string]string{f.Name(): "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}, fingerprint.Files)
+	s.assert.NotEmpty(fingerprint.Hash)
+}
+
+func (s *HandlersTestSuit) TestSilencesUnavailableWithoutStore() {
+	response, code, err := MakeHTTPRequest(s.T(), s.router, "/system/health/v1/silences", "GET", nil)
+	s.assert.NoError(err)
+	s.assert.Equal(http.StatusServiceUnavailable, code)
+	s.assert.Equal("silences are available on masters started with pull\n", string(response))
+}
+
 func (s *HandlersTestSuit) TestIsInListFunc() {
 	array := []string{"DC", "OS", "SYS"}
 	s.assert.Contains(array, "DC")
@@ -611,3 +654,238 @@ func (s *HandlersTestSuit) TestIsInListFunc() {
 func TestHandlersTestSuit(t *testing.T) {
 	suite.Run(t, new(HandlersTestSuit))
 }
+
+func TestSilencesHandlers(t *testing.T) {
+	store, err := silence.NewStore("")
+	requirePackage.NoError(t, err)
+	router := NewRouter(&Dt{Cfg: testCfg(), DtDCOSTools: &fakeDCOSTools{}, MR: &MonitoringResponse{}, Silences: store})
+
+	response, code, err := MakeHTTPRequest(t, router, "/system/health/v1/silences", "POST", strings.NewReader(`{"node": "10.0.0.1"}`))
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, http.StatusBadRequest, code)
+	assertPackage.Contains(t, string(response), "could not create silence: until must be in the future")
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"node": "10.0.0.1", "unit": "dcos-mesos-slave.service", "until": %q, "author": "ops", "reason": "upgrade"}`,
+		until.Format(time.RFC3339))
+	response, code, err = MakeHTTPRequest(t, router, "/system/health/v1/silences", "POST", strings.NewReader(body))
+	requirePackage.NoError(t, err)
+	requirePackage.Equal(t, http.StatusCreated, code, string(response))
+	var created silence.Silence
+	requirePackage.NoError(t, json.Unmarshal(response, &created))
+	assertPackage.NotEmpty(t, created.ID)
+	assertPackage.Equal(t, until, created.Until)
+	assertPackage.Equal(t, "ops", created.Author)
+	assertPackage.True(t, store.Silenced("10.0.0.1", "dcos-mesos-slave.service"))
+
+	response, code, err = MakeHTTPRequest(t, router, "/system/health/v1/silences", "GET", nil)
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, http.StatusOK, code)
+	var active []silence.Silence
+	requirePackage.NoError(t, json.Unmarshal(response, &active))
+	assertPackage.Equal(t, []silence.Silence{created}, active)
+
+	_, code, err = MakeHTTPRequest(t, router, "/system/health/v1/silences/"+created.ID, "DELETE", nil)
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, http.StatusNoContent, code)
+	assertPackage.False(t, store.Silenced("10.0.0.1", "dcos-mesos-slave.service"))
+
+	response, code, err = MakeHTTPRequest(t, router, "/system/health/v1/silences/"+created.ID, "DELETE", nil)
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, http.StatusNotFound, code)
+	assertPackage.Equal(t, fmt.Sprintf("silence %s not found\n", created.ID), string(response))
+
+	// deleted silence is still served to other masters
+	response, code, err = MakeHTTPRequest(t, router, "/system/health/v1/node/silences", "GET", nil)
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, http.StatusOK, code)
+	var all []silence.Silence
+	requirePackage.NoError(t, json.Unmarshal(response, &all))
+	requirePackage.Len(t, all, 1)
+	assertPackage.True(t, all[0].Deleted)
+}
+
+func TestGetUnitLogHandlerWithInvalidOptions(t *testing.T) {
+	h := handler{cfg: testCfg(), job: &DiagnosticsJob{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/system/health/v1/logs/units/unit_a?lines=-1", nil)
+	req = mux.SetURLVars(req, map[string]string{"provider": "units", "entity": "unit_a"})
+	w := httptest.NewRecorder()
+	h.getUnitLogHandler(w, req)
+
+	assertPackage.Equal(t, http.StatusBadRequest, w.Code)
+	assertPackage.Contains(t, w.Body.String(), `invalid lines \"-1\", expected a non-negative number`)
+}
+
+func TestGetUnitLogHandlerStreamsEvents(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	requirePackage.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("first\nsecond\nthird")
+	requirePackage.NoError(t, err)
+
+	job := &DiagnosticsJob{DCOSTools: &fakeDCOSTools{}}
+	job.logProviders.LocalFiles = map[string]FileProvider{"file": {Location: f.Name()}}
+	h := handler{cfg: testCfg(), job: job}
+
+	req := httptest.NewRequest(http.MethodGet, "/system/health/v1/logs/files/file?lines=2", nil)
+	req.Header.Set("Accept", "text/html, text/event-stream;q=0.9")
+	req = mux.SetURLVars(req, map[string]string{"provider": "files", "entity": "file"})
+	w := httptest.NewRecorder()
+	h.getUnitLogHandler(w, req)
+
+	assertPackage.Equal(t, http.StatusOK, w.Code)
+	assertPackage.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assertPackage.Equal(t, "data: second\n\ndata: third\n\n", w.Body.String())
+	assertPackage.True(t, w.Flushed)
+}
+
+func TestNodeLogHandler(t *testing.T) {
+	server, transport := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		assertPackage.Equal(t, "/system/health/v1/logs/units/dcos-mesos-slave.service", r.URL.Path)
+		assertPackage.Equal(t, "follow=true&lines=10", r.URL.RawQuery)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "OK\n")
+	})
+	defer server.Close()
+
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{{IP: "10.10.0.1", Role: "master"}}, nil)
+	tools.On("GetAgentNodes").Return([]dcos.Node{{IP: "10.10.0.2", Role: "agent", MesosID: "agent-1"}}, nil)
+	h := handler{cfg: testCfg(), tools: tools, job: &DiagnosticsJob{Transport: transport}}
+
+	req := httptest.NewRequest(http.MethodGet, "/system/health/v1/nodes/agent-1/logs/units/dcos-mesos-slave.service?follow=true&lines=10", nil)
+	req = mux.SetURLVars(req, map[string]string{"nodeid": "agent-1", "provider": "units", "entity": "dcos-mesos-slave.service"})
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-type", "application/json")
+	h.nodeLogHandler(w, req)
+
+	assertPackage.Equal(t, http.StatusOK, w.Code)
+	assertPackage.Equal(t, []string{"text/plain; charset=utf-8"}, w.Header()["Content-Type"])
+	assertPackage.Equal(t, "OK\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.nodeLogHandler(w, mux.SetURLVars(req, map[string]string{"nodeid": "10.10.0.3"}))
+	assertPackage.Equal(t, http.StatusNotFound, w.Code)
+	assertPackage.Equal(t, "node 10.10.0.3 not found\n", w.Body.String())
+}
+
+func TestParseLogSearchRequestErrors(t *testing.T) {
+	cfg := testCfg()
+	h := handler{cfg: cfg, job: &DiagnosticsJob{Cfg: cfg}}
+	tests := map[string]string{
+		"q=a":                    "exactly one of unit and file must be given",
+		"unit=a&file=b&q=a":      "exactly one of unit and file must be given",
+		"unit=a":                 "exactly one of q and regex must be given",
+		"unit=a&q=a&regex=a":     "exactly one of q and regex must be given",
+		"unit=a&regex=a(":        "invalid regular expression \"a(\": error parsing regexp: missing closing ): `a(`",
+		"unit=a&q=a&since=week":  `invalid since "week": time: invalid duration "week"`,
+		"unit=a&q=a&limit=0":     `invalid limit "0", expected a positive number`,
+		"file=a&q=a&limit=false": `invalid limit "false", expected a positive number`,
+	}
+	for raw, expected := range tests {
+		query, err := url.ParseQuery(raw)
+		requirePackage.NoError(t, err)
+		_, err = h.parseLogSearchRequest(query)
+		assertPackage.EqualError(t, err, expected, raw)
+	}
+}
+
+func TestNodeLogSearchHandler(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	requirePackage.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("2020-06-08 07:06:40 ok\n2020-06-08 07:06:41 error: first\n2020-06-08 07:06:42 error: second\n")
+	requirePackage.NoError(t, err)
+
+	cfg := testCfg()
+	cfg.FlagLogsSearchLimit = 10
+	cfg.FlagCommandExecTimeoutSec = 10
+	job := &DiagnosticsJob{Cfg: cfg, DCOSTools: &fakeDCOSTools{}}
+	job.logProviders.LocalFiles = map[string]FileProvider{"file": {Location: f.Name()}}
+	h := handler{cfg: cfg, job: job}
+
+	req := httptest.NewRequest(http.MethodGet, "/system/health/v1/node/logs/search?file=file&since=0s&regex="+url.QueryEscape(`error: \w+`), nil)
+	w := httptest.NewRecorder()
+	h.nodeLogSearchHandler(w, req)
+
+	assertPackage.Equal(t, http.StatusOK, w.Code)
+	assertPackage.Equal(t,
+		`{"timestamp":"2020-06-08T07:06:41Z","source":"file","line":"2020-06-08 07:06:41 error: first"}`+"\n"+
+			`{"timestamp":"2020-06-08T07:06:42Z","source":"file","line":"2020-06-08 07:06:42 error: second"}`+"\n",
+		w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/system/health/v1/node/logs/search?file=unknown&q=error", nil)
+	w = httptest.NewRecorder()
+	h.nodeLogSearchHandler(w, req)
+	assertPackage.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assertPackage.Contains(t, w.Body.String(), "Not found unknown")
+}
+
+func TestLogSearchHandler(t *testing.T) {
+	server, transport := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		assertPackage.Equal(t, "/system/health/v1/node/logs/search", r.URL.Path)
+		assertPackage.Equal(t, "limit=3&q=error&unit=dcos-mesos-slave.service", r.URL.RawQuery)
+		switch r.Host {
+		case "10.10.0.1:1050":
+			io.WriteString(w, `{"timestamp":"2020-06-08T07:06:41Z","source":"dcos-mesos-slave.service","line":"error 1"}`+"\n")
+			io.WriteString(w, `{"timestamp":"2020-06-08T07:06:44Z","source":"dcos-mesos-slave.service","line":"error 4"}`+"\n")
+		case "10.10.0.2:61001":
+			io.WriteString(w, `{"timestamp":"2020-06-08T07:06:42Z","source":"dcos-mesos-slave.service","line":"error 2"}`+"\n")
+			io.WriteString(w, `{"timestamp":"2020-06-08T07:06:43Z","source":"dcos-mesos-slave.service","line":"error 3"}`+"\n")
+		default:
+			http.Error(w, "Not found dcos-mesos-slave.service", http.StatusServiceUnavailable)
+		}
+	})
+	defer server.Close()
+
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{{IP: "10.10.0.1", Role: "master"}}, nil)
+	tools.On("GetAgentNodes").Return([]dcos.Node{
+		{IP: "10.10.0.2", Role: "agent"},
+		{IP: "10.10.0.3", Role: "agent_public"},
+	}, nil)
+
+	cfg := testCfg()
+	cfg.FlagAgentPort = 61001
+	cfg.FlagLogsSearchConcurrency = 2
+	cfg.FlagLogsSearchLimit = 3
+	cfg.FlagCommandExecTimeoutSec = 10
+	h := handler{cfg: cfg, tools: tools, job: &DiagnosticsJob{Cfg: cfg, Transport: transport}}
+
+	req := httptest.NewRequest(http.MethodGet, "/system/health/v1/logs/search?unit=dcos-mesos-slave.service&q=error&nodes=masters,agents", nil)
+	w := httptest.NewRecorder()
+	h.logSearchHandler(w, req)
+
+	assertPackage.Equal(t, http.StatusOK, w.Code)
+	assertPackage.Equal(t, "true", w.Result().Trailer.Get("X-Search-Truncated"))
+	assertPackage.Equal(t,
+		`{"timestamp":"2020-06-08T07:06:41Z","node":"10.10.0.1","source":"dcos-mesos-slave.service","line":"error 1"}`+"\n"+
+			`{"timestamp":"2020-06-08T07:06:42Z","node":"10.10.0.2","source":"dcos-mesos-slave.service","line":"error 2"}`+"\n"+
+			`{"timestamp":"2020-06-08T07:06:43Z","node":"10.10.0.2","source":"dcos-mesos-slave.service","line":"error 3"}`+"\n"+
+			`{"timestamp":"0001-01-01T00:00:00Z","node":"10.10.0.3","error":"could not search logs, got 503: Not found dcos-mesos-slave.service"}`+"\n",
+		w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/system/health/v1/logs/search?unit=dcos-mesos-slave.service&q=error&nodes=10.10.0.9", nil)
+	w = httptest.NewRecorder()
+	h.logSearchHandler(w, req)
+	assertPackage.Equal(t, http.StatusBadRequest, w.Code)
+	assertPackage.Contains(t, w.Body.String(), "requested nodes: [10.10.0.9] not found")
+}
+
+func TestUIRoutes(t *testing.T) {
+	router := NewRouter(&Dt{Cfg: testCfg(), DtDCOSTools: &fakeDCOSTools{}, MR: &MonitoringResponse{}})
+
+	for url, contentType := range map[string]string{
+		"/system/health/v1/ui":        "text/html; charset=utf-8",
+		"/system/health/v1/ui/app.js": "application/javascript; charset=utf-8",
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		requirePackage.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assertPackage.Equal(t, http.StatusOK, w.Code, url)
+		assertPackage.Equal(t, []string{contentType}, w.Header()["Content-Type"], url)
+	}
+}
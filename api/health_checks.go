@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/config"
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/tlsinspect"
+	"github.com/dcos/dcos-diagnostics/zookeeper"
+)
+
+// healthChecksTimeout limits how long a single run of all health checks could take
+var healthChecksTimeout = time.Minute
+
+// HealthCheck checks health of something that is not a systemd unit. Its result is reported
+// as a synthetic unit of the node health report, so it's aggregated by masters like any other unit.
+type HealthCheck interface {
+	Check(ctx context.Context) HealthResponseValues
+}
+
+// HealthChecks holds health checks of the node and their last results. Checks could take seconds, e.g., when
+// an endpoint does not respond, so they are run in the background by Start and health reports only read
+// the last results. Checks could be replaced while the daemon runs, e.g., when the config is reloaded.
+type HealthChecks struct {
+	sync.RWMutex
+	checks  []HealthCheck
+	results []HealthResponseValues
+	// generation is incremented whenever checks are replaced, so results of replaced checks are not stored
+	generation int
+	changed    chan struct{}
+}
+
+// NewHealthChecks returns HealthChecks running the checks
+func NewHealthChecks(checks []HealthCheck) *HealthChecks {
+	return &HealthChecks{checks: checks, changed: make(chan struct{}, 1)}
+}
+
+// Set replaces checks and drops results of previous checks. New checks are run by Start right away.
+func (h *HealthChecks) Set(checks []HealthCheck) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	h.checks = checks
+	h.results = nil
+	h.generation++
+	h.Unlock()
+
+	select {
+	case h.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs all checks concurrently, stores their results and returns them in the order of checks
+func (h *HealthChecks) Run(ctx context.Context) []HealthResponseValues {
+	if h == nil {
+		return nil
+	}
+	h.RLock()
+	checks, generation := h.checks, h.generation
+	h.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthChecksTimeout)
+	defer cancel()
+
+	results := make([]HealthResponseValues, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = check.Check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	h.Lock()
+	if h.generation == generation {
+		h.results = results
+	}
+	h.Unlock()
+	return results
+}
+
+// Results returns a copy of results of the last run, empty until checks are run for the first time
+func (h *HealthChecks) Results() []HealthResponseValues {
+	if h == nil {
+		return nil
+	}
+	h.RLock()
+	defer h.RUnlock()
+	return append([]HealthResponseValues(nil), h.results...)
+}
+
+// Start runs checks every interval and whenever they are replaced until the context is done
+func (h *HealthChecks) Start(ctx context.Context, interval time.Duration) {
+	if h == nil {
+		return
+	}
+	for {
+		h.Run(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.changed:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// LoadHealthChecks returns health checks of the node built from endpoints configs
+func LoadHealthChecks(cfg *config.Config, tools dcos.Tooler, client *http.Client) ([]HealthCheck, error) {
+	providers, err := loadExternalProviders(cfg.FlagDiagnosticsBundleEndpointsConfigFiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize external log providers: %s", err)
+	}
+
+	role, err := tools.GetNodeRole()
+	if err != nil {
+		return nil, fmt.Errorf("could not get role: %s", err)
+	}
+
+	var checks []HealthCheck
+	if endpoints := tlsEndpoints(cfg, providers.TLSEndpoints, role); len(endpoints) > 0 {
+		inspector, err := tlsInspector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range endpoints {
+			checks = append(checks, TLSCheck{Inspector: inspector, Endpoint: e})
+		}
+	}
+	if cfg.FlagZooKeeperHealth && role == dcos.MasterRole {
+		checks = append(checks, ZooKeeperCheck{Inspector: zookeeperInspector(cfg, tools, client)})
+	}
+	return checks, nil
+}
+
+// TLSCheck is a health check of certificates presented by a TLS endpoint. It's unhealthy when the endpoint
+// could not be reached, its chain does not verify against the configured CA or a certificate expires soon.
+type TLSCheck struct {
+	Inspector *tlsinspect.Inspector
+	Endpoint  tlsinspect.Endpoint
+}
+
+// Check inspects the endpoint
+func (c TLSCheck) Check(ctx context.Context) HealthResponseValues {
+	r := c.Inspector.Inspect(ctx, c.Endpoint)
+	unit := HealthResponseValues{
+		UnitID:     "tls-" + c.Endpoint.Name,
+		UnitHealth: dcos.Healthy,
+		UnitTitle:  fmt.Sprintf("TLS certificate of %s", c.Endpoint.Address),
+		PrettyName: fmt.Sprintf("TLS %s", c.Endpoint.Name),
+	}
+	if !r.Healthy() {
+		unit.UnitHealth = dcos.Unhealthy
+		unit.UnitOutput = strings.Join(r.Problems, "\n")
+		unit.Help = "Renew the certificate or fix the endpoint TLS configuration"
+		return unit
+	}
+	unit.UnitOutput = fmt.Sprintf("certificate chain verified, expires on %s", r.ExpiresAt.Format(time.RFC3339))
+	return unit
+}
+
+// tlsInspector returns Inspector verifying chains against the CA configured with --ca-cert
+func tlsInspector(cfg *config.Config) (*tlsinspect.Inspector, error) {
+	inspector := tlsinspect.NewInspector(nil)
+	if cfg.FlagCACertFile != "" {
+		roots, err := tlsinspect.LoadRoots(cfg.FlagCACertFile)
+		if err != nil {
+			return nil, err
+		}
+		inspector.Roots = roots
+	}
+	if cfg.FlagTLSExpiryWarningDays > 0 {
+		inspector.ExpiryWarning = time.Duration(cfg.FlagTLSExpiryWarningDays) * 24 * time.Hour
+	}
+	return inspector, nil
+}
+
+// ZooKeeperCheck is a health check of the ZooKeeper ensemble run on masters. It's unhealthy when the ensemble
+// has no leader, lost or degraded its quorum or Exhibitor reports a server that is not serving. Inspecting
+// the ensemble dials every server, so like other checks it's run in the background by HealthChecks.
+type ZooKeeperCheck struct {
+	Inspector *zookeeper.Inspector
+}
+
+// Check inspects the ensemble
+func (c ZooKeeperCheck) Check(ctx context.Context) HealthResponseValues {
+	unit := HealthResponseValues{
+		UnitID:     "zookeeper-quorum",
+		UnitHealth: dcos.Healthy,
+		UnitTitle:  "Quorum of the ZooKeeper ensemble run on masters",
+		PrettyName: "ZooKeeper Quorum",
+	}
+	e, err := c.Inspector.Inspect(ctx)
+	if err != nil {
+		unit.UnitHealth = dcos.Unknown
+		unit.UnitOutput = err.Error()
+		return unit
+	}
+	if !e.Healthy() {
+		unit.UnitHealth = dcos.Unhealthy
+		unit.UnitOutput = strings.Join(e.Problems, "\n")
+		unit.Help = "Check dcos-exhibitor logs on masters and restore the failed ZooKeeper servers"
+		return unit
+	}
+	unit.UnitOutput = fmt.Sprintf("leader %s, %d of %d servers serving", e.Leader, e.Serving, e.Size)
+	return unit
+}
+
+// zookeeperInspector returns Inspector of ZooKeeper servers run on masters found by tools
+func zookeeperInspector(cfg *config.Config, tools dcos.Tooler, client *http.Client) *zookeeper.Inspector {
+	return zookeeper.NewInspector(zookeeper.HostServers(func() ([]string, error) {
+		masters, err := tools.GetMasterNodes()
+		if err != nil {
+			return nil, fmt.Errorf("could not get master nodes: %s", err)
+		}
+		return nodeIPs(masters), nil
+	}), cfg.FlagExhibitorClusterStatusURL, client)
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/tlsinspect"
+	"github.com/dcos/dcos-diagnostics/zookeeper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHealthCheck string
+
+func (c fakeHealthCheck) Check(context.Context) HealthResponseValues {
+	return HealthResponseValues{UnitID: string(c), UnitHealth: dcos.Healthy}
+}
+
+func TestHealthChecksRun(t *testing.T) {
+	var nilChecks *HealthChecks
+	assert.Empty(t, nilChecks.Run(context.TODO()))
+	nilChecks.Set([]HealthCheck{fakeHealthCheck("a")})
+
+	checks := NewHealthChecks([]HealthCheck{fakeHealthCheck("a"), fakeHealthCheck("b")})
+	assert.Equal(t, []HealthResponseValues{{UnitID: "a"}, {UnitID: "b"}}, checks.Run(context.TODO()))
+
+	checks.Set([]HealthCheck{fakeHealthCheck("c")})
+	assert.Equal(t, []HealthResponseValues{{UnitID: "c"}}, checks.Run(context.TODO()))
+}
+
+// blockingHealthCheck is a health check that does not finish until it's released
+type blockingHealthCheck struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHealthCheck() blockingHealthCheck {
+	return blockingHealthCheck{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (c blockingHealthCheck) Check(context.Context) HealthResponseValues {
+	c.started <- struct{}{}
+	<-c.release
+	return HealthResponseValues{UnitID: "slow", UnitHealth: dcos.Healthy}
+}
+
+func TestHealthChecksResults(t *testing.T) {
+	var nilChecks *HealthChecks
+	assert.Empty(t, nilChecks.Results())
+
+	checks := NewHealthChecks([]HealthCheck{fakeHealthCheck("a")})
+	assert.Empty(t, checks.Results())
+
+	checks.Run(context.TODO())
+	results := checks.Results()
+	assert.Equal(t, []HealthResponseValues{{UnitID: "a"}}, results)
+	results[0].UnitID = "modified"
+	assert.Equal(t, []HealthResponseValues{{UnitID: "a"}}, checks.Results())
+
+	checks.Set([]HealthCheck{fakeHealthCheck("b")})
+	assert.Empty(t, checks.Results())
+}
+
+func TestHealthChecksResultsDoNotWaitForChecks(t *testing.T) {
+	slow := newBlockingHealthCheck()
+	checks := NewHealthChecks([]HealthCheck{fakeHealthCheck("a")})
+	checks.Run(context.TODO())
+	checks.Set([]HealthCheck{fakeHealthCheck("a"), slow})
+
+	done := make(chan struct{})
+	go func() {
+		checks.Run(context.TODO())
+		close(done)
+	}()
+	<-slow.started
+	assert.Empty(t, checks.Results())
+
+	close(slow.release)
+	<-done
+	assert.Equal(t, []HealthResponseValues{{UnitID: "a"}, {UnitID: "slow"}}, checks.Results())
+}
+
+func TestHealthChecksResultsOfReplacedChecksAreDropped(t *testing.T) {
+	slow := newBlockingHealthCheck()
+	checks := NewHealthChecks([]HealthCheck{slow})
+
+	done := make(chan struct{})
+	go func() {
+		checks.Run(context.TODO())
+		close(done)
+	}()
+	<-slow.started
+	checks.Set([]HealthCheck{fakeHealthCheck("a")})
+	close(slow.release)
+	<-done
+
+	assert.Empty(t, checks.Results())
+}
+
+func TestHealthChecksStart(t *testing.T) {
+	checks := NewHealthChecks([]HealthCheck{fakeHealthCheck("a")})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checks.Start(ctx, time.Hour)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return len(checks.Results()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "a", checks.Results()[0].UnitID)
+
+	// replaced checks are run without waiting for the interval
+	checks.Set([]HealthCheck{fakeHealthCheck("b")})
+	require.Eventually(t, func() bool {
+		results := checks.Results()
+		return len(results) == 1 && results[0].UnitID == "b"
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestTLSCheckUnreachableEndpoint(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+
+	inspector := tlsinspect.NewInspector(nil)
+	inspector.Timeout = time.Second
+	unit := TLSCheck{Inspector: inspector, Endpoint: tlsinspect.Endpoint{Name: "mesos", Address: address}}.Check(context.TODO())
+
+	assert.Equal(t, "tls-mesos", unit.UnitID)
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), unit.UnitHealth)
+	assert.Equal(t, "TLS mesos", unit.PrettyName)
+	assert.Equal(t, "TLS certificate of "+address, unit.UnitTitle)
+	assert.Contains(t, unit.UnitOutput, "could not connect")
+	assert.NotEmpty(t, unit.Help)
+}
+
+func TestLoadHealthChecks(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetNodeRole").Return("master", nil)
+
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{filepath.Join("testdata", "endpoint-config-tls.json")}
+	cfg.FlagTLSExpiryWarningDays = 7
+
+	checks, err := LoadHealthChecks(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+	require.Len(t, checks, 3)
+	var endpoints []tlsinspect.Endpoint
+	for _, c := range checks {
+		require.IsType(t, TLSCheck{}, c)
+		endpoints = append(endpoints, c.(TLSCheck).Endpoint)
+		assert.Equal(t, 7*24*time.Hour, c.(TLSCheck).Inspector.ExpiryWarning)
+	}
+	assert.Equal(t, []tlsinspect.Endpoint{
+		{Name: "adminrouter", Address: "master-0:443", ServerName: "master.mesos"},
+		{Name: "mesos", Address: "master-0:5050"},
+		{Name: "zookeeper", Address: "127.0.0.1:2281"},
+	}, endpoints)
+
+	cfg.FlagCACertFile = filepath.Join("testdata", "not-existing.crt")
+	_, err = LoadHealthChecks(cfg, tools, http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestLoadCollectors_TLS(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetNodeRole").Return("agent", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{filepath.Join("testdata", "endpoint-config-tls.json")}
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "tls-certificates.json", got[1].Name())
+	assert.True(t, got[1].Optional())
+	assert.IsType(t, &collector.TLS{}, got[1])
+}
+
+// fakeZooKeeper is a fake ZooKeeper server answering srvr in the mode
+func fakeZooKeeper(t *testing.T, mode string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			word := make([]byte, 4)
+			if _, err := io.ReadFull(conn, word); err == nil && string(word) == "srvr" {
+				fmt.Fprintf(conn, "Zookeeper version: 3.4.14\nOutstanding: 0\nZxid: 0x100000001\nMode: %s\nNode count: 4\n", mode)
+			}
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func zooKeeperCheck(addresses ...string) ZooKeeperCheck {
+	inspector := zookeeper.NewInspector(func() ([]string, error) { return addresses, nil }, "", http.DefaultClient)
+	inspector.Timeout = time.Second
+	return ZooKeeperCheck{Inspector: inspector}
+}
+
+func TestZooKeeperCheckHealthyEnsemble(t *testing.T) {
+	leader := fakeZooKeeper(t, "leader")
+	unit := zooKeeperCheck(leader, fakeZooKeeper(t, "follower"), fakeZooKeeper(t, "follower")).Check(context.TODO())
+
+	assert.Equal(t, "zookeeper-quorum", unit.UnitID)
+	assert.Equal(t, dcos.Health(dcos.Healthy), unit.UnitHealth)
+	assert.Equal(t, "ZooKeeper Quorum", unit.PrettyName)
+	assert.Equal(t, fmt.Sprintf("leader %s, 3 of 3 servers serving", leader), unit.UnitOutput)
+	assert.Empty(t, unit.Help)
+}
+
+func TestZooKeeperCheckDegradedQuorum(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := l.Addr().String()
+	l.Close()
+
+	unit := zooKeeperCheck(fakeZooKeeper(t, "leader"), fakeZooKeeper(t, "follower"), down).Check(context.TODO())
+
+	assert.Equal(t, dcos.Health(dcos.Unhealthy), unit.UnitHealth)
+	assert.Contains(t, unit.UnitOutput, "server "+down+" is not serving")
+	assert.Contains(t, unit.UnitOutput, "quorum is degraded: 2 of 3 servers serving, 1 more failures lose quorum")
+	assert.NotEmpty(t, unit.Help)
+}
+
+func TestZooKeeperCheckUnknownServers(t *testing.T) {
+	inspector := zookeeper.NewInspector(func() ([]string, error) { return nil, fmt.Errorf("no masters") }, "", http.DefaultClient)
+	unit := ZooKeeperCheck{Inspector: inspector}.Check(context.TODO())
+
+	assert.Equal(t, dcos.Health(dcos.Unknown), unit.UnitHealth)
+	assert.Equal(t, "could not list ZooKeeper servers: no masters", unit.UnitOutput)
+}
+
+func TestLoadHealthChecks_ZooKeeper(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetNodeRole").Return("master", nil)
+	tools.On("GetMasterNodes").Return([]dcos.Node{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}, nil)
+
+	cfg := testCfg()
+	cfg.FlagZooKeeperHealth = true
+
+	checks, err := LoadHealthChecks(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	require.IsType(t, ZooKeeperCheck{}, checks[0])
+	inspector := checks[0].(ZooKeeperCheck).Inspector
+	assert.Equal(t, cfg.FlagExhibitorClusterStatusURL, inspector.ExhibitorURL)
+	servers, err := inspector.Servers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:2181", "10.0.0.2:2181"}, servers)
+
+	tools = new(MockedTools)
+	tools.On("GetNodeRole").Return("agent", nil)
+	checks, err = LoadHealthChecks(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+	assert.Empty(t, checks)
+}
+
+func TestLoadCollectors_ZooKeeper(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagZooKeeperHealth = true
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "zookeeper.json", got[1].Name())
+	assert.True(t, got[1].Optional())
+	assert.IsType(t, &collector.ZooKeeper{}, got[1])
+}
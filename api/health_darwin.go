@@ -5,11 +5,17 @@ import (
 	"sync"
 
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/flapping"
 )
 
 // SystemdUnits used to make GetUnitsProperties thread safe.
 type SystemdUnits struct {
 	sync.Mutex
+	// Checks results are added to the health report as synthetic units
+	Checks *HealthChecks
+	// Flapping remembers restarts of units observed by StartObservingRestarts, health reports only read it.
+	// Flapping is not detected if it's nil.
+	Flapping *flapping.Detector
 }
 
 // GetUnits returns an error on darwin because it's not supported
@@ -6,6 +6,7 @@ import (
 
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/flapping"
 	"github.com/dcos/dcos-diagnostics/util"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +15,11 @@ import (
 // SystemdUnits used to make GetUnitsProperties thread safe.
 type SystemdUnits struct {
 	sync.Mutex
+	// Checks results are added to the health report as synthetic units, checks are not run by health reports
+	Checks *HealthChecks
+	// Flapping remembers restarts of units observed by StartObservingRestarts, health reports only read it.
+	// Flapping is not detected if it's nil.
+	Flapping *flapping.Detector
 }
 
 // GetUnits returns a list of found unit properties.
@@ -41,7 +47,7 @@ func (s *SystemdUnits) GetUnits(tools dcos.Tooler) (allUnits []HealthResponseVal
 			logrus.Errorf("Could not get properties for Unit: %s", unit)
 			continue
 		}
-		normalizedProperty, err := normalizeProperty(currentProperty, tools)
+		normalizedProperty, err := normalizeProperty(currentProperty, tools, s.Flapping)
 		if err != nil {
 			logrus.Errorf("Could not normalize property for Unit %s: %s", unit, err)
 			continue
@@ -67,6 +73,8 @@ func (s *SystemdUnits) GetUnitsProperties(tools dcos.Tooler) (healthReport Units
 	if err != nil {
 		logrus.Errorf("Unable to get a list of systemd units: %s", err)
 	}
+	healthReport.Array = append(healthReport.Array, s.Checks.Results()...)
+	setLevels(healthReport.Array)
 
 	healthReport.IPAddress, err = tools.DetectIP()
 	if err != nil {
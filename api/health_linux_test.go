@@ -1,11 +1,16 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync/atomic"
 	"testing"
 
 	"github.com/dcos/dcos-diagnostics/dcos"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -20,9 +25,9 @@ func TestSystemdUnits_GetUnits(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, []HealthResponseValues{
-		{UnitID: "unit_a", UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_b", UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_c", UnitTitle: title, PrettyName: name},
+		{UnitID: "unit_a", UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
+		{UnitID: "unit_b", UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
+		{UnitID: "unit_c", UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
 	}, units)
 }
 
@@ -35,10 +40,74 @@ func TestSystemdUnits_GetUnitsProperties(t *testing.T) {
 	expected := UnitsHealthResponseJSONStruct{
 		Hostname: "MyHostName", IPAddress: "127.0.0.1", DcosVersion: "", Role: "master", MesosID: "node-id-123", TdtVersion: "dev",
 		Array: []HealthResponseValues{
-			{UnitID: "unit_a", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name},
-			{UnitID: "unit_b", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name},
-			{UnitID: "unit_c", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name},
+			{UnitID: "unit_a", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
+			{UnitID: "unit_b", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
+			{UnitID: "unit_c", UnitHealth: dcos.Healthy, UnitTitle: title, PrettyName: name, Level: dcos.LevelOK},
 		},
 	}
 	assert.Equal(t, expected, units)
 }
+
+func TestSystemdUnits_GetUnitsPropertiesWithChecks(t *testing.T) {
+	s := SystemdUnits{Checks: NewHealthChecks([]HealthCheck{fakeHealthCheck("tls-mesos")})}
+
+	// checks are not run by health reports
+	units, err := s.GetUnitsProperties(&fakeDCOSTools{})
+	assert.NoError(t, err)
+	assert.Len(t, units.Array, 3)
+
+	s.Checks.Run(context.TODO())
+	units, err = s.GetUnitsProperties(&fakeDCOSTools{})
+	assert.NoError(t, err)
+	assert.Len(t, units.Array, 4)
+	assert.Equal(t, HealthResponseValues{UnitID: "tls-mesos", UnitHealth: dcos.Healthy, Level: dcos.LevelOK}, units.Array[3])
+}
+
+func TestSystemdUnits_GetUnitsPropertiesDoesNotQueryZooKeeper(t *testing.T) {
+	var dials int32
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&dials, 1)
+			conn.Close()
+		}
+	}()
+
+	s := SystemdUnits{Checks: NewHealthChecks([]HealthCheck{zooKeeperCheck(l.Addr().String())})}
+	s.Checks.Run(context.TODO())
+	queried := atomic.LoadInt32(&dials)
+	require.NotZero(t, queried)
+
+	for i := 0; i < 3; i++ {
+		units, err := s.GetUnitsProperties(&fakeDCOSTools{})
+		require.NoError(t, err)
+		require.Len(t, units.Array, 4)
+		assert.Equal(t, "zookeeper-quorum", units.Array[3].UnitID)
+		assert.Equal(t, dcos.Health(dcos.Unhealthy), units.Array[3].UnitHealth)
+	}
+	assert.Equal(t, queried, atomic.LoadInt32(&dials), "health reports must serve the last result of the check")
+}
+
+func TestUnitsHealthStatusReportsFingerprint(t *testing.T) {
+	dt := &Dt{
+		Cfg:          testCfg(),
+		DtDCOSTools:  &fakeDCOSTools{},
+		MR:           &MonitoringResponse{},
+		SystemdUnits: &SystemdUnits{},
+	}
+
+	body, status, err := MakeHTTPRequest(t, NewRouter(dt), baseRoute, "GET", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, status, string(body))
+
+	var health UnitsHealthResponseJSONStruct
+	require.NoError(t, json.Unmarshal(body, &health))
+	require.NotNil(t, health.Fingerprint)
+	assert.NotEmpty(t, health.Fingerprint.Hash)
+}
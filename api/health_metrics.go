@@ -0,0 +1,32 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var nodeHealthGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "node_health",
+	Help: "Health of the cluster node, 0 is healthy. Silenced nodes are reported as healthy.",
+}, []string{"node", "role", "silenced"})
+
+var unitHealthGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "unit_health",
+	Help: "Aggregated health of the unit on all cluster nodes, 0 is healthy. Silenced units are reported as healthy.",
+}, []string{"unit", "silenced"})
+
+// updateHealthMetrics replaces health gauges with the aggregated health, so removed nodes and units disappear
+func updateHealthMetrics(nodes map[string]dcos.Node, units map[string]dcos.Unit) {
+	nodeHealthGauge.Reset()
+	for _, node := range nodes {
+		nodeHealthGauge.WithLabelValues(node.IP, node.Role, strconv.FormatBool(node.Silenced)).
+			Set(float64(node.EffectiveHealth()))
+	}
+	unitHealthGauge.Reset()
+	for _, unit := range units {
+		unitHealthGauge.WithLabelValues(unit.UnitName, strconv.FormatBool(unit.Silenced)).Set(float64(unit.Health))
+	}
+}
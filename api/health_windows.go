@@ -7,6 +7,7 @@ import (
 
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/flapping"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,6 +16,11 @@ const dcosVersionEnvName = "DCOS_VERSION"
 // SystemdUnits used to make GetUnitsProperties thread safe.
 type SystemdUnits struct {
 	sync.Mutex
+	// Checks results are added to the health report as synthetic units, checks are not run by health reports
+	Checks *HealthChecks
+	// Flapping remembers restarts of units observed by StartObservingRestarts, health reports only read it.
+	// Flapping is not detected if it's nil.
+	Flapping *flapping.Detector
 }
 
 // GetUnits returns a list of found unit properties.
@@ -37,7 +43,7 @@ func (s *SystemdUnits) GetUnits(tools dcos.Tooler) (allUnits []HealthResponseVal
 		if err != nil {
 			logrus.Errorf("Could not get properties for Unit: %s", unit)
 		} else {
-			normalizedProperty, err = normalizeProperty(currentProperty, tools)
+			normalizedProperty, err = normalizeProperty(currentProperty, tools, s.Flapping)
 			if err != nil {
 				logrus.Errorf("Could not normalize property for Unit %s: %s", unit, err)
 				normalizedProperty.UnitID = ""
@@ -75,6 +81,8 @@ func (s *SystemdUnits) GetUnitsProperties(tools dcos.Tooler) (healthReport Units
 	if err != nil {
 		logrus.Errorf("Unable to get a list of systemd units: %s", err)
 	}
+	healthReport.Array = append(healthReport.Array, s.Checks.Results()...)
+	setLevels(healthReport.Array)
 
 	healthReport.IPAddress, err = tools.DetectIP()
 	if err != nil {
@@ -10,7 +10,8 @@ import (
 )
 
 const (
-	output = "The ActiveState is active, not in running state(4)\njournal output"
+	reason = "The ActiveState is active, not in running state(4)"
+	output = reason + "\njournal output"
 	title  = "My fake description"
 	name   = "PrettyName"
 )
@@ -24,12 +25,12 @@ func TestSystemdUnits_GetUnits(t *testing.T) {
 
 	assert.NoError(t, err)
 	expected := []HealthResponseValues{
-		{UnitID: "dcos-setup.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "dcos-link-env.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "dcos-download.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_a", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_b", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_c", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
+		{UnitID: "dcos-setup.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "dcos-link-env.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "dcos-download.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_a", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_b", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_c", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
 		{UnitID: "unit_to_fail", UnitHealth: dcos.Unhealthy},
 	}
 	assert.Equal(t, expected, units)
@@ -44,13 +45,13 @@ func TestSystemdUnits_GetUnitsProperties(t *testing.T) {
 	assert.NoError(t, err)
 
 	expected := UnitsHealthResponseJSONStruct{Array: []HealthResponseValues{
-		{UnitID: "dcos-setup.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "dcos-link-env.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "dcos-download.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_a", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_b", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_c", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name},
-		{UnitID: "unit_to_fail", UnitHealth: dcos.Unhealthy},
+		{UnitID: "dcos-setup.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "dcos-link-env.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "dcos-download.service", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_a", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_b", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_c", UnitHealth: dcos.Unhealthy, UnitOutput: output, UnitTitle: title, PrettyName: name, Level: dcos.LevelCritical, Reasons: []string{reason}},
+		{UnitID: "unit_to_fail", UnitHealth: dcos.Unhealthy, Level: dcos.LevelCritical},
 	}, Hostname: "MyHostName", IPAddress: "127.0.0.1", DcosVersion: "some version", Role: "master", MesosID: "node-id-123", TdtVersion: "dev"}
 
 	assert.Equal(t, expected, units)
@@ -49,6 +49,13 @@ func (i *interceptor) WriteHeader(code int) {
 	i.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher so streamed responses are not buffered.
+func (i *interceptor) Flush() {
+	if f, ok := i.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (i *interceptor) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hj, ok := i.ResponseWriter.(http.Hijacker)
 	if !ok {
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,6 +25,8 @@ type MonitoringResponse struct {
 	Units       map[string]dcos.Unit
 	Nodes       map[string]dcos.Node
 	UpdatedTime time.Time
+	// Drift is served by its own endpoint and it's not a part of the health report
+	Drift *drift.Report `json:"-"`
 }
 
 // UpdateMonitoringResponse will update the status tree.
@@ -33,6 +36,7 @@ func (mr *MonitoringResponse) UpdateMonitoringResponse(r *MonitoringResponse) {
 	mr.Nodes = r.Nodes
 	mr.Units = r.Units
 	mr.UpdatedTime = r.UpdatedTime
+	mr.Drift = r.Drift
 }
 
 // GetAllUnits returns all systemd units from status tree.
@@ -44,10 +48,11 @@ func (mr *MonitoringResponse) GetAllUnits() UnitsResponseJSONStruct {
 			var r []UnitResponseFieldsStruct
 			for _, unit := range mr.Units {
 				r = append(r, UnitResponseFieldsStruct{
-					unit.UnitName,
-					unit.PrettyName,
-					unit.Health,
-					unit.Title,
+					UnitID:     unit.UnitName,
+					PrettyName: unit.PrettyName,
+					UnitHealth: unit.Health,
+					UnitTitle:  unit.Title,
+					Level:      unit.Level,
 				})
 			}
 			return r
@@ -65,10 +70,11 @@ func (mr *MonitoringResponse) GetUnit(unitName string) (UnitResponseFieldsStruct
 	}
 
 	return UnitResponseFieldsStruct{
-		mr.Units[unitName].UnitName,
-		mr.Units[unitName].PrettyName,
-		mr.Units[unitName].Health,
-		mr.Units[unitName].Title,
+		UnitID:     mr.Units[unitName].UnitName,
+		PrettyName: mr.Units[unitName].PrettyName,
+		UnitHealth: mr.Units[unitName].Health,
+		UnitTitle:  mr.Units[unitName].Title,
+		Level:      mr.Units[unitName].Level,
 	}, nil
 
 }
@@ -85,9 +91,11 @@ func (mr *MonitoringResponse) GetNodesForUnit(unitName string) (NodesResponseJSO
 			var r []*NodeResponseFieldsStruct
 			for _, node := range mr.Units[unitName].Nodes {
 				r = append(r, &NodeResponseFieldsStruct{
-					node.IP,
-					node.Health,
-					node.Role,
+					HostIP:     node.IP,
+					NodeHealth: node.Health,
+					NodeRole:   node.Role,
+					Silenced:   node.Silenced,
+					Level:      node.Level,
 				})
 			}
 			return r
@@ -127,9 +135,13 @@ func (mr *MonitoringResponse) GetNodes() NodesResponseJSONStruct {
 			var nodes []*NodeResponseFieldsStruct
 			for _, node := range mr.Nodes {
 				nodes = append(nodes, &NodeResponseFieldsStruct{
-					node.IP,
-					node.Health,
-					node.Role,
+					HostIP:           node.IP,
+					NodeHealth:       node.Health,
+					NodeRole:         node.Role,
+					Silenced:         node.Silenced,
+					Level:            node.Level,
+					SuppressedHealth: node.SuppressedHealth,
+					SuppressedLevel:  node.SuppressedLevel,
 				})
 			}
 			return nodes
@@ -170,9 +182,13 @@ func (mr *MonitoringResponse) GetNodeByID(nodeIP string) (NodeResponseFieldsStru
 		return NodeResponseFieldsStruct{}, notFoundError{nodeIP}
 	}
 	return NodeResponseFieldsStruct{
-		mr.Nodes[nodeIP].IP,
-		mr.Nodes[nodeIP].Health,
-		mr.Nodes[nodeIP].Role,
+		HostIP:           mr.Nodes[nodeIP].IP,
+		NodeHealth:       mr.Nodes[nodeIP].Health,
+		NodeRole:         mr.Nodes[nodeIP].Role,
+		Silenced:         mr.Nodes[nodeIP].Silenced,
+		Level:            mr.Nodes[nodeIP].Level,
+		SuppressedHealth: mr.Nodes[nodeIP].SuppressedHealth,
+		SuppressedLevel:  mr.Nodes[nodeIP].SuppressedLevel,
 	}, nil
 }
 
@@ -188,10 +204,11 @@ func (mr *MonitoringResponse) GetNodeUnitsID(nodeIP string) (UnitsResponseJSONSt
 			var units []UnitResponseFieldsStruct
 			for _, unit := range mr.Nodes[nodeIp].Units {
 				units = append(units, UnitResponseFieldsStruct{
-					unit.UnitName,
-					unit.PrettyName,
-					unit.Health,
-					unit.Title,
+					UnitID:     unit.UnitName,
+					PrettyName: unit.PrettyName,
+					UnitHealth: unit.Health,
+					UnitTitle:  unit.Title,
+					Level:      unit.Level,
 				})
 			}
 			return units
@@ -216,12 +233,25 @@ func (mr *MonitoringResponse) GetNodeUnitByNodeIDUnitID(nodeIP, unitID string) (
 				UnitTitle:  unit.Title,
 				Help:       helpField,
 				PrettyName: unit.PrettyName,
+				Level:      unit.Level,
+				Reasons:    unit.Reasons,
+				Flapping:   unit.Flapping,
 			}, nil
 		}
 	}
 	return HealthResponseValues{}, notFoundError{unitID}
 }
 
+// GetDrift returns configuration drift of nodes found by the last pull.
+func (mr *MonitoringResponse) GetDrift() (drift.Report, error) {
+	mr.Lock()
+	defer mr.Unlock()
+	if mr.Drift == nil {
+		return drift.Report{}, notFoundError{"drift report"}
+	}
+	return *mr.Drift, nil
+}
+
 // GetLastUpdatedTime returns timestamp of latest updated monitoring response.
 func (mr *MonitoringResponse) GetLastUpdatedTime() string {
 	mr.Lock()
@@ -13,4 +13,8 @@ type UnitPropertiesResponse struct {
 	ActiveEnterTimestampMonotonic   uint64
 	ActiveExitTimestampMonotonic    uint64
 	InactiveEnterTimestampMonotonic uint64
+	StateChangeTimestampMonotonic   uint64
+
+	// NRestarts is reported by systemd 235 and newer
+	NRestarts uint64
 }
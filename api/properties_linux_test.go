@@ -3,9 +3,12 @@ package api
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/flapping"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnitPropertiesResponse_CheckUnitHealth(t *testing.T) {
@@ -72,3 +75,72 @@ func TestUnitPropertiesResponse_CheckUnitHealth(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizePropertyReportsFlapping(t *testing.T) {
+	detector := flapping.NewDetector(time.Hour, 2)
+	props := map[string]interface{}{
+		"Id":          "dcos-mesos-slave.service",
+		"LoadState":   "loaded",
+		"ActiveState": "active",
+		"SubState":    "running",
+		"Description": "Mesos Agent: distributed systems kernel agent",
+		"NRestarts":   uint32(1),
+	}
+
+	unit, err := normalizeProperty(props, &fakeDCOSTools{}, detector)
+	require.NoError(t, err)
+	assert.Nil(t, unit.Flapping, "unit was not observed yet")
+
+	detector.Observe("dcos-mesos-slave.service", 1, 0)
+	unit, err = normalizeProperty(props, &fakeDCOSTools{}, detector)
+	require.NoError(t, err)
+	assert.Equal(t, dcos.LevelOK, unit.Level)
+	assert.Empty(t, unit.Reasons)
+	require.NotNil(t, unit.Flapping)
+	assert.False(t, unit.Flapping.Flapping)
+
+	detector.Observe("dcos-mesos-slave.service", 3, 0)
+	unit, err = normalizeProperty(props, &fakeDCOSTools{}, detector)
+	require.NoError(t, err)
+	assert.Equal(t, dcos.LevelWarning, unit.Level)
+	assert.Equal(t, dcos.Health(dcos.Healthy), unit.UnitHealth, "warnings are healthy for older clients")
+	require.Len(t, unit.Reasons, 1)
+	assert.Contains(t, unit.Reasons[0], "dcos-mesos-slave.service restarted 2 times since")
+	assert.Equal(t, uint64(2), unit.Flapping.Restarts)
+	assert.True(t, unit.Flapping.Flapping)
+}
+
+func TestGetUnitsPropertiesDoesNotObserveRestarts(t *testing.T) {
+	tools := &fakeDCOSTools{}
+	units := &SystemdUnits{Flapping: flapping.NewDetector(time.Hour, 1)}
+
+	report, err := units.GetUnitsProperties(tools)
+	require.NoError(t, err)
+	for _, u := range report.Array {
+		assert.Nil(t, u.Flapping, u.UnitID)
+	}
+
+	require.NoError(t, units.ObserveRestarts(tools))
+	// requesting health many times does not change the observed rate
+	for i := 0; i < 3; i++ {
+		report, err = units.GetUnitsProperties(tools)
+		require.NoError(t, err)
+		require.NotEmpty(t, report.Array)
+		for _, u := range report.Array {
+			require.NotNil(t, u.Flapping, u.UnitID)
+			assert.Equal(t, uint64(0), u.Flapping.Restarts)
+		}
+	}
+	_, ok := units.Flapping.Rate("unit_to_fail")
+	assert.False(t, ok, "units without properties are not observed")
+}
+
+func TestNormalizePropertyWithoutDetector(t *testing.T) {
+	props := map[string]interface{}{"Id": "a.service", "LoadState": "loaded", "ActiveState": "inactive", "SubState": "dead"}
+
+	unit, err := normalizeProperty(props, &fakeDCOSTools{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, dcos.LevelCritical, unit.Level)
+	assert.Equal(t, []string{"Unit a.service is dead"}, unit.Reasons)
+	assert.Nil(t, unit.Flapping)
+}
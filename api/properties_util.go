@@ -1,14 +1,19 @@
 package api
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/flapping"
 	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
 )
 
-func normalizeProperty(unitProps map[string]interface{}, tools dcos.Tooler) (HealthResponseValues, error) {
+// normalizeProperty returns health of the unit. Units restarted too often are reported with a warning
+// if the detector is set. The detector is only read, restarts are observed by SystemdUnits.StartObservingRestarts.
+func normalizeProperty(unitProps map[string]interface{}, tools dcos.Tooler, detector *flapping.Detector) (HealthResponseValues, error) {
 	var (
 		description, prettyName string
 		propsResponse           UnitPropertiesResponse
@@ -23,6 +28,22 @@ func normalizeProperty(unitProps map[string]interface{}, tools dcos.Tooler) (Hea
 		return HealthResponseValues{}, err
 	}
 
+	level := dcos.LevelOf(unitHealth)
+	var reasons []string
+	if unitOutput != "" {
+		reasons = append(reasons, unitOutput)
+	}
+	var rate *flapping.Rate
+	if detector != nil {
+		if r, ok := detector.Rate(propsResponse.ID); ok {
+			rate = &r
+			if r.Flapping {
+				level = dcos.WorstLevel(level, dcos.LevelWarning)
+				reasons = append(reasons, fmt.Sprintf("%s restarted %d times since %s", propsResponse.ID, r.Restarts, r.Since.Format(time.RFC3339)))
+			}
+		}
+	}
+
 	if unitHealth > 0 {
 		journalOutput, err := tools.GetJournalOutput(propsResponse.ID)
 		if err == nil {
@@ -50,5 +71,22 @@ func normalizeProperty(unitProps map[string]interface{}, tools dcos.Tooler) (Hea
 		UnitTitle:  description,
 		Help:       "",
 		PrettyName: prettyName,
+		Level:      level,
+		Reasons:    reasons,
+		Flapping:   rate,
 	}, nil
 }
+
+// setLevels fills levels of units reported without them, e.g., by health checks, and keeps health consistent with levels
+func setLevels(units []HealthResponseValues) {
+	for i := range units {
+		u := &units[i]
+		if u.Level == "" {
+			u.Level = dcos.LevelOf(u.UnitHealth)
+			if u.Level != dcos.LevelOK && u.UnitOutput != "" {
+				u.Reasons = strings.Split(u.UnitOutput, "\n")
+			}
+		}
+		u.UnitHealth = u.Level.Health()
+	}
+}
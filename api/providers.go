@@ -1,15 +1,24 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/containers"
+	"github.com/dcos/dcos-diagnostics/netdiag"
+	"github.com/dcos/dcos-diagnostics/sysinfo"
+	"github.com/dcos/dcos-diagnostics/tlsinspect"
+	"github.com/dcos/dcos-diagnostics/units"
 	"github.com/dcos/dcos-diagnostics/util"
 
 	"github.com/dcos/dcos-diagnostics/config"
@@ -23,6 +32,8 @@ type LogProviders struct {
 	HTTPEndpoints []HTTPProvider
 	LocalFiles    []FileProvider
 	LocalCommands []CommandProvider
+	Containers    []ContainersProvider
+	TLSEndpoints  []TLSProvider
 }
 
 // HTTPProvider is a provider for fetching an HTTP endpoint.
@@ -32,13 +43,89 @@ type HTTPProvider struct {
 	FileName string
 	Role     []string
 	Optional bool
+	CollectorOptions
+	Sampling
 }
 
-// FileProvider is a local file provider.
+// Sampling makes the HTTP provider poll its endpoint many times during bundle creation and store
+// the time series of metrics, see collector.SamplerOptions.
+type Sampling struct {
+	Samples int `json:",omitempty"`
+	// Interval is a duration e.g., 10s
+	Interval     string   `json:",omitempty"`
+	SampleFormat string   `json:",omitempty"`
+	Families     []string `json:",omitempty"`
+}
+
+func (s Sampling) samplerOptions() collector.SamplerOptions {
+	interval, _ := time.ParseDuration(s.Interval)
+	return collector.SamplerOptions{
+		Samples:  s.Samples,
+		Interval: interval,
+		Format:   s.SampleFormat,
+		Families: s.Families,
+	}
+}
+
+func sampleFormat(format string) string {
+	if format == "" {
+		return collector.SampleFormatJSONL
+	}
+	return format
+}
+
+// FileProvider is a local file provider. Location could be a directory or a shell pattern
+// of files selected with FileSelection.
 type FileProvider struct {
 	Location string
 	Role     []string
 	Optional bool
+	CollectorOptions
+	FileSelection
+}
+
+// FileSelection selects files of a directory or a shell pattern, see collector.GlobOptions.
+type FileSelection struct {
+	Include       []string `json:",omitempty"`
+	Exclude       []string `json:",omitempty"`
+	MaxDepth      int      `json:",omitempty"`
+	MaxFiles      int      `json:",omitempty"`
+	MaxTotalBytes int64    `json:",omitempty"`
+	Newest        int      `json:",omitempty"`
+	Symlinks      string   `json:",omitempty"`
+}
+
+func (s FileSelection) globOptions() collector.GlobOptions {
+	return collector.GlobOptions{
+		Include:       s.Include,
+		Exclude:       s.Exclude,
+		MaxDepth:      s.MaxDepth,
+		MaxFiles:      s.MaxFiles,
+		MaxTotalBytes: s.MaxTotalBytes,
+		Newest:        s.Newest,
+		Symlinks:      s.Symlinks,
+	}
+}
+
+// isGlob returns true if the provider collects many files: its location is a pattern, a directory
+// or files are selected with options.
+func (p FileProvider) isGlob() bool {
+	if collector.IsGlob(p.Location) || !reflect.DeepEqual(p.FileSelection, FileSelection{}) {
+		return true
+	}
+	info, err := os.Stat(p.Location)
+	return err == nil && info.IsDir()
+}
+
+// fileCollector returns the collector of the provider. Files collected by Glob are named after their
+// path relative to the location directory.
+func fileCollector(p FileProvider) collector.Collector {
+	if p.isGlob() {
+		root, _ := collector.SplitGlob(p.Location)
+		name := strings.TrimLeft(filepath.ToSlash(root), "/")
+		return collector.NewGlob(name, p.Optional, p.Location, p.globOptions())
+	}
+	return collector.NewFile(strings.TrimLeft(p.Location, "/"), p.Optional, p.Location)
 }
 
 // CommandProvider is a local command to execute.
@@ -46,6 +133,109 @@ type CommandProvider struct {
 	Command  []string
 	Role     []string
 	Optional bool
+	CollectorOptions
+}
+
+// ContainersProvider inspects containers of Docker, containerd and the Mesos containerizer, see
+// collector.ContainersOptions. Runtimes are looked up at their default locations if not set.
+type ContainersProvider struct {
+	// Name of the directory with collected files, "containers" if empty
+	Name             string `json:",omitempty"`
+	DockerSocket     string `json:",omitempty"`
+	ContainerdSocket string `json:",omitempty"`
+	ContainerdState  string `json:",omitempty"`
+	ContainerdLogDir string `json:",omitempty"`
+	MesosRuntimeDir  string `json:",omitempty"`
+	MesosWorkDir     string `json:",omitempty"`
+	CgroupRoot       string `json:",omitempty"`
+	LogLines         int    `json:",omitempty"`
+	LogMaxBytes      int64  `json:",omitempty"`
+	Role             []string
+	Optional         bool
+	CollectorOptions
+}
+
+func (p ContainersProvider) name() string {
+	if p.Name == "" {
+		return "containers"
+	}
+	return p.Name
+}
+
+func (p ContainersProvider) containersOptions() collector.ContainersOptions {
+	return collector.ContainersOptions{
+		Options: containers.Options{
+			DockerSocket:     p.DockerSocket,
+			ContainerdSocket: p.ContainerdSocket,
+			ContainerdState:  p.ContainerdState,
+			ContainerdLogDir: p.ContainerdLogDir,
+			MesosRuntimeDir:  p.MesosRuntimeDir,
+			MesosWorkDir:     p.MesosWorkDir,
+			CgroupRoot:       p.CgroupRoot,
+		},
+		LogLines:    p.LogLines,
+		LogMaxBytes: p.LogMaxBytes,
+	}
+}
+
+// TLSProvider is a TLS endpoint whose certificates are inspected by the TLS collector and health check.
+type TLSProvider struct {
+	// Name identifies the endpoint, its health is reported as tls-<Name> unit
+	Name string
+	// Host is the daemon hostname if empty
+	Host string `json:",omitempty"`
+	Port int
+	// ServerName is sent in SNI and verified against certificate SANs, Host if empty
+	ServerName string `json:",omitempty"`
+	Role       []string
+}
+
+// tlsEndpoints returns endpoints of providers matching the role
+func tlsEndpoints(cfg *config.Config, providers []TLSProvider, role string) []tlsinspect.Endpoint {
+	var endpoints []tlsinspect.Endpoint
+	for _, p := range providers {
+		if !roleMatched(role, p.Role) {
+			continue
+		}
+		host := p.Host
+		if host == "" {
+			host = cfg.FlagHostname
+		}
+		endpoints = append(endpoints, tlsinspect.Endpoint{
+			Name:       p.Name,
+			Address:    net.JoinHostPort(host, strconv.Itoa(p.Port)),
+			ServerName: p.ServerName,
+		})
+	}
+	return endpoints
+}
+
+// CollectorOptions are limits and hints of a single provider, see collector.Options.
+type CollectorOptions struct {
+	// Timeout is a duration e.g., 30s
+	Timeout     string   `json:",omitempty"`
+	MaxBytes    int64    `json:",omitempty"`
+	Retries     int      `json:",omitempty"`
+	TailOnly    bool     `json:",omitempty"`
+	Compression string   `json:",omitempty"`
+	Tags        []string `json:",omitempty"`
+}
+
+// collectorOptions converts options read from the config. Options are validated when the config is loaded.
+func (o CollectorOptions) collectorOptions() collector.Options {
+	timeout, _ := time.ParseDuration(o.Timeout)
+	compression := o.Compression
+	if compression == "none" {
+		compression = ""
+	}
+	return collector.Options{
+		Timeout:     timeout,
+		MaxBytes:    o.MaxBytes,
+		Retries:     o.Retries,
+		TailOnly:    o.TailOnly,
+		Compression: compression,
+		Tags:        o.Tags,
+	}
 }
 
 const (
@@ -79,13 +269,15 @@ func loadExternalProviders(endpointsConfgFiles []string) (externalProviders LogP
 		if err != nil {
 			return externalProviders, fmt.Errorf("could not read %s: %s", endpointsConfigFile, err)
 		}
-		var logProviders LogProviders
-		if err = json.Unmarshal(endpointsConfig, &logProviders); err != nil {
+		logProviders, issues := parseEndpointsConfig(endpointsConfigFile, endpointsConfig, LintOptions{})
+		if err := issuesError(issues); err != nil {
 			return externalProviders, fmt.Errorf("could not parse %s: %s", endpointsConfigFile, err)
 		}
 		externalProviders.HTTPEndpoints = append(externalProviders.HTTPEndpoints, logProviders.HTTPEndpoints...)
 		externalProviders.LocalFiles = append(externalProviders.LocalFiles, logProviders.LocalFiles...)
 		externalProviders.LocalCommands = append(externalProviders.LocalCommands, logProviders.LocalCommands...)
+		externalProviders.Containers = append(externalProviders.Containers, logProviders.Containers...)
+		externalProviders.TLSEndpoints = append(externalProviders.TLSEndpoints, logProviders.TLSEndpoints...)
 	}
 
 	return externalProviders, nil
@@ -105,21 +297,42 @@ func loadSystemdCollectors(cfg *config.Config, DCOSTools dcos.Tooler) ([]collect
 	units = append(units, cfg.SystemdUnits...)
 	collectors := make([]collector.Collector, 0, len(units))
 
-	duration, err := time.ParseDuration(cfg.FlagDiagnosticsBundleUnitsLogsSinceString)
+	options, err := journalOptionsFromConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing '%s': %s", cfg.FlagDiagnosticsBundleUnitsLogsSinceString, err)
+		return nil, err
 	}
 
 	for _, unit := range units {
 		collectors = append(
 			collectors,
-			collector.NewSystemd(unit, false, unit, duration),
+			collector.NewSystemdWithOptions(unit, false, unit, options),
 		)
 	}
 
 	return collectors, nil
 }
 
+// journalOptionsFromConfig returns options used to read units logs for the bundle
+func journalOptionsFromConfig(cfg *config.Config) (units.JournalOptions, error) {
+	options := units.DefaultJournalOptions()
+
+	duration, err := time.ParseDuration(cfg.FlagDiagnosticsBundleUnitsLogsSinceString)
+	if err != nil {
+		return options, fmt.Errorf("error parsing '%s': %s", cfg.FlagDiagnosticsBundleUnitsLogsSinceString, err)
+	}
+	options.Since = duration
+
+	if options.Format, err = units.ParseJournalFormat(cfg.FlagDiagnosticsBundleUnitsLogsFormat); err != nil {
+		return options, err
+	}
+	if cfg.FlagDiagnosticsBundleUnitsLogsPriority != "" {
+		if options.MaxPriority, err = units.ParsePriority(cfg.FlagDiagnosticsBundleUnitsLogsPriority); err != nil {
+			return options, err
+		}
+	}
+	return options, nil
+}
+
 func loadInternalProviders(cfg *config.Config, DCOSTools dcos.Tooler) (internalConfigProviders LogProviders, err error) {
 	units, err := DCOSTools.GetUnitNames()
 	if err != nil {
@@ -188,6 +401,16 @@ func LoadCollectors(cfg *config.Config, tools dcos.Tooler, client *http.Client)
 		FileName: "dcos-diagnostics-health.json",
 	})
 
+	// masters pulling health of the cluster add its configuration drift
+	if role == dcos.MasterRole && cfg.FlagPull {
+		providers.HTTPEndpoints = append(providers.HTTPEndpoints, HTTPProvider{
+			Port:     port,
+			URI:      driftEndpoint,
+			FileName: "dcos-diagnostics-drift.json",
+			Optional: true,
+		})
+	}
+
 	// set filename if not set, some endpoints might be named e.g., after corresponding unit
 	for _, endpoint := range providers.HTTPEndpoints {
 		if !roleMatched(role, endpoint.Role) {
@@ -195,6 +418,9 @@ func LoadCollectors(cfg *config.Config, tools dcos.Tooler, client *http.Client)
 		}
 
 		fileName := fmt.Sprintf("%d-%s.json", endpoint.Port, util.SanitizeString(endpoint.URI))
+		if endpoint.Samples > 0 {
+			fileName = fmt.Sprintf("%d-%s.%s", endpoint.Port, util.SanitizeString(endpoint.URI), sampleFormat(endpoint.SampleFormat))
+		}
 		if endpoint.FileName != "" {
 			fileName = endpoint.FileName
 		}
@@ -205,8 +431,11 @@ func LoadCollectors(cfg *config.Config, tools dcos.Tooler, client *http.Client)
 
 		}
 
-		c := collector.NewEndpoint(fileName, endpoint.Optional, url, client)
-		collectors = append(collectors, c)
+		var c collector.Collector = collector.NewEndpoint(fileName, endpoint.Optional, url, client)
+		if endpoint.Samples > 0 {
+			c = collector.NewSampler(fileName, endpoint.Optional, url, client, endpoint.samplerOptions())
+		}
+		collectors = append(collectors, collector.WithOptions(c, endpoint.collectorOptions()))
 	}
 
 	for _, fileProvider := range providers.LocalFiles {
@@ -214,9 +443,8 @@ func LoadCollectors(cfg *config.Config, tools dcos.Tooler, client *http.Client)
 			continue
 		}
 
-		key := strings.TrimLeft(fileProvider.Location, "/")
-		c := collector.NewFile(key, fileProvider.Optional, fileProvider.Location)
-		collectors = append(collectors, c)
+		c := fileCollector(fileProvider)
+		collectors = append(collectors, collector.WithOptions(c, fileProvider.collectorOptions()))
 	}
 
 	// sanitize command to use as filename
@@ -229,9 +457,69 @@ func LoadCollectors(cfg *config.Config, tools dcos.Tooler, client *http.Client)
 		trimmedCmdWithArgs := strings.Replace(cmdWithArgs, "/", "", -1)
 		key := fmt.Sprintf("%s.output", trimmedCmdWithArgs)
 		c := collector.NewCmd(key, commandProvider.Optional, commandProvider.Command)
-		collectors = append(collectors, c)
+		collectors = append(collectors, collector.WithOptions(c, commandProvider.collectorOptions()))
 
 	}
 
+	for _, containersProvider := range providers.Containers {
+		if !roleMatched(role, containersProvider.Role) {
+			continue
+		}
+		c := collector.NewContainers(containersProvider.name(), containersProvider.Optional, containersProvider.containersOptions())
+		collectors = append(collectors, collector.WithOptions(c, containersProvider.collectorOptions()))
+	}
+
+	if endpoints := tlsEndpoints(cfg, providers.TLSEndpoints, role); len(endpoints) > 0 {
+		inspector, err := tlsInspector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		collectors = append(collectors, collector.NewTLS("tls-certificates.json", true, inspector, endpoints))
+	}
+
+	if cfg.FlagDiagnosticsSystemSnapshot && runtime.GOOS == "linux" {
+		collectors = append(collectors, collector.NewSystem("system-snapshot.json", true, sysinfo.NewReader()))
+	}
+
+	if cfg.FlagDiagnosticsNetwork {
+		collectors = append(collectors, collector.NewNetwork("network.json", true, networkDiagnoser(cfg, tools, role)))
+	}
+
+	if cfg.FlagZooKeeperHealth && role == dcos.MasterRole {
+		collectors = append(collectors, collector.NewZooKeeper("zookeeper.json", true, zookeeperInspector(cfg, tools, client)))
+	}
+
 	return collectors, nil
 }
+
+// networkDiagnoser returns Diagnoser of the node probing masters and agents found by tools
+func networkDiagnoser(cfg *config.Config, tools dcos.Tooler, role string) *netdiag.Diagnoser {
+	node := netdiag.Node{Role: role, Hostname: cfg.FlagHostname}
+	ip, err := tools.DetectIP()
+	if err != nil {
+		logrus.WithError(err).Warn("Could not detect IP, network diagnostics will probe this node")
+	}
+	node.IP = ip
+
+	d := netdiag.NewDiagnoser(node, func() ([]string, []string, error) {
+		masters, err := tools.GetMasterNodes()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get master nodes: %s", err)
+		}
+		agents, err := tools.GetAgentNodes()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get agent nodes: %s", err)
+		}
+		return nodeIPs(masters), nodeIPs(agents), nil
+	})
+	d.AgentSample = cfg.FlagDiagnosticsNetworkAgentSample
+	return d
+}
+
+func nodeIPs(nodes []dcos.Node) []string {
+	ips := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ips = append(ips, node.IP)
+	}
+	return ips
+}
@@ -6,8 +6,15 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/netdiag"
+	"github.com/dcos/dcos-diagnostics/units"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadCollectors(t *testing.T) {
@@ -112,3 +119,179 @@ func TestLoadCollectors_GetNodeRoleReturnsInvalidRole(t *testing.T) {
 	assert.EqualError(t, err, "incorrect role invalid, must be: master, agent or agent_public")
 	assert.Empty(t, got)
 }
+
+func TestLoadCollectors_WithOptions(t *testing.T) {
+	t.Parallel()
+	tools := new(MockedTools)
+
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{
+		filepath.Join("testdata", "endpoint-config-options.json"),
+	}
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	options := map[string]collector.Options{}
+	for _, c := range got {
+		options[c.Name()] = collector.OptionsOf(c)
+	}
+	assert.Equal(t, map[string]collector.Options{
+		"state.json":                   {Timeout: 30 * time.Second, Retries: 2, Compression: collector.CompressionGzip, Tags: []string{"mesos"}},
+		"dcos-diagnostics-health.json": {},
+		"var/log/mesos.log":            {MaxBytes: 1048576, TailOnly: true},
+		"echo_OK.output":               {TailOnly: true},
+	}, options)
+}
+
+func TestLoadCollectors_Samplers(t *testing.T) {
+	t.Parallel()
+	tools := new(MockedTools)
+
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{
+		filepath.Join("testdata", "endpoint-config-sampler.json"),
+	}
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	assert.Equal(t, "5050-metrics_snapshot.jsonl", got[0].Name())
+	assert.IsType(t, &collector.Sampler{}, got[0])
+	assert.Equal(t, "telegraf-metrics.csv", got[1].Name())
+	assert.IsType(t, &collector.Sampler{}, got[1])
+	assert.Equal(t, "dcos-diagnostics-health.json", got[2].Name())
+	assert.IsType(t, &collector.Endpoint{}, got[2])
+}
+
+func TestLoadCollectors_Containers(t *testing.T) {
+	t.Parallel()
+
+	for role, expected := range map[string]string{"agent": "containers", "master": "master-containers"} {
+		tools := new(MockedTools)
+		tools.On("GetNodeRole").Return(role, nil)
+		if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+			tools.On("GetUnitNames").Return([]string{}, nil)
+		}
+		cfg := testCfg()
+		cfg.FlagAgentPort = 61001
+		cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{
+			filepath.Join("testdata", "endpoint-config-containers.json"),
+		}
+
+		got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+		require.NoError(t, err)
+		require.Len(t, got, 2, role)
+
+		assert.Equal(t, "dcos-diagnostics-health.json", got[0].Name())
+		assert.Equal(t, expected, got[1].Name())
+		if role == "agent" {
+			assert.False(t, got[1].Optional())
+			assert.Equal(t, collector.Options{Timeout: time.Minute, MaxBytes: 1048576}, collector.OptionsOf(got[1]))
+		} else {
+			assert.True(t, got[1].Optional())
+			assert.IsType(t, &collector.Containers{}, got[1])
+		}
+	}
+}
+
+func TestLoadCollectors_SystemSnapshot(t *testing.T) {
+	t.Parallel()
+	tools := new(MockedTools)
+
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagDiagnosticsSystemSnapshot = true
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	if runtime.GOOS != "linux" {
+		require.Len(t, got, 1)
+		return
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "system-snapshot.json", got[1].Name())
+	assert.True(t, got[1].Optional())
+	assert.IsType(t, &collector.System{}, got[1])
+}
+
+func TestLoadCollectors_Network(t *testing.T) {
+	t.Parallel()
+	tools := new(MockedTools)
+
+	tools.On("GetNodeRole").Return("agent", nil)
+	tools.On("DetectIP").Return("10.0.1.1", nil)
+	tools.On("GetMasterNodes").Return([]dcos.Node{{IP: "10.0.0.1"}}, nil)
+	tools.On("GetAgentNodes").Return([]dcos.Node{{IP: "10.0.1.1"}, {IP: "10.0.1.2"}}, nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagDiagnosticsNetwork = true
+	cfg.FlagDiagnosticsNetworkAgentSample = -1
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "network.json", got[1].Name())
+	assert.True(t, got[1].Optional())
+	assert.IsType(t, &collector.Network{}, got[1])
+
+	d := networkDiagnoser(cfg, tools, "agent")
+	assert.Equal(t, netdiag.Node{IP: "10.0.1.1", Role: "agent", Hostname: "master-0"}, d.Node)
+	targets, err := d.Targets()
+	require.NoError(t, err)
+	assert.Len(t, targets, len(netdiag.MasterPorts)+len(netdiag.AgentPorts))
+	assert.Equal(t, "10.0.1.2", targets[len(targets)-1].Host)
+}
+
+func TestJournalOptionsFromConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleUnitsLogsFormat = "json"
+	cfg.FlagDiagnosticsBundleUnitsLogsPriority = "warning"
+
+	options, err := journalOptionsFromConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, units.JSON, options.Format)
+	assert.Equal(t, 4, options.MaxPriority)
+	assert.Equal(t, 24*time.Hour, options.Since)
+
+	cfg.FlagDiagnosticsBundleUnitsLogsFormat = "cat"
+	_, err = journalOptionsFromConfig(cfg)
+	assert.EqualError(t, err, `unknown journal format "cat", supported formats are: short, short-iso, json, export`)
+}
+
+func TestLoadCollectors_Drift(t *testing.T) {
+	t.Parallel()
+	tools := new(MockedTools)
+
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{}, nil)
+	}
+	cfg := testCfg()
+	cfg.FlagPull = true
+
+	got, err := LoadCollectors(cfg, tools, http.DefaultClient)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "dcos-diagnostics-drift.json", got[1].Name())
+	assert.True(t, got[1].Optional())
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
+	"github.com/dcos/dcos-diagnostics/silence"
 	"github.com/dcos/dcos-diagnostics/util"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +22,10 @@ type pull struct {
 	runPullerChan      <-chan bool
 	runPullerDoneChan  chan<- bool
 	monitoringResponse *MonitoringResponse
+	// leader returns IPs of the leading master
+	leader func() ([]string, error)
+	// silences suppress health of nodes and units in maintenance, nil store silences nothing
+	silences *silence.Store
 }
 
 // StartPullWithInterval will start to pull a DC/OS cluster health status
@@ -31,6 +37,8 @@ func StartPullWithInterval(dt *Dt) {
 		runPullerChan:      dt.RunPullerChan,
 		runPullerDoneChan:  dt.RunPullerDoneChan,
 		monitoringResponse: dt.MR,
+		leader:             lookupLeader,
+		silences:           dt.Silences,
 	}
 	for {
 		p.runPull()
@@ -50,7 +58,7 @@ func StartPullWithInterval(dt *Dt) {
 }
 
 func (p *pull) runPull() {
-	clusterNodes, err := p.tools.GetMasterNodes()
+	masterNodes, err := p.tools.GetMasterNodes()
 	if err != nil {
 		logrus.Errorf("Could not get master nodes: %s", err)
 	}
@@ -60,6 +68,8 @@ func (p *pull) runPull() {
 		logrus.Errorf("Could not get agent nodes: %s", err)
 	}
 
+	var clusterNodes []dcos.Node
+	clusterNodes = append(clusterNodes, masterNodes...)
 	clusterNodes = append(clusterNodes, agentNodes...)
 
 	// If not nodes found we should wait for a timeout between trying the next pull.
@@ -85,47 +95,177 @@ func (p *pull) runPull() {
 	}
 	wg.Wait()
 
+	p.syncSilences(masterNodes)
+
 	// update collected units/nodes health statuses
-	p.updateHealthStatus(respChan)
+	p.updateHealthStatus(respChan, masterNodes, agentNodes)
 }
 
-// function builds a map of all unique units with status
-func (p *pull) updateHealthStatus(responses <-chan *httpResponse) {
+// function builds a map of all unique units with status and adds synthetic units derived from the whole cluster
+func (p *pull) updateHealthStatus(responses <-chan *httpResponse, masters, agents []dcos.Node) {
 	var (
-		units = make(map[string]dcos.Unit)
-		nodes = make(map[string]dcos.Node)
+		units     = make(map[string]dcos.Unit)
+		nodes     = make(map[string]dcos.Node)
+		collected []*httpResponse
 	)
 
 	for {
 		select {
 		case response := <-responses:
+			collected = append(collected, response)
 			node := response.Node
 			node.Units = response.Units
-			nodes[response.Node.IP] = node
+			nodes[response.Node.IP] = p.suppressNode(node)
 
 			for _, currentUnit := range response.Units {
+				currentUnit = p.suppressUnit(node.IP, currentUnit)
+				currentUnit.Reasons = nodeReasons(node.IP, currentUnit.Reasons)
+				currentUnit.Flapping = nil
 				u, ok := units[currentUnit.UnitName]
 				if ok {
 					u.Nodes = append(u.Nodes, currentUnit.Nodes...)
 					if currentUnit.Health > u.Health {
 						u.Health = currentUnit.Health
 					}
+					u.Level = dcos.WorstLevel(u.Level, currentUnit.Level)
+					u.Reasons = append(u.Reasons, currentUnit.Reasons...)
+					// unit is silenced only if it's silenced on all nodes
+					u.Silenced = u.Silenced && currentUnit.Silenced
 					units[currentUnit.UnitName] = u
 				} else {
 					units[currentUnit.UnitName] = currentUnit
 				}
 			}
 		default:
+			for _, unit := range clusterUnits(clusterState{
+				masters:   masters,
+				agents:    agents,
+				responses: collected,
+				leader:    p.leader,
+				timestamp: p.tools.GetTimestamp(),
+			}) {
+				unit.Level = dcos.LevelOf(unit.Health)
+				if p.silences.Silenced("", unit.UnitName) {
+					unit.Health = dcos.Healthy
+					unit.Level = dcos.LevelOK
+					unit.Silenced = true
+				}
+				units[unit.UnitName] = unit
+			}
+			updateHealthMetrics(nodes, units)
+			report := drift.Compare(driftNodes(collected))
 			p.monitoringResponse.UpdateMonitoringResponse(&MonitoringResponse{
 				Nodes:       nodes,
 				Units:       units,
 				UpdatedTime: time.Now(),
+				Drift:       &report,
 			})
 			return
 		}
 	}
 }
 
+// suppressNode sets suppressed health of the node that is silenced or has silenced units. Silenced node is healthy,
+// suppressed health of a node with silenced units is computed from other units. Raw health of the node is kept.
+func (p *pull) suppressNode(node dcos.Node) dcos.Node {
+	if p.silences.Silenced(node.IP, "") {
+		var healthy dcos.Health = dcos.Healthy
+		node.SuppressedHealth = &healthy
+		node.SuppressedLevel = dcos.LevelOK
+		node.Silenced = true
+		return node
+	}
+
+	var health dcos.Health = dcos.Healthy
+	level := dcos.LevelOK
+	silencedUnits := false
+	for _, unit := range node.Units {
+		if p.silences.Silenced(node.IP, unit.UnitName) {
+			silencedUnits = true
+			continue
+		}
+		if unit.Health > health {
+			health = unit.Health
+		}
+		level = dcos.WorstLevel(level, unit.Level)
+	}
+	if silencedUnits {
+		node.SuppressedHealth = &health
+		node.SuppressedLevel = level
+	}
+	return node
+}
+
+// suppressUnit returns a copy of the unit reported by the node that is healthy if the unit is silenced on the node
+func (p *pull) suppressUnit(ip string, unit dcos.Unit) dcos.Unit {
+	if !p.silences.Silenced(ip, unit.UnitName) {
+		return unit
+	}
+	unit.Health = dcos.Healthy
+	unit.Level = dcos.LevelOK
+	unit.Reasons = nil
+	unit.Silenced = true
+	// nodes are copied so units of the node keep their raw health
+	nodes := make([]dcos.Node, 0, len(unit.Nodes))
+	for _, n := range unit.Nodes {
+		n.Health = dcos.Healthy
+		n.Silenced = true
+		nodes = append(nodes, n)
+	}
+	unit.Nodes = nodes
+	return unit
+}
+
+// nodeReasons returns reasons of the unit health prefixed with the node IP, so they could be told apart once
+// units of all nodes are aggregated
+func nodeReasons(ip string, reasons []string) []string {
+	if len(reasons) == 0 {
+		return nil
+	}
+	prefixed := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		prefixed = append(prefixed, ip+": "+r)
+	}
+	return prefixed
+}
+
+// syncSilences merges silences of other masters, so silences created on any master are applied by all of them
+func (p *pull) syncSilences(masters []dcos.Node) {
+	if p.silences == nil {
+		return
+	}
+	for _, master := range masters {
+		silences, err := p.pullSilences(master)
+		if err != nil {
+			logrus.WithError(err).WithField("IP", master.IP).Warn("Could not get silences")
+			continue
+		}
+		if err := p.silences.Merge(silences); err != nil {
+			logrus.WithError(err).WithField("IP", master.IP).Error("Could not merge silences")
+		}
+	}
+}
+
+// pullSilences returns all silences known to the master
+func (p *pull) pullSilences(master dcos.Node) ([]silence.Silence, error) {
+	url, err := util.UseTLSScheme(fmt.Sprintf("http://%s:%d%s", master.IP, p.cfg.FlagMasterPort, nodeSilencesEndpoint), p.cfg.FlagForceTLS)
+	if err != nil {
+		return nil, fmt.Errorf("could not read useTLSScheme: %s", err)
+	}
+	body, statusCode, err := p.tools.Get(url, time.Duration(p.cfg.FlagPullTimeoutSec)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s: %s", url, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s. Return code %d", url, statusCode)
+	}
+	var silences []silence.Silence
+	if err := json.Unmarshal(body, &silences); err != nil {
+		return nil, fmt.Errorf("could not deserialize silences: %s", err)
+	}
+	return silences, nil
+}
+
 func (p *pull) pullHostStatus(host dcos.Node, respChan chan<- *httpResponse, wg *sync.WaitGroup, rateLimiter chan struct{}) {
 	defer wg.Done()
 	defer func() { rateLimiter <- struct{}{} }()
@@ -136,6 +276,7 @@ func (p *pull) pullHostStatus(host dcos.Node, respChan chan<- *httpResponse, wg
 	markNodeHealthAsUnknown := func(statusCode int) {
 		response.Status = statusCode
 		host.Health = dcos.Unknown
+		host.Level = dcos.LevelUnknown
 		response.Node = host
 		respChan <- &response
 	}
@@ -180,6 +321,8 @@ func (p *pull) pullHostStatus(host dcos.Node, respChan chan<- *httpResponse, wg
 		return
 	}
 	response.Status = statusCode
+	response.DcosVersion = jsonBody.DcosVersion
+	response.DiagnosticsVersion = jsonBody.TdtVersion
 
 	// Update Response and send it back to respChan
 	host.Host = jsonBody.Hostname
@@ -197,6 +340,15 @@ func (p *pull) pullHostStatus(host dcos.Node, respChan chan<- *httpResponse, wg
 		}
 	}
 
+	// nodes running older dcos-diagnostics do not report levels
+	host.Level = dcos.LevelOK
+	for i, propertiesMap := range jsonBody.Array {
+		if propertiesMap.Level == "" {
+			jsonBody.Array[i].Level = dcos.LevelOf(propertiesMap.UnitHealth)
+		}
+		host.Level = dcos.WorstLevel(host.Level, jsonBody.Array[i].Level)
+	}
+
 	for _, propertiesMap := range jsonBody.Array {
 		// update error message per host per Unit
 		host.Output[propertiesMap.UnitID] = propertiesMap.UnitOutput
@@ -207,13 +359,33 @@ func (p *pull) pullHostStatus(host dcos.Node, respChan chan<- *httpResponse, wg
 			Title:      propertiesMap.UnitTitle,
 			Timestamp:  p.tools.GetTimestamp(),
 			PrettyName: propertiesMap.PrettyName,
+			Level:      propertiesMap.Level,
+			Reasons:    propertiesMap.Reasons,
+			Flapping:   propertiesMap.Flapping,
 		})
 	}
 	response.Node = host
+	response.Fingerprint = jsonBody.Fingerprint
+	if response.Fingerprint == nil {
+		response.FingerprintError = "node did not report its configuration fingerprint, it may run an older dcos-diagnostics"
+	}
 	respChan <- &response
 
 }
 
+// driftNodes returns fingerprints of nodes, nodes that did not report their health are reported with an error
+func driftNodes(responses []*httpResponse) []drift.Node {
+	nodes := make([]drift.Node, 0, len(responses))
+	for _, r := range responses {
+		n := drift.Node{IP: r.Node.IP, Role: r.Node.Role, Hostname: r.Node.Host, Fingerprint: r.Fingerprint, Error: r.FingerprintError}
+		if r.Fingerprint == nil && r.FingerprintError == "" {
+			n.Error = fmt.Sprintf("node did not report its health, status code %d", r.Status)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
 func getPullPortByRole(cfg *config.Config, role string) (int, error) {
 	var port int
 	if role != dcos.MasterRole && role != dcos.AgentRole && role != dcos.AgentPublicRole {
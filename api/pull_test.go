@@ -1,10 +1,19 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
+	"github.com/dcos/dcos-diagnostics/silence"
 
 	assertPackage "github.com/stretchr/testify/assert"
+	requirePackage "github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -28,6 +37,7 @@ func (s *PullerTestSuit) SetupTest() {
 		runPullerChan:      s.dt.RunPullerChan,
 		runPullerDoneChan:  s.dt.RunPullerDoneChan,
 		monitoringResponse: s.dt.MR,
+		leader:             func() ([]string, error) { return []string{"127.0.0.1"}, nil },
 	}
 
 	p.runPull()
@@ -94,6 +104,11 @@ func (s *PullerTestSuit) TestMonitoringResponseRace() {
 		defer wg.Done()
 		s.dt.MR.GetNodeUnitByNodeIDUnitID("test-ip", "test-Unit")
 	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.dt.MR.GetDrift()
+	}()
 	wg.Wait()
 }
 
@@ -102,10 +117,11 @@ func (s *PullerTestSuit) TestPullerFindUnit() {
 	unit, err := s.dt.MR.GetUnit("dcos-master.service")
 	s.assert.Nil(err)
 	s.assert.Equal(unit, UnitResponseFieldsStruct{
-		"dcos-master.service",
-		"PrettyName",
-		0,
-		"Nice Master Description.",
+		UnitID:     "dcos-master.service",
+		PrettyName: "PrettyName",
+		UnitHealth: 0,
+		UnitTitle:  "Nice Master Description.",
+		Level:      dcos.LevelOK,
 	})
 }
 
@@ -116,6 +132,157 @@ func (s *PullerTestSuit) TestPullerNotFindUnit() {
 	s.assert.Equal(unit, UnitResponseFieldsStruct{})
 }
 
+func (s *PullerTestSuit) TestPullerAddsClusterUnits() {
+	for _, name := range []string{mastersQuorumUnit, leaderElectedUnit, agentsRegisteredUnit, versionSkewUnit} {
+		_, err := s.dt.MR.GetUnit(name)
+		s.assert.NoError(err, name)
+	}
+
+	unit, err := s.dt.MR.GetUnit(leaderElectedUnit)
+	s.assert.NoError(err)
+	s.assert.Equal(UnitResponseFieldsStruct{
+		UnitID:     leaderElectedUnit,
+		PrettyName: "Leader Elected",
+		UnitHealth: 0,
+		UnitTitle:  "Leading Mesos master is elected",
+		Level:      dcos.LevelOK,
+	}, unit)
+}
+
+func TestPullComparesFingerprints(t *testing.T) {
+	cfg := testCfg()
+	tools := &fakeDCOSTools{}
+	health := UnitsHealthResponseJSONStruct{
+		Array:       []HealthResponseValues{{UnitID: "dcos-master.service", PrettyName: "Master"}},
+		DcosVersion: "1.6",
+		Role:        dcos.MasterRole,
+		Fingerprint: &drift.Fingerprint{DcosVersion: "1.6", Hash: "abc"},
+	}
+	body, err := json.Marshal(health)
+	requirePackage.NoError(t, err)
+	url := fmt.Sprintf("http://127.0.0.1:%d%s", cfg.FlagMasterPort, baseRoute)
+	requirePackage.NoError(t, tools.makeMockedResponse(url, body, http.StatusOK, nil))
+
+	mr := &MonitoringResponse{}
+	p := pull{cfg: cfg, tools: tools, monitoringResponse: mr}
+	p.runPull()
+
+	report, err := mr.GetDrift()
+	requirePackage.NoError(t, err)
+	assertPackage.False(t, report.Drift)
+	requirePackage.Len(t, report.Roles, 1)
+	assertPackage.Equal(t, []drift.Group{{Hash: "abc", DcosVersion: "1.6", Nodes: []string{"127.0.0.1"}}}, report.Roles[0].Groups)
+	// the agent is not reporting its health in this test
+	assertPackage.Equal(t, map[string]string{"127.0.0.2": "node did not report its health, status code 200"}, report.Errors)
+	// fingerprint is reported with health, it's not requested separately
+	assertPackage.NotContains(t, tools.getRequestsMade, fmt.Sprintf("http://127.0.0.1:%d%s", cfg.FlagMasterPort, nodeFingerprintEndpoint))
+}
+
+func TestPullReportsMissingFingerprint(t *testing.T) {
+	cfg := testCfg()
+	tools := &fakeDCOSTools{}
+
+	mr := &MonitoringResponse{}
+	p := pull{cfg: cfg, tools: tools, monitoringResponse: mr}
+	p.runPull()
+
+	report, err := mr.GetDrift()
+	requirePackage.NoError(t, err)
+	assertPackage.Contains(t, report.Errors["127.0.0.1"], "did not report its configuration fingerprint")
+}
+
+func TestPullSuppressesSilencedHealth(t *testing.T) {
+	cfg := testCfg()
+	tools := &fakeDCOSTools{}
+	health := `{"units": [
+		{"id": "dcos-setup.service", "health": 0, "name": "Setup"},
+		{"id": "dcos-master.service", "health": 1, "name": "Master"}
+	]}`
+	requirePackage.NoError(t, tools.makeMockedResponse(fmt.Sprintf("http://127.0.0.1:%d%s", cfg.FlagMasterPort, baseRoute),
+		[]byte(health), http.StatusOK, nil))
+	// the agent is silenced on another master
+	replicated, err := json.Marshal([]silence.Silence{
+		{ID: "agent", Node: "127.0.0.2", Until: time.Now().Add(time.Hour), UpdatedAt: time.Now()},
+	})
+	requirePackage.NoError(t, err)
+	requirePackage.NoError(t, tools.makeMockedResponse(fmt.Sprintf("http://127.0.0.1:%d%s", cfg.FlagMasterPort, nodeSilencesEndpoint),
+		replicated, http.StatusOK, nil))
+
+	store, err := silence.NewStore("")
+	requirePackage.NoError(t, err)
+	_, err = store.Add(silence.Silence{Node: "127.0.0.1", Unit: "dcos-master.service", Until: time.Now().Add(time.Hour), Author: "ops", Reason: "upgrade"})
+	requirePackage.NoError(t, err)
+
+	mr := &MonitoringResponse{}
+	p := pull{cfg: cfg, tools: tools, monitoringResponse: mr, silences: store}
+	p.runPull()
+
+	master, err := mr.GetNodeByID("127.0.0.1")
+	requirePackage.NoError(t, err)
+	// raw health of the node is kept, suppressed health ignores the silenced unit
+	var healthy dcos.Health = dcos.Healthy
+	assertPackage.Equal(t, NodeResponseFieldsStruct{HostIP: "127.0.0.1", NodeHealth: dcos.Unhealthy, NodeRole: dcos.MasterRole,
+		Level: dcos.LevelCritical, SuppressedHealth: &healthy, SuppressedLevel: dcos.LevelOK}, master)
+	// raw health of the unit is still reported by the node
+	requirePackage.Len(t, mr.Nodes["127.0.0.1"].Units, 2)
+	assertPackage.Equal(t, dcos.Health(dcos.Unhealthy), mr.Nodes["127.0.0.1"].Units[1].Health)
+
+	unit := mr.Units["dcos-master.service"]
+	assertPackage.Equal(t, dcos.Health(dcos.Healthy), unit.Health)
+	assertPackage.True(t, unit.Silenced)
+	assertPackage.False(t, mr.Units["dcos-setup.service"].Silenced)
+
+	agent, err := mr.GetNodeByID("127.0.0.2")
+	requirePackage.NoError(t, err)
+	assertPackage.NotEqual(t, dcos.Health(dcos.Healthy), agent.NodeHealth)
+	assertPackage.True(t, agent.Silenced)
+	assertPackage.Equal(t, &healthy, agent.SuppressedHealth)
+	assertPackage.Equal(t, dcos.LevelOK, agent.SuppressedLevel)
+	assertPackage.True(t, store.Silenced("127.0.0.2", ""))
+
+	// without silences the unit makes the master unhealthy
+	p.silences = nil
+	p.runPull()
+	master, err = mr.GetNodeByID("127.0.0.1")
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, dcos.Health(dcos.Unhealthy), master.NodeHealth)
+	assertPackage.Nil(t, master.SuppressedHealth)
+	assertPackage.Equal(t, dcos.Health(dcos.Unhealthy), mr.Units["dcos-master.service"].Health)
+}
+
+func TestPullAggregatesLevels(t *testing.T) {
+	cfg := testCfg()
+	tools := &fakeDCOSTools{}
+	// the second unit is reported by an older node without levels
+	health := `{"units": [
+		{"id": "dcos-mesos-master.service", "health": 0, "level": "warning", "reasons": ["restarted 3 times"]},
+		{"id": "dcos-net.service", "health": 1, "output": "failed"}
+	]}`
+	requirePackage.NoError(t, tools.makeMockedResponse(fmt.Sprintf("http://127.0.0.1:%d%s", cfg.FlagMasterPort, baseRoute),
+		[]byte(health), http.StatusOK, nil))
+
+	mr := &MonitoringResponse{}
+	p := pull{cfg: cfg, tools: tools, monitoringResponse: mr}
+	p.runPull()
+
+	master, err := mr.GetNodeByID("127.0.0.1")
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, dcos.LevelCritical, master.Level)
+
+	unit := mr.Units["dcos-mesos-master.service"]
+	assertPackage.Equal(t, dcos.Health(dcos.Healthy), unit.Health)
+	assertPackage.Equal(t, dcos.LevelWarning, unit.Level)
+	assertPackage.Equal(t, []string{"127.0.0.1: restarted 3 times"}, unit.Reasons)
+	// raw reasons are kept in units of the node
+	assertPackage.Equal(t, []string{"restarted 3 times"}, mr.Nodes["127.0.0.1"].Units[0].Reasons)
+
+	assertPackage.Equal(t, dcos.LevelCritical, mr.Units["dcos-net.service"].Level)
+
+	agent, err := mr.GetNodeByID("127.0.0.2")
+	requirePackage.NoError(t, err)
+	assertPackage.Equal(t, dcos.LevelUnknown, agent.Level)
+}
+
 func TestPullerTestSuit(t *testing.T) {
 	suite.Run(t, new(PullerTestSuit))
 }
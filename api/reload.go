@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/config"
+	"github.com/dcos/dcos-diagnostics/dcos"
+)
+
+// reloadDelay is how long the reloader waits for more changes of watched files before it reloads,
+// so a file written in a few steps is read once it's complete.
+var reloadDelay = time.Second
+
+// reloadableFlags are config flags applied by the reload. Other flags need a restart.
+var reloadableFlags = map[string]bool{
+	"endpoint-config":            true,
+	"diagnostics-units-since":    true,
+	"diagnostics-units-format":   true,
+	"diagnostics-units-priority": true,
+	"system-snapshot":            true,
+	"network-diagnostics":        true,
+	"network-agent-sample":       true,
+	"tls-expiry-warning-days":    true,
+	"zookeeper-health":           true,
+}
+
+// ConfigLoader returns the daemon config read from its file and the path of that file.
+// Empty path means there is no config file.
+type ConfigLoader func() (*config.Config, string, error)
+
+// ConfigStatus describes the config the daemon uses and the result of its last reload.
+type ConfigStatus struct {
+	// Version is a checksum of the config files content
+	Version  string    `json:"version"`
+	Files    []string  `json:"files"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// LastReloadAt is the time of the last reload attempt, nil if config was never reloaded
+	LastReloadAt    *time.Time `json:"last_reload_at,omitempty"`
+	LastReloadError string     `json:"last_reload_error,omitempty"`
+	// RestartRequired lists changed flags that are not applied until the daemon restarts
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// ConfigReloader reloads endpoints configs and daemon config without restarting the daemon.
+// New log providers and bundle collectors are validated before they replace the current ones,
+// so a broken config does not affect the running daemon. Bundles that are already being created
+// keep using the collectors they were started with. Reloadable flags are applied to the diagnostics
+// job and bundle handlers, other changed flags are only reported.
+type ConfigReloader struct {
+	sync.RWMutex
+
+	load           ConfigLoader
+	tools          dcos.Tooler
+	client         *http.Client
+	job            *DiagnosticsJob
+	bundles        *rest.BundleHandler
+	clusterBundles *rest.ClusterBundleHandler
+	checks         *HealthChecks
+
+	initial *config.Config // config the daemon was started with
+	status  ConfigStatus
+}
+
+// NewConfigReloader creates a reloader of the config the daemon was started with.
+func NewConfigReloader(cfg *config.Config, load ConfigLoader, tools dcos.Tooler, client *http.Client,
+	job *DiagnosticsJob, bundles *rest.BundleHandler, clusterBundles *rest.ClusterBundleHandler, checks *HealthChecks) (*ConfigReloader, error) {
+	r := &ConfigReloader{
+		load:           load,
+		tools:          tools,
+		client:         client,
+		job:            job,
+		bundles:        bundles,
+		clusterBundles: clusterBundles,
+		checks:         checks,
+		initial:        cfg,
+	}
+
+	_, configFile, err := load()
+	if err != nil {
+		return nil, err
+	}
+	files := configFiles(cfg, configFile)
+	version, err := configVersion(files)
+	if err != nil {
+		return nil, err
+	}
+	r.status = ConfigStatus{Version: version, Files: files, LoadedAt: time.Now()}
+	return r, nil
+}
+
+// Status returns the current config status.
+func (r *ConfigReloader) Status() ConfigStatus {
+	r.RLock()
+	defer r.RUnlock()
+	return r.status
+}
+
+// Reload reads the config again and replaces log providers and bundle collectors with ones built from it.
+// On error current providers and collectors are kept.
+func (r *ConfigReloader) Reload() error {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	r.status.LastReloadAt = &now
+	err := r.reload()
+	if err != nil {
+		r.status.LastReloadError = err.Error()
+		logrus.WithError(err).Error("Could not reload config")
+		return err
+	}
+	r.status.LastReloadError = ""
+	logrus.WithField("version", r.status.Version).Info("Config reloaded")
+	return nil
+}
+
+func (r *ConfigReloader) reload() error {
+	cfg, configFile, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	files := configFiles(cfg, configFile)
+	version, err := configVersion(files)
+	if err != nil {
+		return err
+	}
+
+	providers, err := newLogProviders(cfg, r.tools)
+	if err != nil {
+		return fmt.Errorf("invalid log providers: %s", err)
+	}
+	collectors, err := LoadCollectors(cfg, r.tools, r.client)
+	if err != nil {
+		return fmt.Errorf("invalid collectors: %s", err)
+	}
+	checks, err := LoadHealthChecks(cfg, r.tools, r.client)
+	if err != nil {
+		return fmt.Errorf("invalid health checks: %s", err)
+	}
+
+	r.job.setConfig(reloadedConfig(r.job.config(), cfg))
+	r.job.setLogProviders(providers)
+	r.bundles.SetCollectors(collectors)
+	r.clusterBundles.SetNetworkDiagnostics(cfg.FlagDiagnosticsNetwork)
+	r.checks.Set(checks)
+
+	if version != r.status.Version {
+		r.status.LoadedAt = time.Now()
+	}
+	r.status.Version = version
+	r.status.Files = files
+	r.status.RestartRequired = changedFlags(r.initial, cfg)
+	return nil
+}
+
+// Watch reloads the config whenever any of its files changes until ctx is done.
+func (r *ConfigReloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create config watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	// directories are watched, so files replaced by editors or config management are noticed
+	watched := map[string]bool{}
+	watch := func() {
+		for _, f := range r.Status().Files {
+			dir := filepath.Dir(f)
+			if watched[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				logrus.WithError(err).Warnf("Could not watch %s", dir)
+				continue
+			}
+			watched[dir] = true
+		}
+	}
+	watch()
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-watcher.Events:
+			if r.isConfigFile(event.Name) {
+				logrus.Debugf("Config file %s changed: %s", event.Name, event.Op)
+				timer = time.After(reloadDelay)
+			}
+		case err := <-watcher.Errors:
+			logrus.WithError(err).Warn("Config watcher error")
+		case <-timer:
+			timer = nil
+			r.Reload() //nolint:errcheck // error is logged and reported in status
+			watch()
+		}
+	}
+}
+
+func (r *ConfigReloader) isConfigFile(name string) bool {
+	for _, f := range r.Status().Files {
+		if filepath.Clean(f) == filepath.Clean(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func configFiles(cfg *config.Config, configFile string) []string {
+	var files []string
+	if configFile != "" {
+		files = append(files, configFile)
+	}
+	return append(files, cfg.FlagDiagnosticsBundleEndpointsConfigFiles...)
+}
+
+// configVersion returns a checksum of names and content of the given files.
+func configVersion(files []string) (string, error) {
+	h := sha256.New()
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return "", fmt.Errorf("could not read %s: %s", f, err)
+		}
+		fmt.Fprintf(h, "%s\n%d\n", f, len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// reloadedConfig returns a copy of the current config with reloadable flags taken from the reloaded config.
+func reloadedConfig(current, reloaded *config.Config) *config.Config {
+	applied := *current
+	a, n := reflect.ValueOf(&applied).Elem(), reflect.ValueOf(*reloaded)
+	for i := 0; i < a.NumField(); i++ {
+		if reloadableFlags[a.Type().Field(i).Tag.Get("mapstructure")] {
+			a.Field(i).Set(n.Field(i))
+		}
+	}
+	return &applied
+}
+
+// changedFlags returns names of flags that are not reloadable and differ between configs.
+func changedFlags(old, new *config.Config) []string {
+	var changed []string
+	o, n := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	for i := 0; i < o.NumField(); i++ {
+		name := o.Type().Field(i).Tag.Get("mapstructure")
+		if name == "" || reloadableFlags[name] {
+			continue
+		}
+		if !reflect.DeepEqual(o.Field(i).Interface(), n.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/config"
+	"github.com/dcos/dcos-diagnostics/dcos"
+)
+
+const (
+	endpointsWithFile     = `{"LocalFiles": [{"Location": "/var/log/a.log"}]}`
+	endpointsWithTwoFiles = `{"LocalFiles": [{"Location": "/var/log/a.log"}, {"Location": "/var/log/b.log"}]}`
+)
+
+func newTestReloader(t *testing.T, content string) (*ConfigReloader, *DiagnosticsJob, string, *config.Config) {
+	reloader, job, _, endpointsConfig, loaded := newTestReloaderWithClusterBundles(t, content)
+	return reloader, job, endpointsConfig, loaded
+}
+
+func newTestReloaderWithClusterBundles(t *testing.T, content string) (*ConfigReloader, *DiagnosticsJob,
+	*rest.ClusterBundleHandler, string, *config.Config) {
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	endpointsConfig := filepath.Join(dir, "endpoints_config.json")
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte(content), 0600))
+
+	tools := new(MockedTools)
+	tools.On("GetNodeRole").Return("master", nil)
+	if runtime.GOOS != GoosWindows && runtime.GOOS != GoosDarwin {
+		tools.On("GetUnitNames").Return([]string{"dcos-diagnostics"}, nil)
+	}
+
+	cfg := testCfg()
+	cfg.FlagDiagnosticsBundleEndpointsConfigFiles = []string{endpointsConfig}
+	loaded := *cfg
+
+	job := &DiagnosticsJob{Cfg: cfg, DCOSTools: tools}
+	require.NoError(t, job.Init())
+
+	bundles, err := rest.NewBundleHandler(cfg.FlagDiagnosticsBundleDir, nil, time.Minute, time.Second, nil, nil)
+	require.NoError(t, err)
+	client := rest.NewDiagnosticsClient(http.DefaultClient)
+	urlBuilder := dcos.NewURLBuilder(cfg.FlagAgentPort, cfg.FlagMasterPort, false)
+	clusterBundles, err := rest.NewClusterBundleHandler(rest.NewParallelCoordinator(client, time.Minute, cfg.FlagDiagnosticsBundleDir),
+		client, tools, cfg.FlagDiagnosticsBundleDir, time.Minute, &urlBuilder, nil, nil)
+	require.NoError(t, err)
+
+	load := func() (*config.Config, string, error) {
+		c := loaded
+		return &c, "", nil
+	}
+	reloader, err := NewConfigReloader(cfg, load, tools, http.DefaultClient, job, bundles, clusterBundles, nil)
+	require.NoError(t, err)
+
+	return reloader, job, clusterBundles, endpointsConfig, &loaded
+}
+
+func TestConfigReloaderReload(t *testing.T) {
+	reloader, job, endpointsConfig, _ := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+
+	status := reloader.Status()
+	assert.Equal(t, []string{endpointsConfig}, status.Files)
+	assert.NotEmpty(t, status.Version)
+	assert.Nil(t, status.LastReloadAt)
+	assert.Contains(t, job.getLogProviders().LocalFiles, "var_log_a.log")
+	assert.NotContains(t, job.getLogProviders().LocalFiles, "var_log_b.log")
+
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte(endpointsWithTwoFiles), 0600))
+	require.NoError(t, reloader.Reload())
+
+	reloaded := reloader.Status()
+	assert.NotEqual(t, status.Version, reloaded.Version)
+	assert.NotNil(t, reloaded.LastReloadAt)
+	assert.Empty(t, reloaded.LastReloadError)
+	assert.Empty(t, reloaded.RestartRequired)
+	assert.Contains(t, job.getLogProviders().LocalFiles, "var_log_b.log")
+}
+
+func TestConfigReloaderReloadKeepsConfigOnError(t *testing.T) {
+	reloader, job, endpointsConfig, _ := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+	version := reloader.Status().Version
+
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte("{invalid"), 0600))
+	err := reloader.Reload()
+	require.Error(t, err)
+
+	status := reloader.Status()
+	assert.Equal(t, version, status.Version)
+	assert.Equal(t, err.Error(), status.LastReloadError)
+	assert.Contains(t, job.getLogProviders().LocalFiles, "var_log_a.log")
+
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte(endpointsWithFile), 0600))
+	require.NoError(t, reloader.Reload())
+	assert.Empty(t, reloader.Status().LastReloadError)
+}
+
+func TestConfigReloaderReloadsHealthChecks(t *testing.T) {
+	reloader, _, endpointsConfig, _ := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+	reloader.checks = NewHealthChecks(nil)
+
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte(`{"TLSEndpoints": [{"Name": "mesos", "Port": 5050}]}`), 0600))
+	require.NoError(t, reloader.Reload())
+
+	require.Len(t, reloader.checks.checks, 1)
+	assert.Equal(t, "master-0:5050", reloader.checks.checks[0].(TLSCheck).Endpoint.Address)
+}
+
+func TestConfigReloaderReportsRestartRequired(t *testing.T) {
+	reloader, _, endpointsConfig, loaded := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+
+	loaded.FlagPort = 1051
+	loaded.FlagDiagnosticsBundleUnitsLogsSinceString = "1h"
+	require.NoError(t, reloader.Reload())
+
+	assert.Equal(t, []string{"port"}, reloader.Status().RestartRequired)
+}
+
+func TestConfigReloaderAppliesReloadableFlags(t *testing.T) {
+	reloader, job, clusterBundles, endpointsConfig, loaded := newTestReloaderWithClusterBundles(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+	h := handler{cfg: reloader.initial, job: job}
+	router := mux.NewRouter()
+	router.HandleFunc("/system/health/v1/diagnostics/{id}", clusterBundles.Create).Methods(http.MethodPut)
+	createWithMatrix := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/system/health/v1/diagnostics/bundle-0",
+			strings.NewReader(`{"network_matrix": true}`)))
+		return w
+	}
+
+	loaded.FlagDiagnosticsBundleUnitsLogsSinceString = "1h"
+	loaded.FlagDiagnosticsBundleUnitsLogsPriority = "err"
+	loaded.FlagDiagnosticsNetwork = false
+	loaded.FlagPort = 1051
+	require.NoError(t, reloader.Reload())
+
+	assert.Equal(t, "1h", job.config().FlagDiagnosticsBundleUnitsLogsSinceString)
+	assert.Equal(t, "err", job.config().FlagDiagnosticsBundleUnitsLogsPriority)
+	assert.Equal(t, 1050, job.config().FlagPort, "flags requiring restart must not be applied")
+	assert.Equal(t, "24h", reloader.initial.FlagDiagnosticsBundleUnitsLogsSinceString, "config in use must not be modified")
+
+	req, err := h.parseLogSearchRequest(url.Values{"unit": {"a"}, "q": {"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, req.since)
+
+	w := createWithMatrix()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code":400,"error":"network matrix requires network diagnostics, enable network-diagnostics"}`, w.Body.String())
+}
+
+func TestConfigReloaderWatch(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	reloader, job, endpointsConfig, _ := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error)
+	go func() { done <- reloader.Watch(ctx) }()
+
+	// give the watcher time to start watching the config directory
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ioutil.WriteFile(endpointsConfig, []byte(endpointsWithTwoFiles), 0600))
+
+	assert.Eventually(t, func() bool {
+		_, ok := job.getLogProviders().LocalFiles["var_log_b.log"]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestChangedFlags(t *testing.T) {
+	old := &config.Config{FlagPort: 1050, FlagDiagnosticsBundleEndpointsConfigFiles: []string{"a"}}
+	new := &config.Config{FlagPort: 1051, FlagPull: true, FlagDiagnosticsBundleEndpointsConfigFiles: []string{"b"}}
+
+	assert.Equal(t, []string{"pull", "port"}, changedFlags(old, new))
+}
+
+func TestConfigHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&handler{}).configHandler(w, httptest.NewRequest(http.MethodGet, "/system/health/v1/config", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	reloader, _, endpointsConfig, _ := newTestReloader(t, endpointsWithFile)
+	defer os.RemoveAll(filepath.Dir(endpointsConfig))
+
+	w = httptest.NewRecorder()
+	(&handler{reloader: reloader}).configHandler(w, httptest.NewRequest(http.MethodGet, "/system/health/v1/config", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var status ConfigStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, reloader.Status().Version, status.Version)
+	assert.Equal(t, []string{endpointsConfig}, status.Files)
+}
@@ -0,0 +1,261 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/search"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// fileFilter selects lines of a bundle file, zero values select everything
+type fileFilter struct {
+	head  int
+	tail  int
+	match search.Matcher
+}
+
+func (f fileFilter) empty() bool {
+	return f.head == 0 && f.tail == 0 && f.match == nil
+}
+
+// parseFileFilter reads head, tail and grep query parameters. Grep is a regular expression.
+func parseFileFilter(r *http.Request) (fileFilter, error) {
+	query := r.URL.Query()
+	var filter fileFilter
+	var err error
+	if filter.head, err = parseLineCount(query.Get("head"), "head"); err != nil {
+		return filter, err
+	}
+	if filter.tail, err = parseLineCount(query.Get("tail"), "tail"); err != nil {
+		return filter, err
+	}
+	if filter.head > 0 && filter.tail > 0 {
+		return filter, fmt.Errorf("only one of head and tail could be given")
+	}
+	if grep := query.Get("grep"); grep != "" {
+		if filter.match, err = search.NewMatcher(grep, true); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+func parseLineCount(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of lines, got %q", name, value)
+	}
+	return n, nil
+}
+
+// copyLines writes lines of r selected by the filter to w. Lines are filtered by grep first
+// and then head or tail is applied.
+func copyLines(w io.Writer, r io.Reader, filter fileFilter) error {
+	if filter.empty() {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	reader := bufio.NewReader(r)
+	var last []string
+	written := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" && (filter.match == nil || filter.match(line)) {
+			switch {
+			case filter.tail > 0:
+				last = append(last, line)
+				if len(last) > filter.tail {
+					last = last[1:]
+				}
+			default:
+				if _, err := io.WriteString(w, line); err != nil {
+					return err
+				}
+				written++
+			}
+		}
+		if err == io.EOF || (filter.head > 0 && written == filter.head) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, line := range last {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileContentType returns the content type of a bundle file. Bundles store logs and command outputs
+// so files are served as text unless they are JSON or compressed.
+func fileContentType(name string) string {
+	switch path.Ext(name) {
+	case ".json":
+		return "application/json"
+	case ".gz":
+		return "application/gzip"
+	case ".zst":
+		return "application/zstd"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// doneBundle returns the finished bundle with the given id. Otherwise it writes an error and returns false.
+func (h BundleHandler) doneBundle(w http.ResponseWriter, id string) (Bundle, bool) {
+	bundle, err := h.getBundleState(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return bundle, false
+	}
+	if bundle.Status == Deleted || bundle.Status == Canceled || bundle.Status == Failed {
+		writeJSONError(w, http.StatusGone,
+			fmt.Errorf("bundle %s was %s", bundle.ID, bundle.Status))
+		return bundle, false
+	}
+
+	if bundle.Status != Done {
+		writeJSONError(w, http.StatusNotFound,
+			fmt.Errorf("bundle %s is not done yet (status %s), try again later", bundle.ID, bundle.Status))
+		return bundle, false
+	}
+	return bundle, true
+}
+
+// ListFiles lists files stored in the bundle with their sizes and modification times
+func (h BundleHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	bundle, ok := h.doneBundle(w, id)
+	if !ok {
+		return
+	}
+	if bundle.Encrypted {
+		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s is encrypted", id))
+		return
+	}
+
+	entries, err := archive.List(filepath.Join(h.workDir, id, dataFileName))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("could not list files of bundle %s: %s", id, err))
+		return
+	}
+	write(w, jsonMarshal(entries))
+}
+
+// GetBundleFile streams a single file stored in the bundle. Lines of the file could be selected
+// with head, tail and grep query parameters.
+func (h BundleHandler) GetBundleFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, name := vars["id"], vars["path"]
+
+	filter, err := parseFileFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	bundle, ok := h.doneBundle(w, id)
+	if !ok {
+		return
+	}
+	if bundle.Encrypted {
+		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s is encrypted", id))
+		return
+	}
+
+	rc, err := archive.Open(filepath.Join(h.workDir, id, dataFileName), name)
+	if err == archive.ErrEntryNotFound {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("file %s not found in bundle %s", name, id))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("could not open %s in bundle %s: %s", name, id, err))
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Add("Content-Type", fileContentType(name))
+	if err := copyLines(w, rc, filter); err != nil {
+		logrus.WithError(err).WithField("ID", id).WithField("file", name).Warn("Could not send bundle file")
+	}
+}
+
+// ListFiles lists files stored in the given cluster bundle, proxying the call to the appropriate master
+func (c *ClusterBundleHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	ctx := context.Background()
+
+	masterWithBundle, bundle, code, err := c.findMasterWithBundle(ctx, id)
+	if err != nil {
+		writeJSONError(w, code, err)
+		return
+	}
+	if bundle.Encrypted {
+		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s is encrypted", id))
+		return
+	}
+
+	entries, err := c.client.ListFiles(ctx, masterWithBundle.baseURL, id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error listing files of bundle: %s", err))
+		return
+	}
+	write(w, jsonMarshal(entries))
+}
+
+// GetBundleFile streams a single file stored in the given cluster bundle, proxying the call to the appropriate master
+func (c *ClusterBundleHandler) GetBundleFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, name := vars["id"], vars["path"]
+
+	if _, err := parseFileFilter(r); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := context.Background()
+
+	masterWithBundle, bundle, code, err := c.findMasterWithBundle(ctx, id)
+	if err != nil {
+		writeJSONError(w, code, err)
+		return
+	}
+	if bundle.Encrypted {
+		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s is encrypted", id))
+		return
+	}
+
+	rc, err := c.client.GetBundleFile(ctx, masterWithBundle.baseURL, id, name, r.URL.Query())
+	if err != nil {
+		if _, ok := err.(*DiagnosticsBundleNotFoundError); ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("file %s not found in bundle %s", name, id))
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error getting file of bundle: %s", err))
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Add("Content-Type", fileContentType(name))
+	if _, err := io.Copy(w, rc); err != nil {
+		logrus.WithError(err).WithField("ID", id).WithField("file", name).Warn("Could not send bundle file")
+	}
+}
@@ -0,0 +1,262 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/dcos"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	bundleFilesEndpoint      = bundleEndpoint + "/files"
+	bundleFilesEntryEndpoint = bundleFilesEndpoint + "/{path:.+}"
+)
+
+const testLog = "1 info\n2 error\n3 info\n4 error\n5 info"
+
+// writeDoneBundle stores a finished bundle with the given state and files in the work dir
+func writeDoneBundle(t *testing.T, workdir, id, state string, files map[string]string) {
+	bundleWorkDir := filepath.Join(workdir, id)
+	require.NoError(t, os.Mkdir(bundleWorkDir, dirPerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleWorkDir, stateFileName), []byte(state), filePerm))
+
+	f, err := os.Create(filepath.Join(bundleWorkDir, dataFileName))
+	require.NoError(t, err)
+	w, err := archive.NewWriter(f, archive.Zip)
+	require.NoError(t, err)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(entry, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func newBundleFilesRouter(t *testing.T) (*mux.Router, string) {
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(workdir) })
+
+	writeDoneBundle(t, workdir, "bundle-0", `{"id": "bundle-0", "status": "Done", "type": "Local"}`,
+		map[string]string{"dcos-mesos-master.service": testLog, "dir/config.json": `{}`})
+	writeDoneBundle(t, workdir, "bundle-1", `{"id": "bundle-1", "status": "Done", "type": "Local", "encrypted": true}`, nil)
+	writeDoneBundle(t, workdir, "bundle-2", `{"id": "bundle-2", "status": "Started", "type": "Local"}`, nil)
+
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleFilesEndpoint, bh.ListFiles).Methods(http.MethodGet)
+	router.HandleFunc(bundleFilesEntryEndpoint, bh.GetBundleFile).Methods(http.MethodGet)
+	return router, workdir
+}
+
+func TestListBundleFiles(t *testing.T) {
+	router, _ := newBundleFilesRouter(t)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle-0/files", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var entries []archive.Entry
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	sizes := map[string]int64{}
+	for _, e := range entries {
+		sizes[e.Name] = e.Size
+		assert.False(t, e.Modified.IsZero())
+	}
+	assert.Equal(t, map[string]int64{"dcos-mesos-master.service": int64(len(testLog)), "dir/config.json": 2}, sizes)
+}
+
+func TestListBundleFilesErrors(t *testing.T) {
+	router, _ := newBundleFilesRouter(t)
+
+	for _, tc := range []struct {
+		id       string
+		code     int
+		expected string
+	}{
+		{"bundle-1", http.StatusConflict, `{"code":409,"error":"bundle bundle-1 is encrypted"}`},
+		{"bundle-2", http.StatusNotFound,
+			`{"code":404,"error":"bundle bundle-2 is not done yet (status Started), try again later"}`},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/"+tc.id+"/files", nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.code, rr.Code)
+			assert.JSONEq(t, tc.expected, rr.Body.String())
+		})
+	}
+}
+
+func TestGetBundleFile(t *testing.T) {
+	router, _ := newBundleFilesRouter(t)
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		code     int
+		expected string
+	}{
+		{"whole file", "dcos-mesos-master.service", http.StatusOK, testLog},
+		{"nested file", "dir/config.json", http.StatusOK, `{}`},
+		{"head", "dcos-mesos-master.service?head=2", http.StatusOK, "1 info\n2 error\n"},
+		{"tail", "dcos-mesos-master.service?tail=2", http.StatusOK, "4 error\n5 info"},
+		{"grep", "dcos-mesos-master.service?grep=err", http.StatusOK, "2 error\n4 error\n"},
+		{"grep and tail", "dcos-mesos-master.service?grep=info&tail=1", http.StatusOK, "5 info"},
+		{"grep and head", "dcos-mesos-master.service?grep=%5E%5B34%5D&head=1", http.StatusOK, "3 info\n"},
+		{"missing file", "missing.log", http.StatusNotFound,
+			`{"code":404,"error":"file missing.log not found in bundle bundle-0"}`},
+		{"head and tail", "dcos-mesos-master.service?head=1&tail=1", http.StatusBadRequest,
+			`{"code":400,"error":"only one of head and tail could be given"}`},
+		{"invalid head", "dcos-mesos-master.service?head=-1", http.StatusBadRequest,
+			`{"code":400,"error":"head must be a positive number of lines, got \"-1\""}`},
+		{"invalid grep", "dcos-mesos-master.service?grep=(", http.StatusBadRequest,
+			`{"code":400,"error":"invalid regular expression \"(\": error parsing regexp: missing closing ): ` + "`(`" + `"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle-0/files/"+tc.path, nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.code, rr.Code)
+			if tc.code == http.StatusOK {
+				assert.Equal(t, tc.expected, rr.Body.String())
+			} else {
+				assert.JSONEq(t, tc.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetBundleFileContentType(t *testing.T) {
+	router, _ := newBundleFilesRouter(t)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle-0/files/dir/config.json", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	req, err = http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle-0/files/dcos-mesos-master.service", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+}
+
+func TestClientBundleFiles(t *testing.T) {
+	router, _ := newBundleFilesRouter(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := NewDiagnosticsClient(server.Client())
+
+	entries, err := client.ListFiles(context.TODO(), server.URL, "bundle-0")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	rc, err := client.GetBundleFile(context.TODO(), server.URL, "bundle-0", "dcos-mesos-master.service", url.Values{"tail": {"1"}})
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "5 info", string(content))
+
+	_, err = client.GetBundleFile(context.TODO(), server.URL, "bundle-0", "missing.log", nil)
+	assert.IsType(t, &DiagnosticsBundleNotFoundError{}, err)
+}
+
+func TestClusterBundleFiles(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{
+		{Role: "master", IP: "192.0.2.1"},
+		{Role: "master", IP: "192.0.2.2"},
+	}, nil)
+
+	client := new(TestifyMockClient)
+	client.On("Status", mock.Anything, "http://192.0.2.1", mock.Anything).Return(nil, &DiagnosticsBundleNotFoundError{})
+	client.On("Status", mock.Anything, "http://192.0.2.2", "bundle-0").Return(&Bundle{ID: "bundle-0", Type: Cluster, Status: Done}, nil)
+	client.On("Status", mock.Anything, "http://192.0.2.2", "bundle-1").
+		Return(&Bundle{ID: "bundle-1", Type: Cluster, Status: Done, Encrypted: true}, nil)
+	client.On("Status", mock.Anything, "http://192.0.2.2", "bundle-2").Return(nil, &DiagnosticsBundleNotFoundError{})
+	client.On("ListFiles", mock.Anything, "http://192.0.2.2", "bundle-0").
+		Return([]archive.Entry{{Name: "a.txt", Size: 1, Modified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil)
+	client.On("GetBundleFile", mock.Anything, "http://192.0.2.2", "bundle-0", "dir/a.txt", url.Values{"head": {"1"}}).
+		Return(ioutil.NopCloser(bytes.NewBufferString("a\n")), nil)
+	client.On("GetBundleFile", mock.Anything, "http://192.0.2.2", "bundle-0", "missing.txt", url.Values{}).
+		Return(nil, &DiagnosticsBundleNotFoundError{id: "bundle-0"})
+
+	bh := ClusterBundleHandler{
+		client:     client,
+		tools:      tools,
+		urlBuilder: MockURLBuilder{},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleFilesEndpoint, bh.ListFiles).Methods(http.MethodGet)
+	router.HandleFunc(bundleFilesEntryEndpoint, bh.GetBundleFile).Methods(http.MethodGet)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+path, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/bundle-0/files")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"name":"a.txt","size":1,"mtime":"2020-01-01T00:00:00Z"}]`, rr.Body.String())
+
+	rr = get("/bundle-0/files/dir/a.txt?head=1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "a\n", rr.Body.String())
+
+	rr = get("/bundle-0/files/missing.txt")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"code":404,"error":"file missing.txt not found in bundle bundle-0"}`, rr.Body.String())
+
+	rr = get("/bundle-0/files/dir/a.txt?head=1&tail=1")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = get("/bundle-1/files")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"code":409,"error":"bundle bundle-1 is encrypted"}`, rr.Body.String())
+
+	rr = get("/bundle-2/files/a.txt")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCopyLinesStopsAfterHead(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := copyLines(buf, strings.NewReader("a\nb\nc\n"), fileFilter{head: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", buf.String())
+}
@@ -1,9 +1,10 @@
 package rest
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,7 +17,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
 	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/encryption"
+	"github.com/dcos/dcos-diagnostics/integrity"
+	diagio "github.com/dcos/dcos-diagnostics/io"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -25,7 +30,9 @@ import (
 // work dir contains only directories, each dir is created for single bundle (id is its name) and should contain:
 const (
 	stateFileName = "state.json" // file with information about diagnostics run
-	dataFileName  = "file.zip"   // data gathered by diagnostics
+	dataFileName  = "file.zip"   // data gathered by diagnostics, archive format is stored in the state file
+
+	signatureFileName = "file.zip.sig" // detached signature of data file, present only when signing key is configured
 
 	summaryErrorsReportFileName = "summaryErrorsReport.txt" // error log in bundle
 
@@ -41,6 +48,10 @@ type Bundle struct {
 	Started time.Time `json:"started_at,omitempty"`
 	Stopped time.Time `json:"stopped_at,omitempty"`
 	Errors  []string  `json:"errors,omitempty"`
+	// Encrypted is true when the data file is encrypted and must be decrypted with `dcos-diagnostics bundle decrypt`
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Format is an archive format of the data file, empty means zip
+	Format archive.Format `json:"format,omitempty"`
 }
 
 func (b *Bundle) IsFinished() bool {
@@ -66,7 +77,10 @@ type realClock struct{}
 
 func (realClock) Now() time.Time { return time.Now() }
 
-func NewBundleHandler(workDir string, collectors []collector.Collector, timeout, collectorTimeout time.Duration) (*BundleHandler, error) {
+// NewBundleHandler creates a BundleHandler. When signingKey is not nil every finished bundle is signed with it.
+// When encryptionKey is not nil bundles are encrypted with it unless other key is given in the create request.
+func NewBundleHandler(workDir string, collectors []collector.Collector, timeout, collectorTimeout time.Duration,
+	signingKey ed25519.PrivateKey, encryptionKey *rsa.PublicKey) (*BundleHandler, error) {
 	err := initializeWorkDir(workDir)
 	if err != nil {
 		return nil, err
@@ -76,9 +90,11 @@ func NewBundleHandler(workDir string, collectors []collector.Collector, timeout,
 		stateFileLock:         &sync.RWMutex{},
 		clock:                 realClock{},
 		workDir:               workDir,
-		collectors:            collectors,
+		collectors:            &collectorList{list: collectors},
 		bundleCreationTimeout: timeout,
 		collectorTimeout:      collectorTimeout,
+		signingKey:            signingKey,
+		encryptionKey:         encryptionKey,
 	}, nil
 }
 
@@ -87,10 +103,47 @@ func NewBundleHandler(workDir string, collectors []collector.Collector, timeout,
 type BundleHandler struct {
 	stateFileLock         *sync.RWMutex // used to synchronize access to state file
 	clock                 Clock
-	workDir               string                // location where bundles are generated and stored
-	collectors            []collector.Collector // information what should be in the bundle
-	bundleCreationTimeout time.Duration         // limits how long bundle creation could take
-	collectorTimeout      time.Duration         // limits how long single collection can take
+	workDir               string             // location where bundles are generated and stored
+	collectors            *collectorList     // information what should be in the bundle
+	bundleCreationTimeout time.Duration      // limits how long bundle creation could take
+	collectorTimeout      time.Duration      // limits how long single collection can take
+	signingKey            ed25519.PrivateKey // signs finished bundles, nil disables signing
+	encryptionKey         *rsa.PublicKey     // default key used to encrypt bundles, nil disables encryption
+}
+
+// collectorList holds collectors that could be replaced while bundles are created
+type collectorList struct {
+	sync.RWMutex
+	list []collector.Collector
+}
+
+func (c *collectorList) get() []collector.Collector {
+	c.RLock()
+	defer c.RUnlock()
+	return c.list
+}
+
+func (c *collectorList) set(collectors []collector.Collector) {
+	c.Lock()
+	defer c.Unlock()
+	c.list = collectors
+}
+
+// SetCollectors replaces collectors used by new bundles. Bundles that are already being created
+// keep using previous collectors.
+func (h BundleHandler) SetCollectors(collectors []collector.Collector) {
+	h.collectors.set(collectors)
+}
+
+// createOptions are optional parameters of the local bundle creation request
+type createOptions struct {
+	// EncryptionKey is a PEM encoded RSA public key that overrides configured encryption key
+	EncryptionKey string `json:"encryption_key,omitempty"`
+	// Encrypt set to false disables encryption. It's used for node bundles that are merged
+	// into a cluster bundle because they are encrypted on a master after merge.
+	Encrypt *bool `json:"encrypt,omitempty"`
+	// Format is an archive format of the bundle (zip, tar.gz or tar.zst). Default is zip.
+	Format string `json:"format,omitempty"`
 }
 
 type node struct {
@@ -103,13 +156,36 @@ func (h BundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	var options createOptions
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&options); err != nil && err != io.EOF { // Accept empty body
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("could not parse request body %s", err))
+			return
+		}
+	}
+
+	encryptionKey, err := encryptionKeyFromRequest(options.EncryptionKey, h.encryptionKey)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if options.Encrypt != nil && !*options.Encrypt {
+		encryptionKey = nil
+	}
+
+	format, err := archive.ParseFormat(options.Format)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	if h.bundleExists(id) {
 		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s already exists", id))
 		return
 	}
 
 	bundleWorkDir := filepath.Join(h.workDir, id)
-	err := os.MkdirAll(bundleWorkDir, dirPerm)
+	err = os.MkdirAll(bundleWorkDir, dirPerm)
 	if err != nil {
 		writeJSONError(w, http.StatusInsufficientStorage, fmt.Errorf("could not create bundle %s workdir: %s", id, err))
 		return
@@ -120,6 +196,10 @@ func (h BundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Started: h.clock.Now(),
 		Status:  Started,
 	}
+	// default format is not stored so state of zip bundles is the same as before formats were introduced
+	if format != archive.Zip {
+		bundle.Format = format
+	}
 
 	bundleStatus, err := h.writeStateFile(bundle)
 	if err != nil {
@@ -145,13 +225,26 @@ func (h BundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx, _ := context.WithTimeout(context.Background(), h.bundleCreationTimeout) //nolint:govet
 	done := make(chan []string)
 
-	go collectAll(ctx, done, dataFile, h.collectors, h.collectorTimeout)
+	go collectAll(ctx, done, dataFile, format, h.collectors.get(), h.collectorTimeout)
 
 	go func() {
 		select {
 		case <-ctx.Done():
 			break
 		case bundle.Errors = <-done:
+			if encryptionKey != nil {
+				if err := encryptDataFile(bundleWorkDir, encryptionKey); err != nil {
+					bundle.Failed(h.clock.Now(), err)
+					if _, e := h.writeStateFile(bundle); e != nil {
+						logrus.WithError(e).Errorf("Could not update state file %s", id)
+					}
+					return
+				}
+				bundle.Encrypted = true
+			}
+			if err := signDataFile(bundleWorkDir, h.signingKey); err != nil {
+				bundle.Errors = append(bundle.Errors, err.Error())
+			}
 			bundle.Status = Done
 			bundle.Stopped = h.clock.Now()
 			if _, e := h.writeStateFile(bundle); e != nil {
@@ -163,35 +256,83 @@ func (h BundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	write(w, bundleStatus)
 }
 
-func collectAll(ctx context.Context, done chan<- []string, dataFile io.WriteCloser,
+// CollectAll runs collectors and writes their data into the archive in the data file the same way
+// as node bundles are created. It returns errors of collectors that are not optional.
+func CollectAll(ctx context.Context, dataFile io.WriteCloser, format archive.Format,
+	collectors []collector.Collector, collectorTimeout time.Duration) []string {
+	done := make(chan []string, 1)
+	collectAll(ctx, done, dataFile, format, collectors, collectorTimeout)
+	return <-done
+}
+
+func collectAll(ctx context.Context, done chan<- []string, dataFile io.WriteCloser, format archive.Format,
 	collectors []collector.Collector, collectorTimeout time.Duration) {
-	zipWriter := zip.NewWriter(dataFile)
+	manifest := integrity.NewManifest()
 	var errors []string
 
+	zipWriter, err := archive.NewWriter(dataFile, format)
+	if err != nil {
+		if e := dataFile.Close(); e != nil {
+			err = fmt.Errorf("%s: %s", err, e)
+		}
+		done <- []string{err.Error()}
+		return
+	}
+
+	collectors = collector.Expand(ctx, collectors)
+
+	// background collectors run for the whole bundle creation, their data is stored when other collectors are done
+	var background []*backgroundResult
 	for _, c := range collectors {
+		if collector.IsBackground(c) {
+			background = append(background, collectInBackground(ctx, c))
+		}
+	}
+
+	for _, c := range collectors {
+		if collector.IsBackground(c) {
+			continue
+		}
 		if ctx.Err() != nil {
 			errors = append(errors, ctx.Err().Error())
 			break
 		}
-		collectorCtx, cancel := context.WithTimeout(ctx, collectorTimeout) //nolint: govet
-		err := collect(collectorCtx, c, zipWriter)
+		timeout := collectorTimeout
+		if t := collector.OptionsOf(c).Timeout; t > 0 {
+			timeout = t
+		}
+		collectorCtx, cancel := context.WithTimeout(ctx, timeout) //nolint: govet
+		err := collect(collectorCtx, c, zipWriter, manifest)
 		cancel()
 		if err != nil && !c.Optional() {
 			errors = append(errors, err.Error())
 		}
 	}
 
+	for _, b := range background {
+		<-b.done
+		err := store(b.collector, b.data, b.err, zipWriter, manifest)
+		if err != nil && !b.collector.Optional() {
+			errors = append(errors, err.Error())
+		}
+	}
+
 	if len(errors) != 0 {
 		summaryErrorReportFile, err := zipWriter.Create(summaryErrorsReportFileName)
 		if err != nil {
 			errors = append(errors, err.Error())
 		} else {
-			if _, err := summaryErrorReportFile.Write([]byte(strings.Join(errors, "\n"))); err != nil {
+			report := strings.NewReader(strings.Join(errors, "\n"))
+			if _, err := manifest.Copy(summaryErrorsReportFileName, summaryErrorReportFile, report); err != nil {
 				errors = append(errors, err.Error())
 			}
 		}
 	}
 
+	if err := writeManifest(zipWriter, manifest); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	if err := zipWriter.Close(); err != nil {
 		errors = append(errors, err.Error())
 	}
@@ -202,27 +343,117 @@ func collectAll(ctx context.Context, done chan<- []string, dataFile io.WriteClos
 	done <- errors
 }
 
-func collect(ctx context.Context, c collector.Collector, zipWriter *zip.Writer) error {
+func collect(ctx context.Context, c collector.Collector, zipWriter archive.Writer, manifest *integrity.Manifest) error {
 	rc, err := c.Collect(ctx)
+	return store(c, rc, err, zipWriter, manifest)
+}
+
+// backgroundResult is data collected by a background collector
+type backgroundResult struct {
+	collector collector.Collector
+	done      chan struct{}
+	data      io.ReadCloser
+	err       error
+}
+
+// collectInBackground starts the collector and reads its data, so it is ready to be stored when done is closed.
+// The collector timeout applies if it is set, otherwise the collector runs until the bundle context is done.
+func collectInBackground(ctx context.Context, c collector.Collector) *backgroundResult {
+	result := &backgroundResult{collector: c, done: make(chan struct{})}
+	go func() {
+		defer close(result.done)
+		rc, err := c.Collect(ctx)
+		if err != nil {
+			result.err = err
+			return
+		}
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		if err != nil {
+			result.err = err
+			return
+		}
+		result.data = ioutil.NopCloser(bytes.NewReader(data))
+	}()
+	return result
+}
+
+// store writes data collected by the collector to the zip. Errors of optional collectors are stored instead of data.
+func store(c collector.Collector, rc io.ReadCloser, err error, zipWriter archive.Writer, manifest *integrity.Manifest) error {
 	if err != nil {
 		if !c.Optional() {
 			return fmt.Errorf("could not collect %s: %s", c.Name(), err)
 		}
 		rc = ioutil.NopCloser(bytes.NewReader([]byte(err.Error())))
 	}
+	name := c.Name()
+	if collector.OptionsOf(c).Compression == collector.CompressionGzip {
+		name += ".gz"
+		rc = diagio.GzipReadCloser(rc)
+	}
 	defer rc.Close()
 
-	zipFile, err := zipWriter.Create(c.Name())
+	zipFile, err := zipWriter.Create(name)
 	if err != nil {
-		return fmt.Errorf("could not create a %s in the zip: %s", c.Name(), err)
+		return fmt.Errorf("could not create a %s in the zip: %s", name, err)
 	}
-	if _, err := io.Copy(zipFile, rc); err != nil {
+	if _, err := manifest.Copy(name, zipFile, rc); err != nil {
 		return fmt.Errorf("could not copy %s data to zip: %s", c.Name(), err)
 	}
 
 	return nil
 }
 
+// writeManifest stores the manifest as the last entry of the archive so it covers all other entries.
+func writeManifest(zipWriter archive.Writer, manifest *integrity.Manifest) error {
+	manifestFile, err := zipWriter.Create(integrity.ManifestFileName)
+	if err != nil {
+		return fmt.Errorf("could not create %s in the zip: %s", integrity.ManifestFileName, err)
+	}
+	if _, err := manifest.WriteTo(manifestFile); err != nil {
+		return fmt.Errorf("could not write %s: %s", integrity.ManifestFileName, err)
+	}
+	return nil
+}
+
+// encryptionKeyFromRequest parses the PEM encoded key sent in the request. If no key was sent, defaultKey is returned.
+func encryptionKeyFromRequest(requestKey string, defaultKey *rsa.PublicKey) (*rsa.PublicKey, error) {
+	if requestKey == "" {
+		return defaultKey, nil
+	}
+	key, err := encryption.ParsePublicKey([]byte(requestKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %s", err)
+	}
+	return key, nil
+}
+
+// encryptDataFile encrypts the bundle data file in place. If encryption fails the data file is removed
+// so a plain text bundle is never served when encryption was requested.
+func encryptDataFile(bundleWorkDir string, key *rsa.PublicKey) error {
+	dataFilePath := filepath.Join(bundleWorkDir, dataFileName)
+	if err := encryption.EncryptFile(dataFilePath, key); err != nil {
+		if e := os.Remove(dataFilePath); e != nil {
+			logrus.WithError(e).WithField("path", dataFilePath).Error("Could not remove not encrypted bundle")
+		}
+		return fmt.Errorf("could not encrypt bundle: %s", err)
+	}
+	return nil
+}
+
+// signDataFile creates a detached signature of the bundle data file. It does nothing when signing key is nil.
+// Encrypted bundles are signed after encryption so the signature can be checked without the private key.
+func signDataFile(bundleWorkDir string, key ed25519.PrivateKey) error {
+	if key == nil {
+		return nil
+	}
+	err := integrity.SignFile(key, filepath.Join(bundleWorkDir, dataFileName), filepath.Join(bundleWorkDir, signatureFileName))
+	if err != nil {
+		return fmt.Errorf("could not sign bundle: %s", err)
+	}
+	return nil
+}
+
 func (h BundleHandler) Get(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -246,26 +477,94 @@ func (h BundleHandler) GetFile(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	bundle, ok := h.doneBundle(w, id)
+	if !ok {
+		return
+	}
+
+	_, err := os.Stat(filepath.Join(h.workDir, id, signatureFileName))
+	signed := err == nil
+	serveBundleFile(w, r, id, filepath.Join(h.workDir, id, dataFileName), bundle.Format, bundle.Encrypted, signed)
+}
+
+// serveBundleFile sends the bundle data file stored in the given format. The format is negotiated
+// with the Accept header and the bundle is converted on the fly when other format is requested.
+// Encrypted bundles can't be converted so they are always sent as stored. Signed bundles are not converted
+// either, because the detached signature would not match the converted archive.
+func serveBundleFile(w http.ResponseWriter, r *http.Request, id, path string, stored archive.Format, encrypted, signed bool) {
+	if encrypted {
+		w.Header().Add("Content-Type", "application/octet-stream")
+		w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s%s.enc", id, stored.Extension()))
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	format, ok := archive.Negotiate(r.Header.Get("Accept"), stored)
+	if !ok {
+		writeJSONError(w, http.StatusNotAcceptable,
+			fmt.Errorf("bundle %s could only be served as %s, %s or %s", id, archive.Zip.ContentType(),
+				archive.TarGz.ContentType(), archive.TarZst.ContentType()))
+		return
+	}
+
+	if stored == "" {
+		stored = archive.Zip
+	}
+	if format != stored && signed {
+		writeJSONError(w, http.StatusNotAcceptable,
+			fmt.Errorf("bundle %s is signed, it could only be served as %s", id, stored.ContentType()))
+		return
+	}
+
+	w.Header().Add("Content-Type", format.ContentType())
+	w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s%s", id, format.Extension()))
+	w.Header().Add("Vary", "Accept")
+	if format == stored {
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	// converted bundle size is unknown so it's streamed without Content-Length
+	if err := archive.Convert(w, path, format); err != nil {
+		logrus.WithError(err).WithField("ID", id).Errorf("Could not convert bundle to %s", format)
+	}
+}
+
+// GetSignature serves the detached signature of the bundle data file.
+func (h BundleHandler) GetSignature(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
 	bundle, err := h.getBundleState(id)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
-	if bundle.Status == Deleted || bundle.Status == Canceled || bundle.Status == Failed {
-		writeJSONError(w, http.StatusGone,
-			fmt.Errorf("bundle %s was %s", bundle.ID, bundle.Status))
+	if bundle.Status != Done {
+		writeJSONError(w, http.StatusNotFound,
+			fmt.Errorf("bundle %s is not done (status %s)", bundle.ID, bundle.Status))
 		return
 	}
 
-	if bundle.Status != Done {
-		writeJSONError(w, http.StatusNotFound,
-			fmt.Errorf("bundle %s is not done yet (status %s), try again later", bundle.ID, bundle.Status))
+	signaturePath := filepath.Join(h.workDir, id, signatureFileName)
+	if _, err := os.Stat(signaturePath); err != nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("bundle %s is not signed", bundle.ID))
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/zip, application/octet-stream")
-	w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s.zip", id))
-	http.ServeFile(w, r, filepath.Join(h.workDir, id, dataFileName))
+	w.Header().Add("Content-Type", "text/plain")
+	w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s", signatureDownloadName(bundle)))
+	http.ServeFile(w, r, signaturePath)
+}
+
+// signatureDownloadName returns the name of the bundle signature matching the name of the stored bundle file,
+// so `bundle verify` finds it next to the downloaded bundle by default.
+func signatureDownloadName(bundle Bundle) string {
+	name := bundle.ID + bundle.Format.Extension()
+	if bundle.Encrypted {
+		name += ".enc"
+	}
+	return name + ".sig"
 }
 
 func (h BundleHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -366,6 +665,10 @@ func (h BundleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("could not delete bundle %s: %s", id, err))
 		return
 	}
+	err = os.Remove(filepath.Join(h.workDir, id, signatureFileName))
+	if err != nil && !os.IsNotExist(err) {
+		logrus.WithField("ID", id).WithError(err).Warn("Could not delete bundle signature")
+	}
 
 	bundle.Status = Deleted
 	newRawState, err := h.writeStateFile(bundle)
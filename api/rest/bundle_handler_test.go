@@ -3,7 +3,13 @@ package rest
 import (
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,7 +20,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
 	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/encryption"
+	"github.com/dcos/dcos-diagnostics/integrity"
 	diagio "github.com/dcos/dcos-diagnostics/io"
 
 	"github.com/gorilla/mux"
@@ -27,6 +36,8 @@ const (
 	bundleEndpoint     = bundlesEndpoint + "/{id}"
 	bundleFileEndpoint = bundleEndpoint + "/file"
 
+	bundleSignatureEndpoint = bundleEndpoint + "/signature"
+
 	collectorTimeout = time.Millisecond
 )
 
@@ -37,7 +48,7 @@ func TestIfReturnsEmptyListWhenDirIsEmpty(t *testing.T) {
 	defer os.RemoveAll(workdir)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -62,7 +73,7 @@ func TestIfReturnsEmptyListWhenDirIsEmptyContainsNoDirs(t *testing.T) {
 	_, err = ioutil.TempFile(workdir, "")
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -89,7 +100,7 @@ func TestIfDirsAsBundlesIdsWithStatusUnknown(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -144,7 +155,7 @@ func TestIfListShowsStatusWithoutAFile(t *testing.T) {
 		"stopped_at":"2019-05-21T00:00:00Z" }`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -174,7 +185,7 @@ func TestIfListWorksWithoutBundleDir(t *testing.T) {
 	err = os.RemoveAll(workdir)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -206,7 +217,7 @@ func TestIfShowsStatusWithoutAFileButStatusDoneShouldChangeStatusToUnknown(t *te
 		"stopped_at":"2019-05-21T00:00:00Z" }`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -249,7 +260,7 @@ func TestIfShowsStatusWithFileAndDontUpdatesFileSize(t *testing.T) {
 	err = ioutil.WriteFile(filepath.Join(bundleWorkDir, dataFileName), []byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint, nil)
@@ -295,7 +306,7 @@ func TestIfGetShowsStatusWithoutAFileWhenBundleIsDeleted(t *testing.T) {
 		"stopped_at":"2019-05-21T00:00:00Z" }`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -335,7 +346,7 @@ func TestIfGetShowsStatusWithoutAFileWhenBundleIsDone(t *testing.T) {
 		"stopped_at":"2019-05-21T00:00:00Z" }`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -367,7 +378,7 @@ func TestIfGetReturns500WhenBundleStateIsNotJson(t *testing.T) {
 		[]byte(`invalid JSON`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle-state-not-json", nil)
@@ -399,7 +410,7 @@ func TestIfDeleteReturns404WhenNoBundleFound(t *testing.T) {
 	defer os.RemoveAll(workdir)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Nanosecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Nanosecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/not-existing-bundle", nil)
@@ -425,7 +436,7 @@ func TestIfDeleteReturns500WhenNoBundleStateFound(t *testing.T) {
 	err = os.Mkdir(bundleWorkDir, dirPerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/not-existing-bundle-state", nil)
@@ -457,7 +468,7 @@ func TestIfDeleteReturns500WhenBundleStateIsNotJson(t *testing.T) {
 		[]byte(`invalid JSON`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/bundle-state-not-json", nil)
@@ -503,7 +514,7 @@ func TestIfDeleteReturns200WhenBundleWasDeletedBefore(t *testing.T) {
 	err = ioutil.WriteFile(stateFilePath, []byte(bundleState), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/deleted-bundle", nil)
@@ -535,7 +546,7 @@ func TestIfDeleteReturns500WhenBundleFileIsMissing(t *testing.T) {
 		"stopped_at":"2019-05-21T00:00:00Z" }`)), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/missing-data-file", nil)
@@ -575,7 +586,7 @@ func TestIfDeleteReturns200WhenBundleWasDeleted(t *testing.T) {
 	err = ioutil.WriteFile(filepath.Join(bundleWorkDir, dataFileName), []byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodDelete, bundlesEndpoint+"/bundle-0", nil)
@@ -621,7 +632,7 @@ func TestIfGetFileReturnsBundle(t *testing.T) {
 		[]byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -664,7 +675,7 @@ func TestIfGetFileReturns404WhenBundleIsStarted(t *testing.T) {
 		[]byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -709,7 +720,7 @@ func TestIfGetFileReturns410WhenBundleIsNotDone(t *testing.T) {
 		[]byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -734,7 +745,7 @@ func TestIfGetFileReturnsErrorWhenBundleDoesNotExists(t *testing.T) {
 	defer os.RemoveAll(workdir)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle", nil)
@@ -769,7 +780,7 @@ func TestIfCreateReturns409WhenBundleWithGivenIdAlreadyExists(t *testing.T) {
 	err = ioutil.WriteFile(filepath.Join(bundleWorkDir, dataFileName), []byte(`OK`), filePerm)
 	require.NoError(t, err)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodPut, bundlesEndpoint+"/bundle-0", nil)
@@ -794,7 +805,7 @@ func TestIfCreateReturns507WhenCouldNotCreateWorkDir(t *testing.T) {
 	bundleWorkDir := filepath.Join(workdir, "bundle-0")
 	err = ioutil.WriteFile(bundleWorkDir, []byte{}, 0000)
 
-	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	req, err := http.NewRequest(http.MethodPut, bundlesEndpoint+"/bundle-0", nil)
@@ -826,7 +837,7 @@ func TestIfE2E_(t *testing.T) {
 		MockCollector{name: "collector-4", rc: slowReader{delay: time.Millisecond}},
 	}
 
-	bh, err := NewBundleHandler(workdir, collectors, time.Second, 100 * time.Millisecond)
+	bh, err := NewBundleHandler(workdir, collectors, time.Second, 100*time.Millisecond, nil, nil)
 	require.NoError(t, err)
 	bh.clock = &MockClock{now: now}
 
@@ -860,6 +871,9 @@ func TestIfE2E_(t *testing.T) {
 		}, bundle)
 	})
 
+	// size depends on zip compression so it's read from the data file
+	var size int64
+
 	t.Run("get bundle-0 status", func(t *testing.T) {
 		for { // busy wait for bundle
 			bundle, err := client.Status(context.TODO(), testServer.URL, "bundle-0")
@@ -872,13 +886,17 @@ func TestIfE2E_(t *testing.T) {
 		bundle, err := client.Status(context.TODO(), testServer.URL, "bundle-0")
 		require.NoError(t, err)
 
+		stat, err := os.Stat(filepath.Join(workdir, "bundle-0", dataFileName))
+		require.NoError(t, err)
+		size = stat.Size()
+
 		assert.Equal(t, &Bundle{
 			ID:      "bundle-0",
 			Type:    Local,
 			Status:  Done,
 			Started: now.Add(time.Hour),
 			Stopped: now.Add(2 * time.Hour),
-			Size:    618,
+			Size:    size,
 			Errors: []string{
 				"could not collect collector-1: some error",
 				"could not copy collector-4 data to zip: context deadline exceeded",
@@ -899,11 +917,16 @@ func TestIfE2E_(t *testing.T) {
 		reader, err := zip.OpenReader(f.Name())
 		require.NoError(t, err)
 
-		require.Len(t, reader.File, 4)
+		require.Len(t, reader.File, 5)
 		assert.Equal(t, "collector-2", reader.File[0].Name)
 		assert.Equal(t, "collector-3", reader.File[1].Name)
 		assert.Equal(t, "collector-4", reader.File[2].Name)
 		assert.Equal(t, "summaryErrorsReport.txt", reader.File[3].Name)
+		assert.Equal(t, "checksums.sha256", reader.File[4].Name)
+
+		problems, err := integrity.VerifyZip(&reader.Reader)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
 
 		rc, err := reader.File[0].Open()
 		require.NoError(t, err)
@@ -951,7 +974,7 @@ could not copy collector-4 data to zip: context deadline exceeded`, string(conte
 			Status:  Deleted,
 			Started: now.Add(time.Hour),
 			Stopped: now.Add(2 * time.Hour),
-			Size:    618,
+			Size:    size,
 			Errors:  []string{
 				"could not collect collector-1: some error",
 				"could not copy collector-4 data to zip: context deadline exceeded",
@@ -974,7 +997,7 @@ could not copy collector-4 data to zip: context deadline exceeded`, string(conte
 			Status:  Deleted,
 			Started: now.Add(time.Hour),
 			Stopped: now.Add(2 * time.Hour),
-			Size:    618,
+			Size:    size,
 			Errors:  []string{
 				"could not collect collector-1: some error",
 				"could not copy collector-4 data to zip: context deadline exceeded",
@@ -983,6 +1006,385 @@ could not copy collector-4 data to zip: context deadline exceeded`, string(conte
 	})
 }
 
+func TestSignedBundle(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workdir)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	collectors := []collector.Collector{
+		MockCollector{name: "collector", rc: ioutil.NopCloser(bytes.NewReader([]byte("OK")))},
+	}
+
+	bh, err := NewBundleHandler(workdir, collectors, time.Second, time.Second, private, nil)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleEndpoint, bh.Create).Methods(http.MethodPut)
+	router.HandleFunc(bundleEndpoint, bh.Get).Methods(http.MethodGet)
+	router.HandleFunc(bundleSignatureEndpoint, bh.GetSignature).Methods(http.MethodGet)
+	router.HandleFunc(bundleFileEndpoint, bh.GetFile).Methods(http.MethodGet)
+
+	testServer := httptest.NewServer(router)
+	defer testServer.Close()
+
+	client := NewDiagnosticsClient(testServer.Client())
+
+	err = client.GetSignature(context.TODO(), testServer.URL, "bundle-0", filepath.Join(workdir, "sig"))
+	assert.IsType(t, &DiagnosticsBundleUnreadableError{}, err)
+
+	_, err = client.CreateBundle(context.TODO(), testServer.URL, "bundle-0")
+	require.NoError(t, err)
+
+	for { // busy wait for bundle
+		bundle, err := client.Status(context.TODO(), testServer.URL, "bundle-0")
+		require.NoError(t, err)
+		if bundle.Status == Done {
+			assert.Empty(t, bundle.Errors)
+			break
+		}
+	}
+
+	signaturePath := filepath.Join(workdir, "bundle-0.zip.sig")
+	err = client.GetSignature(context.TODO(), testServer.URL, "bundle-0", signaturePath)
+	require.NoError(t, err)
+
+	assert.NoError(t, integrity.VerifyFile(public, filepath.Join(workdir, "bundle-0", dataFileName), signaturePath))
+
+	// conversion would break the signature
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+bundlesEndpoint+"/bundle-0/file", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/gzip")
+	resp, err := testServer.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"code":406,"error":"bundle bundle-0 is signed, it could only be served as application/zip"}`, string(body))
+
+	bundlePath := filepath.Join(workdir, "bundle-0.zip")
+	require.NoError(t, client.GetFile(context.TODO(), testServer.URL, "bundle-0", bundlePath))
+	assert.NoError(t, integrity.VerifyFile(public, bundlePath, signaturePath))
+}
+
+func TestEncryptedBundle(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workdir)
+
+	configuredKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	requestKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	requestKeyDER, err := x509.MarshalPKIXPublicKey(&requestKey.PublicKey)
+	require.NoError(t, err)
+	requestKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: requestKeyDER})
+
+	// file collector is used because it returns new reader for every bundle
+	dataPath := filepath.Join(workdir, "data.txt")
+	require.NoError(t, ioutil.WriteFile(dataPath, []byte("OK"), filePerm))
+	collectors := []collector.Collector{collector.NewFile("collector", false, dataPath)}
+
+	bh, err := NewBundleHandler(workdir, collectors, time.Second, time.Second, nil, &configuredKey.PublicKey)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleEndpoint, bh.Create).Methods(http.MethodPut)
+	router.HandleFunc(bundleEndpoint, bh.Get).Methods(http.MethodGet)
+	router.HandleFunc(bundleFileEndpoint, bh.GetFile).Methods(http.MethodGet)
+
+	testServer := httptest.NewServer(router)
+	defer testServer.Close()
+
+	create := func(id string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, testServer.URL+bundlesEndpoint+"/"+id, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp, err := testServer.Client().Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	waitForBundle := func(id string) *Bundle {
+		client := NewDiagnosticsClient(testServer.Client())
+		for { // busy wait for bundle
+			bundle, err := client.Status(context.TODO(), testServer.URL, id)
+			require.NoError(t, err)
+			if bundle.IsFinished() {
+				return bundle
+			}
+		}
+	}
+	decrypt := func(id string, key *rsa.PrivateKey) string {
+		resp, err := testServer.Client().Get(testServer.URL + bundlesEndpoint + "/" + id + "/file")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "attachment; filename="+id+".zip.enc", resp.Header.Get("Content-disposition"))
+
+		decrypted := bytes.NewBuffer(nil)
+		require.NoError(t, encryption.Decrypt(decrypted, resp.Body, key))
+		reader, err := zip.NewReader(bytes.NewReader(decrypted.Bytes()), int64(decrypted.Len()))
+		require.NoError(t, err)
+		rc, err := reader.File[0].Open()
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("encrypted with configured key", func(t *testing.T) {
+		resp := create("bundle-0", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		bundle := waitForBundle("bundle-0")
+		assert.Equal(t, Done, bundle.Status)
+		assert.True(t, bundle.Encrypted)
+		assert.Equal(t, "OK", decrypt("bundle-0", configuredKey))
+	})
+
+	t.Run("encrypted with key from request", func(t *testing.T) {
+		resp := create("bundle-1", string(jsonMarshal(createOptions{EncryptionKey: string(requestKeyPEM)})))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		bundle := waitForBundle("bundle-1")
+		assert.Equal(t, Done, bundle.Status)
+		assert.True(t, bundle.Encrypted)
+		assert.Equal(t, "OK", decrypt("bundle-1", requestKey))
+	})
+
+	t.Run("encryption disabled in request", func(t *testing.T) {
+		resp := create("bundle-2", `{"type":"Local","encrypt":false}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		bundle := waitForBundle("bundle-2")
+		assert.Equal(t, Done, bundle.Status)
+		assert.False(t, bundle.Encrypted)
+
+		encrypted, err := encryption.IsEncrypted(filepath.Join(workdir, "bundle-2", dataFileName))
+		require.NoError(t, err)
+		assert.False(t, encrypted)
+	})
+
+	t.Run("invalid key in request", func(t *testing.T) {
+		resp := create("bundle-3", `{"encryption_key":"invalid"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"code":400,"error":"invalid encryption key: could not decode PEM encoded public key"}`, string(body))
+	})
+}
+
+func TestBundleFormats(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workdir)
+
+	dataPath := filepath.Join(workdir, "data.txt")
+	require.NoError(t, ioutil.WriteFile(dataPath, []byte("OK"), filePerm))
+	collectors := []collector.Collector{collector.NewFile("collector", false, dataPath)}
+
+	bh, err := NewBundleHandler(workdir, collectors, time.Second, time.Second, nil, nil)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleEndpoint, bh.Create).Methods(http.MethodPut)
+	router.HandleFunc(bundleEndpoint, bh.Get).Methods(http.MethodGet)
+	router.HandleFunc(bundleFileEndpoint, bh.GetFile).Methods(http.MethodGet)
+
+	testServer := httptest.NewServer(router)
+	defer testServer.Close()
+
+	req, err := http.NewRequest(http.MethodPut, testServer.URL+bundlesEndpoint+"/bundle-0", bytes.NewBufferString(`{"format":"tar.zst"}`))
+	require.NoError(t, err)
+	resp, err := testServer.Client().Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client := NewDiagnosticsClient(testServer.Client())
+	var bundle *Bundle
+	for { // busy wait for bundle
+		bundle, err = client.Status(context.TODO(), testServer.URL, "bundle-0")
+		require.NoError(t, err)
+		if bundle.IsFinished() {
+			break
+		}
+	}
+	assert.Equal(t, Done, bundle.Status)
+	assert.Equal(t, archive.TarZst, bundle.Format)
+
+	download := func(accept string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+bundlesEndpoint+"/bundle-0/file", nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := testServer.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		path := filepath.Join(workdir, "downloaded")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		_, err = io.Copy(f, resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		return resp, path
+	}
+
+	t.Run("stored format by default", func(t *testing.T) {
+		resp, path := download("")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zstd", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=bundle-0.tar.zst", resp.Header.Get("Content-disposition"))
+
+		problems, err := integrity.VerifyArchive(path)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("converted to accepted format", func(t *testing.T) {
+		resp, path := download("application/zip")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=bundle-0.zip", resp.Header.Get("Content-disposition"))
+
+		problems, err := integrity.VerifyZipFile(path)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("not acceptable format", func(t *testing.T) {
+		resp, _ := download("text/html")
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	t.Run("unknown format in request", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, testServer.URL+bundlesEndpoint+"/bundle-1", bytes.NewBufferString(`{"format":"rar"}`))
+		require.NoError(t, err)
+		resp, err := testServer.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t,
+			`{"code":400,"error":"unknown archive format \"rar\", supported formats are: zip, tar.gz, tar.zst"}`,
+			string(body))
+	})
+}
+
+func TestCollectHonoursCollectorOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zipWriter, err := archive.NewWriter(nopWriteCloser{buf}, archive.Zip)
+	require.NoError(t, err)
+	manifest := integrity.NewManifest()
+
+	c := collector.WithOptions(
+		MockCollector{name: "collector", rc: ioutil.NopCloser(bytes.NewReader([]byte("0123456789")))},
+		collector.Options{MaxBytes: 4, Compression: collector.CompressionGzip},
+	)
+	require.NoError(t, collect(context.TODO(), c, zipWriter, manifest))
+	require.NoError(t, zipWriter.Close())
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, z.File, 1)
+	assert.Equal(t, "collector.gz", z.File[0].Name)
+
+	rc, err := z.File[0].Open()
+	require.NoError(t, err)
+	gz, err := gzip.NewReader(rc)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "0123\n[truncated: data exceeded 4 bytes]\n", string(data))
+}
+
+func TestCollectAllRunsBackgroundCollectorsInParallel(t *testing.T) {
+	started := make(chan struct{})
+	background := backgroundCollector{MockCollector{name: "background", rc: ioutil.NopCloser(bytes.NewReader([]byte("samples")))}, started}
+	// the slow collector waits for the background one, so the bundle could be created only if they run in parallel
+	slow := waitingCollector{MockCollector{name: "slow", rc: ioutil.NopCloser(bytes.NewReader([]byte("OK")))}, started}
+
+	buf := &bytes.Buffer{}
+	done := make(chan []string, 1)
+	collectAll(context.TODO(), done, nopWriteCloser{buf}, archive.Zip, []collector.Collector{background, slow}, time.Second)
+	assert.Empty(t, <-done)
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"slow", "background", integrity.ManifestFileName}, names)
+}
+
+type backgroundCollector struct {
+	MockCollector
+	started chan struct{}
+}
+
+func (b backgroundCollector) Background() bool {
+	return true
+}
+
+func (b backgroundCollector) Collect(ctx context.Context) (io.ReadCloser, error) {
+	close(b.started)
+	return b.MockCollector.Collect(ctx)
+}
+
+type waitingCollector struct {
+	MockCollector
+	started chan struct{}
+}
+
+func (w waitingCollector) Collect(ctx context.Context) (io.ReadCloser, error) {
+	select {
+	case <-w.started:
+		return w.MockCollector.Collect(ctx)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestGetSignatureOfUnsignedBundle(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workdir)
+
+	bundleWorkDir := filepath.Join(workdir, "bundle")
+	require.NoError(t, os.MkdirAll(bundleWorkDir, dirPerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleWorkDir, stateFileName), []byte(`{"id":"bundle","status":"Done"}`), filePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleWorkDir, dataFileName), nil, filePerm))
+
+	bh, err := NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/bundle/signature", nil)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleSignatureEndpoint, bh.GetSignature)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"code":404,"error":"bundle bundle is not signed"}`, rr.Body.String())
+}
+
 func TestBundleHandlerWorkDirIsCreatedIfNotExists(t *testing.T) {
 	t.Parallel()
 
@@ -991,7 +1393,7 @@ func TestBundleHandlerWorkDirIsCreatedIfNotExists(t *testing.T) {
 	err = os.RemoveAll(workdir)
 	require.NoError(t, err)
 
-	_, err = NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout)
+	_, err = NewBundleHandler(workdir, nil, time.Millisecond, collectorTimeout, nil, nil)
 	require.NoError(t, err)
 
 	assert.DirExists(t, workdir)
@@ -1004,7 +1406,7 @@ func TestBundleHandlerWorkDirInitFailsWhenFileExists(t *testing.T) {
 	workdir, err := ioutil.TempFile("", "work-dir")
 	require.NoError(t, err)
 
-	_, err = NewBundleHandler(workdir.Name(), nil, time.Millisecond, collectorTimeout)
+	_, err = NewBundleHandler(workdir.Name(), nil, time.Millisecond, collectorTimeout, nil, nil)
 	assert.Error(t, err)
 }
 
@@ -7,13 +7,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 
+	"github.com/dcos/dcos-diagnostics/archive"
+
 	"github.com/sirupsen/logrus"
 )
 
 const bundlesEndpoint = "/system/health/v1/node/diagnostics"
 
+const clusterBundlesEndpoint = "/system/health/v1/diagnostics"
+
 // Client is an interface that can talk with dcos-diagnostics REST API and manipulate remote bundles
 type Client interface {
 	// CreateBundle requests the given node to start a bundle creation process with that is identified by the given ID
@@ -24,36 +29,84 @@ type Client interface {
 	// url and save it to local filesystem under given path.
 	// Returns an error if there were a problem.
 	GetFile(ctx context.Context, node string, ID string, path string) (err error)
+	// GetSignature downloads the detached signature of the bundle with the given ID from the node
+	// url and save it to local filesystem under given path.
+	// Returns DiagnosticsBundleNotFoundError if the bundle is not signed.
+	GetSignature(ctx context.Context, node string, ID string, path string) (err error)
 	// List will get the list of available bundles on the given node
 	List(ctx context.Context, node string) ([]*Bundle, error)
 	// Delete will delete the bundle with the given id from the given node
 	Delete(ctx context.Context, node string, ID string) error
+	// ListFiles lists files stored in the bundle with the given ID on the given node
+	ListFiles(ctx context.Context, node string, ID string) ([]archive.Entry, error)
+	// GetBundleFile returns the content of a single file stored in the bundle with the given ID on the given node.
+	// The query is passed to the node to select lines of the file.
+	// Returns DiagnosticsBundleNotFoundError if there is no such file.
+	GetBundleFile(ctx context.Context, node string, ID string, name string, query url.Values) (io.ReadCloser, error)
 }
 
 type DiagnosticsClient struct {
 	client *http.Client
+	// endpoint is where bundles are, local bundles of the node or cluster bundles of masters
+	endpoint string
+	// progress is notified about bytes downloaded so far, total is -1 when the size is unknown
+	progress func(done, total int64)
+}
+
+// WithProgress returns a copy of the client that reports progress of downloads to the given function
+func (d DiagnosticsClient) WithProgress(progress func(done, total int64)) DiagnosticsClient {
+	d.progress = progress
+	return d
 }
 
 // NewDiagnosticsClient constructs a diagnostics client
 func NewDiagnosticsClient(client *http.Client) DiagnosticsClient {
 	return DiagnosticsClient{
-		client: client,
+		client:   client,
+		endpoint: bundlesEndpoint,
+	}
+}
+
+// NewClusterDiagnosticsClient constructs a diagnostics client that manipulates cluster bundles of the given master.
+// Cluster bundles are created with CreateClusterBundle.
+func NewClusterDiagnosticsClient(client *http.Client) DiagnosticsClient {
+	return DiagnosticsClient{
+		client:   client,
+		endpoint: clusterBundlesEndpoint,
 	}
 }
 
 func (d DiagnosticsClient) CreateBundle(ctx context.Context, node string, ID string) (*Bundle, error) {
-	url := remoteURL(node, ID)
+	url := d.remoteURL(node, ID)
 
 	logrus.WithField("ID", ID).WithField("url", url).Debug("sending bundle creation request")
 
 	type payload struct {
-		Type Type `json:"type"`
+		Type    Type `json:"type"`
+		Encrypt bool `json:"encrypt"`
 	}
 
+	// node bundles are merged on a master so they must not be encrypted,
+	// the merged bundle is encrypted instead
 	body := jsonMarshal(payload{
-		Type: Local,
+		Type:    Local,
+		Encrypt: false,
 	})
 
+	return d.create(ctx, url, ID, body)
+}
+
+// CreateClusterBundle requests the given master to start a cluster bundle creation process
+// with the given options. The bundle is identified by the given ID.
+func (d DiagnosticsClient) CreateClusterBundle(ctx context.Context, node string, ID string, options ClusterOptions) (*Bundle, error) {
+	url := fmt.Sprintf("%s%s/%s", node, clusterBundlesEndpoint, ID)
+
+	logrus.WithField("ID", ID).WithField("url", url).Debug("sending cluster bundle creation request")
+
+	return d.create(ctx, url, ID, jsonMarshal(options))
+}
+
+func (d DiagnosticsClient) create(ctx context.Context, url string, ID string, body []byte) (*Bundle, error) {
 	request, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
@@ -82,7 +135,7 @@ func (d DiagnosticsClient) CreateBundle(ctx context.Context, node string, ID str
 }
 
 func (d DiagnosticsClient) Status(ctx context.Context, node string, ID string) (*Bundle, error) {
-	url := remoteURL(node, ID)
+	url := d.remoteURL(node, ID)
 
 	logrus.WithField("ID", ID).WithField("url", url).Debug("checking status of bundle")
 
@@ -115,10 +168,22 @@ func (d DiagnosticsClient) Status(ctx context.Context, node string, ID string) (
 }
 
 func (d DiagnosticsClient) GetFile(ctx context.Context, node string, ID string, path string) error {
-	url := fmt.Sprintf("%s/file", remoteURL(node, ID))
+	url := fmt.Sprintf("%s/file", d.remoteURL(node, ID))
 
 	logrus.WithField("ID", ID).WithField("url", url).Debug("downloading local bundle from node")
 
+	return d.download(url, ID, path)
+}
+
+func (d DiagnosticsClient) GetSignature(ctx context.Context, node string, ID string, path string) error {
+	url := fmt.Sprintf("%s/signature", d.remoteURL(node, ID))
+
+	logrus.WithField("ID", ID).WithField("url", url).Debug("downloading bundle signature from node")
+
+	return d.download(url, ID, path)
+}
+
+func (d DiagnosticsClient) download(url string, ID string, path string) error {
 	request, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return err
@@ -141,7 +206,12 @@ func (d DiagnosticsClient) GetFile(ctx context.Context, node string, ID string,
 	}
 	defer destinationFile.Close()
 
-	_, err = io.Copy(destinationFile, resp.Body)
+	var body io.Reader = resp.Body
+	if d.progress != nil {
+		body = &progressReader{r: resp.Body, total: resp.ContentLength, progress: d.progress}
+	}
+
+	_, err = io.Copy(destinationFile, body)
 	if err != nil {
 		return err
 	}
@@ -149,8 +219,23 @@ func (d DiagnosticsClient) GetFile(ctx context.Context, node string, ID string,
 	return nil
 }
 
+// progressReader reports how many bytes were read from the underlying reader
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	p.progress(p.done, p.total)
+	return n, err
+}
+
 func (d DiagnosticsClient) List(ctx context.Context, node string) ([]*Bundle, error) {
-	url := fmt.Sprintf("%s%s", node, bundlesEndpoint)
+	url := d.listURL(node)
 
 	logrus.WithField("node", node).Debug("getting list of bundles from node")
 
@@ -183,7 +268,7 @@ func (d DiagnosticsClient) List(ctx context.Context, node string) ([]*Bundle, er
 }
 
 func (d DiagnosticsClient) Delete(ctx context.Context, node string, id string) error {
-	url := remoteURL(node, id)
+	url := d.remoteURL(node, id)
 
 	logrus.WithField("node", node).WithField("ID", id).Debug("deleting bundle from node")
 
@@ -201,6 +286,63 @@ func (d DiagnosticsClient) Delete(ctx context.Context, node string, id string) e
 	return handleErrorCode(resp, url, id)
 }
 
+func (d DiagnosticsClient) ListFiles(ctx context.Context, node string, ID string) ([]archive.Entry, error) {
+	url := fmt.Sprintf("%s/files", d.remoteURL(node, ID))
+
+	logrus.WithField("ID", ID).WithField("url", url).Debug("listing files of bundle")
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = handleErrorCode(resp, url, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []archive.Entry{}
+	err = json.NewDecoder(resp.Body).Decode(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (d DiagnosticsClient) GetBundleFile(ctx context.Context, node string, ID string, name string, query url.Values) (io.ReadCloser, error) {
+	fileURL := fmt.Sprintf("%s/files/%s", d.remoteURL(node, ID), (&url.URL{Path: name}).EscapedPath())
+	if len(query) > 0 {
+		fileURL += "?" + query.Encode()
+	}
+
+	logrus.WithField("ID", ID).WithField("url", fileURL).Debug("getting file of bundle")
+
+	request, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	err = handleErrorCode(resp, fileURL, ID)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
 func handleErrorCode(resp *http.Response, url string, bundleID string) error {
 	switch {
 	case resp.StatusCode == http.StatusNotFound:
@@ -215,7 +357,14 @@ func handleErrorCode(resp *http.Response, url string, bundleID string) error {
 	return nil
 }
 
-func remoteURL(node string, ID string) string {
-	url := fmt.Sprintf("%s%s/%s", node, bundlesEndpoint, ID)
+func (d DiagnosticsClient) listURL(node string) string {
+	if d.endpoint == "" {
+		return node + bundlesEndpoint
+	}
+	return node + d.endpoint
+}
+
+func (d DiagnosticsClient) remoteURL(node string, ID string) string {
+	url := fmt.Sprintf("%s/%s", d.listURL(node), ID)
 	return url
 }
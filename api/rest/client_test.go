@@ -417,3 +417,55 @@ func TestDeleteWhenBundleUnreadable(t *testing.T) {
 	err := client.Delete(context.TODO(), testServer.URL, "bundle-0")
 	assert.IsType(t, &DiagnosticsBundleUnreadableError{}, err)
 }
+
+func TestClusterClient(t *testing.T) {
+	expectedBundle := Bundle{ID: "bundle-0", Status: Done}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/system/health/v1/diagnostics/bundle-0":
+			if r.Method == http.MethodPut {
+				var o ClusterOptions
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+				assert.Equal(t, ClusterOptions{Masters: true, Format: "tar.gz"}, o)
+			}
+			w.Write(jsonMarshal(expectedBundle))
+		case "/system/health/v1/diagnostics":
+			w.Write(jsonMarshal([]*Bundle{&expectedBundle}))
+		case "/system/health/v1/diagnostics/bundle-0/file":
+			w.Write([]byte("bundle content"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	var done, total int64
+	client := NewClusterDiagnosticsClient(testServer.Client()).WithProgress(func(d, t int64) {
+		done, total = d, t
+	})
+
+	bundle, err := client.CreateClusterBundle(context.TODO(), testServer.URL, "bundle-0", ClusterOptions{Masters: true, Format: "tar.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, expectedBundle, *bundle)
+
+	bundle, err = client.Status(context.TODO(), testServer.URL, "bundle-0")
+	require.NoError(t, err)
+	assert.Equal(t, expectedBundle, *bundle)
+
+	bundles, err := client.List(context.TODO(), testServer.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []*Bundle{&expectedBundle}, bundles)
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := dir + "/bundle.zip"
+
+	require.NoError(t, client.GetFile(context.TODO(), testServer.URL, "bundle-0", path))
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "bundle content", string(content))
+	assert.Equal(t, int64(14), done)
+	assert.Equal(t, int64(14), total)
+}
@@ -2,7 +2,10 @@ package rest
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,9 +13,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/encryption"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -22,33 +28,57 @@ import (
 // ClusterBundleHandler is a handler that will create and manage cluster-wide
 // diagnostics bundles
 type ClusterBundleHandler struct {
-	workDir    string
-	coord      Coordinator
-	client     Client
-	tools      dcos.Tooler
-	timeout    time.Duration
-	clock      Clock
-	urlBuilder dcos.NodeURLBuilder
+	workDir       string
+	coord         Coordinator
+	client        Client
+	tools         dcos.Tooler
+	timeout       time.Duration
+	clock         Clock
+	urlBuilder    dcos.NodeURLBuilder
+	signingKey    ed25519.PrivateKey
+	encryptionKey *rsa.PublicKey
+
+	networkMutex sync.RWMutex
+	// networkDisabled is set when nodes do not collect network reports, so the network matrix could not be assembled
+	networkDisabled bool
 }
 
+// NewClusterBundleHandler creates a ClusterBundleHandler. When signingKey is not nil every merged bundle is signed with it.
+// When encryptionKey is not nil merged bundles are encrypted with it unless other key is given in the create request.
 func NewClusterBundleHandler(c Coordinator, client Client, tools dcos.Tooler, workDir string, timeout time.Duration,
-	urlBuilder dcos.NodeURLBuilder) (*ClusterBundleHandler, error) {
+	urlBuilder dcos.NodeURLBuilder, signingKey ed25519.PrivateKey, encryptionKey *rsa.PublicKey) (*ClusterBundleHandler, error) {
 	err := initializeWorkDir(workDir)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ClusterBundleHandler{
-		coord:      c,
-		client:     client,
-		workDir:    workDir,
-		timeout:    timeout,
-		tools:      tools,
-		clock:      &realClock{},
-		urlBuilder: urlBuilder,
+		coord:         c,
+		client:        client,
+		workDir:       workDir,
+		timeout:       timeout,
+		tools:         tools,
+		clock:         &realClock{},
+		urlBuilder:    urlBuilder,
+		signingKey:    signingKey,
+		encryptionKey: encryptionKey,
 	}, nil
 }
 
+// SetNetworkDiagnostics sets whether nodes collect network reports. It could be changed while the daemon runs,
+// e.g., when the config is reloaded.
+func (c *ClusterBundleHandler) SetNetworkDiagnostics(enabled bool) {
+	c.networkMutex.Lock()
+	defer c.networkMutex.Unlock()
+	c.networkDisabled = !enabled
+}
+
+func (c *ClusterBundleHandler) networkDiagnostics() bool {
+	c.networkMutex.RLock()
+	defer c.networkMutex.RUnlock()
+	return !c.networkDisabled
+}
+
 // Create will send the initial creation request for the bundle to all nodes. The created
 // bundle will exist on the called master node
 func (c *ClusterBundleHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +91,23 @@ func (c *ClusterBundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	encryptionKey, err := encryptionKeyFromRequest(options.EncryptionKey, c.encryptionKey)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	format, err := archive.ParseFormat(options.Format)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if options.NetworkMatrix && !c.networkDiagnostics() {
+		writeJSONError(w, http.StatusBadRequest, errors.New("network matrix requires network diagnostics, enable network-diagnostics"))
+		return
+	}
+
 	if c.bundleExists(id) {
 		writeJSONError(w, http.StatusConflict, fmt.Errorf("bundle %s already exists", id))
 		return
@@ -79,6 +126,10 @@ func (c *ClusterBundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Started: c.clock.Now(),
 		Status:  Started,
 	}
+	// default format is not stored so state of zip bundles is the same as before formats were introduced
+	if format != archive.Zip {
+		bundle.Format = format
+	}
 
 	bundleStatus, err := c.writeStateFile(bundle)
 	if err != nil {
@@ -151,22 +202,29 @@ func (c *ClusterBundleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	statuses := c.coord.CreateBundle(ctx, localBundleID.String(), nodes)
 
-	go c.waitAndCollectRemoteBundle(ctx, bundle, len(nodes), dataFile, statuses)
+	go c.waitAndCollectRemoteBundle(ctx, bundle, len(nodes), dataFile, statuses, encryptionKey, options.NetworkMatrix)
 
 	write(w, bundleStatus)
 }
 
-type options struct {
+// ClusterOptions are options of the cluster bundle creation request
+type ClusterOptions struct {
 	Masters bool `json:"masters"`
 	Agents  bool `json:"agents"`
+	// EncryptionKey is a PEM encoded RSA public key that overrides configured encryption key
+	EncryptionKey string `json:"encryption_key,omitempty"`
+	// Format is an archive format of the merged bundle (zip, tar.gz or tar.zst). Default is zip.
+	Format string `json:"format,omitempty"`
+	// NetworkMatrix adds the reachability matrix of nodes assembled from their network reports
+	NetworkMatrix bool `json:"network_matrix,omitempty"`
 }
 
-var defaultOptions = options{
+var defaultOptions = ClusterOptions{
 	Masters: true,
 	Agents:  true,
 }
 
-func getOptionsFromRequest(r *http.Request) (options, error) {
+func getOptionsFromRequest(r *http.Request) (ClusterOptions, error) {
 	o := defaultOptions
 	if r.Body != nil {
 		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
@@ -185,11 +243,11 @@ func (c *ClusterBundleHandler) failed(bundle Bundle, err error) error {
 }
 
 func (c *ClusterBundleHandler) waitAndCollectRemoteBundle(ctx context.Context, bundle Bundle, numBundles int,
-	dataFile io.WriteCloser, statuses <-chan BundleStatus) {
+	dataFile io.WriteCloser, statuses <-chan BundleStatus, encryptionKey *rsa.PublicKey, networkMatrix bool) {
 
 	defer dataFile.Close()
 
-	bundleFilePath, err := c.coord.CollectBundle(ctx, bundle.ID, numBundles, statuses)
+	bundleFilePath, err := c.coord.CollectBundle(ctx, bundle.ID, numBundles, statuses, bundle.Format, networkMatrix)
 	if err != nil {
 		bundle.Errors = append(bundle.Errors, err.Error())
 	}
@@ -212,6 +270,27 @@ func (c *ClusterBundleHandler) waitAndCollectRemoteBundle(ctx context.Context, b
 		return
 	}
 
+	bundleWorkDir := filepath.Join(c.workDir, bundle.ID)
+	if encryptionKey != nil {
+		// data file is replaced during encryption so it must be closed first
+		if err := dataFile.Close(); err != nil {
+			logrus.WithError(err).WithField("ID", bundle.ID).Error("unable to close bundle data file")
+		}
+		if err := encryptDataFile(bundleWorkDir, encryptionKey); err != nil {
+			logrus.WithError(err).WithField("ID", bundle.ID).Error("unable to encrypt bundle")
+			if e := c.failed(bundle, err); e != nil {
+				logrus.WithField("ID", bundle.ID).Error(e.Error())
+			}
+			return
+		}
+		bundle.Encrypted = true
+	}
+
+	if err := signDataFile(bundleWorkDir, c.signingKey); err != nil {
+		logrus.WithError(err).WithField("ID", bundle.ID).Error("unable to sign bundle")
+		bundle.Errors = append(bundle.Errors, err.Error())
+	}
+
 	bundle.Stopped = c.clock.Now()
 	bundle.Status = Done
 
@@ -329,58 +408,120 @@ func (c *ClusterBundleHandler) Download(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	ctx := context.Background()
+
 	// TODO: for this one specifically, it would be ideal to detect if the bundle exists on the calling master
 	// first since then we can skip the intermediate download
-	masters, err := c.getMasterNodes()
+	masterWithBundle, bundle, code, err := c.findMasterWithBundle(ctx, id)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("unable to get list of masters: %s", err))
+		writeJSONError(w, code, err)
 		return
 	}
 
-	ctx := context.Background()
+	bundleDir, err := ioutil.TempDir("", "bundle-")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error opening temp file to download bundle %s", err))
+		return
+	}
+	defer os.RemoveAll(bundleDir)
 
-	var masterWithBundle node
-	found := false
-	for _, n := range masters {
-		bundle, statusErr := c.client.Status(ctx, n.baseURL, id)
-		if statusErr != nil {
-			switch statusErr.(type) {
-			case *DiagnosticsBundleUnreadableError:
-				writeJSONError(w, http.StatusInternalServerError, statusErr)
-				return
-			case *DiagnosticsBundleNotFoundError:
-				continue
-			}
+	bundleFilename := filepath.Join(bundleDir, "bundle")
+
+	err = c.client.GetFile(ctx, masterWithBundle.baseURL, id, bundleFilename)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error downloading bundle: %s", err))
+		return
+	}
+	encrypted, err := encryption.IsEncrypted(bundleFilename)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error reading bundle: %s", err))
+		return
+	}
+	format := bundle.Format
+	if !encrypted {
+		format, err = archive.Detect(bundleFilename)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error reading bundle: %s", err))
+			return
 		}
+	}
 
-		if bundle.Status == Done {
-			masterWithBundle = n
-			found = true
-			break
+	// signed bundles must not be converted, otherwise their signature would not match
+	signed := true
+	err = c.client.GetSignature(ctx, masterWithBundle.baseURL, id, filepath.Join(bundleDir, "signature"))
+	if err != nil {
+		if _, ok := err.(*DiagnosticsBundleNotFoundError); !ok {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error downloading bundle signature: %s", err))
+			return
 		}
+		signed = false
 	}
-	if !found {
-		writeJSONError(w, http.StatusNotFound, &DiagnosticsBundleNotFoundError{id: id})
+	serveBundleFile(w, r, id, bundleFilename, format, encrypted, signed)
+}
+
+// Signature will download the detached signature of the given bundle, proxying the call to the appropriate master
+func (c *ClusterBundleHandler) Signature(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ctx := context.Background()
+
+	masterWithBundle, bundle, code, err := c.findMasterWithBundle(ctx, id)
+	if err != nil {
+		writeJSONError(w, code, err)
 		return
 	}
 
-	bundleDir, err := ioutil.TempDir("", "bundle-")
+	signatureDir, err := ioutil.TempDir("", "bundle-")
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error opening temp file to download bundle %s", err))
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error opening temp file to download signature %s", err))
 		return
 	}
-	defer os.RemoveAll(bundleDir)
+	defer os.RemoveAll(signatureDir)
 
-	bundleFilename := filepath.Join(bundleDir, "bundle.zip")
+	signatureFilename := filepath.Join(signatureDir, "bundle.sig")
 
-	err = c.client.GetFile(ctx, masterWithBundle.baseURL, id, bundleFilename)
+	err = c.client.GetSignature(ctx, masterWithBundle.baseURL, id, signatureFilename)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error downloading bundle: %s", err))
+		if _, ok := err.(*DiagnosticsBundleNotFoundError); ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("bundle %s is not signed", id))
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, fmt.Errorf("error downloading signature: %s", err))
 		return
 	}
-	w.Header().Add("Content-Type", "application/zip, application/octet-stream")
-	w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s.zip", id))
-	http.ServeFile(w, r, bundleFilename)
+	w.Header().Add("Content-Type", "text/plain")
+	w.Header().Add("Content-disposition", fmt.Sprintf("attachment; filename=%s", signatureDownloadName(*bundle)))
+	http.ServeFile(w, r, signatureFilename)
+}
+
+// findMasterWithBundle returns the master that stores the finished bundle with the given id and the bundle status.
+// On error it also returns an HTTP status code that should be sent to the caller.
+func (c *ClusterBundleHandler) findMasterWithBundle(ctx context.Context, id string) (node, *Bundle, int, error) {
+	masters, err := c.getMasterNodes()
+	if err != nil {
+		return node{}, nil, http.StatusInternalServerError, fmt.Errorf("unable to get list of masters: %s", err)
+	}
+
+	for _, n := range masters {
+		bundle, statusErr := c.client.Status(ctx, n.baseURL, id)
+		if statusErr != nil {
+			switch statusErr.(type) {
+			case *DiagnosticsBundleUnreadableError:
+				return node{}, nil, http.StatusInternalServerError, statusErr
+			case *DiagnosticsBundleNotFoundError:
+				continue
+			default:
+				logrus.WithField("ID", id).WithField("node", n.IP).WithError(statusErr).Warn("unable to get bundle status from master, skipping")
+				continue
+			}
+		}
+
+		if bundle.Status == Done {
+			return n, bundle, http.StatusOK, nil
+		}
+	}
+	return node{}, nil, http.StatusNotFound, &DiagnosticsBundleNotFoundError{id: id}
 }
 
 func (c *ClusterBundleHandler) getMasterNodes() ([]node, error) {
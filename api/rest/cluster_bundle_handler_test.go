@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
 	"github.com/dcos/dcos-diagnostics/dcos"
 
 	"github.com/gorilla/mux"
@@ -536,6 +537,7 @@ func TestDownloadBundle(t *testing.T) {
 		io.Copy(f, bytes.NewBuffer(expectedBytes))
 		return nil
 	})
+	client.On("GetSignature", ctx, "http://192.0.2.5", id, mock.AnythingOfType("string")).Return(&DiagnosticsBundleNotFoundError{id: id})
 
 	coord := new(mockCoordinator)
 	bh := ClusterBundleHandler{
@@ -562,6 +564,197 @@ func TestDownloadBundle(t *testing.T) {
 	assert.Equal(t, bytes.NewBuffer(expectedBytes), rr.Body)
 }
 
+func TestDownloadBundleSignature(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{
+		{
+			Role: "master",
+			IP:   "192.0.2.1",
+		},
+		{
+			Role: "master",
+			IP:   "192.0.2.2",
+		},
+	}, nil)
+
+	id := "bundle-0"
+	client := new(TestifyMockClient)
+	client.On("Status", mock.Anything, "http://192.0.2.1", id).Return(nil, &DiagnosticsBundleNotFoundError{id: id})
+	client.On("Status", mock.Anything, "http://192.0.2.2", id).Return(&Bundle{
+		ID:        "bundle-0",
+		Type:      Cluster,
+		Status:    Done,
+		Format:    archive.TarGz,
+		Encrypted: true,
+	}, nil)
+	client.On("GetSignature", mock.Anything, "http://192.0.2.2", id, mock.AnythingOfType("string")).Return(
+		func(ctx context.Context, url string, id string, signatureFile string) error {
+			return ioutil.WriteFile(signatureFile, []byte("signature\n"), filePerm)
+		})
+
+	bh := ClusterBundleHandler{
+		client:     client,
+		tools:      tools,
+		urlBuilder: MockURLBuilder{},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleSignatureEndpoint, bh.Signature).Methods(http.MethodGet)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/"+id+"/signature", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "signature\n", rr.Body.String())
+	// the signature is named after the downloaded bundle
+	assert.Equal(t, "attachment; filename=bundle-0.tar.gz.enc.sig", rr.Header().Get("Content-disposition"))
+}
+
+func TestDownloadSignatureOfUnsignedBundle(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{
+		{
+			Role: "master",
+			IP:   "192.0.2.1",
+		},
+	}, nil)
+
+	id := "bundle-0"
+	client := new(TestifyMockClient)
+	client.On("Status", mock.Anything, "http://192.0.2.1", id).Return(&Bundle{
+		ID:     "bundle-0",
+		Type:   Cluster,
+		Status: Done,
+	}, nil)
+	client.On("GetSignature", mock.Anything, "http://192.0.2.1", id, mock.AnythingOfType("string")).
+		Return(&DiagnosticsBundleNotFoundError{id: id})
+
+	bh := ClusterBundleHandler{
+		client:     client,
+		tools:      tools,
+		urlBuilder: MockURLBuilder{},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleSignatureEndpoint, bh.Signature).Methods(http.MethodGet)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/"+id+"/signature", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"code":404,"error":"bundle bundle-0 is not signed"}`, rr.Body.String())
+}
+
+func TestDownloadBundleWhenMasterFails(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{
+		{
+			Role: "master",
+			IP:   "192.0.2.1",
+		},
+		{
+			Role: "master",
+			IP:   "192.0.2.2",
+		},
+	}, nil)
+
+	expectedBytes, err := ioutil.ReadFile(filepath.Join("testdata", "combined.zip"))
+	require.NoError(t, err)
+
+	id := "bundle-0"
+	client := new(TestifyMockClient)
+	client.On("Status", mock.Anything, "http://192.0.2.1", id).Return(nil,
+		fmt.Errorf("received unexpected status code [500] from http://192.0.2.1%s/%s: internal error", bundlesEndpoint, id))
+	client.On("Status", mock.Anything, "http://192.0.2.2", id).Return(&Bundle{
+		ID:     "bundle-0",
+		Type:   Cluster,
+		Status: Done,
+	}, nil)
+	client.On("GetFile", mock.Anything, "http://192.0.2.2", id, mock.AnythingOfType("string")).Return(
+		func(ctx context.Context, url string, id string, tempZipFile string) error {
+			return ioutil.WriteFile(tempZipFile, expectedBytes, filePerm)
+		})
+	client.On("GetSignature", mock.Anything, "http://192.0.2.2", id, mock.AnythingOfType("string")).
+		Return(&DiagnosticsBundleNotFoundError{id: id})
+
+	workdir, err := ioutil.TempDir("", "work-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workdir)
+
+	bh := ClusterBundleHandler{
+		workDir:    workdir,
+		client:     client,
+		tools:      tools,
+		urlBuilder: MockURLBuilder{},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleFileEndpoint, bh.Download).Methods(http.MethodGet)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/"+id+"/file", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, expectedBytes, rr.Body.Bytes())
+}
+
+func TestDownloadSignedBundleIsNotConverted(t *testing.T) {
+	tools := new(MockedTools)
+	tools.On("GetMasterNodes").Return([]dcos.Node{
+		{
+			Role: "master",
+			IP:   "192.0.2.1",
+		},
+	}, nil)
+
+	expectedBytes, err := ioutil.ReadFile(filepath.Join("testdata", "combined.zip"))
+	require.NoError(t, err)
+
+	id := "bundle-0"
+	client := new(TestifyMockClient)
+	client.On("Status", mock.Anything, "http://192.0.2.1", id).Return(&Bundle{
+		ID:     "bundle-0",
+		Type:   Cluster,
+		Status: Done,
+	}, nil)
+	client.On("GetFile", mock.Anything, "http://192.0.2.1", id, mock.AnythingOfType("string")).Return(
+		func(ctx context.Context, url string, id string, tempZipFile string) error {
+			return ioutil.WriteFile(tempZipFile, expectedBytes, filePerm)
+		})
+	client.On("GetSignature", mock.Anything, "http://192.0.2.1", id, mock.AnythingOfType("string")).Return(
+		func(ctx context.Context, url string, id string, signatureFile string) error {
+			return ioutil.WriteFile(signatureFile, []byte("signature\n"), filePerm)
+		})
+
+	bh := ClusterBundleHandler{
+		client:     client,
+		tools:      tools,
+		urlBuilder: MockURLBuilder{},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(bundleFileEndpoint, bh.Download).Methods(http.MethodGet)
+
+	req, err := http.NewRequest(http.MethodGet, bundlesEndpoint+"/"+id+"/file", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/zstd")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	assert.JSONEq(t, `{"code":406,"error":"bundle bundle-0 is signed, it could only be served as application/zip"}`, rr.Body.String())
+}
+
 func TestDownloadMissingBundle(t *testing.T) {
 	workdir, err := ioutil.TempDir("", "work-dir")
 	require.NoError(t, err)
@@ -1097,7 +1290,7 @@ func TestClusterBundleHandlerWorkDirIsCreatedIfNotExists(t *testing.T) {
 	client := &MockClient{}
 	tools := &MockedTools{}
 	urlBuilder := MockURLBuilder{}
-	_, err = NewClusterBundleHandler(coord, client, tools, workdir, time.Millisecond, urlBuilder)
+	_, err = NewClusterBundleHandler(coord, client, tools, workdir, time.Millisecond, urlBuilder, nil, nil)
 	require.NoError(t, err)
 
 	assert.DirExists(t, workdir)
@@ -1114,7 +1307,7 @@ func TestClusterBundleHandlerWorkDirInitFailsWhenFileExists(t *testing.T) {
 	client := &MockClient{}
 	tools := &MockedTools{}
 	urlBuilder := MockURLBuilder{}
-	_, err = NewClusterBundleHandler(coord, client, tools, workdir.Name(), time.Millisecond, urlBuilder)
+	_, err = NewClusterBundleHandler(coord, client, tools, workdir.Name(), time.Millisecond, urlBuilder, nil, nil)
 	assert.Error(t, err)
 }
 
@@ -1135,7 +1328,8 @@ func (c mockCoordinator) CreateBundle(ctx context.Context, id string, nodes []no
 	return statuses
 }
 
-func (c mockCoordinator) CollectBundle(ctx context.Context, id string, numBundles int, statuses <-chan BundleStatus) (string, error) {
+func (c mockCoordinator) CollectBundle(ctx context.Context, id string, numBundles int,
+	statuses <-chan BundleStatus, format archive.Format, networkMatrix bool) (string, error) {
 	return filepath.Abs(filepath.Join("testdata", "combined.zip"))
 }
 
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
 	"errors"
@@ -13,6 +12,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/integrity"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,8 +39,10 @@ type Coordinator interface {
 	// on the returned channel.
 	CreateBundle(ctx context.Context, id string, nodes []node) <-chan BundleStatus
 	// CollectBundle waits until all the nodes' bundles have finished, downloads,
-	// and merges them. The resulting bundle zip file path is returned.
-	CollectBundle(ctx context.Context, bundleID string, numBundles int, statuses <-chan BundleStatus) (string, error)
+	// and merges them into a bundle in the given format. The resulting bundle file path is returned.
+	// When networkMatrix is set the bundle gets the reachability matrix assembled from nodes' network reports.
+	CollectBundle(ctx context.Context, bundleID string, numBundles int, statuses <-chan BundleStatus,
+		format archive.Format, networkMatrix bool) (string, error)
 }
 
 // ParallelCoordinator implements Coordinator interface to coordinate bundle
@@ -120,8 +124,10 @@ func (c ParallelCoordinator) CreateBundle(ctx context.Context, id string, nodes
 }
 
 // CollectBundle waits until all the nodes' bundles have finished, downloads,
-// and merges them. The resulting bundle zip file path is returned.
-func (c ParallelCoordinator) CollectBundle(ctx context.Context, bundleID string, numBundles int, statuses <-chan BundleStatus) (string, error) {
+// and merges them into a bundle in the given format. The resulting bundle file path is returned.
+// When networkMatrix is set the bundle gets the reachability matrix assembled from nodes' network reports.
+func (c ParallelCoordinator) CollectBundle(ctx context.Context, bundleID string, numBundles int,
+	statuses <-chan BundleStatus, format archive.Format, networkMatrix bool) (string, error) {
 
 	// holds the paths to the downloaded local bundles before merging
 	var bundlePaths []string
@@ -176,34 +182,47 @@ func (c ParallelCoordinator) CollectBundle(ctx context.Context, bundleID string,
 		}
 	}()
 
-	return mergeZips(report, bundlePaths, c.workDir)
+	return mergeZips(report, bundlePaths, c.workDir, format, networkMatrix)
 }
 
-func mergeZips(report bundleReport, bundlePaths []string, workDir string) (string, error) {
+// mergeZips merges node bundles into a single cluster bundle written in the given format.
+// Node bundles could be stored in any format supported by archive package.
+// When networkMatrix is set the reachability matrix of nodes is written to the merged bundle.
+func mergeZips(report bundleReport, bundlePaths []string, workDir string, format archive.Format, networkMatrix bool) (string, error) {
 
-	bundlePath := filepath.Join(workDir, fmt.Sprintf("bundle-%s.zip", report.ID))
+	bundlePath := filepath.Join(workDir, fmt.Sprintf("bundle-%s%s", report.ID, format.Extension()))
 	mergedZip, err := os.Create(bundlePath)
 	if err != nil {
 		return "", err
 	}
 	defer mergedZip.Close()
 
-	zipWriter := zip.NewWriter(mergedZip)
+	zipWriter, err := archive.NewWriter(mergedZip, format)
+	if err != nil {
+		return "", err
+	}
 	defer zipWriter.Close()
 
+	manifest := integrity.NewManifest()
+
 	reportFile, err := zipWriter.Create(reportFileName)
 	if err != nil {
 		return "", fmt.Errorf("could not create file %s: %s", reportFileName, err)
 	}
-	_, err = io.Copy(reportFile, bytes.NewReader(jsonMarshal(report)))
+	_, err = manifest.Copy(reportFileName, reportFile, bytes.NewReader(jsonMarshal(report)))
 	if err != nil {
 		return "", fmt.Errorf("could not copy file %s to zip: %s", reportFileName, err)
 	}
 
 	errorBuffer := bytes.NewBuffer(nil)
+	matrix := newNetworkMatrix()
 
 	for _, p := range bundlePaths {
-		rc, e := appendToZip(zipWriter, p)
+		var network *bytes.Buffer
+		if networkMatrix {
+			network = bytes.NewBuffer(nil)
+		}
+		rc, e := appendToZip(zipWriter, p, manifest, network)
 		if e != nil {
 			return "", e
 		}
@@ -211,6 +230,21 @@ func mergeZips(report bundleReport, bundlePaths []string, workDir string) (strin
 		if e != nil {
 			return "", e
 		}
+		if networkMatrix {
+			matrix.add(nodeBundleBase(p), network.Bytes())
+		}
+	}
+
+	if networkMatrix {
+		matrix.finish()
+		matrixFile, err := zipWriter.Create(networkMatrixFileName)
+		if err != nil {
+			return "", fmt.Errorf("could not create file %s: %s", networkMatrixFileName, err)
+		}
+		_, err = manifest.Copy(networkMatrixFileName, matrixFile, bytes.NewReader(jsonMarshal(matrix)))
+		if err != nil {
+			return "", fmt.Errorf("could not copy file %s to zip: %s", networkMatrixFileName, err)
+		}
 	}
 
 	if errorBuffer.Len() > 0 {
@@ -218,56 +252,82 @@ func mergeZips(report bundleReport, bundlePaths []string, workDir string) (strin
 		if err != nil {
 			return "", fmt.Errorf("could not create file %s: %s", summaryErrorsReportFileName, err)
 		}
-		_, err = io.Copy(summaryErrorsReportFile, errorBuffer)
+		_, err = manifest.Copy(summaryErrorsReportFileName, summaryErrorsReportFile, errorBuffer)
 		if err != nil {
 			return "", fmt.Errorf("could not copy file %s to zip: %s", summaryErrorsReportFileName, err)
 		}
 	}
 
+	if err := writeManifest(zipWriter, manifest); err != nil {
+		return "", err
+	}
+
 	return mergedZip.Name(), nil
 }
 
-func appendToZip(writer *zip.Writer, path string) (io.ReadCloser, error) {
+// appendToZip copies all files from the node bundle under the given path into the writer
+// and records their checksums in the manifest. Node summary errors report is returned so it could be merged.
+// If the node bundle has its own manifest, it is verified and carried to the merged bundle
+// so each node's contents can still be checked on its own. Verification problems are reported as errors.
+// The node bundle is read in a single pass so it could be in any format supported by archive package.
+// The node network report is also copied to network if it is not nil.
+func appendToZip(writer archive.Writer, path string, manifest *integrity.Manifest, network io.Writer) (io.ReadCloser, error) {
 	rc := ioutil.NopCloser(bytes.NewReader(nil))
-	r, err := zip.OpenReader(path)
+
+	base := nodeBundleBase(path)
+	verifier := integrity.NewVerifier()
+	errorBuffer := bytes.NewBuffer(nil)
+
+	err := archive.Walk(path, func(name string, r io.Reader) error {
+		if name == summaryErrorsReportFileName {
+			// node summary is carried only when node bundle has a manifest and it's known after all files are read
+			return verifier.Add(name, io.TeeReader(r, errorBuffer))
+		}
+		if network != nil && name == networkReportFileName {
+			r = io.TeeReader(r, network)
+		}
+		return addFileToZip(writer, name, r, base, manifest, verifier)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("could not open %s: %s", path, err)
+		return nil, fmt.Errorf("could not read %s: %s", path, err)
 	}
-	defer r.Close()
-
-	base := strings.TrimSuffix(filepath.Base(path), ".zip")
 
-	for _, f := range r.File {
-		if f.Name == summaryErrorsReportFileName {
-			fileReader, err := f.Open()
-			if err != nil {
-				return nil, fmt.Errorf("could not open %s from zip: %s", f.Name, err)
+	if verifier.HasManifest() {
+		if errorBuffer.Len() > 0 {
+			summary := bytes.NewReader(errorBuffer.Bytes())
+			if err := addFileToZip(writer, summaryErrorsReportFileName, summary, base, manifest, verifier); err != nil {
+				return nil, err
 			}
+		}
 
-			buf := bytes.NewBuffer(nil)
-			_, err = io.Copy(buf, fileReader)
-			if err != nil {
-				return nil, fmt.Errorf("could not read %s from zip: %s", f.Name, err)
-			}
-			rc = ioutil.NopCloser(buf)
-			continue
+		var integrityErrors []string
+		problems, err := verifier.Problems()
+		if err != nil {
+			integrityErrors = append(integrityErrors, fmt.Sprintf("could not verify %s bundle: %s", base, err))
 		}
-		if err := addFileToZip(writer, f, base); err != nil {
-			return nil, err
+		for _, p := range problems {
+			integrityErrors = append(integrityErrors, fmt.Sprintf("%s bundle integrity problem: %s", base, p))
+		}
+		if len(integrityErrors) > 0 {
+			if errorBuffer.Len() > 0 {
+				errorBuffer.WriteString("\n")
+			}
+			errorBuffer.WriteString(strings.Join(integrityErrors, "\n"))
 		}
 	}
 
+	if errorBuffer.Len() > 0 {
+		rc = ioutil.NopCloser(errorBuffer)
+	}
+
 	return rc, nil
 }
 
-func addFileToZip(writer *zip.Writer, f *zip.File, base string) error {
-	rc, err := f.Open()
-	if err != nil {
-		return fmt.Errorf("could not open %s from zip: %s", f.Name, err)
-	}
-	defer rc.Close()
-
-	fileName, err := sanitizeExtractPath(f.Name, base)
+// addFileToZip copies the node bundle file into base directory of the merged bundle.
+// Copied data is recorded in the merged bundle manifest and checked against node manifest by verifier.
+func addFileToZip(writer archive.Writer, name string, r io.Reader, base string,
+	manifest *integrity.Manifest, verifier *integrity.Verifier) error {
+	fileName, err := sanitizeExtractPath(name, base)
 	if err != nil {
 		return err
 	}
@@ -276,7 +336,9 @@ func addFileToZip(writer *zip.Writer, f *zip.File, base string) error {
 	if err != nil {
 		return fmt.Errorf("could not create file %s: %s", fileName, err)
 	}
-	_, err = io.Copy(file, rc)
+	h := integrity.NewHash()
+	err = verifier.Add(name, io.TeeReader(r, io.MultiWriter(file, h)))
+	manifest.Add(fileName, h.Sum(nil))
 	if err != nil {
 		return fmt.Errorf("could not copy file %s to zip: %s", fileName, err)
 	}
@@ -284,6 +346,15 @@ func addFileToZip(writer *zip.Writer, f *zip.File, base string) error {
 	return nil
 }
 
+// nodeBundleBase returns the directory name of the node bundle in the merged bundle.
+func nodeBundleBase(path string) string {
+	base := filepath.Base(path)
+	for _, f := range archive.Formats {
+		base = strings.TrimSuffix(base, f.Extension())
+	}
+	return base
+}
+
 // see: https://snyk.io/research/zip-slip-vulnerability
 func sanitizeExtractPath(filePath string, destination string) (string, error) {
 	destpath := filepath.Join(destination, filePath)
@@ -3,15 +3,21 @@ package rest
 import (
 	"archive/zip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/integrity"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -122,7 +128,7 @@ func TestCoordinatorCreateAndCollect(t *testing.T) {
 
 	statuses := c.CreateBundle(ctx, localBundleID, testNodes)
 
-	bundlePath, err := c.CollectBundle(ctx, bundleID, len(testNodes), statuses)
+	bundlePath, err := c.CollectBundle(ctx, bundleID, len(testNodes), statuses, archive.Zip, false)
 	require.NoError(t, err)
 	// ensure that the bundle is placed in the specified directory
 	assert.True(t, filepath.HasPrefix(bundlePath, workDir))
@@ -150,7 +156,13 @@ func TestCoordinatorCreateAndCollect(t *testing.T) {
 		files[f.Name] = string(raw)
 	}
 
+	require.Contains(t, files, integrity.ManifestFileName)
+	delete(files, integrity.ManifestFileName)
 	assert.Equal(t, expectedFiles, files)
+
+	problems, err := integrity.VerifyZip(&zipReader.Reader)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
 }
 
 func TestCoordinatorCreateAndCollectNoNodes(t *testing.T) {
@@ -168,7 +180,7 @@ func TestCoordinatorCreateAndCollectNoNodes(t *testing.T) {
 
 	statuses := c.CreateBundle(ctx, localBundleID, testNodes)
 
-	bundlePath, err := c.CollectBundle(ctx, bundleID, len(testNodes), statuses)
+	bundlePath, err := c.CollectBundle(ctx, bundleID, len(testNodes), statuses, archive.Zip, false)
 	require.NoError(t, err)
 	// ensure that the bundle is placed in the specified directory
 	assert.True(t, filepath.HasPrefix(bundlePath, workDir))
@@ -192,7 +204,13 @@ func TestCoordinatorCreateAndCollectNoNodes(t *testing.T) {
 		files[f.Name] = string(raw)
 	}
 
+	require.Contains(t, files, integrity.ManifestFileName)
+	delete(files, integrity.ManifestFileName)
 	assert.Equal(t, expectedFiles, files)
+
+	problems, err := integrity.VerifyZip(&zipReader.Reader)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
 }
 
 func TestAppendToZipErrorsWithMalformedZip(t *testing.T) {
@@ -211,10 +229,10 @@ func TestAppendToZipErrorsWithMalformedZip(t *testing.T) {
 	defer zipWriter.Close()
 
 	invalidZipPath := filepath.Join(testDataDir, "not_a_zip.txt")
-	rc, err := appendToZip(zipWriter, invalidZipPath)
+	rc, err := appendToZip(zipWriter, invalidZipPath, integrity.NewManifest(), nil)
 	assert.Nil(t, rc)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "zip: not a valid zip file")
+	assert.Contains(t, err.Error(), "unknown archive format")
 }
 
 func TestHandlingForBundleUpdateInProgress(t *testing.T) {
@@ -403,3 +421,211 @@ func TestHandlingForCanceledContext(t *testing.T) {
 		assert.Contains(t, expected, s)
 	}
 }
+
+func TestMergeZipsCarriesNodeManifests(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "merge")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+
+	writeNodeBundle := func(name string, tamper bool) string {
+		path := filepath.Join(workDir, name)
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		w := zip.NewWriter(f)
+		manifest := integrity.NewManifest()
+		entry, err := w.Create("test.txt")
+		require.NoError(t, err)
+		_, err = manifest.Copy("test.txt", entry, strings.NewReader("test"))
+		require.NoError(t, err)
+		if tamper {
+			manifest.Add("test.txt", make([]byte, 32))
+		}
+		entry, err = w.Create(integrity.ManifestFileName)
+		require.NoError(t, err)
+		_, err = manifest.WriteTo(entry)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return path
+	}
+
+	bundlePath, err := mergeZips(
+		bundleReport{ID: "bundle-0", Nodes: map[string]nodeBundleReport{}},
+		[]string{writeNodeBundle("192.0.2.1_agent.zip", false), writeNodeBundle("192.0.2.2_master.zip", true)},
+		workDir,
+		archive.Zip,
+		false,
+	)
+	require.NoError(t, err)
+
+	zipReader, err := zip.OpenReader(bundlePath)
+	require.NoError(t, err)
+	defer zipReader.Close()
+
+	var names []string
+	for _, f := range zipReader.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		reportFileName,
+		"192.0.2.1_agent/test.txt",
+		"192.0.2.1_agent/" + integrity.ManifestFileName,
+		"192.0.2.2_master/test.txt",
+		"192.0.2.2_master/" + integrity.ManifestFileName,
+		summaryErrorsReportFileName,
+		integrity.ManifestFileName,
+	}, names)
+
+	rc, err := zipReader.File[5].Open()
+	require.NoError(t, err)
+	summary, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"192.0.2.2_master bundle integrity problem: test.txt: checksum mismatch (checksums.sha256)",
+		string(summary))
+
+	problems, err := integrity.VerifyZip(&zipReader.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, []integrity.Problem{{
+		Manifest: "192.0.2.2_master/" + integrity.ManifestFileName,
+		Entry:    "192.0.2.2_master/test.txt",
+		Reason:   "checksum mismatch",
+	}}, problems)
+}
+
+func TestMergeZipsAcrossFormats(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "merge")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+
+	writeNodeBundle := func(name string, format archive.Format) string {
+		path := filepath.Join(workDir, name+format.Extension())
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		w, err := archive.NewWriter(f, format)
+		require.NoError(t, err)
+		manifest := integrity.NewManifest()
+		entry, err := w.Create("test.txt")
+		require.NoError(t, err)
+		_, err = manifest.Copy("test.txt", entry, strings.NewReader(name))
+		require.NoError(t, err)
+		require.NoError(t, writeManifest(w, manifest))
+		require.NoError(t, w.Close())
+		return path
+	}
+
+	bundlePath, err := mergeZips(
+		bundleReport{ID: "bundle-0", Nodes: map[string]nodeBundleReport{}},
+		[]string{
+			writeNodeBundle("192.0.2.1_agent", archive.Zip),
+			writeNodeBundle("192.0.2.2_agent", archive.TarGz),
+			writeNodeBundle("192.0.2.3_master", archive.TarZst),
+		},
+		workDir,
+		archive.TarZst,
+		false,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workDir, "bundle-bundle-0.tar.zst"), bundlePath)
+
+	format, err := archive.Detect(bundlePath)
+	require.NoError(t, err)
+	assert.Equal(t, archive.TarZst, format)
+
+	contents := map[string]string{}
+	err = archive.Walk(bundlePath, func(name string, r io.Reader) error {
+		content, err := ioutil.ReadAll(r)
+		contents[name] = string(content)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1_agent", contents["192.0.2.1_agent/test.txt"])
+	assert.Equal(t, "192.0.2.2_agent", contents["192.0.2.2_agent/test.txt"])
+	assert.Equal(t, "192.0.2.3_master", contents["192.0.2.3_master/test.txt"])
+	assert.NotContains(t, contents, summaryErrorsReportFileName)
+
+	problems, err := integrity.VerifyArchive(bundlePath)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestMergeZipsWithNetworkMatrix(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "merge")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+
+	writeNodeBundle := func(name string, network string) string {
+		path := filepath.Join(workDir, name+".zip")
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		w := zip.NewWriter(f)
+		if network != "" {
+			entry, err := w.Create(networkReportFileName)
+			require.NoError(t, err)
+			_, err = io.WriteString(entry, network)
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		return path
+	}
+
+	bundlePath, err := mergeZips(
+		bundleReport{ID: "bundle-0", Nodes: map[string]nodeBundleReport{}},
+		[]string{
+			writeNodeBundle("192.0.2.1_master", `{"node": {"ip": "192.0.2.1"}, "probes": [
+				{"host": "192.0.2.2", "port": 5051, "reachable": true, "latency_ms": 0.4},
+				{"host": "192.0.2.2", "port": 61001, "reachable": true, "latency_ms": 0.2},
+				{"host": "192.0.2.3", "port": 5051, "error": "i/o timeout"},
+				{"host": "192.0.2.3", "port": 61001, "error": "i/o timeout"}
+			]}`),
+			writeNodeBundle("192.0.2.2_agent", `{"node": {"ip": "192.0.2.2"}, "probes": [
+				{"host": "192.0.2.1", "port": 5050, "reachable": true, "latency_ms": 0.3},
+				{"host": "192.0.2.1", "port": 8181, "error": "connection refused"}
+			]}`),
+			writeNodeBundle("192.0.2.3_agent", ""),
+		},
+		workDir,
+		archive.Zip,
+		true,
+	)
+	require.NoError(t, err)
+
+	zipReader, err := zip.OpenReader(bundlePath)
+	require.NoError(t, err)
+	defer zipReader.Close()
+
+	var matrix networkMatrix
+	for _, f := range zipReader.File {
+		if f.Name != networkMatrixFileName {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(rc).Decode(&matrix))
+		rc.Close()
+	}
+
+	assert.Equal(t, networkMatrix{
+		Nodes: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+		Matrix: map[string]map[string]reachability{
+			"192.0.2.1": {
+				"192.0.2.2": {Reachable: true, LatencyMs: 0.2},
+				"192.0.2.3": {FailedPorts: []int{5051, 61001}},
+			},
+			"192.0.2.2": {
+				"192.0.2.1": {Reachable: true, LatencyMs: 0.3, FailedPorts: []int{8181}},
+			},
+		},
+		Unreachable: []unreachablePair{{From: "192.0.2.1", To: "192.0.2.3"}},
+		Errors:      map[string]string{"192.0.2.3_agent": "no network.json in node bundle"},
+	}, matrix)
+
+	problems, err := integrity.VerifyZip(&zipReader.Reader)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
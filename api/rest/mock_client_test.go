@@ -3,6 +3,9 @@
 package rest
 
 import context "context"
+import io "io"
+import url "net/url"
+import archive "github.com/dcos/dcos-diagnostics/archive"
 import mock "github.com/stretchr/testify/mock"
 
 // TestifyMockClient is an autogenerated mock type for the Client type
@@ -73,6 +76,24 @@ func (_m *TestifyMockClient) GetFile(ctx context.Context, node string, ID string
 	return r0
 }
 
+// GetSignature provides a mock function with given fields: ctx, node, ID, path
+func (_m *TestifyMockClient) GetSignature(ctx context.Context, node string, ID string, path string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ret := _m.Called(ctx, node, ID, path)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, node, ID, path)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // List provides a mock function with given fields: ctx, node
 func (_m *TestifyMockClient) List(ctx context.Context, node string) ([]*Bundle, error) {
 	if ctx.Err() != nil {
@@ -126,3 +147,57 @@ func (_m *TestifyMockClient) Status(ctx context.Context, node string, ID string)
 
 	return r0, r1
 }
+
+// ListFiles provides a mock function with given fields: ctx, node, ID
+func (_m *TestifyMockClient) ListFiles(ctx context.Context, node string, ID string) ([]archive.Entry, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	ret := _m.Called(ctx, node, ID)
+
+	var r0 []archive.Entry
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []archive.Entry); ok {
+		r0 = rf(ctx, node, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]archive.Entry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, node, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBundleFile provides a mock function with given fields: ctx, node, ID, name, query
+func (_m *TestifyMockClient) GetBundleFile(ctx context.Context, node string, ID string, name string, query url.Values) (io.ReadCloser, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	ret := _m.Called(ctx, node, ID, name, query)
+
+	var r0 io.ReadCloser
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, url.Values) io.ReadCloser); ok {
+		r0 = rf(ctx, node, ID, name, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, url.Values) error); ok {
+		r1 = rf(ctx, node, ID, name, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
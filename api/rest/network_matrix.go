@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/dcos/dcos-diagnostics/netdiag"
+)
+
+const (
+	networkReportFileName = "network.json"        // network diagnostics report in node bundles
+	networkMatrixFileName = "network-matrix.json" // reachability matrix in cluster bundles
+)
+
+// networkMatrix tells how nodes of the cluster reach each other. It is assembled from network reports
+// of node bundles, so a node reaches only nodes it probed.
+type networkMatrix struct {
+	// Nodes are IPs of nodes that reported or were probed
+	Nodes []string `json:"nodes"`
+	// Matrix holds reachability of the node in the inner key from the node in the outer key
+	Matrix map[string]map[string]reachability `json:"matrix"`
+	// Unreachable are pairs of nodes where no probed port accepted a connection
+	Unreachable []unreachablePair `json:"unreachable,omitempty"`
+	// Errors are keyed by node bundles without a usable network report
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type reachability struct {
+	// Reachable is true if any probed port accepted a connection
+	Reachable bool `json:"reachable"`
+	// LatencyMs is the lowest connect latency of reachable ports
+	LatencyMs   float64 `json:"latency_ms,omitempty"`
+	FailedPorts []int   `json:"failed_ports,omitempty"`
+}
+
+type unreachablePair struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func newNetworkMatrix() *networkMatrix {
+	return &networkMatrix{Matrix: map[string]map[string]reachability{}, Errors: map[string]string{}}
+}
+
+// add records probes of the network report found in the node bundle with the given base name.
+// Empty data means the bundle has no report.
+func (m *networkMatrix) add(base string, data []byte) {
+	if len(data) == 0 {
+		m.Errors[base] = fmt.Sprintf("no %s in node bundle", networkReportFileName)
+		return
+	}
+	var report netdiag.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		m.Errors[base] = fmt.Sprintf("could not decode %s: %s", networkReportFileName, err)
+		return
+	}
+	if report.Node.IP == "" {
+		m.Errors[base] = fmt.Sprintf("%s does not identify the node", networkReportFileName)
+		return
+	}
+	if e, ok := report.Errors["probes"]; ok {
+		m.Errors[base] = fmt.Sprintf("node could not probe: %s", e)
+	}
+
+	row := m.Matrix[report.Node.IP]
+	if row == nil {
+		row = map[string]reachability{}
+		m.Matrix[report.Node.IP] = row
+	}
+	for _, p := range report.Probes {
+		cell := row[p.Host]
+		if p.Reachable {
+			if !cell.Reachable || p.LatencyMs < cell.LatencyMs {
+				cell.LatencyMs = p.LatencyMs
+			}
+			cell.Reachable = true
+		} else {
+			cell.FailedPorts = append(cell.FailedPorts, p.Port)
+		}
+		row[p.Host] = cell
+	}
+}
+
+// finish lists nodes and unreachable pairs in a stable order
+func (m *networkMatrix) finish() {
+	nodes := map[string]bool{}
+	for from, row := range m.Matrix {
+		nodes[from] = true
+		for to, cell := range row {
+			nodes[to] = true
+			sort.Ints(cell.FailedPorts)
+			if !cell.Reachable {
+				m.Unreachable = append(m.Unreachable, unreachablePair{From: from, To: to})
+			}
+		}
+	}
+	m.Nodes = make([]string, 0, len(nodes))
+	for n := range nodes {
+		m.Nodes = append(m.Nodes, n)
+	}
+	sort.Strings(m.Nodes)
+	sort.Slice(m.Unreachable, func(i, j int) bool {
+		if m.Unreachable[i].From != m.Unreachable[j].From {
+			return m.Unreachable[i].From < m.Unreachable[j].From
+		}
+		return m.Unreachable[i].To < m.Unreachable[j].To
+	})
+	if len(m.Errors) == 0 {
+		m.Errors = nil
+	}
+}
@@ -1,13 +1,22 @@
 package rest
 
-import "context"
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/dcos/dcos-diagnostics/archive"
+)
 
 type MockClient struct {
 	createBundle func(ctx context.Context, node string, ID string) (*Bundle, error)
 	status       func(ctx context.Context, node string, ID string) (*Bundle, error)
 	getFile      func(ctx context.Context, node string, ID string, path string) (err error)
+	getSignature func(ctx context.Context, node string, ID string, path string) (err error)
 	list         func(ctx context.Context, node string) ([]*Bundle, error)
 	delete       func(ctx context.Context, node string, ID string) error
+	listFiles    func(ctx context.Context, node string, ID string) ([]archive.Entry, error)
+	getFileEntry func(ctx context.Context, node string, ID string, name string, query url.Values) (io.ReadCloser, error)
 }
 
 func (_m *MockClient) CreateBundle(ctx context.Context, node string, ID string) (*Bundle, error) {
@@ -22,6 +31,10 @@ func (_m *MockClient) GetFile(ctx context.Context, node string, ID string, path
 	return _m.getFile(ctx, node, ID, path)
 }
 
+func (_m *MockClient) GetSignature(ctx context.Context, node string, ID string, path string) error {
+	return _m.getSignature(ctx, node, ID, path)
+}
+
 func (_m *MockClient) List(ctx context.Context, node string) ([]*Bundle, error) {
 	return _m.list(ctx, node)
 }
@@ -29,3 +42,11 @@ func (_m *MockClient) List(ctx context.Context, node string) ([]*Bundle, error)
 func (_m *MockClient) Status(ctx context.Context, node string, ID string) (*Bundle, error) {
 	return _m.status(ctx, node, ID)
 }
+
+func (_m *MockClient) ListFiles(ctx context.Context, node string, ID string) ([]archive.Entry, error) {
+	return _m.listFiles(ctx, node, ID)
+}
+
+func (_m *MockClient) GetBundleFile(ctx context.Context, node string, ID string, name string, query url.Values) (io.ReadCloser, error) {
+	return _m.getFileEntry(ctx, node, ID, name, query)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+)
+
+// ObserveRestarts records restart counters of all units in the flapping detector
+func (s *SystemdUnits) ObserveRestarts(tools dcos.Tooler) error {
+	if s.Flapping == nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err := tools.InitializeUnitControllerConnection(); err != nil {
+		return fmt.Errorf("could not connect to unit controller: %s", err)
+	}
+	defer tools.CloseUnitControllerConnection()
+
+	units, err := tools.GetUnitNames()
+	if err != nil {
+		return fmt.Errorf("could not get units: %s", err)
+	}
+	for _, unit := range units {
+		props, err := tools.GetUnitProperties(unit)
+		if err != nil {
+			logrus.WithField("unit", unit).WithError(err).Warn("Could not get unit properties to observe restarts")
+			continue
+		}
+		var propsResponse UnitPropertiesResponse
+		if err := mapstructure.Decode(props, &propsResponse); err != nil {
+			logrus.WithField("unit", unit).WithError(err).Warn("Could not decode unit properties to observe restarts")
+			continue
+		}
+		s.Flapping.Observe(propsResponse.ID, propsResponse.NRestarts, propsResponse.StateChangeTimestampMonotonic)
+	}
+	s.Flapping.Forget(units)
+	return nil
+}
+
+// StartObservingRestarts observes restarts of units every interval until the context is done, so the flapping rate
+// does not depend on how often health is requested
+func (s *SystemdUnits) StartObservingRestarts(ctx context.Context, tools dcos.Tooler, interval time.Duration) {
+	if s.Flapping == nil {
+		return
+	}
+	for {
+		if err := s.ObserveRestarts(tools); err != nil {
+			logrus.WithError(err).Warn("Could not observe unit restarts")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net/http/pprof"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/ui"
+
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -26,6 +28,36 @@ const nodeBundleEndpoint = nodeBundlesEndpoint + "/{id}"
 // Endpoint to download bundle file
 const nodeBundleFileEndpoint = nodeBundleEndpoint + "/file"
 
+// Endpoint to download detached signature of bundle file
+const nodeBundleSignatureEndpoint = nodeBundleEndpoint + "/signature"
+
+// Endpoint for listing files stored in bundle
+const nodeBundleFilesEndpoint = nodeBundleEndpoint + "/files"
+
+// Endpoint to get a single file stored in bundle
+const nodeBundleFilesEntryEndpoint = nodeBundleFilesEndpoint + "/{path:.+}"
+
+// Endpoint for searching logs of the current node
+const nodeLogsSearchEndpoint = baseRoute + "/node/logs/search"
+
+// Endpoint for searching logs of all cluster nodes
+const logsSearchEndpoint = baseRoute + "/logs/search"
+
+// Endpoint serving configuration fingerprint of the current node
+const nodeFingerprintEndpoint = baseRoute + "/node/fingerprint"
+
+// Endpoint serving configuration drift of all cluster nodes
+const driftEndpoint = baseRoute + "/drift"
+
+// Endpoint for listing and creating silences of nodes and units in maintenance
+const silencesEndpoint = baseRoute + "/silences"
+
+// Endpoint for deleting a silence
+const silenceEndpoint = silencesEndpoint + "/{id}"
+
+// Endpoint serving all silences known to the master including expired and deleted ones, used to replicate silences
+const nodeSilencesEndpoint = baseRoute + "/node/silences"
+
 // Endpoint for listing all cluster bundles
 const clusterBundlesEndpoint = baseRoute + "/diagnostics"
 
@@ -35,6 +67,21 @@ const clusterBundleEndpoint = clusterBundlesEndpoint + "/{id}"
 // Endpoint to download cluster bundle file
 const clusterBundleFileEndpoint = clusterBundleEndpoint + "/file"
 
+// Endpoint to download detached signature of cluster bundle file
+const clusterBundleSignatureEndpoint = clusterBundleEndpoint + "/signature"
+
+// Endpoint for listing files stored in cluster bundle
+const clusterBundleFilesEndpoint = clusterBundleEndpoint + "/files"
+
+// Endpoint to get a single file stored in cluster bundle
+const clusterBundleFilesEntryEndpoint = clusterBundleFilesEndpoint + "/{path:.+}"
+
+// Endpoint serving the web UI
+const uiEndpoint = baseRoute + "/ui"
+
+// Endpoint serving static assets of the web UI
+const uiAssetEndpoint = uiEndpoint + "/{asset}"
+
 type routeHandler struct {
 	url                 string
 	handler             http.HandlerFunc
@@ -105,6 +152,8 @@ func getRoutes(dt *Dt) []routeHandler {
 		job:                dt.DtDiagnosticsJob,
 		systemdUnits:       dt.SystemdUnits,
 		monitoringResponse: dt.MR,
+		reloader:           dt.ConfigReloader,
+		silences:           dt.Silences,
 	}
 
 	bh := dt.BundleHandler
@@ -183,6 +232,41 @@ func getRoutes(dt *Dt) []routeHandler {
 			canFlushCache: true,
 		},
 
+		{
+			// /system/health/v1/config
+			url:     baseRoute + "/config",
+			handler: h.configHandler,
+		},
+		{
+			url:     nodeFingerprintEndpoint,
+			handler: h.nodeFingerprintHandler,
+		},
+		{
+			url:           driftEndpoint,
+			handler:       h.driftHandler,
+			canFlushCache: true,
+		},
+		{
+			url:     silencesEndpoint,
+			handler: h.listSilencesHandler,
+			methods: []string{"GET"},
+		},
+		{
+			url:     silencesEndpoint,
+			handler: h.createSilenceHandler,
+			methods: []string{"POST"},
+		},
+		{
+			url:     silenceEndpoint,
+			handler: h.deleteSilenceHandler,
+			methods: []string{"DELETE"},
+		},
+		{
+			url:     nodeSilencesEndpoint,
+			handler: h.nodeSilencesHandler,
+			methods: []string{"GET"},
+		},
+
 		// diagnostics routes
 		{
 			// /system/health/v1/logs
@@ -201,6 +285,31 @@ func getRoutes(dt *Dt) []routeHandler {
 			},
 			gzip: true,
 		},
+		{
+			url:     nodeLogsSearchEndpoint,
+			handler: h.nodeLogSearchHandler,
+			headers: []header{
+				{
+					name:  "Content-type",
+					value: "application/x-ndjson",
+				},
+			},
+		},
+		{
+			url:     logsSearchEndpoint,
+			handler: h.logSearchHandler,
+			headers: []header{
+				{
+					name:  "Content-type",
+					value: "application/x-ndjson",
+				},
+			},
+		},
+		{
+			// /system/health/v1/nodes/<nodeid>/logs/<provider>/<entity>
+			url:     fmt.Sprintf("%s/nodes/{nodeid}/logs/{provider}/{entity}", baseRoute),
+			handler: h.nodeLogHandler,
+		},
 		//---------------------------------------------------------------------
 		// 					V2 REST API for bundles CRUD
 		//---- Node level API
@@ -229,6 +338,21 @@ func getRoutes(dt *Dt) []routeHandler {
 			handler: bh.GetFile,
 			methods: []string{"GET"},
 		},
+		{
+			url:     nodeBundleSignatureEndpoint,
+			handler: bh.GetSignature,
+			methods: []string{"GET"},
+		},
+		{
+			url:     nodeBundleFilesEndpoint,
+			handler: bh.ListFiles,
+			methods: []string{"GET"},
+		},
+		{
+			url:     nodeBundleFilesEntryEndpoint,
+			handler: bh.GetBundleFile,
+			methods: []string{"GET"},
+		},
 		//---- Cluster level API
 		{
 			url:     clusterBundleEndpoint,
@@ -255,7 +379,32 @@ func getRoutes(dt *Dt) []routeHandler {
 			handler: cbh.Download,
 			methods: []string{"GET"},
 		},
+		{
+			url:     clusterBundleSignatureEndpoint,
+			handler: cbh.Signature,
+			methods: []string{"GET"},
+		},
+		{
+			url:     clusterBundleFilesEndpoint,
+			handler: cbh.ListFiles,
+			methods: []string{"GET"},
+		},
+		{
+			url:     clusterBundleFilesEntryEndpoint,
+			handler: cbh.GetBundleFile,
+			methods: []string{"GET"},
+		},
 		//---------------------------------------------------------------------
+		{
+			url:     uiEndpoint,
+			handler: ui.Handler,
+			gzip:    true,
+		},
+		{
+			url:     uiAssetEndpoint,
+			handler: ui.Handler,
+			gzip:    true,
+		},
 		{
 			// /system/health/v1/report/diagnostics
 			url:     baseRoute + "/report/diagnostics/create",
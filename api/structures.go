@@ -4,6 +4,9 @@ import (
 	"github.com/dcos/dcos-diagnostics/api/rest"
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
+	"github.com/dcos/dcos-diagnostics/flapping"
+	"github.com/dcos/dcos-diagnostics/silence"
 )
 
 // httpResponse a structure of http response from a remote host.
@@ -11,6 +14,12 @@ type httpResponse struct {
 	Status int
 	Units  []dcos.Unit
 	Node   dcos.Node
+	// DcosVersion and DiagnosticsVersion are reported by the node
+	DcosVersion        string
+	DiagnosticsVersion string
+	// Fingerprint is the node configuration, FingerprintError is set if the node did not report it
+	Fingerprint      *drift.Fingerprint
+	FingerprintError string
 }
 
 // UnitsHealthResponseJSONStruct json response /system/health/v1
@@ -22,6 +31,8 @@ type UnitsHealthResponseJSONStruct struct {
 	Role        string                 `json:"node_role"`
 	MesosID     string                 `json:"mesos_id"`
 	TdtVersion  string                 `json:"dcos_diagnostics_version"`
+	// Fingerprint is the node configuration compared by masters to detect drift
+	Fingerprint *drift.Fingerprint `json:"fingerprint,omitempty"`
 }
 
 // HealthResponseValues is a health values json response.
@@ -32,6 +43,11 @@ type HealthResponseValues struct {
 	UnitTitle  string      `json:"description"`
 	Help       string      `json:"help"`
 	PrettyName string      `json:"name"`
+	// Level and Reasons are the severity of health and what caused it, UnitHealth is derived from Level
+	Level   dcos.Level `json:"level,omitempty"`
+	Reasons []string   `json:"reasons,omitempty"`
+	// Flapping is the restart rate of systemd units observed by the node
+	Flapping *flapping.Rate `json:"flapping,omitempty"`
 }
 
 // UnitsResponseJSONStruct contains health overview, collected from all hosts
@@ -45,6 +61,7 @@ type UnitResponseFieldsStruct struct {
 	PrettyName string      `json:"name"`
 	UnitHealth dcos.Health `json:"health"`
 	UnitTitle  string      `json:"description"`
+	Level      dcos.Level  `json:"level,omitempty"`
 }
 
 // NodesResponseJSONStruct contains an array of responses from nodes.
@@ -57,6 +74,12 @@ type NodeResponseFieldsStruct struct {
 	HostIP     string      `json:"host_ip"`
 	NodeHealth dcos.Health `json:"health"`
 	NodeRole   string      `json:"role"`
+	Silenced   bool        `json:"silenced,omitempty"`
+	Level      dcos.Level  `json:"level,omitempty"`
+	// SuppressedHealth and SuppressedLevel are health and level with silences applied, set only when silences
+	// apply to the node
+	SuppressedHealth *dcos.Health `json:"suppressed_health,omitempty"`
+	SuppressedLevel  dcos.Level   `json:"suppressed_level,omitempty"`
 }
 
 // NodeResponseFieldsWithErrorStruct contains node response with errors.
@@ -76,10 +99,13 @@ type Dt struct {
 	DtDiagnosticsJob     *DiagnosticsJob
 	BundleHandler        rest.BundleHandler
 	ClusterBundleHandler *rest.ClusterBundleHandler
+	ConfigReloader       *ConfigReloader
 	RunPullerChan        chan bool
 	RunPullerDoneChan    chan bool
 	SystemdUnits         *SystemdUnits
 	MR                   *MonitoringResponse
+	// Silences are maintenance windows, they are kept only on masters pulling health of the cluster
+	Silences *silence.Store
 }
 
 type bundle struct {
@@ -0,0 +1,141 @@
+// Package archive abstracts archive formats used for diagnostics bundles.
+// Zip is the default format. Tar archives compressed with gzip or zstd are streamed,
+// so they compress text logs better and could be read before whole bundle is downloaded.
+package archive
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is an archive format of a bundle
+type Format string
+
+const (
+	// Zip is a default format compatible with all dcos-diagnostics versions
+	Zip Format = "zip"
+	// TarGz is a tar archive compressed with gzip
+	TarGz Format = "tar.gz"
+	// TarZst is a tar archive compressed with zstd
+	TarZst Format = "tar.zst"
+)
+
+// Formats lists all supported formats, default first
+var Formats = []Format{Zip, TarGz, TarZst}
+
+var contentTypes = map[Format]string{
+	Zip:    "application/zip",
+	TarGz:  "application/gzip",
+	TarZst: "application/zstd",
+}
+
+// ParseFormat returns format with the given name. Empty name means default format.
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return Zip, nil
+	}
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown archive format %q, supported formats are: zip, tar.gz, tar.zst", name)
+}
+
+// Extension returns file name extension with leading dot.
+func (f Format) Extension() string {
+	if f == "" {
+		return "." + string(Zip)
+	}
+	return "." + string(f)
+}
+
+// ContentType returns MIME type used when archive is served over HTTP.
+func (f Format) ContentType() string {
+	if f == "" {
+		return contentTypes[Zip]
+	}
+	return contentTypes[f]
+}
+
+// Negotiate picks a format based on the HTTP Accept header. Preferred format is returned
+// when it's accepted or when Accept header does not list any of supported formats.
+// False is returned when the header lists only media types that can't be served.
+func Negotiate(accept string, preferred Format) (Format, bool) {
+	if preferred == "" {
+		preferred = Zip
+	}
+	if accept == "" {
+		return preferred, true
+	}
+
+	var accepted []Format
+	wildcard := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok && strings.TrimLeft(q, "0.") == "" {
+			continue // q=0 means not acceptable
+		}
+		switch mediaType {
+		case "*/*", "application/*", "application/octet-stream":
+			wildcard = true
+		}
+		for _, f := range Formats {
+			if contentTypes[f] == mediaType {
+				accepted = append(accepted, f)
+			}
+		}
+	}
+
+	for _, f := range accepted {
+		if f == preferred {
+			return preferred, true
+		}
+	}
+	if wildcard {
+		return preferred, true
+	}
+	if len(accepted) > 0 {
+		return accepted[0], true
+	}
+	return preferred, false
+}
+
+// Writer adds files to an archive.
+type Writer interface {
+	// Create adds a file with the given name to the archive. Returned writer must be used
+	// before the next call to Create or Close.
+	Create(name string) (io.Writer, error)
+	// Close finishes the archive. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns Writer that writes archive in the given format to w. Entries of tar archives
+// are staged next to w when it's a file, e.g., in the bundle work dir, and in the default directory
+// for temporary files otherwise.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	var dir string
+	if file, ok := w.(*os.File); ok {
+		dir = filepath.Dir(file.Name())
+	}
+	return newWriter(w, f, dir)
+}
+
+func newWriter(w io.Writer, f Format, dir string) (Writer, error) {
+	switch f {
+	case Zip, "":
+		return newZipWriter(w), nil
+	case TarGz:
+		return newTarGzWriter(w, dir), nil
+	case TarZst:
+		return newTarZstWriter(w, dir)
+	}
+	return nil, fmt.Errorf("unknown archive format %q", f)
+}
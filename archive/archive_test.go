@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	name    string
+	content string
+}
+
+var testEntries = []entry{
+	{"a.txt", "a"},
+	{"dir/b.txt", strings.Repeat("b", 100*1024)},
+	{"empty.txt", ""},
+}
+
+func writeArchive(t *testing.T, dir string, format Format, entries []entry) string {
+	path := filepath.Join(dir, "bundle"+format.Extension())
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w, err := NewWriter(f, format)
+	require.NoError(t, err)
+	for _, e := range entries {
+		file, err := w.Create(e.name)
+		require.NoError(t, err)
+		_, err = io.WriteString(file, e.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+func readArchive(t *testing.T, path string) []entry {
+	var entries []entry
+	err := Walk(path, func(name string, r io.Reader) error {
+		content, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		entries = append(entries, entry{name, string(content)})
+		return nil
+	})
+	require.NoError(t, err)
+	return entries
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		parsed, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		assert.Equal(t, f, parsed)
+	}
+
+	parsed, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, Zip, parsed)
+
+	_, err = ParseFormat("rar")
+	assert.EqualError(t, err, `unknown archive format "rar", supported formats are: zip, tar.gz, tar.zst`)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept     string
+		preferred  Format
+		expected   Format
+		acceptable bool
+	}{
+		{"", TarZst, TarZst, true},
+		{"", "", Zip, true},
+		{"*/*", TarGz, TarGz, true},
+		{"application/octet-stream", TarGz, TarGz, true},
+		{"application/zip", TarZst, Zip, true},
+		{"application/zip, application/zstd", TarZst, TarZst, true},
+		{"application/gzip;q=0.9, */*;q=0.1", TarZst, TarZst, true},
+		{"application/gzip, text/plain", TarZst, TarGz, true},
+		{"application/zstd;q=0, application/zip", TarZst, Zip, true},
+		{"text/html", Zip, Zip, false},
+	}
+
+	for _, tt := range tests {
+		format, ok := Negotiate(tt.accept, tt.preferred)
+		assert.Equal(t, tt.expected, format, tt.accept)
+		assert.Equal(t, tt.acceptable, ok, tt.accept)
+	}
+}
+
+func TestWriteAndWalk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			path := writeArchive(t, dir, format, testEntries)
+
+			detected, err := Detect(path)
+			require.NoError(t, err)
+			assert.Equal(t, format, detected)
+
+			assert.Equal(t, testEntries, readArchive(t, path))
+		})
+	}
+}
+
+func TestWalkStopsOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeArchive(t, dir, TarZst, testEntries)
+	calls := 0
+	err = Walk(path, func(name string, r io.Reader) error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDetectUnknownFormat(t *testing.T) {
+	f, err := ioutil.TempFile("", "archive")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("not an archive")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = Detect(f.Name())
+	assert.EqualError(t, err, "unknown archive format")
+}
+
+func TestTarWriterStagesEntriesNextToArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "bundle.tar.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	w, err := NewWriter(f, TarGz)
+	require.NoError(t, err)
+	entry, err := w.Create("a.txt")
+	require.NoError(t, err)
+	staged, err := filepath.Glob(filepath.Join(dir, "archive-entry*"))
+	require.NoError(t, err)
+	assert.Len(t, staged, 1)
+	_, err = io.WriteString(entry, "a")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	staged, err = filepath.Glob(filepath.Join(dir, "archive-entry*"))
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+}
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src := writeArchive(t, dir, Zip, testEntries)
+
+	for _, format := range []Format{TarGz, TarZst} {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, Convert(buf, src, format))
+
+		dst := filepath.Join(dir, "converted"+format.Extension())
+		require.NoError(t, ioutil.WriteFile(dst, buf.Bytes(), 0600))
+		assert.Equal(t, testEntries, readArchive(t, dst), format)
+	}
+}
@@ -0,0 +1,161 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrEntryNotFound is returned when the archive has no file with the given name
+var ErrEntryNotFound = errors.New("file not found in the archive")
+
+// Entry describes a regular file stored in an archive
+type Entry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// CompressedSize is the size of the file in zip archives, tar archives are compressed as a whole
+	CompressedSize int64     `json:"compressed_size,omitempty"`
+	Modified       time.Time `json:"mtime"`
+}
+
+// List returns regular files in the archive under the given path. Zip archives are listed from
+// their central directory, tar archives are read as a whole.
+func List(path string) ([]Entry, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	if format == Zip {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %s", path, err)
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			modified := f.Modified
+			if modified.IsZero() {
+				modified = f.ModTime()
+			}
+			entries = append(entries, Entry{
+				Name:           f.Name,
+				Size:           int64(f.UncompressedSize64),
+				CompressedSize: int64(f.CompressedSize64),
+				Modified:       modified.UTC(),
+			})
+		}
+		return entries, nil
+	}
+
+	err = walkTarHeaders(path, format, func(header *tar.Header, _ io.Reader) (bool, error) {
+		entries = append(entries, Entry{Name: header.Name, Size: header.Size, Modified: header.ModTime.UTC()})
+		return true, nil
+	})
+	return entries, err
+}
+
+// Open returns a reader of the file with the given name stored in the archive under the given path.
+// ErrEntryNotFound is returned if there is no such file.
+func Open(path, name string) (io.ReadCloser, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == Zip {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %s", path, err)
+		}
+		for _, f := range r.File {
+			if f.Name != name || f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("could not open %s: %s", name, err)
+			}
+			return &entryReader{Reader: rc, close: func() { rc.Close(); r.Close() }}, nil
+		}
+		r.Close()
+		return nil, ErrEntryNotFound
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %s", path, err)
+	}
+	decompressed, closeFn, err := decompress(f, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			closeFn()
+			f.Close()
+			if err == io.EOF {
+				return nil, ErrEntryNotFound
+			}
+			return nil, fmt.Errorf("could not read tar: %s", err)
+		}
+		if header.Typeflag == tar.TypeReg && header.Name == name {
+			return &entryReader{Reader: tr, close: func() { closeFn(); f.Close() }}, nil
+		}
+	}
+}
+
+// walkTarHeaders calls fn for every regular file in the tar archive until fn returns false
+func walkTarHeaders(path string, format Format, fn func(header *tar.Header, r io.Reader) (bool, error)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	decompressed, closeFn, err := decompress(f, format)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read tar: %s", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		next, err := fn(header, tr)
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+// entryReader reads a file stored in an archive and closes the archive when it's closed
+type entryReader struct {
+	io.Reader
+	close func()
+}
+
+func (e *entryReader) Close() error {
+	e.close()
+	return nil
+}
@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAndOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			path := writeArchive(t, dir, format, testEntries)
+
+			entries, err := List(path)
+			require.NoError(t, err)
+			require.Len(t, entries, len(testEntries))
+			for i, e := range testEntries {
+				assert.Equal(t, e.name, entries[i].Name)
+				assert.Equal(t, int64(len(e.content)), entries[i].Size)
+				assert.False(t, entries[i].Modified.IsZero())
+			}
+			if format == Zip {
+				assert.True(t, entries[1].CompressedSize < entries[1].Size)
+			}
+
+			rc, err := Open(path, "dir/b.txt")
+			require.NoError(t, err)
+			content, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			assert.Equal(t, testEntries[1].content, string(content))
+
+			_, err = Open(path, "missing.txt")
+			assert.Equal(t, ErrEntryNotFound, err)
+		})
+	}
+}
+
+func TestListEmptyArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	entries, err := List(writeArchive(t, dir, Zip, nil))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NotNil(t, entries)
+}
@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	zipEmpty  = []byte("PK\x05\x06")
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// WalkFunc is called for every file in the archive. The reader is valid only until WalkFunc returns.
+type WalkFunc func(name string, r io.Reader) error
+
+// Detect returns format of the archive under the given path based on its content.
+func Detect(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("could not read %s: %s", path, err)
+	}
+	return detect(header[:n])
+}
+
+func detect(header []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, zipEmpty):
+		return Zip, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		return TarGz, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return TarZst, nil
+	}
+	return "", fmt.Errorf("unknown archive format")
+}
+
+// Walk calls fn for every regular file in the archive under the given path in the order
+// they are stored. Format is detected from the file content.
+func Walk(path string, fn WalkFunc) error {
+	format, err := Detect(path)
+	if err != nil {
+		return err
+	}
+
+	if format == Zip {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %s", path, err)
+		}
+		defer r.Close()
+		return WalkZip(&r.Reader, fn)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+	return WalkTar(f, format, fn)
+}
+
+// WalkZip calls fn for every file in the zip archive.
+func WalkZip(r *zip.Reader, fn WalkFunc) error {
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("could not open %s: %s", f.Name, err)
+		}
+		err = fn(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkTar calls fn for every regular file in the compressed tar stream.
+func WalkTar(r io.Reader, format Format, fn WalkFunc) error {
+	decompressed, closeFn, err := decompress(r, format)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read tar: %s", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// decompress returns the tar stream of the compressed archive and the function releasing the decompressor
+func decompress(r io.Reader, format Format) (io.Reader, func(), error) {
+	switch format {
+	case TarGz:
+		gz, err := gzip.NewReader(bufio.NewReader(r))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read gzip stream: %s", err)
+		}
+		return gz, func() { gz.Close() }, nil
+	case TarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read zstd stream: %s", err)
+		}
+		return zr, zr.Close, nil
+	}
+	return nil, nil, fmt.Errorf("%q is not a tar format", format)
+}
+
+// Convert rewrites archive read from src in the given format to dst. Entries are staged next to src.
+func Convert(dst io.Writer, src string, format Format) error {
+	w, err := newWriter(dst, format, filepath.Dir(src))
+	if err != nil {
+		return err
+	}
+	err = Walk(src, func(name string, r io.Reader) error {
+		entry, err := w.Create(name)
+		if err != nil {
+			return fmt.Errorf("could not create %s: %s", name, err)
+		}
+		if _, err := io.Copy(entry, r); err != nil {
+			return fmt.Errorf("could not copy %s: %s", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,113 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type zipWriter struct {
+	w *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{w: zip.NewWriter(w)}
+}
+
+func (z *zipWriter) Create(name string) (io.Writer, error) {
+	return z.w.Create(name)
+}
+
+func (z *zipWriter) Close() error {
+	return z.w.Close()
+}
+
+// tarWriter writes a tar stream through a compressor. Tar header needs the size of the file
+// before its content, so every entry is spooled to a temporary file in dir and flushed to the archive
+// when the next entry is created or the archive is closed.
+type tarWriter struct {
+	compressor io.WriteCloser
+	tw         *tar.Writer
+	dir        string // empty dir means the default directory for temporary files
+
+	name  string
+	spool *os.File
+}
+
+func newTarGzWriter(w io.Writer, dir string) *tarWriter {
+	compressor := gzip.NewWriter(w)
+	return &tarWriter{compressor: compressor, tw: tar.NewWriter(compressor), dir: dir}
+}
+
+func newTarZstWriter(w io.Writer, dir string) (*tarWriter, error) {
+	compressor, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("could not create zstd writer: %s", err)
+	}
+	return &tarWriter{compressor: compressor, tw: tar.NewWriter(compressor), dir: dir}, nil
+}
+
+func (t *tarWriter) Create(name string) (io.Writer, error) {
+	if err := t.flush(); err != nil {
+		return nil, err
+	}
+	spool, err := ioutil.TempFile(t.dir, "archive-entry")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file for %s: %s", name, err)
+	}
+	t.name = name
+	t.spool = spool
+	return spool, nil
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.flush(); err != nil {
+		t.compressor.Close()
+		return err
+	}
+	if err := t.tw.Close(); err != nil {
+		t.compressor.Close()
+		return fmt.Errorf("could not close tar: %s", err)
+	}
+	return t.compressor.Close()
+}
+
+func (t *tarWriter) flush() error {
+	if t.spool == nil {
+		return nil
+	}
+	spool := t.spool
+	t.spool = nil
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("could not read temporary file for %s: %s", t.name, err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not read temporary file for %s: %s", t.name, err)
+	}
+
+	header := &tar.Header{
+		Name:    t.name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+		Format:  tar.FormatPAX,
+	}
+	if err := t.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("could not write header for %s: %s", t.name, err)
+	}
+	if _, err := io.CopyN(t.tw, spool, size); err != nil {
+		return fmt.Errorf("could not write %s: %s", t.name, err)
+	}
+	return nil
+}
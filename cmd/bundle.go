@@ -0,0 +1,394 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/encryption"
+	"github.com/dcos/dcos-diagnostics/integrity"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifySignaturePath  string
+	verifyPublicKeyPath  string
+	bundlePrivateKeyPath string
+	decryptOutputPath    string
+
+	createOptions       rest.ClusterOptions
+	createEncryptionKey string
+	createWait          bool
+	waitInterval        time.Duration
+	downloadPath        string
+)
+
+// bundleCmd groups commands that work with downloaded diagnostics bundles
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Work with diagnostics bundles",
+	Long: `Bundle commands create, list, download and delete cluster bundles through the API of a master,
+and verify or decrypt downloaded bundles.`,
+}
+
+// bundleVerifyCmd checks bundle checksums and optionally its signature
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify <bundle>",
+	Short: "Verify bundle checksums manifest and detached signature",
+	Long: `Verify checks every file in the bundle against checksums manifest stored in the bundle.
+Bundles in zip, tar.gz and tar.zst formats are supported. For cluster bundles manifests of each node are checked too.
+When a public key is given the detached signature of the bundle is verified as well.
+Encrypted bundles are decrypted to a temporary file when a private key is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return verifyBundle(args[0], verifySignaturePath, verifyPublicKeyPath, bundlePrivateKeyPath, os.Stdout)
+	},
+}
+
+// bundleDecryptCmd decrypts bundle encrypted with the cluster or request encryption key
+var bundleDecryptCmd = &cobra.Command{
+	Use:   "decrypt <bundle.zip.enc>",
+	Short: "Decrypt encrypted bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := decryptOutputPath
+		if output == "" {
+			output = strings.TrimSuffix(args[0], ".enc")
+			if output == args[0] {
+				output += ".zip"
+			}
+		}
+		if err := decryptBundle(args[0], output, bundlePrivateKeyPath); err != nil {
+			return err
+		}
+		fmt.Printf("Decrypted bundle saved to %s\n", output)
+		return nil
+	},
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create cluster bundle",
+	Long: `Create starts cluster bundle creation on the master and prints the created bundle.
+With --wait it polls the bundle status until the bundle is finished.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		options := createOptions
+		if createEncryptionKey != "" {
+			key, err := ioutil.ReadFile(createEncryptionKey)
+			if err != nil {
+				return fmt.Errorf("could not read encryption key: %s", err)
+			}
+			options.EncryptionKey = string(key)
+		}
+		bundle, err := createBundle(cmd.Context(), client, baseURL(), options)
+		if err != nil {
+			return err
+		}
+		if createWait {
+			bundle, err = waitForBundle(cmd.Context(), client, baseURL(), bundle.ID, waitInterval, os.Stderr)
+			if err != nil {
+				return err
+			}
+		}
+		return printBundle(os.Stdout, clientOutput, bundle)
+	},
+}
+
+var bundleStatusCmd = &cobra.Command{
+	Use:   "status <id>",
+	Short: "Show status of cluster bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		bundle, err := client.Status(cmd.Context(), baseURL(), args[0])
+		if err != nil {
+			return err
+		}
+		return printBundle(os.Stdout, clientOutput, bundle)
+	},
+}
+
+var bundleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cluster bundles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		bundles, err := client.List(cmd.Context(), baseURL())
+		if err != nil {
+			return err
+		}
+		return printBundles(os.Stdout, clientOutput, bundles)
+	},
+}
+
+var bundleDownloadCmd = &cobra.Command{
+	Use:   "download <id>",
+	Short: "Download cluster bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		bundle, err := client.Status(cmd.Context(), baseURL(), args[0])
+		if err != nil {
+			return err
+		}
+		// downloads of large bundles take longer than a single API request
+		client, err = newBundleClient(0)
+		if err != nil {
+			return err
+		}
+		path, err := downloadBundle(cmd.Context(), client, baseURL(), bundle, downloadPath, os.Stderr)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Bundle saved to %s\n", path)
+		return nil
+	},
+}
+
+var bundleDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete cluster bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		if err := client.Delete(cmd.Context(), baseURL(), args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Bundle %s deleted\n", args[0])
+		return nil
+	},
+}
+
+var bundleWaitCmd = &cobra.Command{
+	Use:   "wait <id>",
+	Short: "Wait until cluster bundle is finished",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newBundleClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		bundle, err := waitForBundle(cmd.Context(), client, baseURL(), args[0], waitInterval, os.Stderr)
+		if err != nil {
+			return err
+		}
+		return printBundle(os.Stdout, clientOutput, bundle)
+	},
+}
+
+func newBundleClient(timeout time.Duration) (rest.DiagnosticsClient, error) {
+	client, err := newAPIClient(timeout)
+	if err != nil {
+		return rest.DiagnosticsClient{}, err
+	}
+	return rest.NewClusterDiagnosticsClient(client), nil
+}
+
+func createBundle(ctx context.Context, client rest.DiagnosticsClient, base string, options rest.ClusterOptions) (*rest.Bundle, error) {
+	if _, err := archive.ParseFormat(options.Format); err != nil {
+		return nil, err
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate bundle id: %s", err)
+	}
+	return client.CreateClusterBundle(ctx, base, id.String(), options)
+}
+
+// waitForBundle polls the bundle status until it's finished and reports the status to progress.
+// It returns an error when the bundle is finished but not Done.
+func waitForBundle(ctx context.Context, client rest.DiagnosticsClient, base, id string, interval time.Duration, progress io.Writer) (*rest.Bundle, error) {
+	started := time.Now()
+	for {
+		bundle, err := client.Status(ctx, base, id)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(progress, "\rWaiting for bundle %s: %-10s %s", id, bundle.Status, time.Since(started).Round(time.Second))
+		if bundle.IsFinished() {
+			fmt.Fprintln(progress)
+			if bundle.Status != rest.Done {
+				return bundle, fmt.Errorf("bundle %s is %s: %s", id, bundle.Status, strings.Join(bundle.Errors, "; "))
+			}
+			return bundle, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(progress)
+			return bundle, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// downloadBundle saves the bundle file to the given path, by default the file is named like the bundle
+func downloadBundle(ctx context.Context, client rest.DiagnosticsClient, base string, bundle *rest.Bundle, path string, progress io.Writer) (string, error) {
+	if bundle.Status != rest.Done {
+		return "", fmt.Errorf("bundle %s is %s, only Done bundles could be downloaded", bundle.ID, bundle.Status)
+	}
+	if path == "" {
+		path = bundle.ID + bundle.Format.Extension()
+		if bundle.Encrypted {
+			path += ".enc"
+		}
+	}
+	bar := progressBar{out: progress, width: 40}
+	err := client.WithProgress(bar.update).GetFile(ctx, base, bundle.ID, path)
+	bar.finish()
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+var bundleHeader = []string{"ID", "STATUS", "STARTED", "STOPPED", "SIZE", "FORMAT", "ENCRYPTED"}
+
+// printBundle prints the bundle, as an object in JSON
+func printBundle(out io.Writer, format string, bundle *rest.Bundle) error {
+	return printOutput(out, format, bundle, bundleHeader, bundleRows(bundle))
+}
+
+// printBundles prints bundles, as an array in JSON even when there is only one or none
+func printBundles(out io.Writer, format string, bundles []*rest.Bundle) error {
+	if bundles == nil {
+		bundles = []*rest.Bundle{}
+	}
+	return printOutput(out, format, bundles, bundleHeader, bundleRows(bundles...))
+}
+
+func bundleRows(bundles ...*rest.Bundle) [][]string {
+	var rows [][]string
+	for _, b := range bundles {
+		rows = append(rows, []string{b.ID, b.Status.String(), formatTime(b.Started), formatTime(b.Stopped),
+			formatBytes(b.Size), string(b.Format), strconv.FormatBool(b.Encrypted)})
+	}
+	return rows
+}
+
+func decryptBundle(bundlePath, outputPath, privateKeyPath string) error {
+	if privateKeyPath == "" {
+		return fmt.Errorf("private key is required to decrypt the bundle")
+	}
+	key, err := encryption.LoadPrivateKey(privateKeyPath)
+	if err != nil {
+		return err
+	}
+	return encryption.DecryptFile(bundlePath, outputPath, key)
+}
+
+func verifyBundle(bundlePath, signaturePath, publicKeyPath, privateKeyPath string, out io.Writer) error {
+	if publicKeyPath != "" {
+		if signaturePath == "" {
+			signaturePath = bundlePath + ".sig"
+		}
+		key, err := integrity.LoadPublicKey(publicKeyPath)
+		if err != nil {
+			return err
+		}
+		if err := integrity.VerifyFile(key, bundlePath, signaturePath); err != nil {
+			return fmt.Errorf("signature verification failed: %s", err)
+		}
+		fmt.Fprintf(out, "Signature OK\n")
+	}
+
+	encrypted, err := encryption.IsEncrypted(bundlePath)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		if privateKeyPath == "" {
+			return fmt.Errorf("bundle %s is encrypted, private key is required to verify checksums", bundlePath)
+		}
+		tmpDir, err := ioutil.TempDir("", "bundle-verify")
+		if err != nil {
+			return fmt.Errorf("could not create temporary directory: %s", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		decryptedPath := filepath.Join(tmpDir, "bundle")
+		if err := decryptBundle(bundlePath, decryptedPath, privateKeyPath); err != nil {
+			return err
+		}
+		bundlePath = decryptedPath
+	}
+
+	problems, err := integrity.VerifyArchive(bundlePath)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(out, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("bundle %s failed integrity check: %d problem(s) found", bundlePath, len(problems))
+	}
+	fmt.Fprintf(out, "Checksums OK\n")
+
+	return nil
+}
+
+func init() {
+	bundleVerifyCmd.Flags().StringVar(&verifySignaturePath, "signature", "",
+		"A path to detached bundle signature (default is <bundle>.sig)")
+	bundleVerifyCmd.Flags().StringVar(&verifyPublicKeyPath, "public-key", "",
+		"A path to PEM encoded Ed25519 public key. Signature is not checked if empty")
+	bundleVerifyCmd.Flags().StringVar(&bundlePrivateKeyPath, "private-key", "",
+		"A path to PEM encoded RSA private key used to decrypt encrypted bundle")
+	bundleCmd.AddCommand(bundleVerifyCmd)
+
+	bundleDecryptCmd.Flags().StringVar(&bundlePrivateKeyPath, "private-key", "",
+		"A path to PEM encoded RSA private key")
+	bundleDecryptCmd.Flags().StringVarP(&decryptOutputPath, "output", "o", "",
+		"A path to save decrypted bundle (default is input path without .enc suffix)")
+	bundleCmd.AddCommand(bundleDecryptCmd)
+
+	bundleCreateCmd.Flags().BoolVar(&createOptions.Masters, "masters", true, "Collect data from masters")
+	bundleCreateCmd.Flags().BoolVar(&createOptions.Agents, "agents", true, "Collect data from agents")
+	bundleCreateCmd.Flags().StringVar(&createOptions.Format, "format", "",
+		"Archive format of the bundle: zip, tar.gz or tar.zst (default is zip)")
+	bundleCreateCmd.Flags().BoolVar(&createOptions.NetworkMatrix, "network-matrix", false,
+		"Add reachability matrix of nodes to the bundle")
+	bundleCreateCmd.Flags().StringVar(&createEncryptionKey, "encryption-key", "",
+		"A path to PEM encoded RSA public key that overrides the configured encryption key")
+	bundleCreateCmd.Flags().BoolVar(&createWait, "wait", false, "Wait until the bundle is finished")
+	for _, c := range []*cobra.Command{bundleCreateCmd, bundleWaitCmd} {
+		c.Flags().DurationVar(&waitInterval, "interval", 5*time.Second, "How often the bundle status is checked")
+	}
+	bundleDownloadCmd.Flags().StringVarP(&downloadPath, "destination", "d", "",
+		"A path to save the bundle (default is <id> with the bundle format extension)")
+
+	for _, c := range []*cobra.Command{bundleCreateCmd, bundleStatusCmd, bundleListCmd, bundleDownloadCmd, bundleDeleteCmd, bundleWaitCmd} {
+		addClientFlags(c)
+		bundleCmd.AddCommand(c)
+	}
+	RootCmd.AddCommand(bundleCmd)
+}
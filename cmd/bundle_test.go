@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/encryption"
+	"github.com/dcos/dcos-diagnostics/integrity"
+)
+
+func writeTestBundle(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	manifest := integrity.NewManifest()
+	entry, err := w.Create("test.txt")
+	require.NoError(t, err)
+	_, err = manifest.Copy("test.txt", entry, strings.NewReader(content))
+	require.NoError(t, err)
+	entry, err = w.Create(integrity.ManifestFileName)
+	require.NoError(t, err)
+	_, err = manifest.WriteTo(entry)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func TestVerifyBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle-verify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	publicKeyPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, ioutil.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+
+	bundlePath := filepath.Join(dir, "bundle.zip")
+	writeTestBundle(t, bundlePath, "OK")
+	require.NoError(t, integrity.SignFile(private, bundlePath, bundlePath+".sig"))
+
+	t.Run("checksums only", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		assert.NoError(t, verifyBundle(bundlePath, "", "", "", out))
+		assert.Equal(t, "Checksums OK\n", out.String())
+	})
+
+	t.Run("checksums and signature", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		assert.NoError(t, verifyBundle(bundlePath, "", publicKeyPath, "", out))
+		assert.Equal(t, "Signature OK\nChecksums OK\n", out.String())
+	})
+
+	t.Run("replaced bundle", func(t *testing.T) {
+		otherPath := filepath.Join(dir, "other.zip")
+		writeTestBundle(t, otherPath, "NOT OK")
+
+		out := bytes.NewBuffer(nil)
+		err := verifyBundle(otherPath, bundlePath+".sig", publicKeyPath, "", out)
+		assert.EqualError(t, err, "signature verification failed: signature does not match bundle")
+		assert.Empty(t, out.String())
+	})
+}
+
+func TestDecryptAndVerifyEncryptedBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle-decrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKeyPath := filepath.Join(dir, "private.pem")
+	require.NoError(t, ioutil.WriteFile(privateKeyPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0600))
+
+	bundlePath := filepath.Join(dir, "bundle.zip.enc")
+	writeTestBundle(t, bundlePath, "OK")
+	require.NoError(t, encryption.EncryptFile(bundlePath, &key.PublicKey))
+
+	out := bytes.NewBuffer(nil)
+	err = verifyBundle(bundlePath, "", "", "", out)
+	assert.EqualError(t, err, "bundle "+bundlePath+" is encrypted, private key is required to verify checksums")
+
+	require.NoError(t, verifyBundle(bundlePath, "", "", privateKeyPath, out))
+	assert.Equal(t, "Checksums OK\n", out.String())
+
+	decryptedPath := filepath.Join(dir, "bundle.zip")
+	assert.EqualError(t, decryptBundle(bundlePath, decryptedPath, ""), "private key is required to decrypt the bundle")
+	require.NoError(t, decryptBundle(bundlePath, decryptedPath, privateKeyPath))
+
+	problems, err := integrity.VerifyZipFile(decryptedPath)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestCreateWaitAndDownloadBundle(t *testing.T) {
+	var id string
+	polls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/system/health/v1/diagnostics/"):
+			var o rest.ClusterOptions
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+			assert.Equal(t, rest.ClusterOptions{Masters: true, Format: "tar.gz"}, o)
+			id = strings.TrimPrefix(r.URL.Path, "/system/health/v1/diagnostics/")
+			json.NewEncoder(w).Encode(rest.Bundle{ID: id, Status: rest.Started})
+		case r.URL.Path == "/system/health/v1/diagnostics/"+id:
+			polls++
+			status := rest.InProgress
+			if polls > 1 {
+				status = rest.Done
+			}
+			json.NewEncoder(w).Encode(rest.Bundle{ID: id, Status: status, Format: archive.TarGz, Size: 7})
+		case r.URL.Path == "/system/health/v1/diagnostics/"+id+"/file":
+			w.Write([]byte("content"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer s.Close()
+	client := rest.NewClusterDiagnosticsClient(s.Client())
+
+	_, err := createBundle(context.TODO(), client, s.URL, rest.ClusterOptions{Format: "rar"})
+	assert.EqualError(t, err, `unknown archive format "rar", supported formats are: zip, tar.gz, tar.zst`)
+
+	bundle, err := createBundle(context.TODO(), client, s.URL, rest.ClusterOptions{Masters: true, Format: "tar.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, rest.Started, bundle.Status)
+
+	var progress strings.Builder
+	bundle, err = waitForBundle(context.TODO(), client, s.URL, id, time.Millisecond, &progress)
+	require.NoError(t, err)
+	assert.Equal(t, rest.Done, bundle.Status)
+	assert.Equal(t, 2, polls)
+	assert.Contains(t, progress.String(), "InProgress")
+	assert.Contains(t, progress.String(), "Done")
+
+	var out strings.Builder
+	require.NoError(t, printBundle(&out, outputTable, bundle))
+	assert.Equal(t, "ID                                    STATUS  STARTED  STOPPED  SIZE  FORMAT  ENCRYPTED\n"+
+		id+"  Done    -        -        7 B   tar.gz  false\n", out.String())
+
+	dir, err := ioutil.TempDir("", "bundle-download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	progress.Reset()
+	path, err := downloadBundle(context.TODO(), client, s.URL, bundle, filepath.Join(dir, "bundle.tar.gz"), &progress)
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.Contains(t, progress.String(), "100% 7 B / 7 B")
+}
+
+func TestWaitForFailedBundle(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(rest.Bundle{ID: "bundle-0", Status: rest.Failed, Errors: []string{"no space left"}})
+	}))
+	defer s.Close()
+
+	bundle, err := waitForBundle(context.TODO(), rest.NewClusterDiagnosticsClient(s.Client()), s.URL, "bundle-0", time.Millisecond, ioutil.Discard)
+	assert.EqualError(t, err, "bundle bundle-0 is Failed: no space left")
+	assert.Equal(t, rest.Failed, bundle.Status)
+
+	_, err = downloadBundle(context.TODO(), rest.NewClusterDiagnosticsClient(s.Client()), s.URL, bundle, "", ioutil.Discard)
+	assert.EqualError(t, err, "bundle bundle-0 is Failed, only Done bundles could be downloaded")
+}
+
+func TestPrintBundlesJSON(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, printBundles(&out, outputJSON, nil))
+	assert.Equal(t, "[]\n", out.String())
+
+	out.Reset()
+	require.NoError(t, printBundles(&out, outputJSON, []*rest.Bundle{{ID: "bundle-0", Status: rest.Done}}))
+	var bundles []rest.Bundle
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &bundles))
+	require.Len(t, bundles, 1)
+	assert.Equal(t, "bundle-0", bundles[0].ID)
+
+	out.Reset()
+	require.NoError(t, printBundle(&out, outputJSON, &rest.Bundle{ID: "bundle-0", Status: rest.Done}))
+	var bundle rest.Bundle
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &bundle))
+	assert.Equal(t, "bundle-0", bundle.ID)
+}
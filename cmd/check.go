@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dcos/dcos-diagnostics/api"
+	"github.com/dcos/dcos-diagnostics/dcos"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// exit codes of the check command
+const (
+	checkHealthy   = 0
+	checkUnhealthy = 1
+	checkUnknown   = 2
+	checkError     = 3
+)
+
+const (
+	checkFormatText  = "text"
+	checkFormatJSON  = "json"
+	checkFormatJUnit = "junit"
+)
+
+var (
+	checkFormat  string
+	checkUnits   []string
+	checkCluster bool
+)
+
+// checkCmd reports health of units and custom checks and sets the exit code accordingly
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check health of this node or the whole cluster",
+	Long: `Check reports health of systemd units and custom health checks of this node.
+With --cluster health of every node and of the cluster is read from the health report of a master,
+silenced nodes and units are skipped.
+
+Exit codes:
+  0  all units are healthy (warnings included)
+  1  some units are unhealthy
+  2  no unit is unhealthy but health of some units is unknown
+  3  health could not be checked`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(runCheck(cmd.Context(), os.Stdout))
+	},
+}
+
+// clusterNode is the node of results of units derived from the whole cluster
+const clusterNode = "cluster"
+
+// checkResult is health of a unit on a node
+type checkResult struct {
+	Node    string      `json:"node"`
+	Role    string      `json:"role,omitempty"`
+	Unit    string      `json:"unit"`
+	Name    string      `json:"name,omitempty"`
+	Health  dcos.Health `json:"health"`
+	Level   dcos.Level  `json:"level"`
+	Output  string      `json:"output,omitempty"`
+	Reasons []string    `json:"reasons,omitempty"`
+}
+
+// checkReport is the JSON output of the check command
+type checkReport struct {
+	Level    dcos.Level    `json:"level"`
+	ExitCode int           `json:"exit_code"`
+	Results  []checkResult `json:"results"`
+}
+
+func runCheck(ctx context.Context, out io.Writer) int {
+	if checkFormat != checkFormatText && checkFormat != checkFormatJSON && checkFormat != checkFormatJUnit {
+		logrus.Errorf("Unknown format %q, supported formats are: text, json, junit", checkFormat)
+		return checkError
+	}
+	for _, pattern := range checkUnits {
+		if _, err := path.Match(pattern, ""); err != nil {
+			logrus.Errorf("Invalid unit pattern %q: %s", pattern, err)
+			return checkError
+		}
+	}
+
+	var results []checkResult
+	var err error
+	if checkCluster {
+		var client *http.Client
+		client, err = newTransportClient(clientTimeout)
+		if err == nil {
+			results, err = clusterCheckResults(client, baseURL())
+		}
+	} else {
+		results, err = localCheckResults(ctx)
+	}
+	if err != nil {
+		logrus.Errorf("Could not check health: %s", err)
+		return checkError
+	}
+
+	results = filterCheckResults(results, checkUnits)
+	code := checkExitCode(results)
+	if err := writeCheckResults(out, checkFormat, results, code); err != nil {
+		logrus.Errorf("Could not write results: %s", err)
+		return checkError
+	}
+	return code
+}
+
+// localCheckResults returns health of systemd units and custom checks of this node
+func localCheckResults(ctx context.Context) ([]checkResult, error) {
+	tools, client, err := offlineTools()
+	if err != nil {
+		return nil, err
+	}
+	checks, err := api.LoadHealthChecks(defaultConfig, tools, client)
+	if err != nil {
+		return nil, fmt.Errorf("could not load health checks: %s", err)
+	}
+
+	sdu := &api.SystemdUnits{}
+	units, err := sdu.GetUnits(tools)
+	if err != nil {
+		return nil, fmt.Errorf("could not get units: %s", err)
+	}
+	units = append(units, api.NewHealthChecks(checks).Run(ctx)...)
+
+	node, err := tools.DetectIP()
+	if err != nil {
+		logrus.WithError(err).Warn("Could not detect IP")
+		node, _ = tools.GetHostname()
+	}
+
+	var results []checkResult
+	for _, u := range units {
+		results = append(results, checkResult{
+			Node:    node,
+			Role:    defaultConfig.FlagRole,
+			Unit:    u.UnitID,
+			Name:    u.PrettyName,
+			Health:  u.UnitHealth,
+			Level:   checkLevel(u.Level, u.UnitHealth),
+			Output:  u.UnitOutput,
+			Reasons: u.Reasons,
+		})
+	}
+	return results, nil
+}
+
+// clusterCheckResults returns health of units on every node and of cluster units from the health report of the master.
+// Silenced nodes and units are skipped.
+func clusterCheckResults(client *http.Client, base string) ([]checkResult, error) {
+	var report struct {
+		Units map[string]dcos.Unit
+		Nodes map[string]dcos.Node
+	}
+	if err := getJSON(client, base+healthRoute+"/report", &report); err != nil {
+		return nil, err
+	}
+	if len(report.Nodes) == 0 {
+		return nil, fmt.Errorf("master has not reported any node yet, is it started with pull?")
+	}
+
+	// units of nodes keep their raw health, units silenced on a node are marked in nodes of the aggregated unit
+	silenced := map[string]bool{}
+	var clusterUnits []string
+	for name, u := range report.Units {
+		if len(u.Nodes) == 0 {
+			// cluster units are derived from the whole cluster, no node reports them
+			if !u.Silenced {
+				clusterUnits = append(clusterUnits, name)
+			}
+			continue
+		}
+		for _, n := range u.Nodes {
+			if n.Silenced {
+				silenced[n.IP+"/"+name] = true
+			}
+		}
+	}
+	sort.Strings(clusterUnits)
+
+	ips := make([]string, 0, len(report.Nodes))
+	for ip := range report.Nodes {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var results []checkResult
+	for _, ip := range ips {
+		node := report.Nodes[ip]
+		if node.Silenced {
+			continue
+		}
+		if len(node.Units) == 0 {
+			// unreachable nodes report no units, their health tells why
+			results = append(results, checkResult{
+				Node:   ip,
+				Role:   node.Role,
+				Health: node.Health,
+				Level:  checkLevel(node.Level, node.Health),
+				Output: nodeOutput(node.Output),
+			})
+			continue
+		}
+		// the output of units is kept in the node reporting them
+		for _, u := range node.Units {
+			if silenced[ip+"/"+u.UnitName] {
+				continue
+			}
+			results = append(results, checkResult{
+				Node:    ip,
+				Role:    node.Role,
+				Unit:    u.UnitName,
+				Name:    u.PrettyName,
+				Health:  u.Health,
+				Level:   checkLevel(u.Level, u.Health),
+				Output:  node.Output[u.UnitName],
+				Reasons: u.Reasons,
+			})
+		}
+	}
+	for _, name := range clusterUnits {
+		u := report.Units[name]
+		results = append(results, checkResult{
+			Node:    clusterNode,
+			Unit:    u.UnitName,
+			Name:    u.PrettyName,
+			Health:  u.Health,
+			Level:   checkLevel(u.Level, u.Health),
+			Output:  u.Output,
+			Reasons: u.Reasons,
+		})
+	}
+	return results, nil
+}
+
+func nodeOutput(output map[string]string) string {
+	var lines []string
+	for _, v := range output {
+		if v != "" {
+			lines = append(lines, v)
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func checkLevel(level dcos.Level, health dcos.Health) dcos.Level {
+	if level == "" {
+		return dcos.LevelOf(health)
+	}
+	return level
+}
+
+// filterCheckResults keeps units matching any of the patterns, results of nodes without units are always kept
+func filterCheckResults(results []checkResult, patterns []string) []checkResult {
+	if len(patterns) == 0 {
+		return results
+	}
+	var filtered []checkResult
+	for _, r := range results {
+		if r.Unit == "" {
+			filtered = append(filtered, r)
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, r.Unit); ok {
+				filtered = append(filtered, r)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func checkExitCode(results []checkResult) int {
+	code := checkHealthy
+	for _, r := range results {
+		switch r.Level.Health() {
+		case dcos.Unhealthy:
+			return checkUnhealthy
+		case dcos.Unknown:
+			code = checkUnknown
+		}
+	}
+	return code
+}
+
+func writeCheckResults(out io.Writer, format string, results []checkResult, code int) error {
+	switch format {
+	case checkFormatJSON:
+		levels := make([]dcos.Level, 0, len(results))
+		for _, r := range results {
+			levels = append(levels, r.Level)
+		}
+		if results == nil {
+			results = []checkResult{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(checkReport{Level: dcos.WorstLevel(levels...), ExitCode: code, Results: results})
+	case checkFormatJUnit:
+		return writeJUnit(out, results)
+	}
+
+	var rows [][]string
+	counts := map[dcos.Level]int{}
+	for _, r := range results {
+		counts[r.Level]++
+		output := strings.TrimSpace(r.Output)
+		if i := strings.IndexByte(output, '\n'); i >= 0 {
+			output = output[:i]
+		}
+		unit := r.Unit
+		if unit == "" {
+			unit = "-"
+		}
+		rows = append(rows, []string{r.Node, r.Role, unit, healthName(r.Health), levelName(r.Level, r.Health), output})
+	}
+	if err := printOutput(out, outputTable, nil, []string{"NODE", "ROLE", "UNIT", "HEALTH", "LEVEL", "OUTPUT"}, rows); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\n%d checked: %d ok, %d warning, %d critical, %d unknown\n", len(results),
+		counts[dcos.LevelOK], counts[dcos.LevelWarning], counts[dcos.LevelCritical], counts[dcos.LevelUnknown])
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes results as JUnit XML with a test suite per node. Unhealthy units are failures
+// and units of unknown health are errors.
+func writeJUnit(out io.Writer, results []checkResult) error {
+	var suites junitTestSuites
+	index := map[string]int{}
+	for _, r := range results {
+		i, ok := index[r.Node]
+		if !ok {
+			i = len(suites.Suites)
+			index[r.Node] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: r.Node})
+		}
+		suite := &suites.Suites[i]
+
+		name := r.Unit
+		if name == "" {
+			name = "node"
+		}
+		tc := junitTestCase{ClassName: r.Node, Name: name}
+		details := r.Reasons
+		if r.Output != "" {
+			details = append([]string{r.Output}, r.Reasons...)
+		}
+		message := &junitMessage{Message: string(r.Level), Text: strings.Join(details, "\n")}
+		switch r.Level.Health() {
+		case dcos.Unhealthy:
+			tc.Failure = message
+			suite.Failures++
+		case dcos.Unknown:
+			tc.Error = message
+			suite.Errors++
+		default:
+			tc.SystemOut = r.Output
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func init() {
+	checkCmd.Flags().StringVar(&checkFormat, "format", checkFormatText, "Output format: text, json or junit")
+	checkCmd.Flags().StringSliceVar(&checkUnits, "unit", nil,
+		"Check only units matching the glob pattern, e.g., 'dcos-mesos-*'. Could be given multiple times")
+	checkCmd.Flags().BoolVar(&checkCluster, "cluster", false, "Check all nodes using the health report of a master")
+	addConnectionFlags(checkCmd)
+	RootCmd.AddCommand(checkCmd)
+}
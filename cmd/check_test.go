@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+)
+
+const clusterReport = `{
+  "Units": {},
+  "Nodes": {
+    "10.0.0.2": {"Role": "agent", "IP": "10.0.0.2", "Health": 3, "Output": {"dcos-diagnostics": "connection refused"}},
+    "10.0.0.1": {"Role": "master", "IP": "10.0.0.1", "Health": 1, "Level": "critical",
+      "Output": {"dcos-mesos-master.service": "", "dcos-net.service": "exited"}, "Units": [
+      {"UnitName": "dcos-mesos-master.service", "PrettyName": "Mesos Master", "Health": 0, "Level": "warning",
+       "Reasons": ["restarted 3 times in the last hour"]},
+      {"UnitName": "dcos-net.service", "PrettyName": "Networking", "Health": 1}
+    ]}
+  },
+  "UpdatedTime": "2020-03-01T12:00:00Z"
+}`
+
+func TestClusterCheckResults(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/system/health/v1/report", r.URL.Path)
+		w.Write([]byte(clusterReport))
+	}))
+	defer s.Close()
+
+	results, err := clusterCheckResults(s.Client(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []checkResult{
+		{Node: "10.0.0.1", Role: "master", Unit: "dcos-mesos-master.service", Name: "Mesos Master", Health: dcos.Healthy,
+			Level: dcos.LevelWarning, Reasons: []string{"restarted 3 times in the last hour"}},
+		{Node: "10.0.0.1", Role: "master", Unit: "dcos-net.service", Name: "Networking", Health: dcos.Unhealthy,
+			Level: dcos.LevelCritical, Output: "exited"},
+		{Node: "10.0.0.2", Role: "agent", Health: dcos.Unknown, Level: dcos.LevelUnknown, Output: "connection refused"},
+	}, results)
+
+	assert.Equal(t, checkUnhealthy, checkExitCode(results))
+	assert.Equal(t, checkUnknown, checkExitCode(filterCheckResults(results, []string{"dcos-mesos-*"})))
+	assert.Equal(t, checkHealthy, checkExitCode(results[:1]))
+
+	var out strings.Builder
+	require.NoError(t, writeCheckResults(&out, checkFormatText, results, checkUnhealthy))
+	assert.Equal(t, `NODE      ROLE    UNIT                       HEALTH     LEVEL     OUTPUT
+10.0.0.1  master  dcos-mesos-master.service  Healthy    WARNING
+10.0.0.1  master  dcos-net.service           Unhealthy  CRITICAL  exited
+10.0.0.2  agent   -                          Unknown    UNKNOWN   connection refused
+
+3 checked: 0 ok, 1 warning, 1 critical, 1 unknown
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, writeCheckResults(&out, checkFormatJSON, results[:1], checkHealthy))
+	assert.JSONEq(t, `{"level": "warning", "exit_code": 0, "results": [{"node": "10.0.0.1", "role": "master",
+		"unit": "dcos-mesos-master.service", "name": "Mesos Master", "health": 0, "level": "warning",
+		"reasons": ["restarted 3 times in the last hour"]}]}`, out.String())
+
+	out.Reset()
+	require.NoError(t, writeCheckResults(&out, checkFormatJUnit, results, checkUnhealthy))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="10.0.0.1" tests="2" failures="1" errors="0">
+    <testcase classname="10.0.0.1" name="dcos-mesos-master.service"></testcase>
+    <testcase classname="10.0.0.1" name="dcos-net.service">
+      <failure message="critical">exited</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="10.0.0.2" tests="1" failures="0" errors="1">
+    <testcase classname="10.0.0.2" name="node">
+      <error message="unknown">connection refused</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, out.String())
+}
+
+func TestClusterCheckResultsWithoutNodes(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Units": null, "Nodes": null}`))
+	}))
+	defer s.Close()
+
+	_, err := clusterCheckResults(s.Client(), s.URL)
+	assert.EqualError(t, err, "master has not reported any node yet, is it started with pull?")
+}
+
+func TestClusterCheckResultsIncludesClusterUnits(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+  "Units": {
+    "dcos-net.service": {"UnitName": "dcos-net.service", "Health": 0, "Nodes": [{"IP": "10.0.0.1"}]},
+    "cluster-masters-quorum": {"UnitName": "cluster-masters-quorum", "PrettyName": "Masters Quorum",
+      "Health": 1, "Level": "critical", "Output": "1 of 3 masters are healthy"},
+    "cluster-leader-elected": {"UnitName": "cluster-leader-elected", "PrettyName": "Leader Elected",
+      "Health": 0, "Level": "ok"}
+  },
+  "Nodes": {
+    "10.0.0.1": {"Role": "master", "IP": "10.0.0.1", "Units": [{"UnitName": "dcos-net.service", "Health": 0}]}
+  }
+}`))
+	}))
+	defer s.Close()
+
+	results, err := clusterCheckResults(s.Client(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []checkResult{
+		{Node: "10.0.0.1", Role: "master", Unit: "dcos-net.service", Health: dcos.Healthy, Level: dcos.LevelOK},
+		{Node: clusterNode, Unit: "cluster-leader-elected", Name: "Leader Elected", Health: dcos.Healthy,
+			Level: dcos.LevelOK},
+		{Node: clusterNode, Unit: "cluster-masters-quorum", Name: "Masters Quorum", Health: dcos.Unhealthy,
+			Level: dcos.LevelCritical, Output: "1 of 3 masters are healthy"},
+	}, results)
+	assert.Equal(t, checkUnhealthy, checkExitCode(results))
+}
+
+func TestClusterCheckResultsSkipsSilenced(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+  "Units": {
+    "dcos-net.service": {"UnitName": "dcos-net.service", "Health": 0, "Silenced": true,
+      "Nodes": [{"IP": "10.0.0.1", "Health": 0, "Silenced": true}]},
+    "dcos-mesos-master.service": {"UnitName": "dcos-mesos-master.service", "Health": 0,
+      "Nodes": [{"IP": "10.0.0.1", "Health": 0}]},
+    "cluster-agents-registered": {"UnitName": "cluster-agents-registered", "Health": 0, "Level": "ok",
+      "Silenced": true}
+  },
+  "Nodes": {
+    "10.0.0.1": {"Role": "master", "IP": "10.0.0.1", "Health": 1, "Level": "critical", "SuppressedHealth": 0,
+      "SuppressedLevel": "ok", "Units": [
+      {"UnitName": "dcos-mesos-master.service", "Health": 0},
+      {"UnitName": "dcos-net.service", "Health": 1, "Level": "critical", "Output": "exited"}
+    ]},
+    "10.0.0.2": {"Role": "agent", "IP": "10.0.0.2", "Health": 3, "Silenced": true, "SuppressedHealth": 0,
+      "SuppressedLevel": "ok", "Output": {"dcos-diagnostics": "connection refused"}}
+  }
+}`))
+	}))
+	defer s.Close()
+
+	results, err := clusterCheckResults(s.Client(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []checkResult{
+		{Node: "10.0.0.1", Role: "master", Unit: "dcos-mesos-master.service", Health: dcos.Healthy, Level: dcos.LevelOK},
+	}, results)
+	assert.Equal(t, checkHealthy, checkExitCode(results))
+}
+
+func TestRunCheckReturnsErrorCode(t *testing.T) {
+	defer func(format string, units []string) { checkFormat, checkUnits = format, units }(checkFormat, checkUnits)
+
+	checkFormat, checkUnits = "xml", nil
+	assert.Equal(t, checkError, runCheck(context.TODO(), ioutil.Discard))
+
+	checkFormat, checkUnits = checkFormatText, []string{"dcos-["}
+	assert.Equal(t, checkError, runCheck(context.TODO(), ioutil.Discard))
+}
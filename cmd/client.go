@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/util"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	clientURL     string
+	clientOutput  string
+	clientTimeout time.Duration
+)
+
+// addClientFlags adds flags of commands that talk to dcos-diagnostics API of a master
+func addClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clientOutput, "output", outputTable, "Output format: table or json")
+	addConnectionFlags(cmd)
+}
+
+// addConnectionFlags adds flags of the master URL and the transport used to reach it
+func addConnectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clientURL, "url", "http://"+net.JoinHostPort("127.0.0.1", strconv.Itoa(diagnosticsTCPPort)),
+		"dcos-diagnostics base URL of a master")
+	cmd.Flags().DurationVar(&clientTimeout, "timeout", time.Minute, "A timeout of a single API request")
+	cmd.Flags().StringVar(&defaultConfig.FlagCACertFile, "ca-cert", defaultConfig.FlagCACertFile,
+		"Use certificate authority.")
+	cmd.Flags().StringVar(&defaultConfig.FlagIAMConfig, "iam-config", defaultConfig.FlagIAMConfig,
+		"A path to identity and access management config")
+}
+
+// newAPIClient checks the output format and returns HTTP client authenticated with the configured transport options.
+// Requests are not limited in time when the timeout is 0, e.g., for downloads of large bundles.
+func newAPIClient(timeout time.Duration) (*http.Client, error) {
+	if clientOutput != outputTable && clientOutput != outputJSON {
+		return nil, fmt.Errorf("unknown output format %q, supported formats are: table, json", clientOutput)
+	}
+	return newTransportClient(timeout)
+}
+
+// newTransportClient returns HTTP client authenticated with the configured transport options
+func newTransportClient(timeout time.Duration) (*http.Client, error) {
+	tr, err := initTransport()
+	if err != nil {
+		return nil, err
+	}
+	return util.NewHTTPClient(timeout, tr), nil
+}
+
+func baseURL() string {
+	return strings.TrimSuffix(clientURL, "/")
+}
+
+// getJSON decodes the response of GET request to the given URL
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code [%d] from %s: %s", resp.StatusCode, url, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("could not decode response from %s: %s", url, err)
+	}
+	return nil
+}
+
+// printOutput writes v as indented JSON or as a table with the given header and rows
+func printOutput(out io.Writer, format string, v interface{}, header []string, rows [][]string) error {
+	if format == outputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	// empty last cells are padded, padding is not needed at the end of lines
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(out, strings.TrimRight(line, " \n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// progressBar draws download progress on a single terminal line
+type progressBar struct {
+	out   io.Writer
+	width int
+}
+
+func (p progressBar) update(done, total int64) {
+	if total <= 0 {
+		fmt.Fprintf(p.out, "\r%s downloaded", formatBytes(done))
+		return
+	}
+	filled := int(int64(p.width) * done / total)
+	if filled > p.width {
+		filled = p.width
+	}
+	fmt.Fprintf(p.out, "\r[%s%s] %3d%% %s / %s", strings.Repeat("#", filled), strings.Repeat(".", p.width-filled),
+		100*done/total, formatBytes(done), formatBytes(total))
+}
+
+func (p progressBar) finish() {
+	fmt.Fprintln(p.out)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
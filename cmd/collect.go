@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/api"
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/collector"
+	diagDcos "github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/util"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	rolesDir = "/etc/mesosphere/roles"
+
+	healthReportFileName = "dcos-diagnostics-health.json"
+	healthTableFileName  = "dcos-diagnostics-health.txt"
+)
+
+var (
+	collectOutput    string
+	collectFormat    string
+	collectLocalOnly bool
+)
+
+// collectCmd creates a bundle of this node without the daemon
+var collectCmd = &cobra.Command{
+	Use:   "collect",
+	Short: "Collect diagnostics bundle of this node without the daemon",
+	Long: `Collect creates a bundle of this node in-process with the same collectors as the daemon uses.
+It's meant for nodes where the daemon, Mesos or Exhibitor are broken. The role is read from
+/etc/mesosphere/roles when it's not configured and requests are not authenticated when
+the transport could not be initialized. Health of units is printed and stored in the bundle.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCollect(cmd.Context(), os.Stdout)
+	},
+}
+
+func runCollect(ctx context.Context, out io.Writer) error {
+	output, format, err := bundleOutput(collectOutput, collectFormat)
+	if err != nil {
+		return err
+	}
+
+	tools, client, err := offlineTools()
+	if err != nil {
+		return err
+	}
+
+	collectors, err := api.LoadCollectors(defaultConfig, tools, client)
+	if err != nil {
+		return fmt.Errorf("could not init collectors: %s", err)
+	}
+	if collectLocalOnly {
+		collectors = localCollectors(collectors)
+	}
+
+	sdu := &api.SystemdUnits{}
+	health, healthErr := sdu.GetUnitsProperties(tools)
+	if healthErr != nil {
+		logrus.WithError(healthErr).Warn("Could not get health of units")
+	}
+	writeHealthTable(out, health.Array)
+	collectors = withHealthReport(collectors, health, healthErr)
+
+	dataFile, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create bundle file: %s", err)
+	}
+
+	timeout := time.Minute * time.Duration(defaultConfig.FlagDiagnosticsJobTimeoutMinutes)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errors := rest.CollectAll(ctx, dataFile, format, collectors, defaultConfig.GetSingleEntryTimeout())
+	for _, e := range errors {
+		logrus.Warn(e)
+	}
+	fmt.Fprintf(out, "\nBundle saved to %s\n", output)
+	if len(errors) > 0 {
+		fmt.Fprintf(out, "%d problem(s) found, see summaryErrorsReport.txt in the bundle\n", len(errors))
+	}
+	return nil
+}
+
+// bundleOutput returns the bundle path and its format. The format is guessed from the file
+// extension when it's not given.
+func bundleOutput(output, format string) (string, archive.Format, error) {
+	if output == "" {
+		output = fmt.Sprintf("bundle-%s%s", time.Now().Format("2006-01-02T15-04-05"), archive.Format(format).Extension())
+	}
+	if format == "" {
+		for _, f := range archive.Formats {
+			if strings.HasSuffix(output, f.Extension()) {
+				return output, f, nil
+			}
+		}
+	}
+	f, err := archive.ParseFormat(format)
+	return output, f, err
+}
+
+// offlineTools returns tools and HTTP client that work without the daemon
+func offlineTools() (*diagDcos.Tools, *http.Client, error) {
+	tr, err := initTransport()
+	if err != nil {
+		logrus.WithError(err).Warn("Requests are not authenticated")
+		tr = nil
+	}
+
+	if defaultConfig.FlagRole == "" {
+		defaultConfig.FlagRole = detectRole(rolesDir)
+	}
+	if defaultConfig.FlagRole == "" {
+		logrus.Warnf("Could not detect node role, collecting data of %s", diagDcos.AgentRole)
+		defaultConfig.FlagRole = diagDcos.AgentRole
+	}
+
+	nodeInfo, err := getNodeInfo(tr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not initialize nodeInfo: %s", err)
+	}
+
+	tools := &diagDcos.Tools{
+		ExhibitorURL: defaultConfig.FlagExhibitorClusterStatusURL,
+		ForceTLS:     defaultConfig.FlagForceTLS,
+		Role:         defaultConfig.FlagRole,
+		NodeInfo:     nodeInfo,
+		Transport:    tr,
+	}
+	return tools, util.NewHTTPClient(defaultConfig.GetSingleEntryTimeout(), tr), nil
+}
+
+// detectRole returns the role of the node from DC/OS roles files, empty if none is found
+func detectRole(dir string) string {
+	roles := []struct{ file, role string }{
+		{"master", diagDcos.MasterRole},
+		{"slave", diagDcos.AgentRole},
+		{"slave_public", diagDcos.AgentPublicRole},
+	}
+	for _, r := range roles {
+		if _, err := os.Stat(filepath.Join(dir, r.file)); err == nil {
+			return r.role
+		}
+	}
+	return ""
+}
+
+// localCollectors drops collectors that need other services, leaving only local files, commands and journal
+func localCollectors(collectors []collector.Collector) []collector.Collector {
+	var local []collector.Collector
+	for _, c := range collectors {
+		inner := c
+		if l, ok := c.(*collector.Limited); ok {
+			inner = l.Collector
+		}
+		switch inner.(type) {
+		case *collector.File, *collector.Glob, *collector.Cmd, *collector.Systemd, *collector.System, *collector.Containers:
+			local = append(local, c)
+		}
+	}
+	return local
+}
+
+// withHealthReport replaces the health report served by the daemon with the report of units checked in-process
+func withHealthReport(collectors []collector.Collector, health api.UnitsHealthResponseJSONStruct, healthErr error) []collector.Collector {
+	var result []collector.Collector
+	for _, c := range collectors {
+		if c.Name() != healthReportFileName {
+			result = append(result, c)
+		}
+	}
+
+	return append(result,
+		collector.NewFunction(healthReportFileName, false, func(context.Context) (io.ReadCloser, error) {
+			if healthErr != nil {
+				return nil, healthErr
+			}
+			data, err := json.MarshalIndent(health, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}),
+		collector.NewFunction(healthTableFileName, true, func(context.Context) (io.ReadCloser, error) {
+			buf := &bytes.Buffer{}
+			writeHealthTable(buf, health.Array)
+			return ioutil.NopCloser(buf), nil
+		}),
+	)
+}
+
+func init() {
+	collectCmd.Flags().StringVar(&collectOutput, "output", "",
+		"A path to save the bundle (default is bundle-<time> in the current directory)")
+	collectCmd.Flags().StringVar(&collectFormat, "format", "",
+		"Archive format of the bundle: zip, tar.gz or tar.zst (default is guessed from the output extension or zip)")
+	collectCmd.Flags().BoolVar(&collectLocalOnly, "local-only", false,
+		"Collect only local files, commands and journal, skip endpoints of other services")
+	RootCmd.AddCommand(collectCmd)
+}
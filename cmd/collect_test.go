@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcos/dcos-diagnostics/api"
+	"github.com/dcos/dcos-diagnostics/api/rest"
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/collector"
+	"github.com/dcos/dcos-diagnostics/dcos"
+)
+
+func TestBundleOutput(t *testing.T) {
+	output, format, err := bundleOutput("/tmp/bundle.tar.zst", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/bundle.tar.zst", output)
+	assert.Equal(t, archive.TarZst, format)
+
+	output, format, err = bundleOutput("/tmp/bundle", "tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/bundle", output)
+	assert.Equal(t, archive.TarGz, format)
+
+	output, format, err = bundleOutput("", "")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(output, "bundle-"))
+	assert.True(t, strings.HasSuffix(output, ".zip"))
+	assert.Equal(t, archive.Zip, format)
+
+	_, _, err = bundleOutput("/tmp/bundle.rar", "rar")
+	assert.Error(t, err)
+}
+
+func TestDetectRole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roles")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, "", detectRole(dir))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "slave_public"), nil, 0644))
+	assert.Equal(t, dcos.AgentPublicRole, detectRole(dir))
+}
+
+func TestLocalCollectors(t *testing.T) {
+	file := collector.NewFile("etc/hosts", false, "/etc/hosts")
+	limitedCmd := collector.WithOptions(collector.NewCmd("uptime.output", false, []string{"uptime"}), collector.Options{Retries: 1})
+	endpoint := collector.NewEndpoint("5050-master_state.json", false, "http://127.0.0.1:5050/master/state", nil)
+
+	assert.Equal(t, []collector.Collector{file, limitedCmd}, localCollectors([]collector.Collector{file, endpoint, limitedCmd}))
+}
+
+func TestWriteHealthTable(t *testing.T) {
+	var out strings.Builder
+	healthy := writeHealthTable(&out, []api.HealthResponseValues{
+		{UnitID: "dcos-mesos-master.service", UnitHealth: dcos.Healthy, Level: dcos.LevelOK, UnitOutput: "running"},
+		{UnitID: "dcos-net.service", UnitHealth: dcos.Unhealthy, UnitOutput: "exited\nwith status 1"},
+	})
+	assert.False(t, healthy)
+	assert.Equal(t, `UNIT                       HEALTH     LEVEL     OUTPUT
+dcos-mesos-master.service  Healthy    OK        running
+dcos-net.service           Unhealthy  CRITICAL  exited
+`, out.String())
+}
+
+func TestCollectWithHealthReport(t *testing.T) {
+	health := api.UnitsHealthResponseJSONStruct{
+		Hostname: "master-1",
+		Array:    []api.HealthResponseValues{{UnitID: "dcos-net.service", UnitHealth: dcos.Unhealthy}},
+	}
+	daemonHealth := collector.NewEndpoint(healthReportFileName, false, "http://127.0.0.1:1050/system/health/v1", nil)
+	file := collector.NewFile("etc/hosts", false, "/etc/hosts")
+
+	collectors := withHealthReport([]collector.Collector{daemonHealth, file}, health, nil)
+	require.Len(t, collectors, 3)
+
+	dir, err := ioutil.TempDir("", "collect")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bundle.zip")
+
+	dataFile, err := os.Create(path)
+	require.NoError(t, err)
+	assert.Empty(t, rest.CollectAll(context.TODO(), dataFile, archive.Zip, collectors, time.Second))
+
+	z, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer z.Close()
+	files := map[string]string{}
+	for _, f := range z.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	assert.Contains(t, files[healthReportFileName], `"hostname": "master-1"`)
+	assert.Contains(t, files[healthTableFileName], "dcos-net.service  Unhealthy  CRITICAL")
+	assert.Contains(t, files, "etc/hosts")
+
+	failed := withHealthReport(nil, api.UnitsHealthResponseJSONStruct{}, errors.New("no systemd"))
+	_, err = failed[0].Collect(context.TODO())
+	assert.EqualError(t, err, "no systemd")
+}
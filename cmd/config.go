@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dcos/dcos-diagnostics/api"
+
+	"github.com/spf13/cobra"
+)
+
+var lintStrict bool
+
+// configCmd groups commands that work with dcos-diagnostics configs
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with dcos-diagnostics configs",
+}
+
+// configLintCmd validates endpoints configs
+var configLintCmd = &cobra.Command{
+	Use:   "lint [endpoints_config.json...]",
+	Short: "Validate endpoints configs",
+	Long: `Lint validates endpoints configs against their JSON Schema and reports entries that would not be
+collected as expected, e.g., relative file paths or file names used more than once for the same role.
+Commands that could not be found on this host are reported as warnings. When the role is given,
+only commands of the role are checked.
+Configs used by the daemon are checked if no file is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files := args
+		if len(files) == 0 {
+			files = defaultConfig.FlagDiagnosticsBundleEndpointsConfigFiles
+		}
+		return lintEndpointsConfigs(files, defaultConfig.FlagRole, lintStrict, os.Stdout)
+	},
+}
+
+// configSchemaCmd prints the JSON Schema of endpoints configs
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print JSON Schema of endpoints configs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(api.EndpointsConfigSchema)
+	},
+}
+
+func lintEndpointsConfigs(files []string, role string, strict bool, out io.Writer) error {
+	issues := api.LintEndpointsConfigs(files, api.LintOptions{CheckCommands: true, Role: role})
+
+	var errors, warnings int
+	for _, issue := range issues {
+		fmt.Fprintln(out, issue)
+		if issue.Severity == api.SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+
+	if errors > 0 || (strict && warnings > 0) {
+		return fmt.Errorf("%d error(s) and %d warning(s) found", errors, warnings)
+	}
+	fmt.Fprintf(out, "%d config(s) OK, %d warning(s)\n", len(files), warnings)
+	return nil
+}
+
+func init() {
+	configLintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Fail on warnings")
+	configCmd.AddCommand(configLintCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintEndpointsConfigs(t *testing.T) {
+	out := &bytes.Buffer{}
+	config := filepath.Join("..", "api", "testdata", "endpoint-config-2.json")
+
+	err := lintEndpointsConfigs([]string{config}, "master", false, out)
+	assert.NoError(t, err)
+	assert.Equal(t, config+`:17:18: warning: LocalCommands[0].Command: command "does" not found`+"\n"+
+		config+`:21:18: warning: LocalCommands[1].Command: command "does" not found`+"\n"+
+		"1 config(s) OK, 2 warning(s)\n", out.String())
+
+	out.Reset()
+	err = lintEndpointsConfigs([]string{config}, "master", true, out)
+	assert.EqualError(t, err, "0 error(s) and 2 warning(s) found")
+
+	out.Reset()
+	invalid := filepath.Join("..", "api", "testdata", "endpoint-config-invalid.json")
+	err = lintEndpointsConfigs([]string{config, invalid}, "master", false, out)
+	assert.EqualError(t, err, "4 error(s) and 2 warning(s) found")
+	assert.Contains(t, out.String(), invalid+":5:7: error: HTTPEndpoints[0].Url: unknown field")
+}
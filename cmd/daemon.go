@@ -15,16 +15,26 @@
 package cmd
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/dcos/dcos-diagnostics/api"
 	"github.com/dcos/dcos-diagnostics/api/rest"
 	diagDcos "github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/encryption"
+	"github.com/dcos/dcos-diagnostics/flapping"
+	"github.com/dcos/dcos-diagnostics/integrity"
+	"github.com/dcos/dcos-diagnostics/silence"
 	"github.com/dcos/dcos-diagnostics/util"
 
 	"github.com/dcos/dcos-go/dcos"
@@ -41,6 +51,7 @@ const (
 	diagnosticsBundleDir      = "/var/run/dcos/dcos-diagnostics/diagnostic_bundles"
 	diagnosticsEndpointConfig = "/opt/mesosphere/etc/endpoints_config.json"
 	exhibitorURL              = "http://127.0.0.1:8181/exhibitor/v1/cluster/status"
+	silencesFile              = "/var/lib/dcos/dcos-diagnostics/silences.json"
 )
 
 // daemonCmd represents the daemon command
@@ -75,6 +86,8 @@ func startDiagnosticsDaemon() {
 		Transport:    tr,
 	}
 
+	lintEndpointsConfigsOnStart(DCOSTools)
+
 	// Create and init diagnostics job, do not hard fail on error
 	diagnosticsJob := &api.DiagnosticsJob{
 		Transport: tr,
@@ -98,12 +111,30 @@ func startDiagnosticsDaemon() {
 		logrus.Fatalf("Could not init collectors properly: %s", err)
 	}
 
+	var signingKey ed25519.PrivateKey
+	if defaultConfig.FlagDiagnosticsBundleSigningKey != "" {
+		signingKey, err = integrity.LoadPrivateKey(defaultConfig.FlagDiagnosticsBundleSigningKey)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not load bundle signing key")
+		}
+	}
+
+	var encryptionKey *rsa.PublicKey
+	if defaultConfig.FlagDiagnosticsBundleEncryptionKey != "" {
+		encryptionKey, err = encryption.LoadPublicKey(defaultConfig.FlagDiagnosticsBundleEncryptionKey)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not load bundle encryption key")
+		}
+	}
+
 	bundleTimeout := time.Minute * time.Duration(defaultConfig.FlagDiagnosticsJobTimeoutMinutes)
 	bundleHandler, err := rest.NewBundleHandler(
 		defaultConfig.FlagDiagnosticsBundleDir,
 		collectors,
 		bundleTimeout,
 		defaultConfig.GetSingleEntryTimeout(),
+		signingKey,
+		encryptionKey,
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("BundleHandler could not be created")
@@ -112,10 +143,30 @@ func startDiagnosticsDaemon() {
 	coord := rest.NewParallelCoordinator(diagClient, time.Minute, defaultConfig.FlagDiagnosticsBundleDir)
 	urlBuilder := diagDcos.NewURLBuilder(defaultConfig.FlagAgentPort, defaultConfig.FlagMasterPort, defaultConfig.FlagForceTLS)
 	clusterBundleHandler, err := rest.NewClusterBundleHandler(coord, diagClient, DCOSTools, defaultConfig.FlagDiagnosticsBundleDir,
-		bundleTimeout, &urlBuilder)
+		bundleTimeout, &urlBuilder, signingKey, encryptionKey)
 	if err != nil {
 		logrus.WithError(err).Fatal("ClusterBundleHandler could not be created")
 	}
+	clusterBundleHandler.SetNetworkDiagnostics(defaultConfig.FlagDiagnosticsNetwork)
+
+	checks, err := api.LoadHealthChecks(defaultConfig, DCOSTools, client)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not init health checks")
+	}
+	healthChecks := api.NewHealthChecks(checks)
+	if defaultConfig.FlagUpdateHealthReportInterval <= 0 {
+		logrus.Fatalf("health-update-interval must be positive, got %d", defaultConfig.FlagUpdateHealthReportInterval)
+	}
+	// checks could take seconds so they are run in the background and health reports only read their results
+	go healthChecks.Start(context.Background(), time.Duration(defaultConfig.FlagUpdateHealthReportInterval)*time.Second)
+
+	systemdUnits := &api.SystemdUnits{
+		Checks:   healthChecks,
+		Flapping: flapping.NewDetector(flapping.DefaultWindow, defaultConfig.FlagFlappingRestarts),
+	}
+	// restarts are observed at a fixed interval, health reports could be requested at any rate
+	go systemdUnits.StartObservingRestarts(context.Background(), DCOSTools,
+		time.Duration(defaultConfig.FlagUpdateHealthReportInterval)*time.Second)
 
 	// Inject dependencies used for running dcos-diagnostics.
 	dt := &api.Dt{
@@ -126,10 +177,31 @@ func startDiagnosticsDaemon() {
 		ClusterBundleHandler: clusterBundleHandler,
 		RunPullerChan:        make(chan bool),
 		RunPullerDoneChan:    make(chan bool),
-		SystemdUnits:         &api.SystemdUnits{},
+		SystemdUnits:         systemdUnits,
 		MR:                   &api.MonitoringResponse{},
 	}
 
+	// silences are kept by masters aggregating health of the cluster
+	if defaultConfig.FlagPull {
+		dt.Silences, err = silence.NewStore(defaultConfig.FlagSilencesFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not load silences")
+		}
+	}
+
+	reloader, err := api.NewConfigReloader(defaultConfig, loadConfig, DCOSTools, client, diagnosticsJob, bundleHandler,
+		clusterBundleHandler, healthChecks)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not init config reloader")
+	}
+	dt.ConfigReloader = reloader
+	go func() {
+		if err := reloader.Watch(context.Background()); err != nil {
+			logrus.WithError(err).Error("Config files are not watched, send SIGHUP to reload config")
+		}
+	}()
+	go reloadOnSignal(reloader)
+
 	// start diagnostic server and expose endpoints.
 	logrus.Info("Start dcos-diagnostics")
 
@@ -159,6 +231,34 @@ func startDiagnosticsDaemon() {
 	logrus.Fatal(http.Serve(listeners[0], router))
 }
 
+// lintEndpointsConfigsOnStart logs problems found in endpoints configs. Errors prevent the daemon
+// from starting later, when configs are loaded.
+func lintEndpointsConfigsOnStart(tools diagDcos.Tooler) {
+	role, err := tools.GetNodeRole()
+	if err != nil {
+		logrus.WithError(err).Warn("Could not get role, commands of all roles are checked")
+	}
+	issues := api.LintEndpointsConfigs(defaultConfig.FlagDiagnosticsBundleEndpointsConfigFiles,
+		api.LintOptions{CheckCommands: true, Role: role})
+	for _, issue := range issues {
+		if issue.Severity == api.SeverityError {
+			logrus.Error(issue)
+		} else {
+			logrus.Warn(issue)
+		}
+	}
+}
+
+// reloadOnSignal reloads the config whenever the daemon receives SIGHUP.
+func reloadOnSignal(reloader *api.ConfigReloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		logrus.Info("Received SIGHUP, reloading config")
+		reloader.Reload() //nolint:errcheck // error is logged and reported in status
+	}
+}
+
 func getNodeInfo(tr http.RoundTripper) (nodeutil.NodeInfo, error) {
 	var options []nodeutil.Option
 	defaultStateURL := url.URL{
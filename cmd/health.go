@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dcos/dcos-diagnostics/api"
+	"github.com/dcos/dcos-diagnostics/dcos"
+
+	"github.com/spf13/cobra"
+)
+
+const healthRoute = "/system/health/v1"
+
+// healthCmd groups commands that show the cluster health aggregated by a master
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Show health of cluster units and nodes",
+}
+
+var healthUnitsCmd = &cobra.Command{
+	Use:   "units",
+	Short: "List units of all nodes in the cluster",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		return printUnits(client, baseURL(), clientOutput, os.Stdout)
+	},
+}
+
+var healthNodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List nodes of the cluster",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		return printNodes(client, baseURL(), clientOutput, os.Stdout)
+	},
+}
+
+var healthUnitCmd = &cobra.Command{
+	Use:   "unit <id>",
+	Short: "Show the unit and its health on every node",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newAPIClient(clientTimeout)
+		if err != nil {
+			return err
+		}
+		return printUnit(client, baseURL(), args[0], clientOutput, os.Stdout)
+	},
+}
+
+func printUnits(client *http.Client, base, format string, out io.Writer) error {
+	var units api.UnitsResponseJSONStruct
+	if err := getJSON(client, base+healthRoute+"/units", &units); err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, u := range units.Array {
+		rows = append(rows, []string{u.UnitID, healthName(u.UnitHealth), levelName(u.Level, u.UnitHealth), u.PrettyName})
+	}
+	return printOutput(out, format, units, []string{"ID", "HEALTH", "LEVEL", "NAME"}, rows)
+}
+
+func printNodes(client *http.Client, base, format string, out io.Writer) error {
+	var nodes api.NodesResponseJSONStruct
+	if err := getJSON(client, base+healthRoute+"/nodes", &nodes); err != nil {
+		return err
+	}
+	return printOutput(out, format, nodes, nodesHeader, nodesRows(nodes.Array))
+}
+
+func printUnit(client *http.Client, base, id, format string, out io.Writer) error {
+	unitURL := base + healthRoute + "/units/" + url.PathEscape(id)
+
+	var unit api.UnitResponseFieldsStruct
+	if err := getJSON(client, unitURL, &unit); err != nil {
+		return err
+	}
+	var nodes api.NodesResponseJSONStruct
+	if err := getJSON(client, unitURL+"/nodes", &nodes); err != nil {
+		return err
+	}
+
+	if format == outputJSON {
+		return printOutput(out, format, struct {
+			api.UnitResponseFieldsStruct
+			Nodes []*api.NodeResponseFieldsStruct `json:"nodes"`
+		}{unit, nodes.Array}, nil, nil)
+	}
+
+	fmt.Fprintf(out, "%s (%s): %s\n", unit.PrettyName, unit.UnitID, levelName(unit.Level, unit.UnitHealth))
+	if unit.UnitTitle != "" {
+		fmt.Fprintln(out, unit.UnitTitle)
+	}
+	fmt.Fprintln(out)
+	return printOutput(out, format, nil, nodesHeader, nodesRows(nodes.Array))
+}
+
+var nodesHeader = []string{"IP", "ROLE", "HEALTH", "LEVEL", "SILENCED"}
+
+func nodesRows(nodes []*api.NodeResponseFieldsStruct) [][]string {
+	var rows [][]string
+	for _, n := range nodes {
+		rows = append(rows, []string{n.HostIP, n.NodeRole, healthName(n.NodeHealth), levelName(n.Level, n.NodeHealth),
+			strconv.FormatBool(n.Silenced)})
+	}
+	return rows
+}
+
+func healthName(h dcos.Health) string {
+	switch h {
+	case dcos.Healthy:
+		return "Healthy"
+	case dcos.Unhealthy:
+		return "Unhealthy"
+	case dcos.Unknown:
+		return "Unknown"
+	}
+	return strconv.Itoa(int(h))
+}
+
+// levelName returns the level reported by the master or the level derived from health for older masters
+func levelName(level dcos.Level, health dcos.Health) string {
+	if level == "" {
+		level = dcos.LevelOf(health)
+	}
+	return strings.ToUpper(string(level))
+}
+
+func init() {
+	for _, c := range []*cobra.Command{healthUnitsCmd, healthNodesCmd, healthUnitCmd} {
+		addClientFlags(c)
+		healthCmd.AddCommand(c)
+	}
+	RootCmd.AddCommand(healthCmd)
+}
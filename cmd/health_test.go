@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/system/health/v1/units": `{"units":[
+			{"id":"dcos-mesos-master.service","name":"Mesos Master","health":0,"level":"ok"},
+			{"id":"dcos-net.service","name":"Networking","health":1}]}`,
+		"/system/health/v1/nodes": `{"nodes":[{"host_ip":"10.0.0.1","health":0,"role":"master","level":"warning"}]}`,
+		"/system/health/v1/units/dcos-net.service": `{"id":"dcos-net.service","name":"Networking","health":1,
+			"description":"DNS and networking","level":"critical"}`,
+		"/system/health/v1/units/dcos-net.service/nodes": `{"nodes":[{"host_ip":"10.0.0.2","health":1,"role":"agent","silenced":true}]}`,
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPrintUnits(t *testing.T) {
+	s := newHealthServer(t)
+
+	var out strings.Builder
+	require.NoError(t, printUnits(s.Client(), s.URL, outputTable, &out))
+	assert.Equal(t, `ID                         HEALTH     LEVEL     NAME
+dcos-mesos-master.service  Healthy    OK        Mesos Master
+dcos-net.service           Unhealthy  CRITICAL  Networking
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, printUnits(s.Client(), s.URL, outputJSON, &out))
+	assert.Contains(t, out.String(), `"id": "dcos-net.service"`)
+}
+
+func TestPrintNodes(t *testing.T) {
+	s := newHealthServer(t)
+
+	var out strings.Builder
+	require.NoError(t, printNodes(s.Client(), s.URL, outputTable, &out))
+	assert.Equal(t, `IP        ROLE    HEALTH   LEVEL    SILENCED
+10.0.0.1  master  Healthy  WARNING  false
+`, out.String())
+}
+
+func TestPrintUnit(t *testing.T) {
+	s := newHealthServer(t)
+
+	var out strings.Builder
+	require.NoError(t, printUnit(s.Client(), s.URL, "dcos-net.service", outputTable, &out))
+	assert.Equal(t, `Networking (dcos-net.service): CRITICAL
+DNS and networking
+
+IP        ROLE   HEALTH     LEVEL     SILENCED
+10.0.0.2  agent  Unhealthy  CRITICAL  true
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, printUnit(s.Client(), s.URL, "dcos-net.service", outputJSON, &out))
+	assert.Contains(t, out.String(), `"nodes": [`)
+	assert.Contains(t, out.String(), `"host_ip": "10.0.0.2"`)
+
+	err := printUnit(s.Client(), s.URL, "missing.service", outputTable, &out)
+	assert.EqualError(t, err, "unexpected status code [404] from "+s.URL+"/system/health/v1/units/missing.service: not found")
+}
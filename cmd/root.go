@@ -16,11 +16,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/dcos/dcos-diagnostics/api"
 	"github.com/dcos/dcos-diagnostics/config"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/drift"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,6 +34,8 @@ var (
 	diag          bool
 	cfgFile       string
 	defaultConfig = &config.Config{}
+	// flagsConfig is the config set only by flags, before the config file was read
+	flagsConfig config.Config
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -100,7 +105,7 @@ func init() {
 		"Set pull timeout.")
 	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagUpdateHealthReportInterval, "health-update-interval",
 		60,
-		"Set update health interval in seconds.")
+		"Set interval in seconds of running health checks and unit restart observations.")
 	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagExhibitorClusterStatusURL, "exhibitor-url", exhibitorURL,
 		"Use Exhibitor URL to discover master nodes.")
 	daemonCmd.PersistentFlags().BoolVar(&defaultConfig.FlagForceTLS, "force-tls", defaultConfig.FlagForceTLS,
@@ -121,6 +126,10 @@ func init() {
 		"Use endpoints_config.json")
 	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagDiagnosticsBundleUnitsLogsSinceString,
 		"diagnostics-units-since", "24h", "Collect systemd units logs since")
+	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagDiagnosticsBundleUnitsLogsFormat,
+		"diagnostics-units-format", "short", "Collect systemd units logs in format: short, short-iso, json or export")
+	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagDiagnosticsBundleUnitsLogsPriority,
+		"diagnostics-units-priority", "", "Collect only systemd units logs with priority up to (0-7 or emerg..debug). All if empty")
 	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagDiagnosticsJobTimeoutMinutes,
 		"diagnostics-job-timeout", 720,
 		"Set a global diagnostics job timeout")
@@ -130,6 +139,43 @@ func init() {
 	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagDiagnosticsBundleFetchersCount,
 		"fetchers-count", 1,
 		"Set a number of concurrent fetchers gathering nodes logs")
+	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagDiagnosticsBundleSigningKey,
+		"bundle-signing-key", "",
+		"Set a path to PEM encoded Ed25519 private key used to sign diagnostics bundles. Bundles are not signed if empty")
+	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagDiagnosticsBundleEncryptionKey,
+		"bundle-encryption-key", "",
+		"Set a path to PEM encoded RSA public key used to encrypt diagnostics bundles. Bundles are not encrypted if empty")
+	daemonCmd.PersistentFlags().BoolVar(&defaultConfig.FlagDiagnosticsSystemSnapshot,
+		"system-snapshot", true,
+		"Add a snapshot of processes, memory, mounts, network interfaces and sockets read from procfs and sysfs to diagnostics bundles")
+	daemonCmd.PersistentFlags().BoolVar(&defaultConfig.FlagDiagnosticsNetwork,
+		"network-diagnostics", true,
+		"Add a report of DNS resolution, TCP reachability of masters and agents, MTU, routes, iptables and IPVS to diagnostics bundles")
+	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagDiagnosticsNetworkAgentSample,
+		"network-agent-sample", 10,
+		"Set a number of randomly chosen agents probed by network diagnostics, all agents are probed if negative")
+	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagTLSExpiryWarningDays,
+		"tls-expiry-warning-days", 30,
+		"Report TLS endpoints with certificates expiring within the number of days as unhealthy")
+	daemonCmd.PersistentFlags().BoolVar(&defaultConfig.FlagZooKeeperHealth,
+		"zookeeper-health", true,
+		"Check ZooKeeper ensemble quorum on masters and add a report of the ensemble to diagnostics bundles")
+	daemonCmd.PersistentFlags().StringSliceVar(&defaultConfig.FlagDriftConfigFiles,
+		"drift-config-files", drift.DefaultFiles,
+		"Set config files whose hashes are compared across nodes with the same role to detect configuration drift")
+	daemonCmd.PersistentFlags().StringVar(&defaultConfig.FlagSilencesFile,
+		"silences-file", silencesFile,
+		"Set a file where masters keep silences of nodes and units in maintenance")
+	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagFlappingRestarts,
+		"flapping-restarts", 3,
+		"Report units restarted at least the number of times within an hour as flapping, 0 disables the detection")
+	// logs search flags
+	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagLogsSearchConcurrency,
+		"logs-search-concurrency", 10,
+		"Set a number of nodes searched concurrently by cluster logs search")
+	daemonCmd.PersistentFlags().IntVar(&defaultConfig.FlagLogsSearchLimit,
+		"logs-search-limit", 1000,
+		"Set a maximum number of lines returned by logs search")
 	RootCmd.AddCommand(daemonCmd)
 
 	RootCmd.AddCommand(stateCmd)
@@ -154,6 +200,8 @@ func initConfig() {
 		viper.SetConfigFile(cfgFile)
 	}
 
+	flagsConfig = *defaultConfig
+
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		if err := viper.Unmarshal(defaultConfig); err != nil {
@@ -162,6 +210,22 @@ func initConfig() {
 	}
 }
 
+// loadConfig reads the config file again on top of the config set by flags.
+func loadConfig() (*config.Config, string, error) {
+	cfg := flagsConfig
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return &cfg, "", nil
+	}
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, "", fmt.Errorf("could not read config file %s: %s", configFile, err)
+	}
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, "", fmt.Errorf("could not load config file %s: %s", configFile, err)
+	}
+	return &cfg, configFile, nil
+}
+
 func runDiag() int {
 	sdu := &api.SystemdUnits{}
 	units, err := sdu.GetUnits(&dcos.Tools{})
@@ -170,18 +234,31 @@ func runDiag() int {
 		return 1
 	}
 
-	var fail bool
-	for _, unit := range units {
-		if unit.UnitHealth != 0 {
-			fmt.Printf("[%s]: %s %s\n", unit.UnitID, unit.UnitTitle, unit.UnitOutput)
-			fail = true
-		}
-	}
-
-	if fail {
+	if !writeHealthTable(os.Stdout, units) {
 		logrus.Error("Found unhealthy systemd units")
 		return 1
 	}
 
 	return 0
 }
+
+// writeHealthTable prints health of units as a table with the first line of their output.
+// It returns false if any unit is unhealthy.
+func writeHealthTable(out io.Writer, units []api.HealthResponseValues) bool {
+	healthy := true
+	var rows [][]string
+	for _, unit := range units {
+		if unit.UnitHealth != dcos.Healthy {
+			healthy = false
+		}
+		output := strings.TrimSpace(unit.UnitOutput)
+		if i := strings.IndexByte(output, '\n'); i >= 0 {
+			output = output[:i]
+		}
+		rows = append(rows, []string{unit.UnitID, healthName(unit.UnitHealth), levelName(unit.Level, unit.UnitHealth), output})
+	}
+	if err := printOutput(out, outputTable, nil, []string{"UNIT", "HEALTH", "LEVEL", "OUTPUT"}, rows); err != nil {
+		logrus.WithError(err).Error("Could not print health table")
+	}
+	return healthy
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/dcos/dcos-diagnostics/config"
+	"github.com/dcos/dcos-diagnostics/drift"
 )
 
 const hostnameEnvVarName = "HOSTNAME"
@@ -59,10 +60,21 @@ func Test_initConfig(t *testing.T) {
 		FlagDiagnosticsBundleDir:       "diag-bundles",
 		FlagDiagnosticsBundleEndpointsConfigFiles:    []string{"dcos-diagnostics-endpoint-config.json"},
 		FlagDiagnosticsBundleUnitsLogsSinceString:    "24h",
+		FlagDiagnosticsBundleUnitsLogsFormat:         "short",
 		FlagDiagnosticsJobTimeoutMinutes:             720,
 		FlagDiagnosticsJobGetSingleURLTimeoutMinutes: 1,
 		FlagCommandExecTimeoutSec:                    50,
 		FlagDiagnosticsBundleFetchersCount:           1,
+		FlagDiagnosticsSystemSnapshot:                true,
+		FlagDiagnosticsNetwork:                       true,
+		FlagDiagnosticsNetworkAgentSample:            10,
+		FlagTLSExpiryWarningDays:                     30,
+		FlagZooKeeperHealth:                          true,
+		FlagDriftConfigFiles:                         drift.DefaultFiles,
+		FlagSilencesFile:                             silencesFile,
+		FlagFlappingRestarts:                         3,
+		FlagLogsSearchConcurrency:                    10,
+		FlagLogsSearchLimit:                          1000,
 	}
 
 	assert.Equal(t, expected, defaultConfig)
@@ -99,10 +111,21 @@ func Test_initConfig_multiple_endpints_configs(t *testing.T) {
 		FlagDiagnosticsBundleDir:       "diag-bundles",
 		FlagDiagnosticsBundleEndpointsConfigFiles:    []string{"1", "2"},
 		FlagDiagnosticsBundleUnitsLogsSinceString:    "24h",
+		FlagDiagnosticsBundleUnitsLogsFormat:         "short",
 		FlagDiagnosticsJobTimeoutMinutes:             720,
 		FlagDiagnosticsJobGetSingleURLTimeoutMinutes: 1,
 		FlagCommandExecTimeoutSec:                    50,
 		FlagDiagnosticsBundleFetchersCount:           1,
+		FlagDiagnosticsSystemSnapshot:                true,
+		FlagDiagnosticsNetwork:                       true,
+		FlagDiagnosticsNetworkAgentSample:            10,
+		FlagTLSExpiryWarningDays:                     30,
+		FlagZooKeeperHealth:                          true,
+		FlagDriftConfigFiles:                         drift.DefaultFiles,
+		FlagSilencesFile:                             silencesFile,
+		FlagFlappingRestarts:                         3,
+		FlagLogsSearchConcurrency:                    10,
+		FlagLogsSearchLimit:                          1000,
 	}
 
 	assert.Equal(t, expected, defaultConfig)
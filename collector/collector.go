@@ -59,15 +59,22 @@ type Systemd struct {
 	name     string
 	optional bool
 	unitName string
-	duration time.Duration
+	options  units.JournalOptions
 }
 
 func NewSystemd(name string, optional bool, unitName string, duration time.Duration) *Systemd {
+	options := units.DefaultJournalOptions()
+	options.Since = duration
+	return NewSystemdWithOptions(name, optional, unitName, options)
+}
+
+// NewSystemdWithOptions creates Systemd collector that reads journal with given options (format, priority, boot and matches)
+func NewSystemdWithOptions(name string, optional bool, unitName string, options units.JournalOptions) *Systemd {
 	return &Systemd{
 		name:     name,
 		optional: optional,
 		unitName: unitName,
-		duration: duration,
+		options:  options,
 	}
 }
 
@@ -80,7 +87,7 @@ func (c Systemd) Optional() bool {
 }
 
 func (c Systemd) Collect(ctx context.Context) (goio.ReadCloser, error) {
-	rc, err := units.ReadJournalOutputSince(ctx, c.unitName, c.duration)
+	rc, err := units.ReadJournal(ctx, c.unitName, c.options)
 
 	if err != nil {
 		return nil, fmt.Errorf("could not read %s logs from journal: %s", c.unitName, err)
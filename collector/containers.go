@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/dcos/dcos-diagnostics/containers"
+	"github.com/dcos/dcos-diagnostics/io"
+)
+
+// Defaults of the Containers collector
+const (
+	DefaultContainerLogLines    = 1000
+	DefaultContainerLogMaxBytes = 1 << 20
+)
+
+// ContainersOptions locate container runtimes and limit collected logs
+type ContainersOptions struct {
+	containers.Options
+	// LogLines is the number of last log lines of every container, DefaultContainerLogLines if 0
+	LogLines int
+	// LogMaxBytes keeps only the last bytes of every container log, DefaultContainerLogMaxBytes if 0
+	LogMaxBytes int64
+}
+
+// Containers is a struct implementing Expander interface. It collects the list of containers of Docker, containerd
+// and the Mesos containerizer with their cgroup resource usage. It expands to the list and inspect data and
+// recent logs of every container. Values of secret environment variables are redacted.
+type Containers struct {
+	name      string
+	optional  bool
+	inspector *containers.Inspector
+	options   ContainersOptions
+}
+
+// NewContainers creates Containers collector of runtimes located with the options
+func NewContainers(name string, optional bool, options ContainersOptions) *Containers {
+	if options.LogLines <= 0 {
+		options.LogLines = DefaultContainerLogLines
+	}
+	if options.LogMaxBytes <= 0 {
+		options.LogMaxBytes = DefaultContainerLogMaxBytes
+	}
+	return &Containers{
+		name:      name,
+		optional:  optional,
+		inspector: containers.NewInspector(options.Options),
+		options:   options,
+	}
+}
+
+func (c Containers) Name() string {
+	return c.name
+}
+
+func (c Containers) Optional() bool {
+	return c.optional
+}
+
+// Collect returns the list of containers as JSON
+func (c Containers) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	return inventoryJSON(c.inspector.Inventory(ctx))
+}
+
+func inventoryJSON(inventory *containers.Inventory) (goio.ReadCloser, error) {
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode containers: %s", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Expand returns collectors of the list of containers named containers.json, of docker/<name>/inspect.json
+// and docker/<name>/logs.txt of every Docker container, of containerd/<namespace>/<id>/config.json and logs.txt
+// of every containerd task and of mesos/<id>/stdout.txt and stderr.txt of every Mesos container.
+func (c Containers) Expand(ctx context.Context) ([]Collector, error) {
+	inventory := c.inspector.Inventory(ctx)
+	collectors := []Collector{
+		function{name: path.Join(c.name, "containers.json"), optional: c.optional, collect: func(context.Context) (goio.ReadCloser, error) {
+			return inventoryJSON(inventory)
+		}},
+	}
+	for _, container := range inventory.Containers {
+		container := container
+		switch container.Runtime {
+		case containers.RuntimeDocker:
+			name := container.Name
+			if name == "" {
+				name = container.ID
+			}
+			dir := path.Join(c.name, containers.RuntimeDocker, name)
+			collectors = append(collectors,
+				function{name: path.Join(dir, "inspect.json"), optional: true, collect: func(ctx context.Context) (goio.ReadCloser, error) {
+					data, err := c.inspector.Docker.Inspect(ctx, container.ID)
+					if err != nil {
+						return nil, err
+					}
+					return ioutil.NopCloser(bytes.NewReader(data)), nil
+				}},
+				function{name: path.Join(dir, "logs.txt"), optional: true, collect: func(ctx context.Context) (goio.ReadCloser, error) {
+					rc, err := c.inspector.Docker.Logs(ctx, container.ID, c.options.LogLines)
+					if err != nil {
+						return nil, err
+					}
+					return Limit(rc, c.options.LogMaxBytes, true), nil
+				}},
+			)
+		case containers.RuntimeContainerd:
+			name := path.Join(c.name, containers.RuntimeContainerd, container.Namespace, container.ID, "config.json")
+			collectors = append(collectors,
+				function{name: name, optional: true, collect: func(ctx context.Context) (goio.ReadCloser, error) {
+					data, err := c.inspector.Containerd.Inspect(ctx, container.Namespace, container.ID)
+					if err != nil {
+						return nil, err
+					}
+					return ioutil.NopCloser(bytes.NewReader(data)), nil
+				}},
+			)
+			for _, log := range container.Logs {
+				collectors = append(collectors, c.logFile(path.Join(path.Dir(name), "logs.txt"), log))
+			}
+		case containers.RuntimeMesos:
+			dir := path.Join(c.name, containers.RuntimeMesos, container.ID)
+			for _, log := range container.Logs {
+				collectors = append(collectors, c.logFile(path.Join(dir, filepath.Base(log)+".txt"), log))
+			}
+		}
+	}
+	return collectors, nil
+}
+
+// logFile returns a collector of the last LogMaxBytes of the container log file
+func (c Containers) logFile(name, file string) Collector {
+	return function{name: name, optional: true, collect: func(ctx context.Context) (goio.ReadCloser, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %s", file, err)
+		}
+		return Limit(io.ReadCloserWithContext(ctx, f), c.options.LogMaxBytes, true), nil
+	}}
+}
+
+// function is a collector of data returned by the function
+type function struct {
+	name     string
+	optional bool
+	collect  func(ctx context.Context) (goio.ReadCloser, error)
+}
+
+// NewFunction returns a collector of data returned by the function
+func NewFunction(name string, optional bool, collect func(ctx context.Context) (goio.ReadCloser, error)) Collector {
+	return function{name: name, optional: optional, collect: collect}
+}
+
+func (f function) Name() string {
+	return f.name
+}
+
+func (f function) Optional() bool {
+	return f.optional
+}
+
+func (f function) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	return f.collect(ctx)
+}
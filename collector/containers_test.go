@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/containers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainersExpand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("container runtimes are served on Unix sockets")
+	}
+	dir, err := ioutil.TempDir("", "containers")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"Id": "abc123", "Names": ["/web"], "State": "running"}]`)
+	})
+	mux.HandleFunc("/containers/abc123/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "abc123", "Config": {"Env": ["TOKEN=secret"]}}`)
+	})
+	mux.HandleFunc("/containers/abc123/logs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("line\n", 10))
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	defer server.Close()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mesos", "c1"), 0755))
+	sandbox := filepath.Join(dir, "work", "slaves", "S0", "frameworks", "F0", "executors", "E0", "runs", "c1")
+	require.NoError(t, os.MkdirAll(sandbox, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandbox, "stdout"), []byte(strings.Repeat("out\n", 10)), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sandbox, "stderr"), []byte("err\n"), 0644))
+
+	c := NewContainers("containers", true, ContainersOptions{
+		Options: containers.Options{
+			DockerSocket:     filepath.Join(dir, "docker.sock"),
+			ContainerdSocket: filepath.Join(dir, "containerd.sock"),
+			MesosRuntimeDir:  filepath.Join(dir, "mesos"),
+			MesosWorkDir:     filepath.Join(dir, "work"),
+			CgroupRoot:       filepath.Join(dir, "cgroup"),
+		},
+		LogMaxBytes: 10,
+	})
+
+	collectors, err := c.Expand(context.TODO())
+	require.NoError(t, err)
+	var names []string
+	for _, child := range collectors {
+		names = append(names, child.Name())
+		assert.True(t, child.Optional())
+	}
+	assert.Equal(t, []string{"containers/containers.json", "containers/docker/web/inspect.json", "containers/docker/web/logs.txt",
+		"containers/mesos/c1/stdout.txt", "containers/mesos/c1/stderr.txt"}, names)
+
+	rc, err := collectors[0].Collect(context.TODO())
+	require.NoError(t, err)
+	var inventory containers.Inventory
+	require.NoError(t, json.NewDecoder(rc).Decode(&inventory))
+	require.Len(t, inventory.Containers, 2)
+	assert.Equal(t, "abc123", inventory.Containers[0].ID)
+	assert.Equal(t, "c1", inventory.Containers[1].ID)
+	assert.False(t, inventory.Runtimes[containers.RuntimeContainerd].Available)
+
+	rc, err = collectors[1].Collect(context.TODO())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"TOKEN=<redacted>"`)
+
+	rc, err = collectors[2].Collect(context.TODO())
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "[truncated: first 40 bytes dropped]\nline\nline\n", string(data))
+
+	// sandbox logs are files limited like Docker logs
+	rc, err = collectors[3].Collect(context.TODO())
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "[truncated: first 30 bytes dropped]\nt\nout\nout\n", string(data))
+
+	rc, err = collectors[4].Collect(context.TODO())
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "err\n", string(data))
+}
@@ -0,0 +1,342 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Symlink policies of the Glob collector
+const (
+	// SymlinksSkip ignores symbolic links
+	SymlinksSkip = "skip"
+	// SymlinksFollow collects files and walks directories symbolic links point to
+	SymlinksFollow = "follow"
+	// SymlinksWithinRoot follows only symbolic links that point inside the root directory
+	SymlinksWithinRoot = "within-root"
+)
+
+// GlobOptions select files collected by the Glob collector.
+type GlobOptions struct {
+	// Include collects only files with the path relative to the root or the base name matching
+	// one of the patterns. All files are included if empty
+	Include []string
+	// Exclude skips files and directories with the relative path or the base name matching one of the patterns
+	Exclude []string
+	// MaxDepth limits how deep below the root files are collected, 1 means only files in the root. Unlimited if 0
+	MaxDepth int
+	// MaxFiles limits the number of collected files. Unlimited if 0
+	MaxFiles int
+	// MaxTotalBytes limits the total size of collected files, files that do not fit are skipped. Unlimited if 0
+	MaxTotalBytes int64
+	// Newest collects only the given number of most recently modified files. All files if 0
+	Newest int
+	// Symlinks is the symbolic link policy, SymlinksSkip if empty
+	Symlinks string
+}
+
+// GlobFile is a file selected by the Glob collector
+type GlobFile struct {
+	Path    string // path of the file on the disk
+	Rel     string // slash separated path relative to the root
+	Size    int64
+	ModTime time.Time
+}
+
+// Expander is a collector that stands for many collectors e.g., one for every file matching a pattern.
+type Expander interface {
+	Collector
+	// Expand returns collectors that should be run instead of this one
+	Expand(ctx context.Context) ([]Collector, error)
+}
+
+// Glob is a struct implementing Expander interface. It collects files matching a shell pattern, e.g.,
+// /var/log/mesos/mesos-agent.log*, or all files of a directory. Files are named after their path relative
+// to the root directory (the location up to the first pattern) prefixed with the collector name.
+type Glob struct {
+	name     string
+	optional bool
+	location string
+	options  GlobOptions
+}
+
+// NewGlob creates Glob collector of files matching the location
+func NewGlob(name string, optional bool, location string, options GlobOptions) *Glob {
+	return &Glob{
+		name:     name,
+		optional: optional,
+		location: location,
+		options:  options,
+	}
+}
+
+func (c Glob) Name() string {
+	return c.name
+}
+
+func (c Glob) Optional() bool {
+	return c.optional
+}
+
+// Collect returns the list of selected files with their sizes
+func (c Glob) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	files, err := c.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	for _, f := range files {
+		fmt.Fprintf(buf, "%s\t%d\t%s\n", f.Rel, f.Size, f.ModTime.UTC().Format(time.RFC3339))
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// Expand returns File collector for every selected file
+func (c Glob) Expand(ctx context.Context) ([]Collector, error) {
+	files, err := c.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectors := make([]Collector, 0, len(files))
+	for _, f := range files {
+		collectors = append(collectors, NewFile(path.Join(c.name, f.Rel), c.optional, f.Path))
+	}
+	return collectors, nil
+}
+
+// Root returns the directory files are collected from
+func (c Glob) Root() string {
+	root, _ := SplitGlob(c.location)
+	return root
+}
+
+// Select returns files matching the location and options. Files are ordered by their relative path
+// or from the newest one if only the newest files are collected.
+func (c Glob) Select(ctx context.Context) ([]GlobFile, error) {
+	root, pattern := SplitGlob(c.location)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("could not read %s: not a directory", root)
+	}
+
+	w := &globWalker{ctx: ctx, options: c.options, pattern: pattern, visited: map[string]bool{}}
+	if w.root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", root, err)
+	}
+	if err := w.walk(root, w.root, ""); err != nil {
+		return nil, err
+	}
+
+	files := w.files
+	if c.options.Newest > 0 {
+		sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime.After(files[j].ModTime) })
+		if len(files) > c.options.Newest {
+			files = files[:c.options.Newest]
+		}
+	}
+	if c.options.MaxFiles > 0 && len(files) > c.options.MaxFiles {
+		files = files[:c.options.MaxFiles]
+	}
+	if c.options.MaxTotalBytes > 0 {
+		var total int64
+		selected := files[:0]
+		for _, f := range files {
+			if total+f.Size > c.options.MaxTotalBytes {
+				continue
+			}
+			total += f.Size
+			selected = append(selected, f)
+		}
+		files = selected
+	}
+	return files, nil
+}
+
+// SplitGlob splits the location into the root directory and the slash separated pattern of files
+// relative to it. The pattern is empty if the location has no pattern.
+func SplitGlob(location string) (root, pattern string) {
+	location = filepath.Clean(location)
+	if !hasMeta(location) {
+		return location, ""
+	}
+	parts := strings.Split(filepath.ToSlash(location), "/")
+	for i, part := range parts {
+		if hasMeta(part) {
+			root = filepath.FromSlash(strings.Join(parts[:i], "/"))
+			if root == "" {
+				root = string(filepath.Separator)
+			}
+			return root, strings.Join(parts[i:], "/")
+		}
+	}
+	return location, ""
+}
+
+// IsGlob returns true if the location contains a shell pattern
+func IsGlob(location string) bool {
+	return hasMeta(location)
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+type globWalker struct {
+	ctx     context.Context
+	options GlobOptions
+	pattern string
+	root    string // root with symbolic links resolved
+	visited map[string]bool
+	files   []GlobFile
+}
+
+// walk visits the directory found at dir. real is the directory with symbolic links resolved,
+// rel is its slash separated path relative to the root.
+func (w *globWalker) walk(dir, real, rel string) error {
+	if w.visited[real] {
+		return nil
+	}
+	w.visited[real] = true
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %s", dir, err)
+	}
+	for _, entry := range entries {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		entryRel := path.Join(rel, entry.Name())
+		entryPath := filepath.Join(dir, entry.Name())
+		entryReal := filepath.Join(real, entry.Name())
+		if w.excluded(entryRel) {
+			continue
+		}
+
+		info := os.FileInfo(entry)
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if info, entryReal = w.resolve(entryPath); info == nil {
+				continue
+			}
+		}
+
+		depth := strings.Count(entryRel, "/") + 1
+		if info.IsDir() {
+			if w.options.MaxDepth > 0 && depth >= w.options.MaxDepth {
+				continue
+			}
+			if w.pattern != "" && depth >= strings.Count(w.pattern, "/")+1 {
+				continue
+			}
+			if err := w.walk(entryPath, entryReal, entryRel); err != nil {
+				return err
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() || !w.included(entryRel) {
+			continue
+		}
+		w.files = append(w.files, GlobFile{Path: entryPath, Rel: entryRel, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return nil
+}
+
+// resolve returns info and the resolved path of the file the link points to or nil if the link should not be followed.
+func (w *globWalker) resolve(link string) (os.FileInfo, string) {
+	switch w.options.Symlinks {
+	case SymlinksFollow, SymlinksWithinRoot:
+	default:
+		return nil, ""
+	}
+	real, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return nil, ""
+	}
+	if w.options.Symlinks == SymlinksWithinRoot {
+		if rel, err := filepath.Rel(w.root, real); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, ""
+		}
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return nil, ""
+	}
+	return info, real
+}
+
+func (w *globWalker) included(rel string) bool {
+	if w.pattern != "" {
+		if ok, _ := path.Match(w.pattern, rel); !ok {
+			return false
+		}
+	}
+	if len(w.options.Include) == 0 {
+		return true
+	}
+	return matchAny(w.options.Include, rel)
+}
+
+func (w *globWalker) excluded(rel string) bool {
+	return matchAny(w.options.Exclude, rel)
+}
+
+// matchAny returns true if the relative path or its base name matches one of the patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand replaces expanders with collectors they stand for. Collector options apply to every
+// collector of the expander. Expanders that fail are replaced with a collector returning the error,
+// so it is reported when they are collected.
+func Expand(ctx context.Context, collectors []Collector) []Collector {
+	expanded := make([]Collector, 0, len(collectors))
+	for _, c := range collectors {
+		options := OptionsOf(c)
+		e, ok := c.(Expander)
+		if l, limited := c.(*Limited); limited {
+			e, ok = l.Collector.(Expander)
+		}
+		if !ok {
+			expanded = append(expanded, c)
+			continue
+		}
+		children, err := e.Expand(ctx)
+		if err != nil {
+			expanded = append(expanded, failed{Collector: c, err: err})
+			continue
+		}
+		for _, child := range children {
+			expanded = append(expanded, WithOptions(child, options))
+		}
+	}
+	return expanded
+}
+
+// failed is a collector that could not be expanded
+type failed struct {
+	Collector
+	err error
+}
+
+func (f failed) Collect(context.Context) (goio.ReadCloser, error) {
+	return nil, f.err
+}
@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// globFixture creates a directory tree with files of the given content modified in the order of the list
+func globFixture(t *testing.T, files ...string) string {
+	dir, err := ioutil.TempDir("", "glob")
+	require.NoError(t, err)
+
+	modTime := time.Now().Add(-time.Hour)
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(f), 0644))
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+		modTime = modTime.Add(time.Minute)
+	}
+	return dir
+}
+
+func selected(t *testing.T, location string, options GlobOptions) []string {
+	files, err := NewGlob("test", false, location, options).Select(context.TODO())
+	require.NoError(t, err)
+	var rel []string
+	for _, f := range files {
+		rel = append(rel, f.Rel)
+	}
+	return rel
+}
+
+func TestSplitGlob(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("paths are unix specific")
+	}
+	tests := []struct {
+		location string
+		root     string
+		pattern  string
+	}{
+		{"/var/lib/dcos/*.json", "/var/lib/dcos", "*.json"},
+		{"/var/log/mesos/mesos-agent.log*", "/var/log/mesos", "mesos-agent.log*"},
+		{"/var/log/*/stdout", "/var/log", "*/stdout"},
+		{"/opt/mesosphere/etc/", "/opt/mesosphere/etc", ""},
+		{"/*.log", "/", "*.log"},
+	}
+	for _, tt := range tests {
+		root, pattern := SplitGlob(tt.location)
+		assert.Equal(t, tt.root, root, tt.location)
+		assert.Equal(t, tt.pattern, pattern, tt.location)
+	}
+}
+
+func TestGlobSelect(t *testing.T) {
+	dir := globFixture(t,
+		"mesos-agent.log.2",
+		"mesos-agent.log.1",
+		"mesos-agent.log",
+		"conf/zoo.cfg",
+		"conf/deep/er/file.json",
+		"conf/secret.key",
+		"state.json",
+	)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, []string{"mesos-agent.log", "mesos-agent.log.1", "mesos-agent.log.2"},
+		selected(t, filepath.Join(dir, "mesos-agent.log*"), GlobOptions{}))
+	assert.Equal(t, []string{"conf/deep/er/file.json", "conf/secret.key", "conf/zoo.cfg", "mesos-agent.log",
+		"mesos-agent.log.1", "mesos-agent.log.2", "state.json"},
+		selected(t, dir, GlobOptions{}))
+	assert.Equal(t, []string{"conf/zoo.cfg"}, selected(t, filepath.Join(dir, "*", "*.cfg"), GlobOptions{}))
+
+	t.Run("include and exclude", func(t *testing.T) {
+		assert.Equal(t, []string{"conf/deep/er/file.json", "state.json"},
+			selected(t, dir, GlobOptions{Include: []string{"*.json"}}))
+		assert.Equal(t, []string{"zoo.cfg"},
+			selected(t, filepath.Join(dir, "conf"), GlobOptions{Exclude: []string{"*.key", "deep"}}))
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		assert.Equal(t, []string{"mesos-agent.log", "mesos-agent.log.1", "mesos-agent.log.2", "state.json"},
+			selected(t, dir, GlobOptions{MaxDepth: 1}))
+		assert.Equal(t, []string{"conf/secret.key", "conf/zoo.cfg"},
+			selected(t, dir, GlobOptions{MaxDepth: 2, Include: []string{"conf/*"}}))
+	})
+
+	t.Run("newest and max files", func(t *testing.T) {
+		assert.Equal(t, []string{"mesos-agent.log", "mesos-agent.log.1"},
+			selected(t, filepath.Join(dir, "mesos-agent.log*"), GlobOptions{Newest: 2}))
+		assert.Equal(t, []string{"conf/deep/er/file.json", "conf/secret.key"},
+			selected(t, dir, GlobOptions{MaxFiles: 2}))
+	})
+
+	t.Run("max total bytes", func(t *testing.T) {
+		// file content is its name, so mesos-agent.log (15 bytes) fits but longer ones do not
+		assert.Equal(t, []string{"mesos-agent.log", "state.json"},
+			selected(t, dir, GlobOptions{MaxDepth: 1, MaxTotalBytes: 25}))
+	})
+}
+
+func TestGlobSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+	outside := globFixture(t, "outside.log")
+	defer os.RemoveAll(outside)
+	dir := globFixture(t, "logs/inside.log")
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Symlink(filepath.Join(dir, "logs", "inside.log"), filepath.Join(dir, "inside-link.log")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "outside.log"), filepath.Join(dir, "outside-link.log")))
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "logs", "loop")))
+
+	assert.Equal(t, []string{"logs/inside.log"}, selected(t, dir, GlobOptions{}))
+	assert.Equal(t, []string{"inside-link.log", "logs/inside.log"}, selected(t, dir, GlobOptions{Symlinks: SymlinksWithinRoot}))
+	assert.Equal(t, []string{"inside-link.log", "logs/inside.log", "outside-link.log"}, selected(t, dir, GlobOptions{Symlinks: SymlinksFollow}))
+}
+
+func TestGlobSelectErrors(t *testing.T) {
+	_, err := NewGlob("test", false, "/not/existing/*.log", GlobOptions{}).Select(context.TODO())
+	assert.Contains(t, err.Error(), "could not read /not/existing")
+}
+
+func TestExpand(t *testing.T) {
+	dir := globFixture(t, "a.log", "b/c.log")
+	defer os.RemoveAll(dir)
+
+	collectors := Expand(context.TODO(), []Collector{
+		NewCmd("cmd", false, []string{"echo"}),
+		WithOptions(NewGlob("var/log", true, dir, GlobOptions{}), Options{MaxBytes: 1}),
+		NewGlob("missing", true, "/not/existing/*", GlobOptions{}),
+	})
+
+	require.Len(t, collectors, 4)
+	assert.Equal(t, "cmd", collectors[0].Name())
+	assert.Equal(t, "var/log/a.log", collectors[1].Name())
+	assert.Equal(t, "var/log/b/c.log", collectors[2].Name())
+	assert.True(t, collectors[2].Optional())
+	assert.Equal(t, Options{MaxBytes: 1}, OptionsOf(collectors[2]))
+
+	rc, err := collectors[1].Collect(context.TODO())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n[truncated: data exceeded 1 bytes]\n", string(data))
+
+	assert.Equal(t, "missing", collectors[3].Name())
+	_, err = collectors[3].Collect(context.TODO())
+	assert.Contains(t, err.Error(), "could not read /not/existing")
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+
+	"github.com/dcos/dcos-diagnostics/netdiag"
+)
+
+// Network is a struct implementing Collector interface. It collects a JSON report of the node networking:
+// resolution of the DC/OS DNS names, TCP reachability of masters and agents, overlay MTU, routes and
+// iptables and IPVS summaries.
+type Network struct {
+	name      string
+	optional  bool
+	diagnoser *netdiag.Diagnoser
+}
+
+// NewNetwork creates Network collector of the report made by the diagnoser
+func NewNetwork(name string, optional bool, diagnoser *netdiag.Diagnoser) *Network {
+	return &Network{
+		name:      name,
+		optional:  optional,
+		diagnoser: diagnoser,
+	}
+}
+
+func (c Network) Name() string {
+	return c.name
+}
+
+func (c Network) Optional() bool {
+	return c.optional
+}
+
+func (c Network) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	report, err := c.diagnoser.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not diagnose network: %s", err)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode network report: %s", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
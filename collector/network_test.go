@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/netdiag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkCollect(t *testing.T) {
+	d := &netdiag.Diagnoser{
+		Node:     netdiag.Node{IP: "10.0.0.1", Role: "agent"},
+		ProcRoot: "not-existing",
+		SysRoot:  "not-existing",
+		Resolver: net.DefaultResolver,
+		Peers: func() ([]string, []string, error) {
+			return nil, nil, errors.New("mesos is down")
+		},
+	}
+	c := NewNetwork("network.json", true, d)
+	assert.Equal(t, "network.json", c.Name())
+	assert.True(t, c.Optional())
+
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	defer rc.Close()
+
+	var report netdiag.Report
+	require.NoError(t, json.NewDecoder(rc).Decode(&report))
+	assert.Equal(t, netdiag.Node{IP: "10.0.0.1", Role: "agent"}, report.Node)
+	assert.Equal(t, "mesos is down", report.Errors["probes"])
+}
+
+func TestNetworkCollectFailsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := NewNetwork("network.json", true, netdiag.NewDiagnoser(netdiag.Node{}, nil)).Collect(ctx)
+	assert.EqualError(t, err, "could not diagnose network: context canceled")
+}
@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	goio "io"
+	"strings"
+	"time"
+)
+
+// CompressionGzip hints that collected data should be stored gzip compressed
+const CompressionGzip = "gzip"
+
+const (
+	truncatedHeadMarker = "\n[truncated: data exceeded %d bytes]\n"
+	truncatedTailMarker = "[truncated: first %d bytes dropped]\n"
+)
+
+// retryDelay is how long to wait before failed collection is retried
+var retryDelay = time.Second
+
+// Options describe how a single collector is run and how its data is stored.
+type Options struct {
+	// Timeout limits how long the collection could take. The default collector timeout is used if 0
+	Timeout time.Duration
+	// MaxBytes limits the size of collected data. Data is truncated with a marker when the limit
+	// is exceeded. Unlimited if 0
+	MaxBytes int64
+	// Retries is how many times failed collection is retried
+	Retries int
+	// TailOnly keeps the last MaxBytes of data instead of the first ones
+	TailOnly bool
+	// Compression hints how data should be stored, CompressionGzip or uncompressed if empty
+	Compression string
+	// Tags are free form labels of the collector
+	Tags []string
+}
+
+func (o Options) isZero() bool {
+	return o.Timeout == 0 && o.MaxBytes == 0 && o.Retries == 0 && !o.TailOnly && o.Compression == "" && len(o.Tags) == 0
+}
+
+// Limited is a collector that honours its own options
+type Limited struct {
+	Collector
+	options Options
+}
+
+// WithOptions returns the collector limited by the options. The collector is returned as is
+// if no option is set.
+func WithOptions(c Collector, options Options) Collector {
+	if options.isZero() {
+		return c
+	}
+	return &Limited{Collector: c, options: options}
+}
+
+// OptionsOf returns options of the collector, zero options if it has none.
+func OptionsOf(c Collector) Options {
+	if l, ok := c.(*Limited); ok {
+		return l.options
+	}
+	return Options{}
+}
+
+// Options returns options of the collector
+func (c *Limited) Options() Options {
+	return c.options
+}
+
+// Collect collects data with the collector timeout, retries failures and limits the size of collected data.
+// The timeout applies until returned reader is closed.
+func (c *Limited) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.options.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+	}
+
+	rc, err := Retry(ctx, c.options.Retries, func() (goio.ReadCloser, error) {
+		return c.Collector.Collect(ctx)
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnClose{ReadCloser: Limit(rc, c.options.MaxBytes, c.options.TailOnly), cancel: cancel}, nil
+}
+
+// Retry calls collect until it succeeds, at most retries times more after the first failure.
+func Retry(ctx context.Context, retries int, collect func() (goio.ReadCloser, error)) (goio.ReadCloser, error) {
+	rc, err := collect()
+	for i := 0; err != nil && i < retries; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s (retried %d times)", err, i)
+		case <-time.After(retryDelay):
+		}
+		rc, err = collect()
+	}
+	if err != nil && retries > 0 {
+		return nil, fmt.Errorf("%s (retried %d times)", err, retries)
+	}
+	return rc, err
+}
+
+// Limit returns a reader of at most maxBytes of data followed by a marker if data was truncated.
+// With tailOnly the last maxBytes are returned preceded by the marker, so the whole data is read
+// before the first byte is returned. Data is not limited if maxBytes is 0.
+func Limit(rc goio.ReadCloser, maxBytes int64, tailOnly bool) goio.ReadCloser {
+	if maxBytes <= 0 {
+		return rc
+	}
+	if tailOnly {
+		return &tailReader{ReadCloser: rc, max: maxBytes}
+	}
+	return &headReader{ReadCloser: rc, max: maxBytes, remaining: maxBytes}
+}
+
+type headReader struct {
+	goio.ReadCloser
+	max       int64
+	remaining int64
+	marker    goio.Reader
+}
+
+func (r *headReader) Read(p []byte) (int, error) {
+	if r.marker != nil {
+		return r.marker.Read(p)
+	}
+	if r.remaining > 0 {
+		if int64(len(p)) > r.remaining {
+			p = p[:r.remaining]
+		}
+		n, err := r.ReadCloser.Read(p)
+		r.remaining -= int64(n)
+		return n, err
+	}
+
+	// the limit is reached, check if there is more data to drop
+	n, err := goio.ReadFull(r.ReadCloser, make([]byte, 1))
+	if n == 0 {
+		if err == goio.EOF || err == goio.ErrUnexpectedEOF {
+			return 0, goio.EOF
+		}
+		return 0, err
+	}
+	r.marker = bytes.NewReader([]byte(fmt.Sprintf(truncatedHeadMarker, r.max)))
+	return r.marker.Read(p)
+}
+
+type tailReader struct {
+	goio.ReadCloser
+	max  int64
+	tail goio.Reader
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	if r.tail == nil {
+		if err := r.readTail(); err != nil {
+			return 0, err
+		}
+	}
+	return r.tail.Read(p)
+}
+
+// readTail reads the whole data keeping only its last bytes. They are kept in a ring buffer
+// that grows with data up to max bytes, so short data does not allocate the whole limit.
+func (r *tailReader) readTail() error {
+	var ring []byte
+	start := 0 // index of the oldest byte once the ring is full
+	chunk := make([]byte, 32*1024)
+	var total int64
+	for {
+		n, err := r.ReadCloser.Read(chunk)
+		total += int64(n)
+		data := chunk[:n]
+		if room := r.max - int64(len(ring)); room > 0 {
+			if room > int64(len(data)) {
+				room = int64(len(data))
+			}
+			ring = append(ring, data[:room]...)
+			data = data[room:]
+		}
+		if len(data) > len(ring) {
+			data = data[len(data)-len(ring):]
+		}
+		for len(data) > 0 {
+			copied := copy(ring[start:], data)
+			data = data[copied:]
+			start = (start + copied) % len(ring)
+		}
+		if err == goio.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tail := goio.MultiReader(bytes.NewReader(ring[start:]), bytes.NewReader(ring[:start]))
+	dropped := total - int64(len(ring))
+	if dropped == 0 {
+		r.tail = tail
+		return nil
+	}
+	r.tail = goio.MultiReader(strings.NewReader(fmt.Sprintf(truncatedTailMarker, dropped)), tail)
+	return nil
+}
+
+type cancelOnClose struct {
+	goio.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCollector struct {
+	name     string
+	optional bool
+	collect  func(ctx context.Context) (goio.ReadCloser, error)
+}
+
+func (f fakeCollector) Name() string {
+	return f.name
+}
+
+func (f fakeCollector) Optional() bool {
+	return f.optional
+}
+
+func (f fakeCollector) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	return f.collect(ctx)
+}
+
+func TestWithOptionsReturnsCollectorIfNoOptionIsSet(t *testing.T) {
+	c := NewCmd("test", false, nil)
+
+	assert.Equal(t, c, WithOptions(c, Options{}))
+	assert.Equal(t, Options{}, OptionsOf(c))
+}
+
+func TestWithOptions(t *testing.T) {
+	c := WithOptions(NewCmd("test", true, nil), Options{Retries: 1, Tags: []string{"mesos"}})
+
+	assert.Equal(t, "test", c.Name())
+	assert.True(t, c.Optional())
+	assert.Equal(t, Options{Retries: 1, Tags: []string{"mesos"}}, OptionsOf(c))
+}
+
+func TestLimit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; b.Len() < 100000; i++ {
+		fmt.Fprintf(&b, "%d,", i)
+	}
+	numbers := b.String()
+
+	tests := []struct {
+		data     string
+		max      int64
+		tailOnly bool
+		expected string
+	}{
+		{"0123456789", 0, false, "0123456789"},
+		{"0123456789", 10, false, "0123456789"},
+		{"0123456789", 4, false, "0123" + fmt.Sprintf(truncatedHeadMarker, 4)},
+		{"0123456789", 10, true, "0123456789"},
+		{"0123456789", 4, true, fmt.Sprintf(truncatedTailMarker, 6) + "6789"},
+		{strings.Repeat("x", 100000) + "end", 3, true, fmt.Sprintf(truncatedTailMarker, 100000) + "end"},
+		{numbers[:100000], 45678, true, fmt.Sprintf(truncatedTailMarker, 54322) + numbers[54322:100000]},
+		// buffer grows with data, so a huge limit does not allocate memory up front
+		{"0123456789", 1 << 40, true, "0123456789"},
+	}
+
+	for _, tt := range tests {
+		rc := Limit(ioutil.NopCloser(strings.NewReader(tt.data)), tt.max, tt.tailOnly)
+		raw, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, string(raw), "max=%d tailOnly=%t", tt.max, tt.tailOnly)
+		assert.NoError(t, rc.Close())
+	}
+}
+
+func TestRetry(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	calls := 0
+	rc, err := Retry(context.TODO(), 2, func() (goio.ReadCloser, error) {
+		calls++
+		if calls < 3 {
+			return nil, fmt.Errorf("failure %d", calls)
+		}
+		return ioutil.NopCloser(strings.NewReader("OK")), nil
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, rc)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	_, err = Retry(context.TODO(), 2, func() (goio.ReadCloser, error) {
+		calls++
+		return nil, fmt.Errorf("failure %d", calls)
+	})
+	assert.EqualError(t, err, "failure 3 (retried 2 times)")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = Retry(ctx, 2, func() (goio.ReadCloser, error) {
+		return nil, fmt.Errorf("failure")
+	})
+	assert.EqualError(t, err, "failure (retried 0 times)")
+}
+
+func TestLimitedCollectTimeout(t *testing.T) {
+	c := WithOptions(fakeCollector{name: "slow", collect: func(ctx context.Context) (goio.ReadCloser, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}, Options{Timeout: 10 * time.Millisecond})
+
+	_, err := c.Collect(context.TODO())
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLimitedCollect(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	calls := 0
+	c := WithOptions(fakeCollector{name: "flaky", collect: func(ctx context.Context) (goio.ReadCloser, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("failure")
+		}
+		return ioutil.NopCloser(strings.NewReader("0123456789")), nil
+	}}, Options{Timeout: time.Minute, Retries: 1, MaxBytes: 5})
+
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.NoError(t, rc.Close())
+
+	assert.Equal(t, "01234"+fmt.Sprintf(truncatedHeadMarker, 5), string(raw))
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,344 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Sample formats of the Sampler collector
+const (
+	// SampleFormatJSONL writes one JSON object with all metrics per sample
+	SampleFormatJSONL = "jsonl"
+	// SampleFormatCSV writes one time,metric,value row per metric of every sample
+	SampleFormatCSV = "csv"
+)
+
+// DefaultSampleInterval is used when the interval of the Sampler is not set
+const DefaultSampleInterval = 10 * time.Second
+
+// Background is implemented by collectors that mostly wait e.g., Sampler. They are run in parallel with
+// other collectors for the whole bundle creation.
+type Background interface {
+	Collector
+	Background() bool
+}
+
+// IsBackground returns true if the collector should be run in parallel with other collectors
+func IsBackground(c Collector) bool {
+	if l, ok := c.(*Limited); ok {
+		c = l.Collector
+	}
+	b, ok := c.(Background)
+	return ok && b.Background()
+}
+
+// SamplerOptions describe how and which metrics are sampled
+type SamplerOptions struct {
+	// Samples is how many times the endpoint is polled
+	Samples int
+	// Interval between samples, DefaultSampleInterval if 0
+	Interval time.Duration
+	// Format of the time series, SampleFormatJSONL if empty
+	Format string
+	// Families of Prometheus metrics to keep. All if empty
+	Families []string
+}
+
+// Sampler is a struct implementing Collector interface. It polls an endpoint serving JSON or Prometheus
+// text metrics and returns the time series of numeric values. Sampling stops early when the context is done
+// and samples taken so far are returned.
+type Sampler struct {
+	name     string
+	optional bool
+	url      string
+	client   *http.Client
+	options  SamplerOptions
+}
+
+// NewSampler creates Sampler collector of metrics served at url
+func NewSampler(name string, optional bool, url string, client *http.Client, options SamplerOptions) *Sampler {
+	if options.Interval <= 0 {
+		options.Interval = DefaultSampleInterval
+	}
+	if options.Format == "" {
+		options.Format = SampleFormatJSONL
+	}
+	return &Sampler{
+		name:     name,
+		optional: optional,
+		url:      url,
+		client:   client,
+		options:  options,
+	}
+}
+
+func (c Sampler) Name() string {
+	return c.name
+}
+
+func (c Sampler) Optional() bool {
+	return c.optional
+}
+
+// Background returns true, Sampler runs for the whole bundle creation
+func (c Sampler) Background() bool {
+	return true
+}
+
+type sample struct {
+	Time    time.Time          `json:"time"`
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+func (c Sampler) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	var samples []sample
+	var lastErr error
+	succeeded := 0
+
+sampling:
+	for i := 0; i < c.options.Samples; i++ {
+		s := sample{Time: time.Now().UTC()}
+		metrics, err := c.sample(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			lastErr = err
+			s.Error = err.Error()
+		} else {
+			succeeded++
+			s.Metrics = metrics
+		}
+		samples = append(samples, s)
+
+		if i == c.options.Samples-1 {
+			break
+		}
+		timer := time.NewTimer(c.options.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			break sampling
+		case <-timer.C:
+		}
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return nil, fmt.Errorf("could not sample %s: %s", c.url, lastErr)
+	}
+
+	buf := &bytes.Buffer{}
+	var err error
+	if c.options.Format == SampleFormatCSV {
+		err = writeSamplesCSV(buf, samples)
+	} else {
+		err = writeSamplesJSONL(buf, samples)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not write samples of %s: %s", c.url, err)
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+func (c Sampler) sample(ctx context.Context) (map[string]float64, error) {
+	request, err := http.NewRequest(http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new HTTP request: %s", err)
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json, text/plain;version=0.0.4;q=0.9")
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch url %s: %s", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", c.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s. Return code %d. Body: %s", c.url, resp.StatusCode, string(body))
+	}
+
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return jsonMetrics(body)
+	}
+	return prometheusMetrics(body, c.options.Families)
+}
+
+// jsonMetrics returns numeric values of the JSON document named after their path e.g., master/cpus_total
+// or frameworks.0.used_resources.cpus
+func jsonMetrics(body []byte) (map[string]float64, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not parse JSON metrics: %s", err)
+	}
+	metrics := map[string]float64{}
+	flatten(metrics, "", doc)
+	return metrics, nil
+}
+
+func flatten(metrics map[string]float64, prefix string, v interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch t := v.(type) {
+	case json.Number:
+		if f, err := t.Float64(); err == nil {
+			metrics[prefix] = f
+		}
+	case bool:
+		if t {
+			metrics[prefix] = 1
+		} else {
+			metrics[prefix] = 0
+		}
+	case map[string]interface{}:
+		for k, item := range t {
+			flatten(metrics, join(k), item)
+		}
+	case []interface{}:
+		for i, item := range t {
+			flatten(metrics, join(strconv.Itoa(i)), item)
+		}
+	}
+}
+
+// prometheusMetrics returns values of the metric families in Prometheus text format named after the metric
+// and its labels e.g., http_requests_total{code="200"}. Summaries and histograms are split into their
+// _sum, _count and quantile or bucket series.
+func prometheusMetrics(body []byte, families []string) (map[string]float64, error) {
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Prometheus metrics: %s", err)
+	}
+
+	keep := func(name string) bool {
+		if len(families) == 0 {
+			return true
+		}
+		for _, f := range families {
+			if f == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	metrics := map[string]float64{}
+	// NaN and infinite values could not be encoded in JSON
+	put := func(name string, value float64) {
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			metrics[name] = value
+		}
+	}
+	for name, family := range parsed {
+		if !keep(name) {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := m.GetLabel()
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				put(seriesName(name, labels), m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				put(seriesName(name, labels), m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				put(seriesName(name, labels), m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				put(seriesName(name+"_sum", labels), s.GetSampleSum())
+				put(seriesName(name+"_count", labels), float64(s.GetSampleCount()))
+				for _, q := range s.GetQuantile() {
+					put(seriesName(name, labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue())
+				}
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				put(seriesName(name+"_sum", labels), h.GetSampleSum())
+				put(seriesName(name+"_count", labels), float64(h.GetSampleCount()))
+				for _, b := range h.GetBucket() {
+					put(seriesName(name+"_bucket", labels, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount()))
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// seriesName returns the name of the metric with its labels and the extra label given as a name and a value
+func seriesName(name string, labels []*dto.LabelPair, extra ...string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[0], extra[1]))
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func writeSamplesJSONL(w goio.Writer, samples []sample) error {
+	enc := json.NewEncoder(w)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSamplesCSV(w goio.Writer, samples []sample) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "metric", "value"}); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		t := s.Time.Format(time.RFC3339Nano)
+		names := make([]string, 0, len(s.Metrics))
+		for name := range s.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := cw.Write([]string{t, name, formatFloat(s.Metrics[name])}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
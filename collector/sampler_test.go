@@ -0,0 +1,140 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prometheusMetricsFixture = `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{code="200",method="get"} %d
+requests_total{code="500",method="get"} 1
+# HELP memory_bytes Memory in use.
+# TYPE memory_bytes gauge
+memory_bytes 1024
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 0.5
+latency_seconds_count 4
+`
+
+func collectSamples(t *testing.T, c Collector) string {
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSamplerIsBackgroundCollector(t *testing.T) {
+	s := NewSampler("metrics", false, "http://127.0.0.1", http.DefaultClient, SamplerOptions{Samples: 1})
+	assert.True(t, IsBackground(s))
+	assert.True(t, IsBackground(WithOptions(s, Options{Retries: 1})))
+	assert.False(t, IsBackground(NewCmd("cmd", false, nil)))
+}
+
+func TestSamplerJSON(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"master/tasks_running": %d, "master/elected": true, "frameworks": [{"cpus": 0.5}], "version": "1.10"}`, calls)
+	}))
+	defer server.Close()
+
+	s := NewSampler("metrics", false, server.URL, server.Client(), SamplerOptions{Samples: 2, Interval: time.Millisecond})
+	lines := strings.Split(strings.TrimSpace(collectSamples(t, s)), "\n")
+
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"metrics":{"frameworks.0.cpus":0.5,"master/elected":1,"master/tasks_running":1}`)
+	assert.Contains(t, lines[1], `"metrics":{"frameworks.0.cpus":0.5,"master/elected":1,"master/tasks_running":2}`)
+}
+
+func TestSamplerPrometheusCSV(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, prometheusMetricsFixture, 10*calls)
+	}))
+	defer server.Close()
+
+	s := NewSampler("metrics", false, server.URL, server.Client(), SamplerOptions{
+		Samples:  2,
+		Interval: time.Millisecond,
+		Format:   SampleFormatCSV,
+		Families: []string{"requests_total", "latency_seconds"},
+	})
+	rows := strings.Split(strings.TrimSpace(collectSamples(t, s)), "\n")
+
+	require.Len(t, rows, 13)
+	assert.Equal(t, "time,metric,value", rows[0])
+	var metrics []string
+	for _, row := range rows[1:7] {
+		metrics = append(metrics, row[strings.Index(row, ",")+1:])
+	}
+	assert.Equal(t, []string{
+		`"latency_seconds_bucket{le=""+Inf""}",4`,
+		`"latency_seconds_bucket{le=""0.1""}",3`,
+		`latency_seconds_count,4`,
+		`latency_seconds_sum,0.5`,
+		`"requests_total{code=""200"",method=""get""}",10`,
+		`"requests_total{code=""500"",method=""get""}",1`,
+	}, metrics)
+	assert.True(t, strings.HasSuffix(rows[11], `"requests_total{code=""200"",method=""get""}",20`))
+}
+
+func TestSamplerStopsWhenContextIsDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"a": 1}`)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	s := NewSampler("metrics", false, server.URL, server.Client(), SamplerOptions{Samples: 100, Interval: time.Hour})
+
+	start := time.Now()
+	rc, err := s.Collect(ctx)
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) < time.Minute)
+
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+}
+
+func TestSamplerErrors(t *testing.T) {
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && failures > 0 {
+			fmt.Fprint(w, `{"a": 1}`)
+			return
+		}
+		failures++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s := NewSampler("metrics", false, server.URL+"/down", server.Client(), SamplerOptions{Samples: 2, Interval: time.Millisecond})
+	_, err := s.Collect(context.TODO())
+	assert.EqualError(t, err, "could not sample "+server.URL+"/down: unable to fetch "+server.URL+"/down. Return code 503. Body: unavailable\n")
+
+	failures = 0
+	s = NewSampler("metrics", false, server.URL+"/flaky", server.Client(), SamplerOptions{Samples: 2, Interval: time.Millisecond})
+	lines := strings.Split(strings.TrimSpace(collectSamples(t, s)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"error":"unable to fetch`)
+	assert.Contains(t, lines[1], `"metrics":{"a":1}`)
+}
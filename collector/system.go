@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+
+	"github.com/dcos/dcos-diagnostics/sysinfo"
+)
+
+// System is a struct implementing Collector interface. It collects a JSON snapshot of the host (processes,
+// memory, load, mounts, network interfaces, sockets and kernel parameters) read directly from procfs and sysfs.
+type System struct {
+	name     string
+	optional bool
+	reader   *sysinfo.Reader
+}
+
+// NewSystem creates System collector of the snapshot read with the reader
+func NewSystem(name string, optional bool, reader *sysinfo.Reader) *System {
+	return &System{
+		name:     name,
+		optional: optional,
+		reader:   reader,
+	}
+}
+
+func (c System) Name() string {
+	return c.name
+}
+
+func (c System) Optional() bool {
+	return c.optional
+}
+
+func (c System) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	snapshot, err := c.reader.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not read system snapshot: %s", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode system snapshot: %s", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/sysinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemCollect(t *testing.T) {
+	proc, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(proc)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(proc, "loadavg"), []byte("1.00 0.50 0.25 1/100 42\n"), 0644))
+
+	c := NewSystem("system-snapshot.json", true, &sysinfo.Reader{ProcRoot: proc, SysRoot: filepath.Join(proc, "sys")})
+	assert.Equal(t, "system-snapshot.json", c.Name())
+	assert.True(t, c.Optional())
+
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	defer rc.Close()
+
+	var snapshot sysinfo.Snapshot
+	require.NoError(t, json.NewDecoder(rc).Decode(&snapshot))
+	assert.Equal(t, &sysinfo.Load{Load1: 1, Load5: 0.5, Load15: 0.25, Running: 1, Total: 100}, snapshot.Load)
+	assert.Contains(t, snapshot.Errors, "memory")
+}
+
+func TestSystemCollectFailsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := NewSystem("system-snapshot.json", true, sysinfo.NewReader()).Collect(ctx)
+	assert.EqualError(t, err, "could not read system snapshot: context canceled")
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+
+	"github.com/dcos/dcos-diagnostics/tlsinspect"
+)
+
+// TLS is a struct implementing Collector interface. It collects JSON results of inspecting TLS endpoints:
+// presented certificate chains with their SANs and expiry and whether they verify against the configured CA.
+type TLS struct {
+	name      string
+	optional  bool
+	inspector *tlsinspect.Inspector
+	endpoints []tlsinspect.Endpoint
+}
+
+// NewTLS creates TLS collector of the endpoints inspected with the inspector
+func NewTLS(name string, optional bool, inspector *tlsinspect.Inspector, endpoints []tlsinspect.Endpoint) *TLS {
+	return &TLS{
+		name:      name,
+		optional:  optional,
+		inspector: inspector,
+		endpoints: endpoints,
+	}
+}
+
+func (c TLS) Name() string {
+	return c.name
+}
+
+func (c TLS) Optional() bool {
+	return c.optional
+}
+
+func (c TLS) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	results := make([]tlsinspect.Result, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("could not inspect TLS endpoints: %s", err)
+		}
+		results = append(results, c.inspector.Inspect(ctx, e))
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode TLS endpoints: %s", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/tlsinspect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSCollect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+
+	c := NewTLS("tls-certificates.json", true, tlsinspect.NewInspector(nil), []tlsinspect.Endpoint{
+		{Name: "adminrouter", Address: address},
+	})
+	assert.Equal(t, "tls-certificates.json", c.Name())
+	assert.True(t, c.Optional())
+
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	defer rc.Close()
+
+	var results []tlsinspect.Result
+	require.NoError(t, json.NewDecoder(rc).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "adminrouter", results[0].Name)
+	assert.NotEmpty(t, results[0].Error)
+	assert.False(t, results[0].Healthy())
+}
+
+func TestTLSCollectFailsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := NewTLS("tls-certificates.json", true, tlsinspect.NewInspector(nil), []tlsinspect.Endpoint{{Name: "mesos"}}).Collect(ctx)
+	assert.EqualError(t, err, "could not inspect TLS endpoints: context canceled")
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+
+	"github.com/dcos/dcos-diagnostics/zookeeper"
+)
+
+// ZooKeeper is a struct implementing Collector interface. It collects JSON report of the ZooKeeper ensemble:
+// leader, followers, zxid lag, outstanding requests and quorum of servers and their state seen by Exhibitor.
+type ZooKeeper struct {
+	name      string
+	optional  bool
+	inspector *zookeeper.Inspector
+}
+
+// NewZooKeeper creates ZooKeeper collector of the ensemble inspected with the inspector
+func NewZooKeeper(name string, optional bool, inspector *zookeeper.Inspector) *ZooKeeper {
+	return &ZooKeeper{
+		name:      name,
+		optional:  optional,
+		inspector: inspector,
+	}
+}
+
+func (c ZooKeeper) Name() string {
+	return c.name
+}
+
+func (c ZooKeeper) Optional() bool {
+	return c.optional
+}
+
+func (c ZooKeeper) Collect(ctx context.Context) (goio.ReadCloser, error) {
+	ensemble, err := c.inspector.Inspect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect ZooKeeper ensemble: %s", err)
+	}
+	data, err := json.MarshalIndent(ensemble, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode ZooKeeper ensemble: %s", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
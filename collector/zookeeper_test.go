@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/zookeeper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZooKeeperCollect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+
+	c := NewZooKeeper("zookeeper.json", true, zookeeper.NewInspector(func() ([]string, error) {
+		return []string{address}, nil
+	}, "", http.DefaultClient))
+	assert.Equal(t, "zookeeper.json", c.Name())
+	assert.True(t, c.Optional())
+
+	rc, err := c.Collect(context.TODO())
+	require.NoError(t, err)
+	defer rc.Close()
+
+	var ensemble zookeeper.Ensemble
+	require.NoError(t, json.NewDecoder(rc).Decode(&ensemble))
+	require.Len(t, ensemble.Servers, 1)
+	assert.Equal(t, address, ensemble.Servers[0].Address)
+	assert.NotEmpty(t, ensemble.Servers[0].Error)
+	assert.False(t, ensemble.HasQuorum)
+	assert.False(t, ensemble.Healthy())
+}
+
+func TestZooKeeperCollectFailsWhenServersAreUnknown(t *testing.T) {
+	c := NewZooKeeper("zookeeper.json", true, zookeeper.NewInspector(func() ([]string, error) {
+		return nil, fmt.Errorf("no masters")
+	}, "", http.DefaultClient))
+
+	_, err := c.Collect(context.TODO())
+	assert.EqualError(t, err, "could not inspect ZooKeeper ensemble: could not list ZooKeeper servers: no masters")
+}
@@ -38,10 +38,26 @@ type Config struct {
 	FlagDiagnosticsBundleDir                     string   `mapstructure:"diagnostics-bundle-dir"`
 	FlagDiagnosticsBundleEndpointsConfigFiles    []string `mapstructure:"endpoint-config"`
 	FlagDiagnosticsBundleUnitsLogsSinceString    string   `mapstructure:"diagnostics-units-since"`
+	FlagDiagnosticsBundleUnitsLogsFormat         string   `mapstructure:"diagnostics-units-format"`
+	FlagDiagnosticsBundleUnitsLogsPriority       string   `mapstructure:"diagnostics-units-priority"`
 	FlagDiagnosticsJobTimeoutMinutes             int      `mapstructure:"diagnostics-job-timeout"`
 	FlagDiagnosticsJobGetSingleURLTimeoutMinutes int      `mapstructure:"diagnostics-url-timeout"`
 	FlagCommandExecTimeoutSec                    int      `mapstructure:"command-exec-timeout"`
 	FlagDiagnosticsBundleFetchersCount           int      `mapstructure:"fetchers-count"`
+	FlagDiagnosticsBundleSigningKey              string   `mapstructure:"bundle-signing-key"`
+	FlagDiagnosticsBundleEncryptionKey           string   `mapstructure:"bundle-encryption-key"`
+	FlagDiagnosticsSystemSnapshot                bool     `mapstructure:"system-snapshot"`
+	FlagDiagnosticsNetwork                       bool     `mapstructure:"network-diagnostics"`
+	FlagDiagnosticsNetworkAgentSample            int      `mapstructure:"network-agent-sample"`
+	FlagTLSExpiryWarningDays                     int      `mapstructure:"tls-expiry-warning-days"`
+	FlagZooKeeperHealth                          bool     `mapstructure:"zookeeper-health"`
+	FlagDriftConfigFiles                         []string `mapstructure:"drift-config-files"`
+	FlagSilencesFile                             string   `mapstructure:"silences-file"`
+	FlagFlappingRestarts                         int      `mapstructure:"flapping-restarts"`
+
+	// logs search flags
+	FlagLogsSearchConcurrency int `mapstructure:"logs-search-concurrency"`
+	FlagLogsSearchLimit       int `mapstructure:"logs-search-limit"`
 }
 
 func (c Config) GetSingleEntryTimeout() time.Duration {
@@ -0,0 +1,108 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Usage is the resource usage of a container read from its cgroup
+type Usage struct {
+	CgroupPath       string  `json:"cgroup_path"`
+	MemoryBytes      uint64  `json:"memory_bytes"`
+	MemoryLimitBytes uint64  `json:"memory_limit_bytes,omitempty"`
+	CPUSeconds       float64 `json:"cpu_seconds"`
+	Pids             uint64  `json:"pids,omitempty"`
+}
+
+// CgroupUsage returns usage of the first cgroup found at one of the paths. Both the unified (v2) hierarchy
+// and v1 memory, cpuacct and pids controllers mounted at the root are supported.
+func CgroupUsage(root string, paths []string) (*Usage, error) {
+	unified := exists(filepath.Join(root, "cgroup.controllers"))
+	for _, p := range paths {
+		if unified {
+			if dir := filepath.Join(root, p); exists(filepath.Join(dir, "memory.current")) {
+				return unifiedUsage(p, dir)
+			}
+			continue
+		}
+		if exists(filepath.Join(root, "memory", p, "memory.usage_in_bytes")) {
+			return v1Usage(root, p)
+		}
+	}
+	return nil, fmt.Errorf("could not find cgroup in %s, tried %s", root, strings.Join(paths, ", "))
+}
+
+func unifiedUsage(p, dir string) (*Usage, error) {
+	u := &Usage{CgroupPath: p}
+	var err error
+	if u.MemoryBytes, err = readUint(filepath.Join(dir, "memory.current")); err != nil {
+		return nil, err
+	}
+	// memory.max is "max" when the memory is not limited
+	u.MemoryLimitBytes, _ = readUint(filepath.Join(dir, "memory.max"))
+	u.Pids, _ = readUint(filepath.Join(dir, "pids.current"))
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(stat))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse %s: %s", filepath.Join(dir, "cpu.stat"), err)
+			}
+			u.CPUSeconds = float64(usec) / 1e6
+		}
+	}
+	return u, nil
+}
+
+func v1Usage(root, p string) (*Usage, error) {
+	u := &Usage{CgroupPath: p}
+	var err error
+	if u.MemoryBytes, err = readUint(filepath.Join(root, "memory", p, "memory.usage_in_bytes")); err != nil {
+		return nil, err
+	}
+	u.MemoryLimitBytes, _ = readUint(filepath.Join(root, "memory", p, "memory.limit_in_bytes"))
+	u.Pids, _ = readUint(filepath.Join(root, "pids", p, "pids.current"))
+	if ns, err := readUint(filepath.Join(root, "cpuacct", p, "cpuacct.usage")); err == nil {
+		u.CPUSeconds = float64(ns) / 1e9
+	}
+	return u, nil
+}
+
+// systemdCgroupPath converts the slice:prefix:name cgroup path used with the systemd cgroup driver
+// to the path in the hierarchy. Other paths are returned unchanged.
+func systemdCgroupPath(p string) string {
+	parts := strings.Split(p, ":")
+	if len(parts) != 3 || strings.HasPrefix(p, "/") {
+		return strings.TrimPrefix(p, "/")
+	}
+	return filepath.Join(parts[0], parts[1]+"-"+parts[2]+".scope")
+}
+
+func readUint(file string) (uint64, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s: %s", file, err)
+	}
+	return v, nil
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
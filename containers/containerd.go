@@ -0,0 +1,144 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default locations of containerd
+const (
+	DefaultContainerdSocket = "/run/containerd/containerd.sock"
+	DefaultContainerdState  = "/run/containerd"
+	// DefaultContainerdLogDir is where the kubelet keeps logs of containers created with the containerd CRI plugin
+	DefaultContainerdLogDir = "/var/log/pods"
+)
+
+// containerdRuntimes are directories of the containerd state directory with bundles of running tasks
+var containerdRuntimes = []string{"io.containerd.runtime.v2.task", "io.containerd.runtime.v1.linux"}
+
+// Containerd reads tasks of containerd from OCI bundles in its state directory. The containerd API is gRPC only,
+// so the socket is only used to tell whether containerd is running.
+type Containerd struct {
+	socket string
+	state  string
+	logDir string
+}
+
+// NewContainerd returns Containerd reading tasks from the state directory and their CRI logs from the log directory
+func NewContainerd(socket, state, logDir string) *Containerd {
+	return &Containerd{socket: socket, state: state, logDir: logDir}
+}
+
+// Available returns true if the containerd socket exists
+func (c *Containerd) Available() bool {
+	info, err := os.Stat(c.socket)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// ociSpec is the part of the OCI runtime spec (config.json) describing the container
+type ociSpec struct {
+	Annotations map[string]string `json:"annotations"`
+	Linux       struct {
+		CgroupsPath string `json:"cgroupsPath"`
+	} `json:"linux"`
+}
+
+// List returns running tasks of all namespaces
+func (c *Containerd) List(ctx context.Context) ([]Container, error) {
+	var containers []Container
+	for _, runtime := range containerdRuntimes {
+		namespaces, err := ioutil.ReadDir(filepath.Join(c.state, runtime))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read containerd tasks: %s", err)
+		}
+		for _, ns := range namespaces {
+			tasks, err := ioutil.ReadDir(filepath.Join(c.state, runtime, ns.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("could not read containerd tasks: %s", err)
+			}
+			for _, task := range tasks {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if !task.IsDir() {
+					continue
+				}
+				containers = append(containers, c.task(filepath.Join(c.state, runtime, ns.Name(), task.Name()), ns.Name(), task.Name()))
+			}
+		}
+	}
+	return containers, nil
+}
+
+func (c *Containerd) task(dir, namespace, id string) Container {
+	container := Container{Runtime: RuntimeContainerd, Namespace: namespace, ID: id, State: "running"}
+	if pid, err := ioutil.ReadFile(filepath.Join(dir, "init.pid")); err == nil {
+		container.PID, _ = strconv.Atoi(strings.TrimSpace(string(pid)))
+	}
+	var spec ociSpec
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "config.json")); err == nil && json.Unmarshal(data, &spec) == nil {
+		container.Image = spec.Annotations["io.kubernetes.cri.image-name"]
+		container.Name = spec.Annotations["io.kubernetes.cri.container-name"]
+		if spec.Linux.CgroupsPath != "" {
+			container.CgroupPaths = []string{systemdCgroupPath(spec.Linux.CgroupsPath)}
+		}
+		if log := c.criLog(spec.Annotations); log != "" {
+			container.Logs = []string{log}
+		}
+	}
+	if len(container.CgroupPaths) == 0 {
+		container.CgroupPaths = []string{filepath.Join(namespace, id)}
+	}
+	return container
+}
+
+// criLog returns the log of the last attempt of the container created with the containerd CRI plugin. Logs are kept
+// in <namespace>_<pod>_<uid>/<container>/<attempt>.log of the log directory. Other clients of containerd decide where
+// output of their tasks goes, so their tasks have no log.
+func (c *Containerd) criLog(annotations map[string]string) string {
+	namespace := annotations["io.kubernetes.cri.sandbox-namespace"]
+	pod := annotations["io.kubernetes.cri.sandbox-name"]
+	uid := annotations["io.kubernetes.cri.sandbox-uid"]
+	name := annotations["io.kubernetes.cri.container-name"]
+	if namespace == "" || pod == "" || uid == "" || name == "" {
+		return ""
+	}
+	files, _ := filepath.Glob(filepath.Join(c.logDir, namespace+"_"+pod+"_"+uid, name, "*.log"))
+	log, last := "", -1
+	for _, f := range files {
+		attempt, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(f), ".log"))
+		if err == nil && attempt > last {
+			log, last = f, attempt
+		}
+	}
+	return log
+}
+
+// Inspect returns the OCI runtime spec of the task with values of secret environment variables redacted
+func (c *Containerd) Inspect(ctx context.Context, namespace, id string) ([]byte, error) {
+	for _, runtime := range containerdRuntimes {
+		data, err := ioutil.ReadFile(filepath.Join(c.state, runtime, namespace, id, "config.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read containerd task %s/%s: %s", namespace, id, err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("could not decode containerd task %s/%s: %s", namespace, id, err)
+		}
+		redactEnvIn(doc, "process", "env")
+		return marshal(doc)
+	}
+	return nil, fmt.Errorf("could not find containerd task %s/%s", namespace, id)
+}
@@ -0,0 +1,99 @@
+// Package containers inspects containers run on the node by Docker, containerd and the Mesos containerizer (UCR).
+package containers
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Container runtimes
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimeMesos      = "mesos"
+)
+
+// Redacted replaces values of secret environment variables
+const Redacted = "<redacted>"
+
+// secretEnv matches names of environment variables that usually hold secrets
+var secretEnv = regexp.MustCompile(`(?i)(SECRET|PASSW(OR)?D|TOKEN|CREDENTIAL|PRIVATE|API_?KEY|ACCESS_?KEY|AUTH)`)
+
+// Container is a container found on the node
+type Container struct {
+	Runtime string `json:"runtime"`
+	ID      string `json:"id"`
+	// Namespace is the containerd namespace
+	Namespace string `json:"namespace,omitempty"`
+	// Parent is the ID of the parent container of a nested Mesos container
+	Parent  string `json:"parent,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Image   string `json:"image,omitempty"`
+	State   string `json:"state,omitempty"`
+	PID     int    `json:"pid,omitempty"`
+	Created string `json:"created,omitempty"`
+	// CgroupPaths are paths below cgroup hierarchies the container could be placed in
+	CgroupPaths []string `json:"-"`
+	Usage       *Usage   `json:"usage,omitempty"`
+	UsageError  string   `json:"usage_error,omitempty"`
+	// Logs are paths of files with output of the container, Docker serves logs by its API
+	Logs []string `json:"logs,omitempty"`
+}
+
+// RedactEnv returns KEY=VALUE environment variables with values of secret ones replaced with Redacted
+func RedactEnv(env []string) []string {
+	redacted := make([]string, 0, len(env))
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 && secretEnv.MatchString(parts[0]) {
+			e = parts[0] + "=" + Redacted
+		}
+		redacted = append(redacted, e)
+	}
+	return redacted
+}
+
+// redactEnvIn replaces the list of environment variables found under the keys of the JSON document
+func redactEnvIn(doc map[string]interface{}, keys ...string) {
+	for i, key := range keys {
+		v, ok := doc[key]
+		if !ok {
+			return
+		}
+		if i < len(keys)-1 {
+			if doc, ok = v.(map[string]interface{}); !ok {
+				return
+			}
+			continue
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		env := make([]string, 0, len(items))
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				env = append(env, s)
+			}
+		}
+		redacted := make([]interface{}, 0, len(env))
+		for _, e := range RedactEnv(env) {
+			redacted = append(redacted, e)
+		}
+		doc[key] = redacted
+	}
+}
+
+// marshal returns the indented JSON document without escaping HTML characters, so redacted values stay readable
+func marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
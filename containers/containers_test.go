@@ -0,0 +1,289 @@
+package containers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocker serves a subset of the Docker Engine API on a Unix socket in dir
+func fakeDocker(t *testing.T, dir string) (string, func()) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("Docker API is served on a Unix socket")
+	}
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("all"))
+		fmt.Fprint(w, `[
+			{"Id": "abc123", "Names": ["/marathon-app"], "Image": "nginx:1.19", "State": "running", "Created": 1600000000},
+			{"Id": "def456", "Names": ["/exited"], "Image": "alpine", "State": "exited", "Created": 1600000100}
+		]`)
+	})
+	mux.HandleFunc("/containers/abc123/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "abc123", "Config": {"Tty": false, "Env": ["PATH=/bin", "DB_PASSWORD=hunter2", "AWS_ACCESS_KEY_ID=AKIA", "PORT0=80"]}, "HostConfig": {"Memory": 1048576}}`)
+	})
+	mux.HandleFunc("/containers/abc123/logs", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "5", r.URL.Query().Get("tail"))
+		assert.Equal(t, "1", r.URL.Query().Get("timestamps"))
+		for _, frame := range []struct {
+			stream byte
+			data   string
+		}{{1, "2020-09-13T12:26:40Z started\n"}, {2, "2020-09-13T12:26:41Z warning\n"}} {
+			header := []byte{frame.stream, 0, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(header[4:], uint32(len(frame.data)))
+			w.Write(header)
+			w.Write([]byte(frame.data))
+		}
+	})
+	mux.HandleFunc("/containers/tty/logs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "plain tty output\n")
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	return socket, func() { server.Close() }
+}
+
+// fakeCgroups creates v1 memory, cpuacct and pids hierarchies with a cgroup at the path
+func fakeCgroups(t *testing.T, root, path string) {
+	for file, value := range map[string]string{
+		filepath.Join("memory", path, "memory.usage_in_bytes"): "4096",
+		filepath.Join("memory", path, "memory.limit_in_bytes"): "1048576",
+		filepath.Join("cpuacct", path, "cpuacct.usage"):        "2500000000",
+		filepath.Join("pids", path, "pids.current"):            "3",
+	} {
+		writeFile(t, filepath.Join(root, file), value)
+	}
+}
+
+func writeFile(t *testing.T, file, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "containers")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestRedactEnv(t *testing.T) {
+	assert.Equal(t, []string{
+		"PATH=/bin",
+		"MESOS_TASK_ID=task",
+		"DB_PASSWORD=" + Redacted,
+		"api_key=" + Redacted,
+		"GITHUB_TOKEN=" + Redacted,
+		"EMPTY_SECRET=" + Redacted,
+		"NO_VALUE",
+	}, RedactEnv([]string{
+		"PATH=/bin",
+		"MESOS_TASK_ID=task",
+		"DB_PASSWORD=hunter2",
+		"api_key=123",
+		"GITHUB_TOKEN=ghp",
+		"EMPTY_SECRET=",
+		"NO_VALUE",
+	}))
+}
+
+func TestDocker(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	socket, stop := fakeDocker(t, dir)
+	defer stop()
+
+	d := NewDocker(socket)
+	assert.True(t, d.Available())
+	assert.False(t, NewDocker(filepath.Join(dir, "missing.sock")).Available())
+
+	list, err := d.List(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, Container{
+		Runtime:     RuntimeDocker,
+		ID:          "abc123",
+		Name:        "marathon-app",
+		Image:       "nginx:1.19",
+		State:       "running",
+		Created:     "2020-09-13T12:26:40Z",
+		CgroupPaths: []string{"docker/abc123", "system.slice/docker-abc123.scope"},
+	}, list[0])
+	assert.Equal(t, "exited", list[1].State)
+
+	inspect, err := d.Inspect(context.TODO(), "abc123")
+	require.NoError(t, err)
+	var doc struct {
+		Config struct {
+			Env []string
+		}
+		HostConfig map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(inspect, &doc))
+	assert.Equal(t, []string{"PATH=/bin", "DB_PASSWORD=" + Redacted, "AWS_ACCESS_KEY_ID=" + Redacted, "PORT0=80"}, doc.Config.Env)
+	assert.Equal(t, float64(1048576), doc.HostConfig["Memory"])
+
+	_, err = d.Inspect(context.TODO(), "missing")
+	assert.EqualError(t, err, "unable to fetch /containers/missing/json from "+socket+". Return code 404. Body: 404 page not found")
+}
+
+func TestDockerLogs(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	socket, stop := fakeDocker(t, dir)
+	defer stop()
+
+	rc, err := NewDocker(socket).Logs(context.TODO(), "abc123", 5)
+	require.NoError(t, err)
+	logs, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "2020-09-13T12:26:40Z started\n2020-09-13T12:26:41Z warning\n", string(logs))
+
+	rc, err = NewDocker(socket).Logs(context.TODO(), "tty", 5)
+	require.NoError(t, err)
+	logs, err = ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "plain tty output\n", string(logs))
+}
+
+func TestContainerd(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	task := filepath.Join(dir, "io.containerd.runtime.v2.task", "k8s.io", "task1")
+	writeFile(t, filepath.Join(task, "init.pid"), "4242\n")
+	writeFile(t, filepath.Join(task, "config.json"), `{
+		"process": {"env": ["PATH=/bin", "SECRET_KEY=abc"]},
+		"annotations": {"io.kubernetes.cri.image-name": "nginx", "io.kubernetes.cri.container-name": "web",
+			"io.kubernetes.cri.sandbox-namespace": "default", "io.kubernetes.cri.sandbox-name": "web-0",
+			"io.kubernetes.cri.sandbox-uid": "1234"},
+		"linux": {"cgroupsPath": "kubepods.slice:cri-containerd:task1"}
+	}`)
+	writeFile(t, filepath.Join(dir, "io.containerd.runtime.v1.linux", "default", "task2", "config.json"), `{}`)
+
+	logs := filepath.Join(dir, "pods", "default_web-0_1234", "web")
+	for _, attempt := range []string{"2.log", "10.log", "9.log"} {
+		writeFile(t, filepath.Join(logs, attempt), "")
+	}
+
+	c := NewContainerd(filepath.Join(dir, "containerd.sock"), dir, filepath.Join(dir, "pods"))
+	assert.False(t, c.Available())
+
+	list, err := c.List(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Container{
+		{Runtime: RuntimeContainerd, Namespace: "k8s.io", ID: "task1", Name: "web", Image: "nginx", State: "running", PID: 4242,
+			CgroupPaths: []string{"kubepods.slice/cri-containerd-task1.scope"}, Logs: []string{filepath.Join(logs, "10.log")}},
+		{Runtime: RuntimeContainerd, Namespace: "default", ID: "task2", State: "running", CgroupPaths: []string{"default/task2"}},
+	}, list)
+
+	spec, err := c.Inspect(context.TODO(), "k8s.io", "task1")
+	require.NoError(t, err)
+	assert.Contains(t, string(spec), `"SECRET_KEY=<redacted>"`)
+	assert.NotContains(t, string(spec), "abc")
+
+	_, err = c.Inspect(context.TODO(), "k8s.io", "missing")
+	assert.EqualError(t, err, "could not find containerd task k8s.io/missing")
+}
+
+func TestMesos(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	writeFile(t, filepath.Join(dir, "c1", "pid"), "100")
+	writeFile(t, filepath.Join(dir, "c1", "containers", "n1", "pid"), "101")
+	writeFile(t, filepath.Join(dir, "c1", "containers", "n1", "termination"), "")
+	writeFile(t, filepath.Join(dir, "c2", "pid"), "200")
+
+	workDir, cleanupWorkDir := tempDir(t)
+	defer cleanupWorkDir()
+	runs := filepath.Join(workDir, "slaves", "S0", "frameworks", "F0", "executors", "E0", "runs")
+	writeFile(t, filepath.Join(runs, "c1", "stdout"), "started\n")
+	writeFile(t, filepath.Join(runs, "c1", "stderr"), "")
+	writeFile(t, filepath.Join(runs, "c1", "containers", "n1", "stderr"), "failed\n")
+
+	m := NewMesos(dir, workDir)
+	assert.True(t, m.Available())
+	assert.False(t, NewMesos(filepath.Join(dir, "missing"), workDir).Available())
+
+	list, err := m.List(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Container{
+		{Runtime: RuntimeMesos, ID: "c1", State: "running", PID: 100, CgroupPaths: []string{"mesos/c1"},
+			Logs: []string{filepath.Join(runs, "c1", "stdout"), filepath.Join(runs, "c1", "stderr")}},
+		{Runtime: RuntimeMesos, ID: "c1.n1", Parent: "c1", State: "terminated", PID: 101, CgroupPaths: []string{"mesos/c1/mesos/n1"},
+			Logs: []string{filepath.Join(runs, "c1", "containers", "n1", "stderr")}},
+		{Runtime: RuntimeMesos, ID: "c2", State: "running", PID: 200, CgroupPaths: []string{"mesos/c2"}},
+	}, list)
+}
+
+func TestCgroupUsage(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	v1 := filepath.Join(dir, "v1")
+	fakeCgroups(t, v1, "system.slice/docker-abc.scope")
+	usage, err := CgroupUsage(v1, []string{"docker/abc", "system.slice/docker-abc.scope"})
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{CgroupPath: "system.slice/docker-abc.scope", MemoryBytes: 4096, MemoryLimitBytes: 1048576, CPUSeconds: 2.5, Pids: 3}, usage)
+
+	v2 := filepath.Join(dir, "v2")
+	writeFile(t, filepath.Join(v2, "cgroup.controllers"), "cpu memory pids")
+	writeFile(t, filepath.Join(v2, "mesos", "c1", "memory.current"), "8192\n")
+	writeFile(t, filepath.Join(v2, "mesos", "c1", "memory.max"), "max\n")
+	writeFile(t, filepath.Join(v2, "mesos", "c1", "cpu.stat"), "usage_usec 1500000\nuser_usec 1000000\n")
+	usage, err = CgroupUsage(v2, []string{"mesos/c1"})
+	require.NoError(t, err)
+	assert.Equal(t, &Usage{CgroupPath: "mesos/c1", MemoryBytes: 8192, CPUSeconds: 1.5}, usage)
+
+	_, err = CgroupUsage(v2, []string{"mesos/c2", "mesos/c3"})
+	assert.EqualError(t, err, "could not find cgroup in "+v2+", tried mesos/c2, mesos/c3")
+}
+
+func TestInventory(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	socket, stop := fakeDocker(t, dir)
+	defer stop()
+
+	cgroups := filepath.Join(dir, "cgroup")
+	fakeCgroups(t, cgroups, "docker/abc123")
+	writeFile(t, filepath.Join(dir, "mesos", "c1", "pid"), "100")
+
+	inventory := NewInspector(Options{
+		DockerSocket:     socket,
+		ContainerdSocket: filepath.Join(dir, "containerd.sock"),
+		MesosRuntimeDir:  filepath.Join(dir, "mesos"),
+		CgroupRoot:       cgroups,
+	}).Inventory(context.TODO())
+
+	assert.Equal(t, map[string]RuntimeStatus{
+		RuntimeDocker:     {Available: true},
+		RuntimeContainerd: {},
+		RuntimeMesos:      {Available: true},
+	}, inventory.Runtimes)
+	require.Len(t, inventory.Containers, 3)
+	assert.Equal(t, &Usage{CgroupPath: "docker/abc123", MemoryBytes: 4096, MemoryLimitBytes: 1048576, CPUSeconds: 2.5, Pids: 3},
+		inventory.Containers[0].Usage)
+	// usage of stopped containers is not read
+	assert.Nil(t, inventory.Containers[1].Usage)
+	assert.Empty(t, inventory.Containers[1].UsageError)
+	assert.Equal(t, "mesos", inventory.Containers[2].Runtime)
+	assert.Equal(t, "could not find cgroup in "+cgroups+", tried mesos/c1", inventory.Containers[2].UsageError)
+}
@@ -0,0 +1,173 @@
+package containers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDockerSocket is where the Docker daemon serves its API
+const DefaultDockerSocket = "/var/run/docker.sock"
+
+// Docker is a client of the Docker Engine API served on a Unix socket
+type Docker struct {
+	socket string
+	client *http.Client
+}
+
+// NewDocker returns Docker client connecting to the socket
+func NewDocker(socket string) *Docker {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Docker{socket: socket, client: &http.Client{Transport: transport}}
+}
+
+// Available returns true if the Docker socket exists
+func (d *Docker) Available() bool {
+	info, err := os.Stat(d.socket)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+func (d *Docker) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new HTTP request: %s", err)
+	}
+	resp, err := d.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s from %s: %s", path, d.socket, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unable to fetch %s from %s. Return code %d. Body: %s", path, d.socket, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// List returns all containers, including stopped ones
+func (d *Docker) List(ctx context.Context) ([]Container, error) {
+	resp, err := d.get(ctx, "/containers/json", url.Values{"all": {"1"}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Image   string   `json:"Image"`
+		State   string   `json:"State"`
+		Created int64    `json:"Created"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("could not decode Docker containers: %s", err)
+	}
+	containers := make([]Container, 0, len(list))
+	for _, c := range list {
+		container := Container{
+			Runtime: RuntimeDocker,
+			ID:      c.ID,
+			Image:   c.Image,
+			State:   c.State,
+			Created: time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+			// cgroupfs and systemd cgroup drivers
+			CgroupPaths: []string{"docker/" + c.ID, "system.slice/docker-" + c.ID + ".scope"},
+		}
+		if len(c.Names) > 0 {
+			container.Name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// Inspect returns the low-level information about the container with values of secret environment variables redacted
+func (d *Docker) Inspect(ctx context.Context, id string) ([]byte, error) {
+	resp, err := d.get(ctx, "/containers/"+url.PathEscape(id)+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var doc map[string]interface{}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not decode Docker container %s: %s", id, err)
+	}
+	redactEnvIn(doc, "Config", "Env")
+	return marshal(doc)
+}
+
+// Logs returns the last lines of the container stdout and stderr with timestamps
+func (d *Docker) Logs(ctx context.Context, id string, lines int) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+		"tail":       {strconv.Itoa(lines)},
+	}
+	resp, err := d.get(ctx, "/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+	body := bufio.NewReader(resp.Body)
+	if !multiplexed(body) {
+		return readCloser{Reader: body, Closer: resp.Body}, nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer resp.Body.Close()
+		pw.CloseWithError(demultiplex(pw, body))
+	}()
+	return pr, nil
+}
+
+// multiplexed returns true if the logs stream starts with a header of the stream multiplexing stdout and stderr
+// of containers without a TTY. The header is [STREAM_TYPE, 0, 0, 0, SIZE1, SIZE2, SIZE3, SIZE4].
+func multiplexed(r *bufio.Reader) bool {
+	header, err := r.Peek(8)
+	if err != nil {
+		return false
+	}
+	return header[0] <= 2 && header[1] == 0 && header[2] == 0 && header[3] == 0
+}
+
+// demultiplex writes frames of the multiplexed stream to w
+func demultiplex(w io.Writer, r io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("could not read Docker logs frame header: %s", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return fmt.Errorf("could not read Docker logs frame: %s", err)
+		}
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
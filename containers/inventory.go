@@ -0,0 +1,109 @@
+package containers
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultCgroupRoot is where cgroup hierarchies are mounted
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// Options locate container runtimes on the node. Defaults are used for empty options.
+type Options struct {
+	DockerSocket     string
+	ContainerdSocket string
+	ContainerdState  string
+	ContainerdLogDir string
+	MesosRuntimeDir  string
+	MesosWorkDir     string
+	CgroupRoot       string
+}
+
+func (o Options) withDefaults() Options {
+	defaults := map[*string]string{
+		&o.DockerSocket:     DefaultDockerSocket,
+		&o.ContainerdSocket: DefaultContainerdSocket,
+		&o.ContainerdState:  DefaultContainerdState,
+		&o.ContainerdLogDir: DefaultContainerdLogDir,
+		&o.MesosRuntimeDir:  DefaultMesosRuntimeDir,
+		&o.MesosWorkDir:     DefaultMesosWorkDir,
+		&o.CgroupRoot:       DefaultCgroupRoot,
+	}
+	for option, value := range defaults {
+		if *option == "" {
+			*option = value
+		}
+	}
+	return o
+}
+
+// RuntimeStatus tells whether the runtime was found on the node and why its containers could not be listed
+type RuntimeStatus struct {
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Inventory is the list of containers of all runtimes found on the node
+type Inventory struct {
+	Time       time.Time                `json:"time"`
+	Runtimes   map[string]RuntimeStatus `json:"runtimes"`
+	Containers []Container              `json:"containers"`
+}
+
+// Inspector lists and inspects containers of all runtimes
+type Inspector struct {
+	Docker     *Docker
+	Containerd *Containerd
+	Mesos      *Mesos
+	CgroupRoot string
+}
+
+// NewInspector returns Inspector of runtimes located with the options
+func NewInspector(options Options) *Inspector {
+	options = options.withDefaults()
+	return &Inspector{
+		Docker:     NewDocker(options.DockerSocket),
+		Containerd: NewContainerd(options.ContainerdSocket, options.ContainerdState, options.ContainerdLogDir),
+		Mesos:      NewMesos(options.MesosRuntimeDir, options.MesosWorkDir),
+		CgroupRoot: options.CgroupRoot,
+	}
+}
+
+type lister interface {
+	Available() bool
+	List(ctx context.Context) ([]Container, error)
+}
+
+// Inventory lists containers of available runtimes with their resource usage. Runtimes that could not be
+// listed are reported in the inventory.
+func (i *Inspector) Inventory(ctx context.Context) *Inventory {
+	inventory := &Inventory{Time: time.Now().UTC(), Runtimes: map[string]RuntimeStatus{}, Containers: []Container{}}
+	for _, r := range []struct {
+		name    string
+		runtime lister
+	}{
+		{RuntimeDocker, i.Docker},
+		{RuntimeContainerd, i.Containerd},
+		{RuntimeMesos, i.Mesos},
+	} {
+		if !r.runtime.Available() {
+			inventory.Runtimes[r.name] = RuntimeStatus{}
+			continue
+		}
+		containers, err := r.runtime.List(ctx)
+		if err != nil {
+			inventory.Runtimes[r.name] = RuntimeStatus{Available: true, Error: err.Error()}
+			continue
+		}
+		inventory.Runtimes[r.name] = RuntimeStatus{Available: true}
+		for _, c := range containers {
+			if c.State == "running" {
+				if c.Usage, err = CgroupUsage(i.CgroupRoot, c.CgroupPaths); err != nil {
+					c.UsageError = err.Error()
+				}
+			}
+			inventory.Containers = append(inventory.Containers, c)
+		}
+	}
+	return inventory
+}
@@ -0,0 +1,119 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Default locations of the Mesos containerizer
+const (
+	DefaultMesosRuntimeDir = "/var/run/mesos/containers"
+	// DefaultMesosWorkDir is the work directory of the Mesos agent with sandboxes of containers
+	DefaultMesosWorkDir = "/var/lib/mesos/slave"
+)
+
+// Mesos reads containers of the Mesos containerizer (UCR) from its runtime directory. Every container has
+// a directory with the pid of its init process and, once it exits, its termination. Nested containers
+// are kept in the containers directory of their parent. Output of containers is kept in their sandboxes
+// in the work directory of the agent.
+type Mesos struct {
+	runtimeDir string
+	workDir    string
+}
+
+// NewMesos returns Mesos reading containers from the runtime directory and their sandboxes from the work directory
+func NewMesos(runtimeDir, workDir string) *Mesos {
+	return &Mesos{runtimeDir: runtimeDir, workDir: workDir}
+}
+
+// Available returns true if the runtime directory exists
+func (m *Mesos) Available() bool {
+	info, err := os.Stat(m.runtimeDir)
+	return err == nil && info.IsDir()
+}
+
+// List returns all containers including nested ones
+func (m *Mesos) List(ctx context.Context) ([]Container, error) {
+	var containers []Container
+	if err := m.list(ctx, m.runtimeDir, "", "mesos", &containers); err != nil {
+		return nil, err
+	}
+
+	// parents are listed before their nested containers
+	sandboxes := m.sandboxes()
+	for i, c := range containers {
+		sandbox, ok := sandboxes[c.ID]
+		if !ok && c.Parent != "" {
+			if parent, found := sandboxes[c.Parent]; found {
+				sandbox, ok = filepath.Join(parent, "containers", strings.TrimPrefix(c.ID, c.Parent+".")), true
+			}
+		}
+		if !ok {
+			continue
+		}
+		sandboxes[c.ID] = sandbox
+		for _, name := range []string{"stdout", "stderr"} {
+			if file := filepath.Join(sandbox, name); exists(file) {
+				containers[i].Logs = append(containers[i].Logs, file)
+			}
+		}
+	}
+	return containers, nil
+}
+
+// sandboxes returns sandbox directories of top level containers by their IDs. Sandboxes are kept in
+// slaves/<agent>/frameworks/<framework>/executors/<executor>/runs/<container> of the work directory.
+func (m *Mesos) sandboxes() map[string]string {
+	runs, _ := filepath.Glob(filepath.Join(m.workDir, "slaves", "*", "frameworks", "*", "executors", "*", "runs", "*"))
+	sandboxes := make(map[string]string, len(runs))
+	for _, run := range runs {
+		// latest links to the last run of the executor
+		if id := filepath.Base(run); id != "latest" {
+			sandboxes[id] = run
+		}
+	}
+	return sandboxes
+}
+
+func (m *Mesos) list(ctx context.Context, dir, parent, cgroup string, containers *[]Container) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("could not read Mesos containers: %s", err)
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		containerDir := filepath.Join(dir, entry.Name())
+		id := entry.Name()
+		if parent != "" {
+			id = parent + "." + entry.Name()
+		}
+		containerCgroup := path.Join(cgroup, entry.Name())
+		c := Container{Runtime: RuntimeMesos, ID: id, Parent: parent, State: "running", CgroupPaths: []string{containerCgroup}}
+		if pid, err := ioutil.ReadFile(filepath.Join(containerDir, "pid")); err == nil {
+			c.PID, _ = strconv.Atoi(strings.TrimSpace(string(pid)))
+		}
+		if exists(filepath.Join(containerDir, "termination")) {
+			c.State = "terminated"
+		}
+		*containers = append(*containers, c)
+
+		nested := filepath.Join(containerDir, "containers")
+		if exists(nested) {
+			if err := m.list(ctx, nested, id, path.Join(containerCgroup, "mesos"), containers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package dcos
 import (
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/flapping"
 	"github.com/dcos/dcos-go/dcos"
 )
 
@@ -37,6 +38,15 @@ type Unit struct {
 	Title      string
 	Timestamp  time.Time
 	PrettyName string
+	// Output explains health of units derived by masters from the whole cluster state
+	Output string `json:",omitempty"`
+	// Silenced is set by masters when the unit is in a maintenance window and its health is suppressed
+	Silenced bool `json:",omitempty"`
+	// Level and Reasons are reported alongside Health, Health is derived from Level
+	Level   Level    `json:",omitempty"`
+	Reasons []string `json:",omitempty"`
+	// Flapping is the restart rate observed by the node, it's not set for units aggregated from many nodes
+	Flapping *flapping.Rate `json:",omitempty"`
 }
 
 // Node for DC/OS node.
@@ -49,6 +59,31 @@ type Node struct {
 	Output  map[string]string
 	Units   []Unit `json:",omitempty"`
 	MesosID string
+	// Silenced is set by masters when the node is in a maintenance window and its health is suppressed
+	Silenced bool `json:",omitempty"`
+	// Level is the most severe level of node units
+	Level Level `json:",omitempty"`
+	// SuppressedHealth and SuppressedLevel are set by masters when the node or any of its units is silenced.
+	// They ignore silenced units and are healthy when the whole node is silenced. Health and Level are kept
+	// as reported by the node.
+	SuppressedHealth *Health `json:",omitempty"`
+	SuppressedLevel  Level   `json:",omitempty"`
+}
+
+// EffectiveHealth returns health of the node with silences applied
+func (n Node) EffectiveHealth() Health {
+	if n.SuppressedHealth != nil {
+		return *n.SuppressedHealth
+	}
+	return n.Health
+}
+
+// EffectiveLevel returns level of the node with silences applied
+func (n Node) EffectiveLevel() Level {
+	if n.SuppressedHealth != nil {
+		return n.SuppressedLevel
+	}
+	return n.Level
 }
 
 // Tooler DC/OS specific tools interface.
@@ -0,0 +1,59 @@
+package dcos
+
+// Level is a severity of health reported alongside Health. Every level maps onto Health, so clients reading
+// only Health keep working: warnings are healthy and critical is unhealthy.
+type Level string
+
+const (
+	// LevelOK indicates Unit is healthy
+	LevelOK Level = "ok"
+	// LevelWarning indicates Unit works but needs attention, e.g., it's flapping
+	LevelWarning Level = "warning"
+	// LevelCritical indicates Unit is not healthy
+	LevelCritical Level = "critical"
+	// LevelUnknown indicates Unit health could not be determined
+	LevelUnknown Level = "unknown"
+)
+
+// levelRanks orders levels like Health is ordered, so unknown is the worst
+var levelRanks = map[Level]int{
+	LevelOK:       0,
+	LevelWarning:  1,
+	LevelCritical: 2,
+	LevelUnknown:  3,
+}
+
+// LevelOf returns the level of Health reported by nodes that do not report levels
+func LevelOf(h Health) Level {
+	switch h {
+	case Healthy:
+		return LevelOK
+	case Unknown:
+		return LevelUnknown
+	default:
+		return LevelCritical
+	}
+}
+
+// Health returns Health the level is reported as to clients that do not read levels
+func (l Level) Health() Health {
+	switch l {
+	case LevelOK, LevelWarning:
+		return Healthy
+	case LevelUnknown:
+		return Unknown
+	default:
+		return Unhealthy
+	}
+}
+
+// WorstLevel returns the most severe of levels
+func WorstLevel(levels ...Level) Level {
+	worst := LevelOK
+	for _, l := range levels {
+		if levelRanks[l] > levelRanks[worst] {
+			worst = l
+		}
+	}
+	return worst
+}
@@ -0,0 +1,27 @@
+package dcos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelMapsOntoHealth(t *testing.T) {
+	assert.Equal(t, Health(Healthy), LevelOK.Health())
+	assert.Equal(t, Health(Healthy), LevelWarning.Health())
+	assert.Equal(t, Health(Unhealthy), LevelCritical.Health())
+	assert.Equal(t, Health(Unknown), LevelUnknown.Health())
+
+	for _, h := range []Health{Healthy, Unhealthy, Unknown} {
+		assert.Equal(t, h, LevelOf(h).Health())
+	}
+	assert.Equal(t, LevelCritical, LevelOf(Health(2)))
+}
+
+func TestWorstLevel(t *testing.T) {
+	assert.Equal(t, LevelOK, WorstLevel())
+	assert.Equal(t, LevelOK, WorstLevel(LevelOK, ""))
+	assert.Equal(t, LevelWarning, WorstLevel(LevelOK, LevelWarning))
+	assert.Equal(t, LevelCritical, WorstLevel(LevelCritical, LevelWarning))
+	assert.Equal(t, LevelUnknown, WorstLevel(LevelCritical, LevelUnknown, LevelOK))
+}
@@ -0,0 +1,179 @@
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Node is a fingerprint reported by a node of the cluster
+type Node struct {
+	IP          string       `json:"ip"`
+	Role        string       `json:"role"`
+	Hostname    string       `json:"hostname,omitempty"`
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+	// Error is set when the fingerprint could not be fetched
+	Error string `json:"error,omitempty"`
+}
+
+// Group is a set of nodes with the same fingerprint
+type Group struct {
+	Hash               string   `json:"hash"`
+	DcosVersion        string   `json:"dcos_version"`
+	DiagnosticsVersion string   `json:"dcos_diagnostics_version"`
+	Nodes              []string `json:"nodes"`
+}
+
+// Outlier is a node whose fingerprint differs from the baseline of its role
+type Outlier struct {
+	IP          string   `json:"ip"`
+	Hostname    string   `json:"hostname,omitempty"`
+	Hash        string   `json:"hash"`
+	Differences []string `json:"differences"`
+}
+
+// RoleDrift compares nodes with the same role. The baseline is the fingerprint of most nodes.
+type RoleDrift struct {
+	Role     string    `json:"role"`
+	Baseline string    `json:"baseline"`
+	Groups   []Group   `json:"groups"`
+	Outliers []Outlier `json:"outliers,omitempty"`
+}
+
+// Report is the configuration drift of the cluster
+type Report struct {
+	Time time.Time `json:"time"`
+	// Drift is true if any node differs from the baseline of its role
+	Drift bool        `json:"drift"`
+	Roles []RoleDrift `json:"roles"`
+	// Errors maps IPs of nodes without fingerprint to the reason
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Compare groups nodes of every role by their fingerprints and reports nodes differing from the majority
+func Compare(nodes []Node) Report {
+	r := Report{Time: time.Now().UTC(), Roles: []RoleDrift{}}
+
+	byRole := map[string][]Node{}
+	for _, n := range nodes {
+		if n.Fingerprint == nil {
+			if r.Errors == nil {
+				r.Errors = map[string]string{}
+			}
+			r.Errors[n.IP] = n.Error
+			continue
+		}
+		byRole[n.Role] = append(byRole[n.Role], n)
+	}
+
+	roles := make([]string, 0, len(byRole))
+	for role := range byRole {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	for _, role := range roles {
+		d := compareRole(role, byRole[role])
+		if len(d.Outliers) > 0 {
+			r.Drift = true
+		}
+		r.Roles = append(r.Roles, d)
+	}
+	return r
+}
+
+func compareRole(role string, nodes []Node) RoleDrift {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].IP < nodes[j].IP })
+
+	groups := map[string]*Group{}
+	fingerprints := map[string]*Fingerprint{}
+	for _, n := range nodes {
+		f := n.Fingerprint
+		g, ok := groups[f.Hash]
+		if !ok {
+			g = &Group{Hash: f.Hash, DcosVersion: f.DcosVersion, DiagnosticsVersion: f.DiagnosticsVersion}
+			groups[f.Hash] = g
+			fingerprints[f.Hash] = f
+		}
+		g.Nodes = append(g.Nodes, n.IP)
+	}
+
+	d := RoleDrift{Role: role}
+	for _, g := range groups {
+		d.Groups = append(d.Groups, *g)
+	}
+	// the biggest group goes first and it's the baseline, ties are broken by hash to keep the report stable
+	sort.Slice(d.Groups, func(i, j int) bool {
+		if len(d.Groups[i].Nodes) != len(d.Groups[j].Nodes) {
+			return len(d.Groups[i].Nodes) > len(d.Groups[j].Nodes)
+		}
+		return d.Groups[i].Hash < d.Groups[j].Hash
+	})
+	d.Baseline = d.Groups[0].Hash
+	baseline := fingerprints[d.Baseline]
+
+	for _, n := range nodes {
+		if n.Fingerprint.Hash == d.Baseline {
+			continue
+		}
+		d.Outliers = append(d.Outliers, Outlier{
+			IP:          n.IP,
+			Hostname:    n.Hostname,
+			Hash:        n.Fingerprint.Hash,
+			Differences: Diff(*baseline, *n.Fingerprint),
+		})
+	}
+	return d
+}
+
+// Diff returns human readable differences of the fingerprint from the baseline
+func Diff(baseline, f Fingerprint) []string {
+	var diff []string
+	if f.DcosVersion != baseline.DcosVersion {
+		diff = append(diff, fmt.Sprintf("dcos_version is %q instead of %q", f.DcosVersion, baseline.DcosVersion))
+	}
+	if f.DiagnosticsVersion != baseline.DiagnosticsVersion {
+		diff = append(diff, fmt.Sprintf("dcos_diagnostics_version is %q instead of %q", f.DiagnosticsVersion, baseline.DiagnosticsVersion))
+	}
+	diff = append(diff, diffHashes("file", baseline.Files, f.Files)...)
+	diff = append(diff, diffHashes("unit", baseline.Units, f.Units)...)
+
+	packages := map[string]bool{}
+	for _, p := range f.Packages {
+		packages[p] = true
+	}
+	for _, p := range baseline.Packages {
+		if !packages[p] {
+			diff = append(diff, fmt.Sprintf("package %s is not active", p))
+		}
+		delete(packages, p)
+	}
+	var extra []string
+	for p := range packages {
+		extra = append(extra, p)
+	}
+	sort.Strings(extra)
+	for _, p := range extra {
+		diff = append(diff, fmt.Sprintf("package %s is active only on this node", p))
+	}
+	return diff
+}
+
+func diffHashes(kind string, baseline, hashes map[string]string) []string {
+	var diff []string
+	for _, name := range sortedKeys(baseline) {
+		hash, ok := hashes[name]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s %s is missing", kind, name))
+		case hash != baseline[name]:
+			diff = append(diff, fmt.Sprintf("%s %s differs", kind, name))
+		}
+	}
+	for _, name := range sortedKeys(hashes) {
+		if _, ok := baseline[name]; !ok {
+			diff = append(diff, fmt.Sprintf("%s %s exists only on this node", kind, name))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,134 @@
+package drift
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNodeDir creates a node configuration with a config file, a unit and an active package
+func newNodeDir(t *testing.T, config, unit, pkg string) Source {
+	dir, err := ioutil.TempDir("", "drift")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, d := range []string{"etc", "units", "active", "packages/" + pkg} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, d), 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "etc", "config.json"), []byte(config), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "units", "dcos-mesos-master.service"), []byte(unit), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "packages", pkg), filepath.Join(dir, "active", "mesos")))
+
+	return Source{
+		Files:       []string{filepath.Join(dir, "etc", "config.json"), filepath.Join(dir, "etc", "not-existing")},
+		UnitsDir:    filepath.Join(dir, "units"),
+		PackagesDir: filepath.Join(dir, "active"),
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	s := newNodeDir(t, "{}", "[Unit]", "mesos--1234")
+
+	f := s.Fingerprint("2.1.0", "0.4.0")
+
+	assert.Empty(t, f.Errors)
+	assert.Equal(t, "2.1.0", f.DcosVersion)
+	assert.Equal(t, "0.4.0", f.DiagnosticsVersion)
+	assert.Equal(t, map[string]string{
+		s.Files[0]: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	}, f.Files)
+	assert.Len(t, f.Units, 1)
+	assert.Len(t, f.Units["dcos-mesos-master.service"], 64)
+	assert.Equal(t, []string{"mesos--1234"}, f.Packages)
+	assert.Len(t, f.Hash, 64)
+
+	assert.Equal(t, f, s.Fingerprint("2.1.0", "0.4.0"), "fingerprint must be stable")
+	assert.NotEqual(t, f.Hash, s.Fingerprint("2.2.0", "0.4.0").Hash)
+}
+
+func TestFingerprintOfMissingDirs(t *testing.T) {
+	f := Source{Files: []string{"not-existing"}, UnitsDir: "not-existing", PackagesDir: "not-existing"}.Fingerprint("", "")
+
+	assert.Empty(t, f.Errors)
+	assert.Empty(t, f.Files)
+	assert.Empty(t, f.Units)
+	assert.Empty(t, f.Packages)
+	assert.NotEmpty(t, f.Hash)
+}
+
+func TestCompare(t *testing.T) {
+	same := newNodeDir(t, "{}", "[Unit]", "mesos--1234").Fingerprint("2.1.0", "0.4.0")
+	upgraded := newNodeDir(t, "{}", "[Unit]\nAfter=x", "mesos--5678").Fingerprint("2.2.0", "0.4.0")
+	agent := newNodeDir(t, `{"agent": true}`, "[Unit]", "mesos--1234").Fingerprint("2.1.0", "0.4.0")
+
+	r := Compare([]Node{
+		{IP: "10.0.0.3", Role: "master", Fingerprint: &upgraded, Hostname: "master-3"},
+		{IP: "10.0.0.1", Role: "master", Fingerprint: &same},
+		{IP: "10.0.0.2", Role: "master", Fingerprint: &same},
+		{IP: "10.0.1.1", Role: "agent", Fingerprint: &agent},
+		{IP: "10.0.1.2", Role: "agent", Error: "could not fetch fingerprint"},
+	})
+
+	assert.True(t, r.Drift)
+	assert.Equal(t, map[string]string{"10.0.1.2": "could not fetch fingerprint"}, r.Errors)
+	require.Len(t, r.Roles, 2)
+
+	agents := r.Roles[0]
+	assert.Equal(t, "agent", agents.Role)
+	assert.Equal(t, agent.Hash, agents.Baseline)
+	assert.Empty(t, agents.Outliers)
+
+	masters := r.Roles[1]
+	assert.Equal(t, "master", masters.Role)
+	assert.Equal(t, same.Hash, masters.Baseline)
+	assert.Equal(t, []Group{
+		{Hash: same.Hash, DcosVersion: "2.1.0", DiagnosticsVersion: "0.4.0", Nodes: []string{"10.0.0.1", "10.0.0.2"}},
+		{Hash: upgraded.Hash, DcosVersion: "2.2.0", DiagnosticsVersion: "0.4.0", Nodes: []string{"10.0.0.3"}},
+	}, masters.Groups)
+	require.Len(t, masters.Outliers, 1)
+	assert.Equal(t, "10.0.0.3", masters.Outliers[0].IP)
+	assert.Equal(t, "master-3", masters.Outliers[0].Hostname)
+	// config files are in different temporary dirs, so their paths differ too
+	assert.Contains(t, masters.Outliers[0].Differences, `dcos_version is "2.2.0" instead of "2.1.0"`)
+	assert.Contains(t, masters.Outliers[0].Differences, "unit dcos-mesos-master.service differs")
+	assert.Contains(t, masters.Outliers[0].Differences, "package mesos--1234 is not active")
+	assert.Contains(t, masters.Outliers[0].Differences, "package mesos--5678 is active only on this node")
+}
+
+func TestCompareWithoutDrift(t *testing.T) {
+	f := Fingerprint{Files: map[string]string{"a": "1"}}
+	f.Hash = f.digest()
+
+	r := Compare([]Node{{IP: "10.0.0.1", Role: "master", Fingerprint: &f}, {IP: "10.0.0.2", Role: "master", Fingerprint: &f}})
+
+	assert.False(t, r.Drift)
+	assert.Empty(t, r.Errors)
+	require.Len(t, r.Roles, 1)
+	assert.Empty(t, r.Roles[0].Outliers)
+}
+
+func TestDiff(t *testing.T) {
+	baseline := Fingerprint{
+		Files:    map[string]string{"/etc/a": "1", "/etc/b": "2"},
+		Units:    map[string]string{"a.service": "1"},
+		Packages: []string{"a--1", "b--1"},
+	}
+	f := Fingerprint{
+		Files:    map[string]string{"/etc/a": "3", "/etc/c": "4"},
+		Units:    map[string]string{"a.service": "1"},
+		Packages: []string{"a--1", "b--2"},
+	}
+
+	assert.Equal(t, []string{
+		"file /etc/a differs",
+		"file /etc/b is missing",
+		"file /etc/c exists only on this node",
+		"package b--1 is not active",
+		"package b--2 is active only on this node",
+	}, Diff(baseline, f))
+	assert.Empty(t, Diff(baseline, baseline))
+}
@@ -0,0 +1,188 @@
+// Package drift detects configuration drift across nodes. Every node computes a fingerprint of its configuration:
+// hashes of selected config files and systemd unit files and IDs of active packages. Masters compare fingerprints
+// of nodes with the same role and report nodes that differ from the majority.
+package drift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultUnitsDir holds DC/OS systemd unit files
+	DefaultUnitsDir = "/etc/systemd/system/dcos.target.wants"
+	// DefaultPackagesDir holds links to active DC/OS packages named after them
+	DefaultPackagesDir = "/opt/mesosphere/active"
+)
+
+// DefaultFiles are DC/OS config files expected to be identical on nodes with the same role
+var DefaultFiles = []string{
+	"/opt/mesosphere/etc/expanded.config.json",
+	"/opt/mesosphere/etc/user.config.yaml",
+	"/opt/mesosphere/etc/dcos-version.json",
+	"/opt/mesosphere/etc/cluster-id",
+}
+
+// Fingerprint describes configuration of a node. Files and units map names to SHA-256 of their content,
+// files that do not exist are omitted.
+type Fingerprint struct {
+	DcosVersion        string            `json:"dcos_version"`
+	DiagnosticsVersion string            `json:"dcos_diagnostics_version"`
+	Files              map[string]string `json:"files"`
+	Units              map[string]string `json:"units"`
+	Packages           []string          `json:"packages"`
+	// Hash is a digest of all other fields, nodes with the same hash have the same configuration
+	Hash string `json:"hash"`
+	// Errors lists problems with reading configuration, they are not part of the hash
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Source reads configuration of the node
+type Source struct {
+	Files       []string
+	UnitsDir    string
+	PackagesDir string
+}
+
+// NewSource returns Source reading the files, DC/OS units and packages
+func NewSource(files []string) Source {
+	return Source{
+		Files:       files,
+		UnitsDir:    DefaultUnitsDir,
+		PackagesDir: DefaultPackagesDir,
+	}
+}
+
+// Fingerprint returns fingerprint of the node configuration. Versions are reported by the caller.
+func (s Source) Fingerprint(dcosVersion, diagnosticsVersion string) Fingerprint {
+	f := Fingerprint{
+		DcosVersion:        dcosVersion,
+		DiagnosticsVersion: diagnosticsVersion,
+		Files:              map[string]string{},
+		Units:              map[string]string{},
+	}
+
+	for _, path := range s.Files {
+		hash, err := hashFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			f.Errors = append(f.Errors, err.Error())
+			continue
+		}
+		f.Files[path] = hash
+	}
+
+	if s.UnitsDir != "" {
+		if err := s.readUnits(&f); err != nil {
+			f.Errors = append(f.Errors, err.Error())
+		}
+	}
+	if s.PackagesDir != "" {
+		packages, err := readPackages(s.PackagesDir)
+		if err != nil {
+			f.Errors = append(f.Errors, err.Error())
+		}
+		f.Packages = packages
+	}
+
+	f.Hash = f.digest()
+	return f
+}
+
+func (s Source) readUnits(f *Fingerprint) error {
+	entries, err := ioutil.ReadDir(s.UnitsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not list units in %s: %s", s.UnitsDir, err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		// unit files are usually links to package directories, hash their targets
+		hash, err := hashFile(filepath.Join(s.UnitsDir, e.Name()))
+		if err != nil {
+			f.Errors = append(f.Errors, err.Error())
+			continue
+		}
+		f.Units[e.Name()] = hash
+	}
+	return nil
+}
+
+// readPackages returns IDs of active packages, the names of directories links in the dir point to
+func readPackages(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list packages in %s: %s", dir, err)
+	}
+	var packages []string
+	for _, e := range entries {
+		target, err := os.Readlink(filepath.Join(dir, e.Name()))
+		if err != nil {
+			// not a link, use its name
+			packages = append(packages, e.Name())
+			continue
+		}
+		packages = append(packages, filepath.Base(target))
+	}
+	sort.Strings(packages)
+	return packages, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", err
+		}
+		return "", fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("could not read %s: %s", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digest returns SHA-256 of versions, files, units and packages in a stable order
+func (f Fingerprint) digest() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dcos_version=%s\ndcos_diagnostics_version=%s\n", f.DcosVersion, f.DiagnosticsVersion)
+	for _, name := range sortedKeys(f.Files) {
+		fmt.Fprintf(&b, "file:%s=%s\n", name, f.Files[name])
+	}
+	for _, name := range sortedKeys(f.Units) {
+		fmt.Fprintf(&b, "unit:%s=%s\n", name, f.Units[name])
+	}
+	for _, p := range f.Packages {
+		fmt.Fprintf(&b, "package:%s\n", p)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,234 @@
+// Package encryption implements an RSA-OAEP envelope used to keep diagnostics bundles encrypted
+// at rest and in transit. Data is encrypted with a random AES-256-GCM key in fixed size chunks
+// so bundles of any size could be streamed. The AES key is encrypted with the recipient RSA public key.
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// magic prefixes every encrypted bundle and identifies the format version.
+const magic = "DCOSDIAGENC1"
+
+const (
+	keySize   = 32
+	chunkSize = 64 * 1024
+)
+
+// ErrTruncated is returned when encrypted data ends before the final chunk.
+var ErrTruncated = errors.New("encrypted data is truncated")
+
+// Encrypt reads plain data from src and writes its encrypted form to dst.
+func Encrypt(dst io.Writer, src io.Reader, key *rsa.PublicKey) error {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return fmt.Errorf("could not generate data key: %s", err)
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, dataKey, []byte(magic))
+	if err != nil {
+		return fmt.Errorf("could not encrypt data key: %s", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	header := bytes.NewBufferString(magic)
+	if err := binary.Write(header, binary.BigEndian, uint16(len(wrappedKey))); err != nil {
+		return err
+	}
+	header.Write(wrappedKey)
+	if _, err := dst.Write(header.Bytes()); err != nil {
+		return fmt.Errorf("could not write header: %s", err)
+	}
+
+	r := bufio.NewReaderSize(src, chunkSize)
+	plain := make([]byte, chunkSize)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(r, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("could not read data: %s", err)
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr == io.EOF
+		if peekErr != nil && !last {
+			return fmt.Errorf("could not read data: %s", peekErr)
+		}
+
+		sealed := aead.Seal(nil, nonce(counter, last), plain[:n], nil)
+		if err := binary.Write(dst, binary.BigEndian, uint32(len(sealed))); err != nil {
+			return fmt.Errorf("could not write chunk: %s", err)
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return fmt.Errorf("could not write chunk: %s", err)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt reads data encrypted with Encrypt from src and writes plain data to dst.
+func Decrypt(dst io.Writer, src io.Reader, key *rsa.PrivateKey) error {
+	r := bufio.NewReader(src)
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != magic {
+		return errors.New("data is not an encrypted bundle")
+	}
+	var wrappedKeyLen uint16
+	if err := binary.Read(r, binary.BigEndian, &wrappedKeyLen); err != nil {
+		return ErrTruncated
+	}
+	wrappedKey := make([]byte, wrappedKeyLen)
+	if _, err := io.ReadFull(r, wrappedKey); err != nil {
+		return ErrTruncated
+	}
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, wrappedKey, []byte(magic))
+	if err != nil {
+		return fmt.Errorf("could not decrypt data key, bundle was encrypted for a different key: %s", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	maxSealedLen := uint32(chunkSize + aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		var sealedLen uint32
+		if err := binary.Read(r, binary.BigEndian, &sealedLen); err != nil {
+			return ErrTruncated
+		}
+		if sealedLen > maxSealedLen {
+			return fmt.Errorf("malformed chunk %d: length %d exceeds %d", counter, sealedLen, maxSealedLen)
+		}
+		sealed := make([]byte, sealedLen)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return ErrTruncated
+		}
+
+		last := false
+		plain, err := aead.Open(nil, nonce(counter, last), sealed, nil)
+		if err != nil {
+			last = true
+			plain, err = aead.Open(nil, nonce(counter, last), sealed, nil)
+			if err != nil {
+				return fmt.Errorf("could not decrypt chunk %d: %s", counter, err)
+			}
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return fmt.Errorf("could not write data: %s", err)
+		}
+		if last {
+			if _, err := r.Peek(1); err != io.EOF {
+				return errors.New("unexpected data after the final chunk")
+			}
+			return nil
+		}
+	}
+}
+
+// IsEncrypted checks if the file under the given path starts with encrypted bundle header.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, len(magic))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, fmt.Errorf("could not read %s: %s", path, err)
+	}
+	return string(header[:n]) == magic, nil
+}
+
+// EncryptFile encrypts the file under the given path in place.
+func EncryptFile(path string, key *rsa.PublicKey) error {
+	return transformFile(path, func(dst io.Writer, src io.Reader) error {
+		return Encrypt(dst, src, key)
+	})
+}
+
+// DecryptFile decrypts the file under src path and writes plain data to dst path.
+func DecryptFile(src, dst string, key *rsa.PrivateKey) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %s", dst, err)
+	}
+	if err := Decrypt(out, in, key); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// transformFile writes the output of transform to a temporary file next to path and replaces path with it.
+func transformFile(path string, transform func(dst io.Writer, src io.Reader) error) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer in.Close()
+
+	tmpPath := path + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %s", tmpPath, err)
+	}
+	if err := transform(out, in); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write %s: %s", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not replace %s: %s", path, err)
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %s", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %s", err)
+	}
+	return aead, nil
+}
+
+// nonce builds a unique nonce for every chunk. The last chunk is marked so truncated data is detected.
+func nonce(counter uint64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n, counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKey is shared by tests because RSA key generation is slow
+var testKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17} {
+		plain := bytes.Repeat([]byte("diagnostics data"), size/16+1)[:size]
+
+		encrypted := bytes.NewBuffer(nil)
+		require.NoError(t, Encrypt(encrypted, bytes.NewReader(plain), &testKey.PublicKey))
+		if size > 16 {
+			assert.NotContains(t, encrypted.String(), "diagnostics data")
+		}
+
+		decrypted := bytes.NewBuffer(nil)
+		require.NoError(t, Decrypt(decrypted, encrypted, testKey), "size %d", size)
+		assert.Equal(t, string(plain), decrypted.String(), "size %d", size)
+	}
+}
+
+func TestDecryptDetectsTruncationAndTampering(t *testing.T) {
+	plain := make([]byte, 2*chunkSize+1)
+	encrypted := bytes.NewBuffer(nil)
+	require.NoError(t, Encrypt(encrypted, bytes.NewReader(plain), &testKey.PublicKey))
+	data := encrypted.Bytes()
+
+	err := Decrypt(ioutil.Discard, bytes.NewReader(data[:len(data)-30]), testKey)
+	assert.Equal(t, ErrTruncated, err)
+
+	// drop the last chunk entirely
+	lastChunkLen := 4 + 1 + 16
+	err = Decrypt(ioutil.Discard, bytes.NewReader(data[:len(data)-lastChunkLen]), testKey)
+	assert.Equal(t, ErrTruncated, err)
+
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-1] ^= 0xff
+	err = Decrypt(ioutil.Discard, bytes.NewReader(tampered), testKey)
+	assert.EqualError(t, err, "could not decrypt chunk 2: cipher: message authentication failed")
+
+	err = Decrypt(ioutil.Discard, bytes.NewReader(append(data, 0)), testKey)
+	assert.EqualError(t, err, "unexpected data after the final chunk")
+
+	err = Decrypt(ioutil.Discard, bytes.NewReader([]byte("PK\x03\x04 plain zip")), testKey)
+	assert.EqualError(t, err, "data is not an encrypted bundle")
+}
+
+func TestDecryptWithDifferentKey(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	encrypted := bytes.NewBuffer(nil)
+	require.NoError(t, Encrypt(encrypted, bytes.NewReader([]byte("data")), &testKey.PublicKey))
+
+	err = Decrypt(ioutil.Discard, encrypted, otherKey)
+	assert.Contains(t, err.Error(), "could not decrypt data key, bundle was encrypted for a different key")
+}
+
+func TestEncryptFileAndDecryptFileWithKeysFromPEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encryption")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	privatePath := filepath.Join(dir, "private.pem")
+	require.NoError(t, ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(testKey),
+	}), 0600))
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&testKey.PublicKey)
+	require.NoError(t, err)
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+
+	publicKey, err := LoadPublicKey(publicPath)
+	require.NoError(t, err)
+	privateKey, err := LoadPrivateKey(privatePath)
+	require.NoError(t, err)
+
+	dataPath := filepath.Join(dir, "file.zip")
+	require.NoError(t, ioutil.WriteFile(dataPath, []byte("bundle"), 0600))
+
+	encrypted, err := IsEncrypted(dataPath)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+
+	require.NoError(t, EncryptFile(dataPath, publicKey))
+
+	encrypted, err = IsEncrypted(dataPath)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	_, err = os.Stat(dataPath + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	decryptedPath := filepath.Join(dir, "decrypted.zip")
+	require.NoError(t, DecryptFile(dataPath, decryptedPath, privateKey))
+
+	raw, err := ioutil.ReadFile(decryptedPath)
+	require.NoError(t, err)
+	assert.Equal(t, "bundle", string(raw))
+}
+
+func TestParsePublicKey_Errors(t *testing.T) {
+	_, err := ParsePublicKey([]byte("not a key"))
+	assert.EqualError(t, err, "could not decode PEM encoded public key")
+
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}))
+	assert.Contains(t, err.Error(), "could not parse public key")
+}
@@ -0,0 +1,74 @@
+package encryption
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// LoadPublicKey reads a PEM encoded RSA public key from the file under the given path.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key: %s", err)
+	}
+	return ParsePublicKey(raw)
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key in PKIX or PKCS #1 form.
+func ParsePublicKey(raw []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode PEM encoded public key")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse public key: %s", err)
+		}
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key: %s", err)
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is %T not RSA", key)
+	}
+	return publicKey, nil
+}
+
+// LoadPrivateKey reads a PEM encoded RSA private key in PKCS #8 or PKCS #1 form.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key: %s", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode PEM from %s", path)
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse private key %s: %s", path, err)
+		}
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key %s: %s", path, err)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is %T not RSA", path, key)
+	}
+	return privateKey, nil
+}
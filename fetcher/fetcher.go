@@ -1,7 +1,6 @@
 package fetcher
 
 import (
-	"archive/zip"
 	"compress/gzip"
 	"context"
 	"fmt"
@@ -13,7 +12,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/dcos/dcos-diagnostics/archive"
+	"github.com/dcos/dcos-diagnostics/collector"
 	"github.com/dcos/dcos-diagnostics/dcos"
+	diagio "github.com/dcos/dcos-diagnostics/io"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
@@ -24,6 +26,7 @@ type EndpointRequest struct {
 	Node     dcos.Node
 	FileName string
 	Optional bool
+	Options  collector.Options
 }
 
 // StatusUpdate is an update message published by Fetcher when EndpointRequest is done. If error occurred	 during
@@ -71,13 +74,19 @@ func New(
 
 // Run starts fetcher. This method should be run as a goroutine
 func (f *Fetcher) Run(ctx context.Context) {
-	zipWriter := zip.NewWriter(f.file)
+	filename := f.file.Name()
 
-	f.workOffRequests(ctx, zipWriter)
+	// fetched files are merged into a legacy bundle which is always a zip archive
+	zipWriter, err := archive.NewWriter(f.file, archive.Zip)
+	if err != nil {
+		f.file.Close()
+		f.results <- BulkResponse{ZipFilePath: filename, Error: err}
+		return
+	}
 
-	filename := f.file.Name()
+	f.workOffRequests(ctx, zipWriter)
 
-	err := zipWriter.Close()
+	err = zipWriter.Close()
 	if err != nil {
 		err = fmt.Errorf("could not close zip writer %s: %s", f.file.Name(), err)
 	}
@@ -90,7 +99,7 @@ func (f *Fetcher) Run(ctx context.Context) {
 	}
 }
 
-func (f *Fetcher) workOffRequests(ctx context.Context, zipWriter *zip.Writer) {
+func (f *Fetcher) workOffRequests(ctx context.Context, zipWriter archive.Writer) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -113,10 +122,24 @@ func (f *Fetcher) workOffRequests(ctx context.Context, zipWriter *zip.Writer) {
 	}
 }
 
-func (f *Fetcher) getDataToZip(ctx context.Context, r EndpointRequest, zipWriter *zip.Writer) error {
+func (f *Fetcher) getDataToZip(ctx context.Context, r EndpointRequest, zipWriter archive.Writer) error {
+	if r.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Options.Timeout)
+		defer cancel()
+	}
+
 	start := time.Now()
 
-	resp, err := get(ctx, f.client, r.URL)
+	var resp *http.Response
+	_, err := collector.Retry(ctx, r.Options.Retries, func() (io.ReadCloser, error) {
+		var err error
+		resp, err = get(ctx, f.client, r.URL)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	})
 	if err != nil {
 		if !r.Optional {
 			return fmt.Errorf("could not get from url %s: %s", r.URL, err)
@@ -128,8 +151,26 @@ func (f *Fetcher) getDataToZip(ctx context.Context, r EndpointRequest, zipWriter
 	duration := time.Since(start)
 	f.prometheusVector.WithLabelValues(resp.Request.URL.Path, strconv.Itoa(resp.StatusCode)).Observe(duration.Seconds())
 
-	defer resp.Body.Close()
-	if resp.Header.Get("Content-Encoding") == "gzip" {
+	body := resp.Body
+	defer func() { body.Close() }()
+	gzipped := resp.Header.Get("Content-Encoding") == "gzip"
+	if r.Options.MaxBytes > 0 {
+		// the limit applies to uncompressed data
+		if gzipped {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				return fmt.Errorf("could not read compressed data from %s: %s", r.URL, err)
+			}
+			body = readCloser{Reader: gz, Closer: body}
+			gzipped = false
+		}
+		body = collector.Limit(body, r.Options.MaxBytes, r.Options.TailOnly)
+	}
+	if !gzipped && r.Options.Compression == collector.CompressionGzip {
+		body = diagio.GzipReadCloser(body)
+		gzipped = true
+	}
+	if gzipped {
 		r.FileName += ".gz"
 	}
 
@@ -138,13 +179,18 @@ func (f *Fetcher) getDataToZip(ctx context.Context, r EndpointRequest, zipWriter
 	if err != nil {
 		return fmt.Errorf("could not create a %s in the zip: %s", filename, err)
 	}
-	if _, err := io.Copy(zipFile, resp.Body); err != nil {
+	if _, err := io.Copy(zipFile, body); err != nil {
 		return fmt.Errorf("could not copy data to zip: %s", err)
 	}
 
 	return nil
 }
 
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
 	logrus.Debugf("Using URL %s to collect a log", url)
 	request, err := http.NewRequest("GET", url, nil)
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/dcos/dcos-diagnostics/collector"
 	"github.com/dcos/dcos-diagnostics/dcos"
 	"github.com/dcos/dcos-diagnostics/mocks"
 	"github.com/stretchr/testify/assert"
@@ -140,3 +141,71 @@ func mockServer(handle func(w http.ResponseWriter, r *http.Request)) (*httptest.
 
 	return server, transport
 }
+
+func Test_FetcherAppliesEndpointOptions(t *testing.T) {
+	input := make(chan EndpointRequest)
+	statusUpdate := make(chan StatusUpdate)
+	output := make(chan BulkResponse)
+
+	calls := 0
+	server, _ := mockServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "not yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		_, err := gz.Write([]byte("0123456789"))
+		require.NoError(t, err)
+	})
+	defer server.Close()
+
+	observer := &mocks.MockObserver{}
+	observer.On("Observe", mock.Anything)
+	mockHistogram := &mocks.MockHistogram{}
+	mockHistogram.On("WithLabelValues", "/data", "200").Return(observer)
+
+	f, err := New("", http.DefaultClient, input, statusUpdate, output, mockHistogram)
+	require.NoError(t, err)
+	go f.Run(context.TODO())
+
+	input <- EndpointRequest{
+		URL:      server.URL + "/data",
+		Node:     dcos.Node{IP: "127.0.0.1", Role: dcos.AgentRole},
+		FileName: "tail",
+		Options:  collector.Options{Retries: 1, MaxBytes: 4, TailOnly: true},
+	}
+	assert.Equal(t, StatusUpdate{URL: server.URL + "/data"}, <-statusUpdate)
+
+	input <- EndpointRequest{
+		URL:      server.URL + "/data",
+		Node:     dcos.Node{IP: "127.0.0.1", Role: dcos.AgentRole},
+		FileName: "head",
+		Options:  collector.Options{MaxBytes: 4, Compression: collector.CompressionGzip},
+	}
+	assert.Equal(t, StatusUpdate{URL: server.URL + "/data"}, <-statusUpdate)
+	close(input)
+
+	zipfile := <-output
+	z, err := zip.OpenReader(zipfile.ZipFilePath)
+	require.NoError(t, err)
+	require.Len(t, z.File, 2)
+
+	assert.Equal(t, "127.0.0.1_agent/tail", z.File[0].Name)
+	rc, err := z.File[0].Open()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "[truncated: first 6 bytes dropped]\n6789", string(body))
+
+	assert.Equal(t, "127.0.0.1_agent/head.gz", z.File[1].Name)
+	rc, err = z.File[1].Open()
+	require.NoError(t, err)
+	r, err := gzip.NewReader(rc)
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "0123\n[truncated: data exceeded 4 bytes]\n", string(body))
+}
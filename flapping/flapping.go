@@ -0,0 +1,116 @@
+// Package flapping detects units that restart repeatedly. A single look at a unit tells only whether it's restarting
+// right now, so Detector remembers restart counters and state changes of units observed on every health report.
+package flapping
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultWindow is how long restarts are remembered
+const DefaultWindow = time.Hour
+
+// Rate is how often the unit restarted and changed its state within the window
+type Rate struct {
+	Restarts     uint64 `json:"restarts"`
+	StateChanges int    `json:"state_changes"`
+	// PerHour is the restart rate over the observed part of the window
+	PerHour float64 `json:"per_hour"`
+	// Since is when the oldest observation in the window was made
+	Since    time.Time `json:"since"`
+	Flapping bool      `json:"flapping"`
+}
+
+type sample struct {
+	time time.Time
+	// restarts is NRestarts of the unit, it's reset when systemd reloads or the node reboots
+	restarts uint64
+	// stateChange is StateChangeTimestampMonotonic of the unit
+	stateChange uint64
+}
+
+// Detector reports units restarted at least Threshold times within Window as flapping
+type Detector struct {
+	sync.Mutex
+	Window    time.Duration
+	Threshold int
+
+	samples map[string][]sample
+	now     func() time.Time
+}
+
+// NewDetector returns Detector. Units are never reported as flapping if the threshold is not positive.
+func NewDetector(window time.Duration, threshold int) *Detector {
+	return &Detector{Window: window, Threshold: threshold, samples: map[string][]sample{}, now: time.Now}
+}
+
+// Observe records the restart counter and the last state change of the unit and returns its rate within the window
+func (d *Detector) Observe(unit string, restarts, stateChange uint64) Rate {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	samples := d.inWindow(append(d.samples[unit], sample{time: now, restarts: restarts, stateChange: stateChange}), now)
+	d.samples[unit] = samples
+	return d.rate(samples, now)
+}
+
+// Rate returns the rate of the unit within the window without recording anything. It returns false if the unit was
+// never observed.
+func (d *Detector) Rate(unit string) (Rate, bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	samples := d.samples[unit]
+	if len(samples) == 0 {
+		return Rate{}, false
+	}
+	now := d.now()
+	return d.rate(d.inWindow(samples, now), now), true
+}
+
+// inWindow drops samples older than the window but always keeps the latest one
+func (d *Detector) inWindow(samples []sample, now time.Time) []sample {
+	first := 0
+	for first < len(samples)-1 && now.Sub(samples[first].time) > d.Window {
+		first++
+	}
+	return samples[first:]
+}
+
+func (d *Detector) rate(samples []sample, now time.Time) Rate {
+	r := Rate{Since: samples[0].time}
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		if cur.restarts >= prev.restarts {
+			r.Restarts += cur.restarts - prev.restarts
+		} else {
+			// counter was reset, all restarts it reports happened since
+			r.Restarts += cur.restarts
+		}
+		if cur.stateChange != prev.stateChange {
+			r.StateChanges++
+		}
+	}
+	if observed := now.Sub(r.Since); observed > 0 {
+		r.PerHour = float64(r.Restarts) / observed.Hours()
+	}
+	r.Flapping = d.Threshold > 0 && r.Restarts >= uint64(d.Threshold)
+	return r
+}
+
+// Forget drops observations of units that are not in the list, e.g., units that were removed
+func (d *Detector) Forget(keep []string) {
+	d.Lock()
+	defer d.Unlock()
+
+	known := make(map[string]bool, len(keep))
+	for _, unit := range keep {
+		known[unit] = true
+	}
+	for unit := range d.samples {
+		if !known[unit] {
+			delete(d.samples, unit)
+		}
+	}
+}
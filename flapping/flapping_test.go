@@ -0,0 +1,93 @@
+package flapping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDetector(threshold int) (*Detector, *time.Time) {
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDetector(time.Hour, threshold)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func TestDetectorReportsFlapping(t *testing.T) {
+	d, now := newTestDetector(3)
+	start := *now
+
+	r := d.Observe("dcos-mesos-slave.service", 5, 100)
+	assert.Equal(t, Rate{Since: start}, r, "first observation has nothing to compare with")
+
+	*now = now.Add(10 * time.Minute)
+	r = d.Observe("dcos-mesos-slave.service", 7, 200)
+	assert.Equal(t, uint64(2), r.Restarts)
+	assert.Equal(t, 1, r.StateChanges)
+	assert.Equal(t, 12.0, r.PerHour)
+	assert.False(t, r.Flapping)
+
+	*now = now.Add(20 * time.Minute)
+	r = d.Observe("dcos-mesos-slave.service", 8, 300)
+	assert.Equal(t, Rate{Restarts: 3, StateChanges: 2, PerHour: 6, Since: start, Flapping: true}, r)
+
+	// other units are observed separately
+	assert.Equal(t, Rate{Since: *now}, d.Observe("dcos-net.service", 8, 300))
+}
+
+func TestDetectorForgetsOldRestarts(t *testing.T) {
+	d, now := newTestDetector(3)
+
+	d.Observe("dcos-mesos-slave.service", 0, 1)
+	*now = now.Add(30 * time.Minute)
+	d.Observe("dcos-mesos-slave.service", 3, 2)
+	*now = now.Add(31 * time.Minute)
+	r := d.Observe("dcos-mesos-slave.service", 3, 2)
+	assert.Equal(t, Rate{Since: now.Add(-31 * time.Minute)}, r)
+}
+
+func TestDetectorHandlesCounterReset(t *testing.T) {
+	d, now := newTestDetector(0)
+
+	d.Observe("dcos-mesos-slave.service", 10, 1)
+	*now = now.Add(time.Minute)
+	r := d.Observe("dcos-mesos-slave.service", 2, 2)
+	assert.Equal(t, uint64(2), r.Restarts)
+	assert.False(t, r.Flapping, "threshold 0 disables flapping")
+}
+
+func TestDetectorForget(t *testing.T) {
+	d, now := newTestDetector(1)
+
+	d.Observe("a.service", 0, 0)
+	d.Observe("b.service", 0, 0)
+	d.Forget([]string{"a.service"})
+
+	*now = now.Add(time.Minute)
+	assert.True(t, d.Observe("a.service", 1, 0).Flapping)
+	assert.False(t, d.Observe("b.service", 1, 0).Flapping)
+}
+
+func TestDetectorRateDoesNotObserve(t *testing.T) {
+	d, now := newTestDetector(2)
+	_, ok := d.Rate("a.service")
+	assert.False(t, ok, "unit was never observed")
+
+	start := *now
+	d.Observe("a.service", 0, 1)
+	*now = now.Add(30 * time.Minute)
+	d.Observe("a.service", 2, 2)
+	*now = now.Add(10 * time.Minute)
+	for i := 0; i < 3; i++ {
+		r, ok := d.Rate("a.service")
+		assert.True(t, ok)
+		assert.Equal(t, Rate{Restarts: 2, StateChanges: 1, PerHour: 3, Since: start, Flapping: true}, r)
+	}
+
+	// old observations are not counted by the rate even if they were not dropped yet
+	*now = now.Add(time.Hour)
+	r, ok := d.Rate("a.service")
+	assert.True(t, ok)
+	assert.Equal(t, Rate{Since: start.Add(30 * time.Minute)}, r)
+}
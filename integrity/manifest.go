@@ -0,0 +1,106 @@
+// Package integrity provides checksum manifests and detached signatures
+// that let support prove a diagnostics bundle was not modified after collection.
+package integrity
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ManifestFileName is the name of the checksum manifest stored in every bundle.
+// The format is compatible with `sha256sum --check`.
+const ManifestFileName = "checksums.sha256"
+
+// Manifest holds SHA-256 checksums of bundle entries in the order they were added.
+type Manifest struct {
+	names []string
+	sums  map[string]string
+}
+
+// NewManifest creates an empty Manifest
+func NewManifest() *Manifest {
+	return &Manifest{
+		sums: make(map[string]string),
+	}
+}
+
+// Add records a checksum of the given entry. Adding the same name twice overrides its checksum.
+func (m *Manifest) Add(name string, sum []byte) {
+	if _, ok := m.sums[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.sums[name] = hex.EncodeToString(sum)
+}
+
+// Copy copies src to dst and records a checksum of copied data under the given name.
+// The checksum is recorded even if copy fails so the manifest describes exactly what was written.
+func (m *Manifest) Copy(name string, dst io.Writer, src io.Reader) (int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, h), src)
+	m.Add(name, h.Sum(nil))
+	return n, err
+}
+
+// Sum returns a hex encoded checksum of the given entry.
+func (m *Manifest) Sum(name string) (string, bool) {
+	sum, ok := m.sums[name]
+	return sum, ok
+}
+
+// Names returns names of all entries in the manifest.
+func (m *Manifest) Names() []string {
+	return append([]string{}, m.names...)
+}
+
+// Len returns number of entries in the manifest.
+func (m *Manifest) Len() int {
+	return len(m.names)
+}
+
+// WriteTo writes the manifest in `sha256sum` format.
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, name := range m.names {
+		n, err := fmt.Fprintf(w, "%s  %s\n", m.sums[name], name)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ParseManifest reads a manifest written with Manifest.WriteTo.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := NewManifest()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		parts := strings.SplitN(text, "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed manifest line %d: %q", line, text)
+		}
+		sum, err := hex.DecodeString(parts[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("malformed checksum in manifest line %d: %q", line, parts[0])
+		}
+		m.Add(parts[1], sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read manifest: %s", err)
+	}
+	return m, nil
+}
+
+// NewHash returns a hash used to compute manifest checksums.
+func NewHash() hash.Hash {
+	return sha256.New()
+}
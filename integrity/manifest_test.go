@@ -0,0 +1,54 @@
+package integrity
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest_CopyRecordsChecksum(t *testing.T) {
+	m := NewManifest()
+	dst := bytes.NewBuffer(nil)
+
+	n, err := m.Copy("hello.txt", dst, strings.NewReader("hello\n"))
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 6, n)
+	assert.Equal(t, "hello\n", dst.String())
+	sum, ok := m.Sum("hello.txt")
+	assert.True(t, ok)
+	// echo hello | sha256sum
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", sum)
+}
+
+func TestManifest_WriteToAndParseRoundTrip(t *testing.T) {
+	m := NewManifest()
+	m.Add("b", bytes.Repeat([]byte{1}, 32))
+	m.Add("a/c", bytes.Repeat([]byte{2}, 32))
+	m.Add("b", bytes.Repeat([]byte{3}, 32))
+
+	buf := bytes.NewBuffer(nil)
+	_, err := m.WriteTo(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"0303030303030303030303030303030303030303030303030303030303030303  b\n"+
+			"0202020202020202020202020202020202020202020202020202020202020202  a/c\n",
+		buf.String())
+
+	parsed, err := ParseManifest(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a/c"}, parsed.Names())
+	assert.Equal(t, 2, parsed.Len())
+}
+
+func TestParseManifest_Malformed(t *testing.T) {
+	_, err := ParseManifest(strings.NewReader("not a manifest"))
+	assert.EqualError(t, err, `malformed manifest line 1: "not a manifest"`)
+
+	_, err = ParseManifest(strings.NewReader("\nabcd  file"))
+	assert.EqualError(t, err, `malformed checksum in manifest line 2: "abcd"`)
+}
@@ -0,0 +1,145 @@
+package integrity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ErrInvalidSignature is returned when a signature does not match signed data.
+var ErrInvalidSignature = errors.New("signature does not match bundle")
+
+// LoadPrivateKey reads an Ed25519 private key from a PEM encoded PKCS #8 file.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key %s: %s", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is %T not ed25519", path, key)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads an Ed25519 public key from a PEM encoded PKIX file.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key %s: %s", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is %T not ed25519", path, key)
+	}
+	return publicKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key: %s", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode PEM from %s", path)
+	}
+	return block, nil
+}
+
+// Sign returns a detached signature of data read from r. Ed25519 signs a manifest of the data: a line in
+// `sha256sum` format of data read from standard input, i.e., "<hex encoded SHA-256>  -\n", so large bundles
+// do not have to be kept in memory and the signature could be checked with standard tools:
+//
+//	sha256sum < bundle.zip > manifest
+//	base64 -d bundle.zip.sig > signature
+//	openssl pkeyutl -verify -pubin -inkey public.pem -rawin -in manifest -sigfile signature
+//
+// The signature is base64 encoded so it could be safely served and stored as text.
+func Sign(key ed25519.PrivateKey, r io.Reader) ([]byte, error) {
+	manifest, err := signedManifest(r)
+	if err != nil {
+		return nil, err
+	}
+	signature := ed25519.Sign(key, manifest)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(signature)))
+	base64.StdEncoding.Encode(encoded, signature)
+	return append(encoded, '\n'), nil
+}
+
+// Verify checks the signature created with Sign against data read from r.
+func Verify(key ed25519.PublicKey, r io.Reader, signature []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("could not decode signature: %s", err)
+	}
+	manifest, err := signedManifest(r)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, manifest, raw) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignFile signs the file under path and writes the signature to signaturePath.
+func SignFile(key ed25519.PrivateKey, path, signaturePath string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	signature, err := Sign(key, f)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(signaturePath, signature, 0600); err != nil {
+		return fmt.Errorf("could not write signature: %s", err)
+	}
+	return nil
+}
+
+// VerifyFile checks the file under path against the signature stored in signaturePath.
+func VerifyFile(key ed25519.PublicKey, path, signaturePath string) error {
+	signature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return fmt.Errorf("could not read signature: %s", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	return Verify(key, f, signature)
+}
+
+// signedManifest returns the manifest of data read from r that is signed by Sign
+func signedManifest(r io.Reader) ([]byte, error) {
+	m := NewManifest()
+	if _, err := m.Copy("-", ioutil.Discard, r); err != nil {
+		return nil, fmt.Errorf("could not read data to sign: %s", err)
+	}
+	var manifest bytes.Buffer
+	if _, err := m.WriteTo(&manifest); err != nil {
+		return nil, fmt.Errorf("could not write manifest: %s", err)
+	}
+	return manifest.Bytes(), nil
+}
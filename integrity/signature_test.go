@@ -0,0 +1,101 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signature, err := Sign(private, strings.NewReader("bundle"))
+	require.NoError(t, err)
+
+	assert.NoError(t, Verify(public, strings.NewReader("bundle"), signature))
+	assert.Equal(t, ErrInvalidSignature, Verify(public, strings.NewReader("modified"), signature))
+
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, ErrInvalidSignature, Verify(otherPublic, strings.NewReader("bundle"), signature))
+
+	assert.EqualError(t, Verify(public, strings.NewReader("bundle"), []byte("!")),
+		"could not decode signature: illegal base64 data at input byte 0")
+}
+
+func TestSignSignsManifest(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signature, err := Sign(private, strings.NewReader("bundle"))
+	require.NoError(t, err)
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	require.NoError(t, err)
+
+	// output of `echo -n bundle | sha256sum`
+	manifest := "1e6ed65d77d6364eeaed5a745ba5c4985ae2b700dd85d7cf7f027bdf294a33fc  -\n"
+	sum := sha256.Sum256([]byte("bundle"))
+	require.Equal(t, hex.EncodeToString(sum[:])+"  -\n", manifest)
+	assert.True(t, ed25519.Verify(public, []byte(manifest), raw))
+}
+
+func TestSignFileAndVerifyFileWithKeysFromPEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrity")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	privatePath := filepath.Join(dir, "private.pem")
+	require.NoError(t, ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	publicPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+
+	loadedPrivate, err := LoadPrivateKey(privatePath)
+	require.NoError(t, err)
+	loadedPublic, err := LoadPublicKey(publicPath)
+	require.NoError(t, err)
+
+	dataPath := filepath.Join(dir, "file.zip")
+	signaturePath := filepath.Join(dir, "file.zip.sig")
+	require.NoError(t, ioutil.WriteFile(dataPath, []byte("bundle"), 0600))
+
+	require.NoError(t, SignFile(loadedPrivate, dataPath, signaturePath))
+	assert.NoError(t, VerifyFile(loadedPublic, dataPath, signaturePath))
+
+	require.NoError(t, ioutil.WriteFile(dataPath, []byte("tampered"), 0600))
+	assert.Equal(t, ErrInvalidSignature, VerifyFile(loadedPublic, dataPath, signaturePath))
+}
+
+func TestLoadPrivateKey_Errors(t *testing.T) {
+	_, err := LoadPrivateKey("not-existing-key")
+	assert.Contains(t, err.Error(), "could not read key")
+
+	f, err := ioutil.TempFile("", "key")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("not a pem")
+	require.NoError(t, err)
+
+	_, err = LoadPrivateKey(f.Name())
+	assert.EqualError(t, err, "could not decode PEM from "+f.Name())
+}
@@ -0,0 +1,148 @@
+package integrity
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/dcos/dcos-diagnostics/archive"
+)
+
+// Problem describes a single integrity violation found in a bundle.
+type Problem struct {
+	Manifest string `json:"manifest"`
+	Entry    string `json:"entry"`
+	Reason   string `json:"reason"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s (%s)", p.Entry, p.Reason, p.Manifest)
+}
+
+// Verifier checks bundle entries read one by one, so bundles could be verified while they are
+// streamed or merged. The top level manifest must cover every entry of the bundle. Nested
+// manifests (e.g., node manifests in a cluster bundle) are checked against entries in their directory.
+type Verifier struct {
+	sums      map[string]string
+	manifests map[string]*Manifest
+}
+
+// NewVerifier creates a Verifier with no entries
+func NewVerifier() *Verifier {
+	return &Verifier{
+		sums:      make(map[string]string),
+		manifests: make(map[string]*Manifest),
+	}
+}
+
+// Add reads the entry with the given name and records its checksum. Entries named
+// checksums.sha256 are parsed as manifests.
+func (v *Verifier) Add(name string, r io.Reader) error {
+	h := NewHash()
+	var manifest bytes.Buffer
+	var dst io.Writer = h
+	isManifest := path.Base(name) == ManifestFileName
+	if isManifest {
+		dst = io.MultiWriter(h, &manifest)
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		return fmt.Errorf("could not read %s: %s", name, err)
+	}
+	if isManifest {
+		m, err := ParseManifest(&manifest)
+		if err != nil {
+			return fmt.Errorf("could not parse %s: %s", name, err)
+		}
+		v.manifests[name] = m
+	}
+	if name != ManifestFileName {
+		v.sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return nil
+}
+
+// HasManifest returns true when the top level manifest was added.
+func (v *Verifier) HasManifest() bool {
+	_, ok := v.manifests[ManifestFileName]
+	return ok
+}
+
+// Problems checks all added entries against all added manifests.
+func (v *Verifier) Problems() ([]Problem, error) {
+	top, ok := v.manifests[ManifestFileName]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", ManifestFileName)
+	}
+
+	var problems []Problem
+	names := make([]string, 0, len(v.manifests))
+	for name := range v.manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := v.manifests[name]
+		dir := path.Dir(name)
+		prefix := ""
+		if dir != "." {
+			prefix = dir + "/"
+		}
+		for _, entry := range m.Names() {
+			expected, _ := m.Sum(entry)
+			actual, ok := v.sums[prefix+entry]
+			switch {
+			case !ok:
+				problems = append(problems, Problem{Manifest: name, Entry: prefix + entry, Reason: "missing from bundle"})
+			case actual != expected:
+				problems = append(problems, Problem{Manifest: name, Entry: prefix + entry, Reason: "checksum mismatch"})
+			}
+		}
+	}
+
+	entries := make([]string, 0, len(v.sums))
+	for entry := range v.sums {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	for _, entry := range entries {
+		if _, ok := top.Sum(entry); !ok {
+			problems = append(problems, Problem{Manifest: ManifestFileName, Entry: entry, Reason: "not listed in manifest"})
+		}
+	}
+
+	return problems, nil
+}
+
+// VerifyZip checks every manifest found in the zip archive with a Verifier.
+func VerifyZip(r *zip.Reader) ([]Problem, error) {
+	v := NewVerifier()
+	if err := archive.WalkZip(r, v.Add); err != nil {
+		return nil, err
+	}
+	return v.Problems()
+}
+
+// VerifyArchive checks the bundle under the given path. Any format supported by archive package is accepted.
+func VerifyArchive(path string) ([]Problem, error) {
+	v := NewVerifier()
+	if err := archive.Walk(path, v.Add); err != nil {
+		return nil, err
+	}
+	return v.Problems()
+}
+
+// VerifyZipFile opens the zip file under the given path and checks it with VerifyZip.
+func VerifyZipFile(path string) ([]Problem, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %s", path, err)
+	}
+	defer r.Close()
+
+	return VerifyZip(&r.Reader)
+}
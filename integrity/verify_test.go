@@ -0,0 +1,136 @@
+package integrity
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-diagnostics/archive"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	name    string
+	content string
+}
+
+func buildZip(t *testing.T, entries []entry, manifests map[string][]entry) *zip.Reader {
+	buf := bytes.NewBuffer(nil)
+	w := zip.NewWriter(buf)
+	for _, e := range entries {
+		f, err := w.Create(e.name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	for name, listed := range manifests {
+		m := NewManifest()
+		for _, e := range listed {
+			_, err := m.Copy(e.name, bytes.NewBuffer(nil), strings.NewReader(e.content))
+			require.NoError(t, err)
+		}
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = m.WriteTo(f)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return r
+}
+
+func TestVerifyZip_ValidBundle(t *testing.T) {
+	entries := []entry{{"a.txt", "a"}, {"dir/b.txt", "b"}}
+	r := buildZip(t, entries, map[string][]entry{ManifestFileName: entries})
+
+	problems, err := VerifyZip(r)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestVerifyZip_WithoutManifest(t *testing.T) {
+	r := buildZip(t, []entry{{"a.txt", "a"}}, nil)
+
+	_, err := VerifyZip(r)
+	assert.EqualError(t, err, "bundle has no checksums.sha256")
+}
+
+func TestVerifyZip_TamperedBundle(t *testing.T) {
+	r := buildZip(t,
+		[]entry{{"a.txt", "modified"}, {"extra.txt", "x"}},
+		map[string][]entry{ManifestFileName: {{"a.txt", "a"}, {"removed.txt", "r"}}},
+	)
+
+	problems, err := VerifyZip(r)
+	require.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{Manifest: ManifestFileName, Entry: "a.txt", Reason: "checksum mismatch"},
+		{Manifest: ManifestFileName, Entry: "removed.txt", Reason: "missing from bundle"},
+		{Manifest: ManifestFileName, Entry: "extra.txt", Reason: "not listed in manifest"},
+	}, problems)
+}
+
+func TestVerifyZip_NestedManifests(t *testing.T) {
+	node := []entry{{"a.txt", "a"}}
+	nodeManifest := buildZip(t, nil, map[string][]entry{ManifestFileName: node})
+	rc, err := nodeManifest.File[0].Open()
+	require.NoError(t, err)
+	nodeManifestContent := bytes.NewBuffer(nil)
+	_, err = nodeManifestContent.ReadFrom(rc)
+	require.NoError(t, err)
+
+	top := []entry{
+		{"node/a.txt", "tampered"},
+		{"node/" + ManifestFileName, nodeManifestContent.String()},
+	}
+	r := buildZip(t, top, map[string][]entry{ManifestFileName: top})
+
+	problems, err := VerifyZip(r)
+	require.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{Manifest: "node/" + ManifestFileName, Entry: "node/a.txt", Reason: "checksum mismatch"},
+	}, problems)
+}
+
+func TestVerifyArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range archive.Formats {
+		t.Run(string(format), func(t *testing.T) {
+			path := filepath.Join(dir, "bundle"+format.Extension())
+			f, err := os.Create(path)
+			require.NoError(t, err)
+			w, err := archive.NewWriter(f, format)
+			require.NoError(t, err)
+
+			m := NewManifest()
+			entry, err := w.Create("a.txt")
+			require.NoError(t, err)
+			_, err = m.Copy("a.txt", entry, strings.NewReader("a"))
+			require.NoError(t, err)
+			m.Add("removed.txt", make([]byte, 32))
+			entry, err = w.Create(ManifestFileName)
+			require.NoError(t, err)
+			_, err = m.WriteTo(entry)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			require.NoError(t, f.Close())
+
+			problems, err := VerifyArchive(path)
+			require.NoError(t, err)
+			assert.Equal(t, []Problem{
+				{Manifest: ManifestFileName, Entry: "removed.txt", Reason: "missing from bundle"},
+			}, problems)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package io
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipReadCloser returns a reader of gzip compressed data read from r. Closing it closes r.
+func GzipReadCloser(r io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, r)
+		if e := gz.Close(); err == nil {
+			err = e
+		}
+		pw.CloseWithError(err)
+	}()
+	return &gzipReadCloser{PipeReader: pr, source: r}
+}
+
+type gzipReadCloser struct {
+	*io.PipeReader
+	source io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.PipeReader.Close()
+	return g.source.Close()
+}
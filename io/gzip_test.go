@@ -0,0 +1,28 @@
+package io
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipReadCloser(t *testing.T) {
+	content := strings.Repeat("data\n", 1000)
+	r := GzipReadCloser(ioutil.NopCloser(strings.NewReader(content)))
+
+	compressed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.True(t, len(compressed) < len(content))
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(decompressed))
+}
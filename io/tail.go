@@ -0,0 +1,121 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const tailChunkSize = 4096
+
+// tailPollInterval is how often a followed file is checked for new data.
+var tailPollInterval = 250 * time.Millisecond
+
+// TailFile returns a reader of the file under the given path that starts numFromTail lines before its end.
+// Zero numFromTail reads the whole file unless follow is set, then only data appended after the call is read.
+//
+// If follow is set, the reader waits for data appended to the file instead of returning io.EOF
+// until ctx is done. A truncated file is read again from its beginning.
+func TailFile(ctx context.Context, path string, numFromTail uint64, follow bool) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset int64
+	switch {
+	case numFromTail > 0:
+		offset, err = tailOffset(f, numFromTail)
+	case follow:
+		offset, err = f.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not find the end of %s: %s", path, err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not seek %s: %s", path, err)
+	}
+
+	if !follow {
+		return f, nil
+	}
+	return &followReader{ctx: ctx, f: f, offset: offset}, nil
+}
+
+// tailOffset returns the offset of the first of the last lines of the file.
+// Newline at the end of the file does not start a new line.
+func tailOffset(f *os.File, lines uint64) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := info.Size()
+
+	buf := make([]byte, tailChunkSize)
+	var found uint64
+	for pos := end; pos > 0; {
+		size := int64(len(buf))
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		if _, err := f.ReadAt(buf[:size], pos); err != nil {
+			return 0, err
+		}
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == end-1 {
+				continue
+			}
+			found++
+			if found == lines {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+type followReader struct {
+	ctx    context.Context
+	f      *os.File
+	offset int64
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		info, err := r.f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		if info.Size() < r.offset {
+			if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+				return 0, err
+			}
+			r.offset = 0
+			continue
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(tailPollInterval):
+		}
+	}
+}
+
+func (r *followReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,110 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "tail")
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func appendToFile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestTailFile(t *testing.T) {
+	long := strings.Repeat("x", 2*tailChunkSize) + "\n"
+	tests := []struct {
+		content  string
+		lines    uint64
+		expected string
+	}{
+		{"a\nb\nc\n", 0, "a\nb\nc\n"},
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\nc\n", 10, "a\nb\nc\n"},
+		{"", 1, ""},
+		{"a\n" + long + long, 2, long + long},
+		{"a\n" + long + long, 1, long},
+	}
+
+	for _, tt := range tests {
+		path := tempFile(t, tt.content)
+		r, err := TailFile(context.TODO(), path, tt.lines, false)
+		require.NoError(t, err)
+		out, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, string(out))
+		assert.NoError(t, r.Close())
+		os.Remove(path)
+	}
+}
+
+func TestTailFileMissing(t *testing.T) {
+	_, err := TailFile(context.TODO(), "not/existing/file", 0, true)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTailFileFollow(t *testing.T) {
+	tailPollInterval = 10 * time.Millisecond
+	path := tempFile(t, "a\nb\nc\n")
+	defer os.Remove(path)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	r, err := TailFile(ctx, path, 1, true)
+	require.NoError(t, err)
+	defer r.Close()
+	lines := bufio.NewReader(r)
+
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "c\n", line)
+
+	appendToFile(t, path, "d\n")
+	line, err = lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "d\n", line)
+
+	// truncated file is read from the beginning
+	require.NoError(t, ioutil.WriteFile(path, []byte("e\n"), 0600))
+	line, err = lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "e\n", line)
+
+	cancel()
+	_, err = lines.ReadString('\n')
+	assert.EqualError(t, err, "context canceled")
+}
+
+func TestTailFileFollowReadsOnlyNewData(t *testing.T) {
+	tailPollInterval = 10 * time.Millisecond
+	path := tempFile(t, "old\n")
+	defer os.Remove(path)
+
+	r, err := TailFile(context.TODO(), path, 0, true)
+	require.NoError(t, err)
+	defer r.Close()
+
+	appendToFile(t, path, "new\n")
+	line, err := bufio.NewReader(r).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", line)
+}
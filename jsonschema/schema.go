@@ -0,0 +1,233 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dcos/dcos-diagnostics/util"
+)
+
+const definitionsRef = "#/definitions/"
+
+// Schema is a JSON Schema. Only keywords needed to describe configs are supported:
+// type, properties, required, additionalProperties (boolean only), items, enum (strings only),
+// minimum, maximum, minLength, minItems, pattern and references to definitions.
+//
+// Object property names are matched case-insensitively, the same way encoding/json decodes
+// structs, so a document accepted by the schema decodes into the struct it describes.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Description          string             `json:"description"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            int                `json:"minLength"`
+	MinItems             int                `json:"minItems"`
+	Pattern              string             `json:"pattern"`
+	Definitions          map[string]*Schema `json:"definitions"`
+
+	pattern *regexp.Regexp
+	root    *Schema
+}
+
+// Compile parses the schema and checks that its patterns and references are valid.
+func Compile(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("could not parse schema: %s", err)
+	}
+	if err := s.compile(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Schema) compile(root *Schema) error {
+	s.root = root
+	if s.Ref != "" {
+		if _, err := s.resolve(); err != nil {
+			return err
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema pattern %q: %s", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	var children []*Schema
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	for _, d := range s.Definitions {
+		children = append(children, d)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+	for _, c := range children {
+		if err := c.compile(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) resolve() (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	if !strings.HasPrefix(s.Ref, definitionsRef) {
+		return nil, fmt.Errorf("unsupported schema reference %q", s.Ref)
+	}
+	def, ok := s.root.Definitions[strings.TrimPrefix(s.Ref, definitionsRef)]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema reference %q", s.Ref)
+	}
+	return def, nil
+}
+
+// Validate returns all problems found in the value, in the document order.
+func (s *Schema) Validate(v *Value) []Error {
+	var errs []Error
+	s.validate(v, "", &errs)
+	return errs
+}
+
+func (s *Schema) validate(v *Value, path string, errs *[]Error) {
+	s, err := s.resolve()
+	if err != nil {
+		*errs = append(*errs, Error{Offset: v.Offset, Path: path, Message: err.Error()})
+		return
+	}
+	report := func(format string, args ...interface{}) {
+		*errs = append(*errs, Error{Offset: v.Offset, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !hasType(v, s.Type) {
+		report("expected %s, got %s", s.Type, v.Kind)
+		return
+	}
+
+	switch v.Kind {
+	case String:
+		if len(v.String) < s.MinLength {
+			if v.String == "" {
+				report("must not be empty")
+			} else {
+				report("must be at least %d characters long", s.MinLength)
+			}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v.String) {
+			report("%q does not match %s", v.String, s.Pattern)
+		}
+		if len(s.Enum) > 0 && !util.IsInList(v.String, s.Enum) {
+			report("invalid value %q, must be one of: %s", v.String, strings.Join(s.Enum, ", "))
+		}
+	case Number:
+		f, _ := v.Number.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			report("%s is less than %g", v.Number, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			report("%s is greater than %g", v.Number, *s.Maximum)
+		}
+	case Array:
+		if len(v.Items) < s.MinItems {
+			report("must have at least %d items", s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range v.Items {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case Object:
+		s.validateObject(v, path, errs)
+	}
+}
+
+func (s *Schema) validateObject(v *Value, path string, errs *[]Error) {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := map[string]bool{}
+	for _, m := range v.Members {
+		memberPath := m.Key
+		if path != "" {
+			memberPath = path + "." + m.Key
+		}
+		report := func(format string, args ...interface{}) {
+			*errs = append(*errs, Error{Offset: m.KeyOffset, Path: memberPath, Message: fmt.Sprintf(format, args...)})
+		}
+
+		name, miscased, ok := findName(names, m.Key)
+		if miscased {
+			report("field must be spelled %s", name)
+			continue
+		}
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				report("unknown field, must be one of: %s", strings.Join(names, ", "))
+			}
+			continue
+		}
+		if seen[name] {
+			report("duplicate field %s", name)
+			continue
+		}
+		seen[name] = true
+		s.Properties[name].validate(m.Value, memberPath, errs)
+	}
+
+	for _, name := range s.Required {
+		if !seen[name] {
+			*errs = append(*errs, Error{Offset: v.Offset, Path: path, Message: fmt.Sprintf("missing required field %s", name)})
+		}
+	}
+}
+
+// findName returns the property name matching the key. Keys are matched case-sensitively, a key differing from
+// a property name only in case is returned as miscased because encoding/json would still decode it into the field.
+func findName(names []string, key string) (name string, miscased bool, ok bool) {
+	for _, name := range names {
+		if name == key {
+			return name, false, true
+		}
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return name, true, false
+		}
+	}
+	return "", false, false
+}
+
+func hasType(v *Value, t string) bool {
+	switch t {
+	case "integer":
+		if v.Kind != Number {
+			return false
+		}
+		f, err := v.Number.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		return v.Kind == Number
+	case "boolean":
+		return v.Kind == Bool
+	}
+	return v.Kind.String() == t
+}
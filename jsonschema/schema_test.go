@@ -0,0 +1,94 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["Name", "Items"],
+  "properties": {
+    "Name": {"type": "string", "minLength": 1, "pattern": "^[a-z]"},
+    "Port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "Items": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/item"}}
+  },
+  "definitions": {
+    "item": {"type": "string", "enum": ["a", "b"]}
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(testSchema))
+	require.NoError(t, err)
+
+	v, err := Parse([]byte(`{"Name": "ok", "Port": 1050, "Items": ["a", "b"]}`))
+	require.NoError(t, err)
+	assert.Empty(t, schema.Validate(v))
+
+	v, err = Parse([]byte(`{"name": "ok", "Port": 1050, "Items": ["a", "b"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Error{
+		{Offset: 1, Path: "name", Message: "field must be spelled Name"},
+		{Message: "missing required field Name"},
+	}, schema.Validate(v))
+
+	data := []byte(`{"Name": "Bad", "Port": 1.5, "Items": ["a", "c", 1], "Other": 1, "name": "x"}`)
+	v, err = Parse(data)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, e := range schema.Validate(v) {
+		messages = append(messages, e.Error())
+	}
+	assert.Equal(t, []string{
+		`Name: "Bad" does not match ^[a-z]`,
+		`Port: expected integer, got number`,
+		`Items[1]: invalid value "c", must be one of: a, b`,
+		`Items[2]: expected string, got number`,
+		`Other: unknown field, must be one of: Items, Name, Port`,
+		`name: field must be spelled Name`,
+	}, messages)
+
+	v, err = Parse([]byte(`{"Name": "", "Port": 0, "Items": []}`))
+	require.NoError(t, err)
+	messages = nil
+	for _, e := range schema.Validate(v) {
+		messages = append(messages, e.Error())
+	}
+	assert.Equal(t, []string{
+		`Name: must not be empty`,
+		`Name: "" does not match ^[a-z]`,
+		`Port: 0 is less than 1`,
+		`Items: must have at least 1 items`,
+	}, messages)
+
+	v, err = Parse([]byte(`{"Name": "ok", "Items": ["a"], "Name": "x"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Error{{Offset: 31, Path: "Name", Message: "duplicate field Name"}}, schema.Validate(v))
+
+	v, err = Parse([]byte(`[]`))
+	require.NoError(t, err)
+	assert.Equal(t, []Error{{Message: "expected object, got array"}}, schema.Validate(v))
+
+	v, err = Parse([]byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Error{
+		{Message: "missing required field Name"},
+		{Message: "missing required field Items"},
+	}, schema.Validate(v))
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := Compile([]byte(`{"items": {"$ref": "#/definitions/missing"}}`))
+	assert.EqualError(t, err, `unknown schema reference "#/definitions/missing"`)
+
+	_, err = Compile([]byte(`{"pattern": "("}`))
+	assert.EqualError(t, err, "invalid schema pattern \"(\": error parsing regexp: missing closing ): `(`")
+
+	_, err = Compile([]byte(`{`))
+	assert.EqualError(t, err, "could not parse schema: unexpected end of JSON input")
+}
@@ -0,0 +1,187 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const unexpectedEnd = "unexpected end of JSON input"
+
+// Kind is a type of JSON value.
+type Kind int
+
+// JSON value kinds
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+func (k Kind) String() string {
+	return [...]string{"null", "boolean", "number", "string", "array", "object"}[k]
+}
+
+// Value is a decoded JSON value that remembers where it starts in the document,
+// so problems found in it could be reported with their location.
+type Value struct {
+	Kind   Kind
+	Offset int64 // offset of the first byte of the value in the document
+
+	Bool    bool
+	Number  json.Number
+	String  string
+	Items   []*Value
+	Members []Member // object members in the document order
+}
+
+// Member is a single key and value of an object.
+type Member struct {
+	Key       string
+	KeyOffset int64
+	Value     *Value
+}
+
+// Error is a problem found at the given offset of a document.
+// Path points to the value in the document e.g., HTTPEndpoints[0].Port
+type Error struct {
+	Offset  int64
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Parse decodes a single JSON document.
+func Parse(data []byte) (*Value, error) {
+	p := &parser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	offset := p.start()
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, Error{Offset: offset, Message: "unexpected data after the top-level value"}
+	}
+	return v, nil
+}
+
+// Position returns 1-based line and column of the offset in the document.
+func Position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+type parser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// start returns the offset of the next token. Decoder reports the offset right after
+// the previous token, so separators and white space are skipped.
+func (p *parser) start() int64 {
+	offset := p.dec.InputOffset()
+	for ; offset < int64(len(p.data)); offset++ {
+		switch p.data[offset] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (p *parser) token() (json.Token, int64, error) {
+	offset := p.start()
+	token, err := p.dec.Token()
+	if err == io.EOF {
+		return nil, offset, Error{Offset: int64(len(p.data)), Message: unexpectedEnd}
+	}
+	if err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			errOffset := int64(len(p.data))
+			if syntaxErr.Error() != unexpectedEnd {
+				// syntax error offset points right after the invalid character
+				errOffset = syntaxErr.Offset - 1
+			}
+			return nil, offset, Error{Offset: errOffset, Message: syntaxErr.Error()}
+		}
+		return nil, offset, Error{Offset: offset, Message: err.Error()}
+	}
+	return token, offset, nil
+}
+
+func (p *parser) value() (*Value, error) {
+	token, offset, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	v := &Value{Offset: offset}
+
+	switch t := token.(type) {
+	case nil:
+		v.Kind = Null
+	case bool:
+		v.Kind, v.Bool = Bool, t
+	case json.Number:
+		v.Kind, v.Number = Number, t
+	case string:
+		v.Kind, v.String = String, t
+	case json.Delim:
+		if t == '[' {
+			v.Kind = Array
+			for p.dec.More() {
+				item, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				v.Items = append(v.Items, item)
+			}
+		} else {
+			v.Kind = Object
+			for p.dec.More() {
+				key, keyOffset, err := p.token()
+				if err != nil {
+					return nil, err
+				}
+				member, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				v.Members = append(v.Members, Member{Key: key.(string), KeyOffset: keyOffset, Value: member})
+			}
+		}
+		// closing delimiter
+		if _, _, err := p.token(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Member returns the value of the object member with the given key. Keys are matched
+// case-sensitively.
+func (v *Value) Member(key string) *Value {
+	for _, m := range v.Members {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return nil
+}
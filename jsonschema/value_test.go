@@ -0,0 +1,71 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`{
+  "a": [1, "x", true, null],
+  "B": {"c": 2.5}
+}`)
+	v, err := Parse(data)
+	require.NoError(t, err)
+
+	require.Equal(t, Object, v.Kind)
+	require.Len(t, v.Members, 2)
+	assert.Equal(t, "a", v.Members[0].Key)
+	assert.Equal(t, int64(4), v.Members[0].KeyOffset)
+
+	assert.Nil(t, v.Member("A"))
+	a := v.Member("a")
+	require.NotNil(t, a)
+	require.Equal(t, Array, a.Kind)
+	require.Len(t, a.Items, 4)
+	assert.Equal(t, json.Number("1"), a.Items[0].Number)
+	assert.Equal(t, "x", a.Items[1].String)
+	assert.True(t, a.Items[2].Bool)
+	assert.Equal(t, Null, a.Items[3].Kind)
+
+	line, column := Position(data, a.Items[1].Offset)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 12, column)
+
+	assert.Nil(t, v.Member("b"))
+	c := v.Member("B").Member("c")
+	line, column = Position(data, c.Offset)
+	assert.Equal(t, 3, line)
+	assert.Equal(t, 14, column)
+
+	assert.Nil(t, v.Member("d"))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		data   string
+		err    string
+		line   int
+		column int
+	}{
+		{"", "unexpected end of JSON input", 1, 1},
+		{"{\n  \"a\": 1,\n  invalid\n}", "invalid character 'i' looking for beginning of value", 3, 3},
+		{"[1, 2", "unexpected end of JSON input", 1, 6},
+		{"[x", "invalid character 'x' looking for beginning of value", 1, 2},
+		{"{} []", "unexpected data after the top-level value", 1, 4},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.data))
+		require.Error(t, err, tt.data)
+		e, ok := err.(Error)
+		require.True(t, ok, tt.data)
+		assert.Equal(t, tt.err, e.Message, tt.data)
+		line, column := Position([]byte(tt.data), e.Offset)
+		assert.Equal(t, tt.line, line, tt.data)
+		assert.Equal(t, tt.column, column, tt.data)
+	}
+}
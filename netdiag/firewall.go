@@ -0,0 +1,100 @@
+package netdiag
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Chain is a summary of an iptables chain
+type Chain struct {
+	Table  string `json:"table"`
+	Chain  string `json:"chain"`
+	Policy string `json:"policy,omitempty"`
+	Rules  int    `json:"rules"`
+}
+
+// ParseIPTablesSave returns chains found in the iptables-save output with the number of their rules.
+// Chains are in the order of the output.
+func ParseIPTablesSave(output []byte) ([]Chain, error) {
+	var chains []Chain
+	index := map[string]int{}
+	table := ""
+	key := func(chain string) string { return table + "/" + chain }
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || line == "COMMIT":
+		case strings.HasPrefix(line, "*"):
+			table = strings.TrimPrefix(line, "*")
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(strings.TrimPrefix(line, ":"))
+			c := Chain{Table: table, Chain: fields[0]}
+			if len(fields) > 1 && fields[1] != "-" {
+				c.Policy = fields[1]
+			}
+			index[key(c.Chain)] = len(chains)
+			chains = append(chains, c)
+		case strings.HasPrefix(line, "-A "):
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid iptables rule %q", line)
+			}
+			i, ok := index[key(fields[1])]
+			if !ok {
+				return nil, fmt.Errorf("rule of undeclared chain %s in table %s", fields[1], table)
+			}
+			chains[i].Rules++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read iptables-save output: %s", err)
+	}
+	return chains, nil
+}
+
+// IPVS is a summary of IPVS virtual servers used by dcos-net for VIPs
+type IPVS struct {
+	Services            int `json:"services"`
+	Destinations        int `json:"destinations"`
+	ActiveConnections   int `json:"active_connections"`
+	InactiveConnections int `json:"inactive_connections"`
+}
+
+// ReadIPVS returns the summary of the IPVS table, usually /proc/net/ip_vs
+func ReadIPVS(file string) (*IPVS, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	ipvs := &IPVS{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "TCP", "UDP", "SCTP", "FWM":
+			ipvs.Services++
+		case "->":
+			// the header line "-> RemoteAddress:Port Forward Weight ActiveConn InActConn" is skipped
+			if len(fields) < 6 || fields[1] == "RemoteAddress:Port" {
+				continue
+			}
+			var active, inactive int
+			if _, err := fmt.Sscan(fields[4]+" "+fields[5], &active, &inactive); err != nil {
+				return nil, fmt.Errorf("could not parse %s: %s", file, err)
+			}
+			ipvs.Destinations++
+			ipvs.ActiveConnections += active
+			ipvs.InactiveConnections += inactive
+		}
+	}
+	return ipvs, nil
+}
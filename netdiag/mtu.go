@@ -0,0 +1,61 @@
+package netdiag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VXLANOverhead is the number of bytes VXLAN encapsulation adds to every packet of the overlay
+const VXLANOverhead = 50
+
+// OverlayInterfaces are interfaces of the DC/OS overlay network: the VXLAN endpoint and the Docker
+// and Mesos bridges
+var OverlayInterfaces = []string{"vtep1024", "d-dcos", "m-dcos"}
+
+// Interface is a network interface with its MTU
+type Interface struct {
+	Name      string `json:"name"`
+	MTU       int    `json:"mtu"`
+	OperState string `json:"operstate,omitempty"`
+}
+
+// PathMTU is MTU of the path to the host known to the kernel
+type PathMTU struct {
+	Host  string `json:"host"`
+	MTU   int    `json:"mtu,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// OverlayMTUWarnings returns warnings about overlay interfaces with MTU too large to fit encapsulated packets
+// into MTU of the interfaces of default routes
+func OverlayMTUWarnings(interfaces []Interface, routes []Route) []string {
+	mtu := map[string]int{}
+	for _, i := range interfaces {
+		mtu[i.Name] = i.MTU
+	}
+	uplinks := map[string]bool{}
+	for _, r := range routes {
+		if r.Default() {
+			uplinks[r.Interface] = true
+		}
+	}
+
+	var warnings []string
+	for uplink := range uplinks {
+		uplinkMTU, ok := mtu[uplink]
+		if !ok {
+			continue
+		}
+		for _, overlay := range OverlayInterfaces {
+			overlayMTU, ok := mtu[overlay]
+			if !ok || overlayMTU+VXLANOverhead <= uplinkMTU {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf(
+				"MTU %d of %s exceeds MTU %d of %s minus %d bytes of VXLAN overhead, large packets will be dropped",
+				overlayMTU, overlay, uplinkMTU, uplink, VXLANOverhead))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
@@ -0,0 +1,250 @@
+// Package netdiag diagnoses networking of a DC/OS node: resolution of the well-known DNS names, TCP reachability
+// of masters and agents, MTU of the overlay, routing tables and iptables and IPVS summaries.
+package netdiag
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+
+	"github.com/dcos/dcos-diagnostics/sysinfo"
+)
+
+// Defaults of the Diagnoser
+const (
+	DefaultProbeTimeout = 3 * time.Second
+	DefaultAgentSample  = 10
+)
+
+// DefaultNames are DNS names resolved on every node
+var DefaultNames = []string{
+	dcos.DNSRecordLeader,
+	dcos.DNSRecordMasters,
+	dcos.DNSRecordMarathonLeader,
+	"ready.spartan",
+}
+
+// Port is a TCP port of a DC/OS service
+type Port struct {
+	Number  int
+	Service string
+}
+
+// MasterPorts are probed on every master
+var MasterPorts = []Port{
+	{dcos.PortAdminrouterHTTP, "adminrouter"},
+	{443, "adminrouter-tls"},
+	{2181, "zookeeper"},
+	{dcos.PortMesosMaster, "mesos-master"},
+	{dcos.PortMarathonHTTP, "marathon"},
+	{dcos.PortMesosDNS, "mesos-dns"},
+	{dcos.PortExhibitor, "exhibitor"},
+}
+
+// AgentPorts are probed on sampled agents
+var AgentPorts = []Port{
+	{dcos.PortMesosAgent, "mesos-agent"},
+	{61001, "adminrouter-agent"},
+}
+
+// Peers returns IPs of masters and agents of the cluster
+type Peers func() (masters []string, agents []string, err error)
+
+// Node identifies the node the report was made on
+type Node struct {
+	IP       string `json:"ip,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// Report is the result of network diagnostics of the node. Sections that could not be diagnosed are reported
+// in Errors with the section name as a key.
+type Report struct {
+	Time        time.Time         `json:"time"`
+	Node        Node              `json:"node"`
+	DNS         []Lookup          `json:"dns,omitempty"`
+	Probes      []Probe           `json:"probes,omitempty"`
+	Interfaces  []Interface       `json:"interfaces,omitempty"`
+	PathMTU     []PathMTU         `json:"path_mtu,omitempty"`
+	MTUWarnings []string          `json:"mtu_warnings,omitempty"`
+	Routes      []Route           `json:"routes,omitempty"`
+	IPTables    []Chain           `json:"iptables,omitempty"`
+	IPVS        *IPVS             `json:"ipvs,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// Diagnoser runs network diagnostics of the node
+type Diagnoser struct {
+	// Node identifies the node, its IP is not probed
+	Node Node
+	// Peers returns nodes to probe
+	Peers Peers
+	// Names are DNS names to resolve
+	Names []string
+	// AgentSample is the number of randomly chosen agents to probe, all agents are probed if it is negative
+	AgentSample int
+	// ProbeTimeout limits a single TCP connect
+	ProbeTimeout time.Duration
+	// ProcRoot is where procfs is mounted, usually /proc
+	ProcRoot string
+	// SysRoot is where sysfs is mounted, usually /sys
+	SysRoot string
+	// Resolver resolves DNS names
+	Resolver *net.Resolver
+	// Dial connects to probed targets
+	Dial DialFunc
+	// IPTablesSave returns the output of iptables-save
+	IPTablesSave func(ctx context.Context) ([]byte, error)
+}
+
+// NewDiagnoser returns Diagnoser of the host with default settings
+func NewDiagnoser(node Node, peers Peers) *Diagnoser {
+	return &Diagnoser{
+		Node:         node,
+		Peers:        peers,
+		Names:        DefaultNames,
+		AgentSample:  DefaultAgentSample,
+		ProbeTimeout: DefaultProbeTimeout,
+		ProcRoot:     "/proc",
+		SysRoot:      "/sys",
+		Resolver:     net.DefaultResolver,
+		Dial:         (&net.Dialer{}).DialContext,
+		IPTablesSave: func(ctx context.Context) ([]byte, error) {
+			return exec.CommandContext(ctx, "iptables-save").Output()
+		},
+	}
+}
+
+// Run returns the report of the node. It fails only if the context is done, sections that could not be
+// diagnosed are reported in the report.
+func (d *Diagnoser) Run(ctx context.Context) (*Report, error) {
+	r := &Report{Time: time.Now().UTC(), Node: d.Node, Errors: map[string]string{}}
+
+	sections := []struct {
+		name string
+		run  func() error
+	}{
+		{"dns", func() error { r.DNS = LookupNames(ctx, d.Resolver, d.Names); return nil }},
+		{"probes", func() (err error) { r.Probes, err = d.probes(ctx); return }},
+		{"routes", func() (err error) { r.Routes, err = ReadRoutes(d.ProcRoot); return }},
+		{"interfaces", func() (err error) { r.Interfaces, err = d.interfaces(); return }},
+		{"path_mtu", func() error { r.PathMTU = d.pathMTU(r.Probes); return nil }},
+		{"iptables", func() (err error) { r.IPTables, err = d.iptables(ctx); return }},
+		{"ipvs", func() (err error) { r.IPVS, err = ReadIPVS(filepath.Join(d.ProcRoot, "net", "ip_vs")); return }},
+	}
+	for _, section := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := section.run(); err != nil {
+			r.Errors[section.name] = err.Error()
+		}
+	}
+	r.MTUWarnings = OverlayMTUWarnings(r.Interfaces, r.Routes)
+	if len(r.Errors) == 0 {
+		r.Errors = nil
+	}
+	return r, nil
+}
+
+// Targets returns masters' ports and ports of a sample of agents to probe from the node
+func (d *Diagnoser) Targets() ([]Target, error) {
+	if d.Peers == nil {
+		return nil, nil
+	}
+	masters, agents, err := d.Peers()
+	if err != nil {
+		return nil, err
+	}
+	agents = sample(agents, d.AgentSample)
+
+	var targets []Target
+	add := func(hosts []string, role string, ports []Port) {
+		for _, host := range hosts {
+			if host == d.Node.IP {
+				continue
+			}
+			for _, port := range ports {
+				targets = append(targets, Target{Host: host, Port: port.Number, Role: role, Service: port.Service})
+			}
+		}
+	}
+	add(masters, "master", MasterPorts)
+	add(agents, "agent", AgentPorts)
+	return targets, nil
+}
+
+func (d *Diagnoser) probes(ctx context.Context) ([]Probe, error) {
+	targets, err := d.Targets()
+	if err != nil {
+		return nil, err
+	}
+	return ProbeTargets(ctx, d.Dial, targets, d.ProbeTimeout), nil
+}
+
+func (d *Diagnoser) interfaces() ([]Interface, error) {
+	reader := &sysinfo.Reader{ProcRoot: d.ProcRoot, SysRoot: d.SysRoot}
+	netInterfaces, err := reader.NetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	interfaces := make([]Interface, 0, len(netInterfaces))
+	for _, i := range netInterfaces {
+		interfaces = append(interfaces, Interface{Name: i.Name, MTU: i.MTU, OperState: i.OperState})
+	}
+	return interfaces, nil
+}
+
+// pathMTU returns MTU of paths to every probed host
+func (d *Diagnoser) pathMTU(probes []Probe) []PathMTU {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, p := range probes {
+		if !seen[p.Host] {
+			seen[p.Host] = true
+			hosts = append(hosts, p.Host)
+		}
+	}
+	sort.Strings(hosts)
+	result := make([]PathMTU, 0, len(hosts))
+	for _, host := range hosts {
+		p := PathMTU{Host: host}
+		mtu, err := ProbePathMTU(host)
+		if err != nil {
+			p.Error = err.Error()
+		} else {
+			p.MTU = mtu
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func (d *Diagnoser) iptables(ctx context.Context) ([]Chain, error) {
+	if d.IPTablesSave == nil {
+		return nil, nil
+	}
+	output, err := d.IPTablesSave(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIPTablesSave(output)
+}
+
+// sample returns n randomly chosen elements of s or s if it is not longer than n or n is negative
+func sample(s []string, n int) []string {
+	if n < 0 || len(s) <= n {
+		return s
+	}
+	shuffled := append([]string(nil), s...)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	shuffled = shuffled[:n]
+	sort.Strings(shuffled)
+	return shuffled
+}
@@ -0,0 +1,181 @@
+package netdiag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiagnoser() *Diagnoser {
+	return &Diagnoser{
+		Node: Node{IP: "10.0.0.1", Role: "master"},
+		Peers: func() ([]string, []string, error) {
+			return []string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.1.1", "10.0.1.2", "10.0.1.3"}, nil
+		},
+		AgentSample:  2,
+		ProbeTimeout: time.Second,
+		ProcRoot:     filepath.Join("testdata", "proc"),
+		SysRoot:      filepath.Join("testdata", "sys"),
+		Resolver: &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("no DNS in tests")
+		}},
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "10.0.0.2:5050" {
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			}
+			return nil, fmt.Errorf("dial %s %s: connection refused", network, address)
+		},
+		IPTablesSave: func(context.Context) ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join("testdata", "iptables-save.txt"))
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	d := testDiagnoser()
+	d.Names = []string{"leader.mesos.invalid"}
+
+	r, err := d.Run(context.TODO())
+	require.NoError(t, err)
+
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, Node{IP: "10.0.0.1", Role: "master"}, r.Node)
+
+	require.Len(t, r.DNS, 1)
+	assert.Equal(t, "leader.mesos.invalid", r.DNS[0].Name)
+	assert.NotEmpty(t, r.DNS[0].Error)
+
+	require.Len(t, r.Probes, len(MasterPorts)+2*len(AgentPorts))
+	reachable := 0
+	for _, p := range r.Probes {
+		assert.NotEqual(t, "10.0.0.1", p.Host, "the node itself must not be probed")
+		if p.Reachable {
+			reachable++
+			assert.Equal(t, Target{Host: "10.0.0.2", Port: 5050, Role: "master", Service: "mesos-master"}, p.Target)
+		} else {
+			assert.Contains(t, p.Error, "connection refused")
+		}
+	}
+	assert.Equal(t, 1, reachable)
+	assert.Len(t, r.PathMTU, 3)
+
+	assert.Equal(t, []Interface{
+		{Name: "d-dcos", MTU: 1420, OperState: "up"},
+		{Name: "eth0", MTU: 1500, OperState: "up"},
+		{Name: "vtep1024", MTU: 1500, OperState: "unknown"},
+	}, r.Interfaces)
+	assert.Equal(t, []string{
+		"MTU 1500 of vtep1024 exceeds MTU 1500 of eth0 minus 50 bytes of VXLAN overhead, large packets will be dropped",
+	}, r.MTUWarnings)
+	assert.Len(t, r.Routes, 5)
+	assert.Len(t, r.IPTables, 6)
+	assert.Equal(t, &IPVS{Services: 2, Destinations: 3, ActiveConnections: 3, InactiveConnections: 7}, r.IPVS)
+}
+
+func TestRunReportsFailedSections(t *testing.T) {
+	d := testDiagnoser()
+	d.Names = nil
+	d.ProcRoot = "not-existing"
+	d.Peers = func() ([]string, []string, error) { return nil, nil, errors.New("mesos is down") }
+	d.IPTablesSave = func(context.Context) ([]byte, error) { return nil, errors.New("iptables-save not found") }
+
+	r, err := d.Run(context.TODO())
+	require.NoError(t, err)
+
+	assert.Equal(t, "mesos is down", r.Errors["probes"])
+	assert.Equal(t, "iptables-save not found", r.Errors["iptables"])
+	assert.Contains(t, r.Errors, "routes")
+	assert.Contains(t, r.Errors, "ipvs")
+	assert.NotContains(t, r.Errors, "interfaces")
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := testDiagnoser().Run(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTargetsSampleAgents(t *testing.T) {
+	d := testDiagnoser()
+	d.AgentSample = -1
+
+	targets, err := d.Targets()
+	require.NoError(t, err)
+	assert.Len(t, targets, len(MasterPorts)+3*len(AgentPorts))
+	assert.Equal(t, Target{Host: "10.0.0.2", Port: 80, Role: "master", Service: "adminrouter"}, targets[0])
+	assert.Equal(t, Target{Host: "10.0.1.3", Port: 61001, Role: "agent", Service: "adminrouter-agent"}, targets[len(targets)-1])
+}
+
+func TestProbeTargets(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := l2.Addr().(*net.TCPAddr).Port
+	l2.Close()
+
+	probes := ProbeTargets(context.TODO(), (&net.Dialer{}).DialContext, []Target{
+		{Host: "127.0.0.1", Port: port},
+		{Host: "127.0.0.1", Port: closedPort},
+	}, time.Second)
+
+	require.Len(t, probes, 2)
+	assert.True(t, probes[0].Reachable)
+	assert.Empty(t, probes[0].Error)
+	assert.False(t, probes[1].Reachable)
+	assert.NotEmpty(t, probes[1].Error)
+}
+
+func TestReadRoutes(t *testing.T) {
+	routes, err := ReadRoutes(filepath.Join("testdata", "proc"))
+	require.NoError(t, err)
+	assert.Equal(t, []Route{
+		{Interface: "eth0", Destination: "0.0.0.0/0", Gateway: "10.0.0.1", Metric: 100},
+		{Interface: "eth0", Destination: "10.0.0.0/16", Metric: 100},
+		{Interface: "d-dcos", Destination: "9.255.0.0/24"},
+		{Interface: "eth0", Destination: "fe80::/64", Metric: 256},
+		{Interface: "eth0", Destination: "::/0", Gateway: "fe80::1", Metric: 1024},
+	}, routes)
+	assert.True(t, routes[0].Default())
+	assert.False(t, routes[1].Default())
+	assert.True(t, routes[4].Default())
+}
+
+func TestParseIPTablesSave(t *testing.T) {
+	output, err := ioutil.ReadFile(filepath.Join("testdata", "iptables-save.txt"))
+	require.NoError(t, err)
+
+	chains, err := ParseIPTablesSave(output)
+	require.NoError(t, err)
+	assert.Equal(t, []Chain{
+		{Table: "nat", Chain: "PREROUTING", Policy: "ACCEPT", Rules: 1},
+		{Table: "nat", Chain: "POSTROUTING", Policy: "ACCEPT", Rules: 2},
+		{Table: "nat", Chain: "DOCKER"},
+		{Table: "filter", Chain: "INPUT", Policy: "ACCEPT"},
+		{Table: "filter", Chain: "FORWARD", Policy: "DROP", Rules: 1},
+		{Table: "filter", Chain: "DOCKER"},
+	}, chains)
+
+	_, err = ParseIPTablesSave([]byte("*filter\n-A INPUT -j ACCEPT\n"))
+	assert.EqualError(t, err, "rule of undeclared chain INPUT in table filter")
+}
+
+func TestOverlayMTUWarnings(t *testing.T) {
+	routes := []Route{{Interface: "eth0", Destination: "0.0.0.0/0"}}
+	assert.Empty(t, OverlayMTUWarnings([]Interface{{Name: "eth0", MTU: 9001}, {Name: "vtep1024", MTU: 8951}}, routes))
+	assert.Len(t, OverlayMTUWarnings([]Interface{{Name: "eth0", MTU: 1500}, {Name: "m-dcos", MTU: 1500}}, routes), 1)
+	assert.Empty(t, OverlayMTUWarnings([]Interface{{Name: "eth1", MTU: 1500}, {Name: "vtep1024", MTU: 1500}}, routes))
+}
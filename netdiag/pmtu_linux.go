@@ -0,0 +1,36 @@
+package netdiag
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProbePathMTU returns MTU of the path to the host known to the kernel. It connects a UDP socket with
+// fragmentation disabled, so no packets are sent, and reads its IP_MTU option.
+func ProbePathMTU(host string) (int, error) {
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("could not probe path MTU: %s is not an IPv4 address", host)
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not create socket: %s", err)
+	}
+	defer unix.Close(fd)
+
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_DO); err != nil {
+		return 0, fmt.Errorf("could not disable fragmentation: %s", err)
+	}
+	addr := &unix.SockaddrInet4{Port: 9}
+	copy(addr.Addr[:], ip)
+	if err := unix.Connect(fd, addr); err != nil {
+		return 0, fmt.Errorf("could not connect to %s: %s", host, err)
+	}
+	mtu, err := unix.GetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MTU)
+	if err != nil {
+		return 0, fmt.Errorf("could not get path MTU to %s: %s", host, err)
+	}
+	return mtu, nil
+}
@@ -0,0 +1,17 @@
+package netdiag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbePathMTU(t *testing.T) {
+	mtu, err := ProbePathMTU("127.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, mtu > 0)
+
+	_, err = ProbePathMTU("::1")
+	assert.Error(t, err)
+}
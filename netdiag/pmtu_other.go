@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package netdiag
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ProbePathMTU is supported only on Linux
+func ProbePathMTU(host string) (int, error) {
+	return 0, errors.New("probing path MTU is not supported on " + runtime.GOOS)
+}
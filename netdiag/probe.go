@@ -0,0 +1,103 @@
+package netdiag
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Target is a TCP service probed from the node
+type Target struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Role    string `json:"role,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// Address returns host:port of the target
+func (t Target) Address() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// Probe is the result of a TCP connect to the target
+type Probe struct {
+	Target
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Lookup is the result of resolving a DNS name
+type Lookup struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses,omitempty"`
+	LatencyMs float64  `json:"latency_ms"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// DialFunc connects to the address
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// probeConcurrency limits the number of connections opened at the same time
+const probeConcurrency = 16
+
+// ProbeTargets connects to every target and measures how long it took. Results are in the order of targets.
+func ProbeTargets(ctx context.Context, dial DialFunc, targets []Target, timeout time.Duration) []Probe {
+	probes := make([]Probe, len(targets))
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t Target) {
+			defer func() { <-sem; wg.Done() }()
+			probes[i] = probe(ctx, dial, t, timeout)
+		}(i, t)
+	}
+	wg.Wait()
+	return probes
+}
+
+func probe(ctx context.Context, dial DialFunc, t Target, timeout time.Duration) Probe {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	p := Probe{Target: t}
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", t.Address())
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	p.LatencyMs = milliseconds(time.Since(start))
+	p.Reachable = true
+	conn.Close()
+	return p
+}
+
+// LookupNames resolves the names with the resolver. Results are ordered by name.
+func LookupNames(ctx context.Context, resolver *net.Resolver, names []string) []Lookup {
+	lookups := make([]Lookup, 0, len(names))
+	for _, name := range names {
+		l := Lookup{Name: name}
+		start := time.Now()
+		addresses, err := resolver.LookupHost(ctx, name)
+		l.LatencyMs = milliseconds(time.Since(start))
+		if err != nil {
+			l.Error = err.Error()
+		} else {
+			sort.Strings(addresses)
+			l.Addresses = addresses
+		}
+		lookups = append(lookups, l)
+	}
+	sort.Slice(lookups, func(i, j int) bool { return lookups[i].Name < lookups[j].Name })
+	return lookups
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,154 @@
+package netdiag
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Route is an entry of the kernel routing table
+type Route struct {
+	Interface   string `json:"interface"`
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway,omitempty"`
+	Metric      uint64 `json:"metric"`
+}
+
+// Default returns true for the default route
+func (r Route) Default() bool {
+	return r.Destination == "0.0.0.0/0" || r.Destination == "::/0"
+}
+
+// ReadRoutes returns IPv4 and IPv6 routes read from procfs. IPv6 routes are skipped if IPv6 is disabled.
+func ReadRoutes(procRoot string) ([]Route, error) {
+	routes, err := readRoutes(filepath.Join(procRoot, "net", "route"), parseRoute)
+	if err != nil {
+		return nil, err
+	}
+	routes6, err := readRoutes(filepath.Join(procRoot, "net", "ipv6_route"), parseRoute6)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return append(routes, routes6...), nil
+}
+
+func readRoutes(file string, parse func(fields []string) (*Route, error)) ([]Route, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var routes []Route
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "Iface" {
+			continue
+		}
+		route, err := parse(fields)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %s", file, err)
+		}
+		if route != nil {
+			routes = append(routes, *route)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", file, err)
+	}
+	return routes, nil
+}
+
+// parseRoute parses a line of /proc/net/route:
+// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+func parseRoute(fields []string) (*Route, error) {
+	if len(fields) < 8 {
+		return nil, fmt.Errorf("expected at least 8 fields, got %d", len(fields))
+	}
+	destination, err := parseIPv4(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := parseIPv4(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	mask, err := parseIPv4(fields[7])
+	if err != nil {
+		return nil, err
+	}
+	metric, err := strconv.ParseUint(fields[6], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	ones, _ := net.IPMask(mask.To4()).Size()
+	route := &Route{
+		Interface:   fields[0],
+		Destination: fmt.Sprintf("%s/%d", destination, ones),
+		Metric:      metric,
+	}
+	if !gateway.IsUnspecified() {
+		route.Gateway = gateway.String()
+	}
+	return route, nil
+}
+
+// parseIPv4 parses an address written as a little-endian hex number
+func parseIPv4(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != net.IPv4len {
+		return nil, fmt.Errorf("invalid IPv4 address %q", s)
+	}
+	return net.IPv4(b[3], b[2], b[1], b[0]), nil
+}
+
+// parseRoute6 parses a line of /proc/net/ipv6_route:
+// Destination PrefixLength Source PrefixLength NextHop Metric RefCnt Use Flags Iface
+// Loopback routes are skipped.
+func parseRoute6(fields []string) (*Route, error) {
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("expected at least 10 fields, got %d", len(fields))
+	}
+	if fields[9] == "lo" {
+		return nil, nil
+	}
+	destination, err := parseIPv6(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := strconv.ParseUint(fields[1], 16, 8)
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := parseIPv6(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	metric, err := strconv.ParseUint(fields[5], 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	route := &Route{
+		Interface:   fields[9],
+		Destination: fmt.Sprintf("%s/%d", destination, prefix),
+		Metric:      metric,
+	}
+	if !gateway.IsUnspecified() {
+		route.Gateway = gateway.String()
+	}
+	return route, nil
+}
+
+func parseIPv6(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != net.IPv6len {
+		return nil, fmt.Errorf("invalid IPv6 address %q", s)
+	}
+	return net.IP(b), nil
+}
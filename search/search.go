@@ -0,0 +1,248 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/units"
+)
+
+// maxLineSize is the longest file line that could be searched
+const maxLineSize = 1024 * 1024
+
+// lineTimeLayouts are layouts of timestamps that file lines could start with
+var lineTimeLayouts = []struct {
+	layout string
+	fields int
+}{
+	{time.RFC3339Nano, 1},
+	{"2006-01-02T15:04:05-0700", 1},
+	{"2006-01-02 15:04:05", 2},
+	{"2006-01-02 15:04:05.999999999", 2},
+}
+
+// Result is a log line matching the search or an error of a node that could not be searched.
+type Result struct {
+	Time   time.Time `json:"timestamp"`
+	Node   string    `json:"node,omitempty"`
+	Source string    `json:"source,omitempty"`
+	Line   string    `json:"line,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Matcher reports whether the line matches the search.
+type Matcher func(line string) bool
+
+// NewMatcher returns a matcher of lines containing q, or matching q as a regular expression if regex is set.
+func NewMatcher(q string, regex bool) (Matcher, error) {
+	if !regex {
+		return func(line string) bool { return strings.Contains(line, q) }, nil
+	}
+	re, err := regexp.Compile(q)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %s", q, err)
+	}
+	return re.MatchString, nil
+}
+
+// Journal returns at most limit journal messages of the unit logged in the since duration before now
+// that match.
+func Journal(ctx context.Context, unit string, since time.Duration, match Matcher, limit int) ([]Result, error) {
+	options := units.DefaultJournalOptions()
+	options.Format = units.JSON
+	options.Since = since
+
+	r, err := units.ReadJournal(ctx, unit, options)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return decodeJournal(r, unit, match, limit)
+}
+
+// decodeJournal reads journal entries in JSON format.
+func decodeJournal(r io.Reader, source string, match Matcher, limit int) ([]Result, error) {
+	var results []Result
+	decoder := json.NewDecoder(r)
+	for len(results) < limit {
+		var entry map[string]interface{}
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return results, fmt.Errorf("could not decode journal entry: %s", err)
+		}
+
+		message := fieldValue(entry["MESSAGE"])
+		if !match(message) {
+			continue
+		}
+		usec, err := strconv.ParseInt(fieldValue(entry["__REALTIME_TIMESTAMP"]), 10, 64)
+		if err != nil {
+			return results, fmt.Errorf("invalid journal entry timestamp: %s", err)
+		}
+		results = append(results, Result{
+			Time:   time.Unix(0, usec*int64(time.Microsecond)).UTC(),
+			Source: source,
+			Line:   message,
+		})
+	}
+	return results, nil
+}
+
+// fieldValue returns a journal field value. Binary values are exported as arrays of bytes.
+func fieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		b := make([]byte, 0, len(v))
+		for _, c := range v {
+			if f, ok := c.(float64); ok {
+				b = append(b, byte(f))
+			}
+		}
+		return string(b)
+	}
+	return ""
+}
+
+// File returns at most limit lines of the file under the given path that match and were logged
+// in the since duration before now. Time of a line is read from its beginning. Lines without time
+// have the time of the previous line, and are always searched if no line before has time.
+func File(ctx context.Context, path string, since time.Duration, match Matcher, limit int) ([]Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var start time.Time
+	if since > 0 {
+		start = time.Now().Add(-since)
+	}
+
+	var results []Result
+	var lastTime time.Time
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() && len(results) < limit {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		line := scanner.Text()
+		if t, ok := parseLineTime(line); ok {
+			lastTime = t
+		}
+		if (!lastTime.IsZero() && lastTime.Before(start)) || !match(line) {
+			continue
+		}
+		results = append(results, Result{Time: lastTime, Source: path, Line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return results, fmt.Errorf("could not read %s: %s", path, err)
+	}
+	return results, nil
+}
+
+func parseLineTime(line string) (time.Time, bool) {
+	fields := strings.SplitN(line, " ", 3)
+	for _, l := range lineTimeLayouts {
+		if len(fields) < l.fields {
+			continue
+		}
+		if t, err := time.Parse(l.layout, strings.Join(fields[:l.fields], " ")); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// FetchFunc searches a single node and passes its results ordered by time to add.
+type FetchFunc func(ctx context.Context, node dcos.Node, add func(Result)) error
+
+// Cluster searches the nodes with at most concurrency of them searched at once and passes at most limit
+// results annotated with node IP and merged by time to emit. Results are merged while nodes are searched:
+// a result is emitted once every node that is still searched returned a result that is not older, so results
+// flow as nodes respond. Results with the same time are emitted in order of nodes. Nodes that could not be
+// searched are emitted as results with errors after the merged ones. It reports if any result was dropped
+// because of the limit.
+func Cluster(ctx context.Context, nodes []dcos.Node, concurrency, limit int, fetch FetchFunc, emit func(Result)) bool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// a node passes at most limit results, so it's never blocked by the merge waiting for other nodes
+	streams := make([]chan Result, len(nodes))
+	dropped := make([]bool, len(nodes))
+	errs := make([]error, len(nodes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		streams[i] = make(chan Result, limit)
+		wg.Add(1)
+		go func(i int, node dcos.Node) {
+			defer wg.Done()
+			defer close(streams[i])
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			sent := 0
+			errs[i] = fetch(ctx, node, func(r Result) {
+				if sent == limit {
+					dropped[i] = true
+					return
+				}
+				r.Node = node.IP
+				streams[i] <- r
+				sent++
+			})
+		}(i, node)
+	}
+
+	truncated := false
+	heads := make([]*Result, len(nodes))
+	for emitted := 0; ; emitted++ {
+		oldest := -1
+		for i := range streams {
+			if heads[i] == nil && streams[i] != nil {
+				if r, ok := <-streams[i]; ok {
+					heads[i] = &r
+				} else {
+					streams[i] = nil
+				}
+			}
+			if heads[i] != nil && (oldest < 0 || heads[i].Time.Before(heads[oldest].Time)) {
+				oldest = i
+			}
+		}
+		if oldest < 0 {
+			break
+		}
+		if emitted == limit {
+			truncated = true
+			break
+		}
+		emit(*heads[oldest])
+		heads[oldest] = nil
+	}
+
+	wg.Wait()
+	for i, err := range errs {
+		truncated = truncated || dropped[i]
+		if err != nil {
+			emit(Result{Node: nodes[i].IP, Error: err.Error()})
+		}
+	}
+	return truncated
+}
@@ -0,0 +1,201 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dcos/dcos-diagnostics/dcos"
+	"github.com/dcos/dcos-diagnostics/units"
+)
+
+func TestNewMatcher(t *testing.T) {
+	match, err := NewMatcher("a.c", false)
+	require.NoError(t, err)
+	assert.True(t, match("xa.cx"))
+	assert.False(t, match("abc"))
+
+	match, err = NewMatcher("^a.c$", true)
+	require.NoError(t, err)
+	assert.True(t, match("abc"))
+	assert.False(t, match("xabc"))
+
+	_, err = NewMatcher("a(", true)
+	assert.EqualError(t, err, "invalid regular expression \"a(\": error parsing regexp: missing closing ): `a(`")
+}
+
+func TestDecodeJournal(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for i, message := range []string{"started", "failed to start", "failed again", "failed for good"} {
+		e := &units.JournalEntry{
+			Fields:            map[string]string{"MESSAGE": message, "_SYSTEMD_UNIT": "dcos-mesos-master.service"},
+			RealtimeTimestamp: uint64(1591600000000000 + i*1000000),
+		}
+		require.NoError(t, units.WriteJournalEntry(buf, e, units.JSON))
+	}
+
+	match, err := NewMatcher("failed", false)
+	require.NoError(t, err)
+	results, err := decodeJournal(buf, "dcos-mesos-master.service", match, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Result{
+		{Time: time.Unix(1591600001, 0).UTC(), Source: "dcos-mesos-master.service", Line: "failed to start"},
+		{Time: time.Unix(1591600002, 0).UTC(), Source: "dcos-mesos-master.service", Line: "failed again"},
+	}, results)
+}
+
+func TestFile(t *testing.T) {
+	now := time.Now().UTC()
+	old := now.Add(-2 * time.Hour).Format(time.RFC3339Nano)
+	recent := now.Add(-time.Minute).Format("2006-01-02 15:04:05")
+
+	f, err := ioutil.TempFile("", "search")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = fmt.Fprintf(f, "error without time\n%s error old\n  old error continued\n%s error recent\n  recent error continued\nrecent\n", old, recent)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	match, err := NewMatcher("error", false)
+	require.NoError(t, err)
+	results, err := File(context.TODO(), f.Name(), time.Hour, match, 10)
+	require.NoError(t, err)
+
+	recentTime, err := time.Parse("2006-01-02 15:04:05", recent)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Source: f.Name(), Line: "error without time"},
+		{Time: recentTime, Source: f.Name(), Line: recent + " error recent"},
+		{Time: recentTime, Source: f.Name(), Line: "  recent error continued"},
+	}, results)
+
+	results, err = File(context.TODO(), f.Name(), 0, match, 2)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, old+" error old", results[1].Line)
+
+	_, err = File(context.TODO(), "not/existing/file", 0, match, 1)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// collect returns results of Cluster
+func collect(ctx context.Context, nodes []dcos.Node, concurrency, limit int, fetch FetchFunc) ([]Result, bool) {
+	var results []Result
+	truncated := Cluster(ctx, nodes, concurrency, limit, fetch, func(r Result) { results = append(results, r) })
+	return results, truncated
+}
+
+func TestClusterMergesByTime(t *testing.T) {
+	at := func(sec int64, line string) Result { return Result{Time: time.Unix(sec, 0), Line: line} }
+	nodeResults := map[string][]Result{
+		"a": {at(1, "a1"), at(3, "a3"), at(3, "a3'")},
+		"b": nil,
+		"c": {at(2, "c2"), at(3, "c3"), at(4, "c4")},
+	}
+	nodes := []dcos.Node{{IP: "a"}, {IP: "b"}, {IP: "c"}}
+	fetch := func(ctx context.Context, node dcos.Node, add func(Result)) error {
+		for _, r := range nodeResults[node.IP] {
+			add(r)
+		}
+		return nil
+	}
+	withNode := func(r Result, node string) Result { r.Node = node; return r }
+
+	merged, truncated := collect(context.TODO(), nodes, 3, 5, fetch)
+	assert.True(t, truncated)
+	assert.Equal(t, []Result{withNode(at(1, "a1"), "a"), withNode(at(2, "c2"), "c"), withNode(at(3, "a3"), "a"),
+		withNode(at(3, "a3'"), "a"), withNode(at(3, "c3"), "c")}, merged)
+
+	merged, truncated = collect(context.TODO(), nodes[:1], 3, 5, fetch)
+	assert.False(t, truncated)
+	assert.Len(t, merged, 3)
+
+	// node that returned more results than the limit
+	merged, truncated = collect(context.TODO(), nodes[:1], 3, 2, fetch)
+	assert.True(t, truncated)
+	assert.Len(t, merged, 2)
+}
+
+func TestClusterEmitsResultsBeforeSlowNodesFinish(t *testing.T) {
+	release := make(chan struct{})
+	fetch := func(ctx context.Context, node dcos.Node, add func(Result)) error {
+		add(Result{Time: time.Unix(1, 0), Line: "first"})
+		if node.IP == "slow" {
+			<-release
+			add(Result{Time: time.Unix(3, 0), Line: "last"})
+		}
+		return nil
+	}
+
+	var lines []string
+	emitted := make(chan struct{}, 10)
+	go func() {
+		Cluster(context.TODO(), []dcos.Node{{IP: "fast"}, {IP: "slow"}}, 2, 10, fetch, func(r Result) {
+			lines = append(lines, r.Node+" "+r.Line)
+			emitted <- struct{}{}
+		})
+		close(emitted)
+	}()
+
+	<-emitted
+	<-emitted
+	close(release)
+	for range emitted {
+	}
+	assert.Equal(t, []string{"fast first", "slow first", "slow last"}, lines)
+}
+
+func TestCluster(t *testing.T) {
+	var nodes []dcos.Node
+	for i := 1; i <= 10; i++ {
+		nodes = append(nodes, dcos.Node{IP: fmt.Sprintf("10.0.0.%d", i)})
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	fetch := func(ctx context.Context, node dcos.Node, add func(Result)) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if node.IP == "10.0.0.3" {
+			return errors.New("unavailable")
+		}
+		last := strings.TrimPrefix(node.IP, "10.0.0.")
+		add(Result{Time: time.Unix(int64(len(last)), 0), Line: last})
+		return nil
+	}
+
+	results, truncated := collect(context.TODO(), nodes, 3, 9, fetch)
+
+	assert.False(t, truncated)
+	assert.Equal(t, 3, maxRunning)
+	require.Len(t, results, 10)
+	assert.Equal(t, "10.0.0.1", results[0].Node)
+	assert.Equal(t, "1", results[0].Line)
+	assert.Equal(t, "10.0.0.10", results[8].Node)
+	assert.Equal(t, "10", results[8].Line)
+	assert.Equal(t, Result{Node: "10.0.0.3", Error: "unavailable"}, results[9])
+
+	results, truncated = collect(context.TODO(), nodes, 3, 2, fetch)
+	assert.True(t, truncated)
+	assert.Len(t, results, 3)
+}
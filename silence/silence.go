@@ -0,0 +1,258 @@
+// Package silence keeps maintenance windows of nodes and units. Health of silenced items is still reported
+// by nodes, but masters suppress it when aggregating health of the cluster. Silences are stored in a file on
+// every master and masters merge silences of each other, so a silence created on any master reaches all of them.
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// retention is how long expired and deleted silences are kept, so other masters learn about deletions
+const retention = 24 * time.Hour
+
+// ErrNotFound is returned when there is no silence with a given ID
+var ErrNotFound = errors.New("silence not found")
+
+// Silence suppresses health of a node, a unit on all nodes or a unit on a single node until the given time
+type Silence struct {
+	ID string `json:"id"`
+	// Node is the IP of the silenced node, empty for units silenced on all nodes
+	Node string `json:"node,omitempty"`
+	// Unit is the ID of the silenced unit, empty for whole nodes
+	Unit      string    `json:"unit,omitempty"`
+	Until     time.Time `json:"until"`
+	Author    string    `json:"author"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt decides which copy of the silence wins when masters merge their silences
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// Active returns true if the silence suppresses health at the given time
+func (s Silence) Active(now time.Time) bool {
+	return !s.Deleted && now.Before(s.Until)
+}
+
+// Matches returns true if the silence covers the unit on the node. Empty unit stands for the node itself.
+func (s Silence) Matches(node, unit string) bool {
+	if s.Node != "" && s.Node != node {
+		return false
+	}
+	if s.Unit != "" && s.Unit != unit {
+		return false
+	}
+	// silence of a single unit does not cover the whole node
+	return s.Unit == "" || unit != ""
+}
+
+// Validate returns an error if the silence could not be created at the given time
+func (s Silence) Validate(now time.Time) error {
+	if s.Node == "" && s.Unit == "" {
+		return errors.New("node or unit must be set")
+	}
+	if !s.Until.After(now) {
+		return fmt.Errorf("until must be in the future, got %s", s.Until.Format(time.RFC3339))
+	}
+	if s.Author == "" {
+		return errors.New("author must be set")
+	}
+	if s.Reason == "" {
+		return errors.New("reason must be set")
+	}
+	return nil
+}
+
+// Store keeps silences in memory and in the file, if the path is set
+type Store struct {
+	sync.RWMutex
+	path     string
+	silences map[string]Silence
+	now      func() time.Time
+}
+
+// NewStore returns Store persisted in the file at the path. Silences are read from the file if it exists.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, silences: map[string]Silence{}, now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read silences: %s", err)
+	}
+	var silences []Silence
+	if err := json.Unmarshal(data, &silences); err != nil {
+		return nil, fmt.Errorf("could not parse silences from %s: %s", path, err)
+	}
+	for _, silence := range silences {
+		s.silences[silence.ID] = silence
+	}
+	return s, nil
+}
+
+// Add validates the silence, assigns it a new ID and stores it
+func (s *Store) Add(silence Silence) (Silence, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now().UTC()
+	if err := silence.Validate(now); err != nil {
+		return Silence{}, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return Silence{}, fmt.Errorf("could not generate silence ID: %s", err)
+	}
+	silence.ID = id.String()
+	silence.CreatedAt = now
+	silence.UpdatedAt = now
+	silence.Deleted = false
+	s.silences[silence.ID] = silence
+	return silence, s.save()
+}
+
+// Delete ends the silence. It's kept as deleted for a while so other masters delete it too.
+func (s *Store) Delete(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	silence, ok := s.silences[id]
+	if !ok || silence.Deleted {
+		return ErrNotFound
+	}
+	silence.Deleted = true
+	silence.UpdatedAt = s.now().UTC()
+	s.silences[id] = silence
+	return s.save()
+}
+
+// Active returns silences suppressing health now sorted by their end
+func (s *Store) Active() []Silence {
+	if s == nil {
+		return nil
+	}
+	s.RLock()
+	defer s.RUnlock()
+
+	now := s.now()
+	silences := []Silence{}
+	for _, silence := range s.silences {
+		if silence.Active(now) {
+			silences = append(silences, silence)
+		}
+	}
+	sortSilences(silences)
+	return silences
+}
+
+// All returns all silences including expired and deleted ones, it's used to merge silences of masters
+func (s *Store) All() []Silence {
+	s.RLock()
+	defer s.RUnlock()
+
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silences = append(silences, silence)
+	}
+	sortSilences(silences)
+	return silences
+}
+
+// Merge adds silences of another master. Silences known to both are replaced if the other copy was updated later.
+func (s *Store) Merge(silences []Silence) error {
+	s.Lock()
+	defer s.Unlock()
+
+	changed := false
+	for _, silence := range silences {
+		if silence.ID == "" {
+			continue
+		}
+		current, ok := s.silences[silence.ID]
+		if ok && !silence.UpdatedAt.After(current.UpdatedAt) {
+			continue
+		}
+		s.silences[silence.ID] = silence
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// Silenced returns true if an active silence covers the unit on the node. Empty unit stands for the node itself.
+func (s *Store) Silenced(node, unit string) bool {
+	if s == nil {
+		return false
+	}
+	s.RLock()
+	defer s.RUnlock()
+
+	now := s.now()
+	for _, silence := range s.silences {
+		if silence.Active(now) && silence.Matches(node, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// save drops silences that ended long ago and writes the rest to the file. It must be called with the lock held.
+func (s *Store) save() error {
+	now := s.now()
+	for id, silence := range s.silences {
+		if now.Sub(silence.Until) > retention || (silence.Deleted && now.Sub(silence.UpdatedAt) > retention) {
+			delete(s.silences, id)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	silences := make([]Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silences = append(silences, silence)
+	}
+	sortSilences(silences)
+	data, err := json.MarshalIndent(silences, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode silences: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("could not create silences dir: %s", err)
+	}
+	// write to a temporary file first, so a crash does not leave a truncated file
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("could not write silences: %s", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("could not write silences: %s", err)
+	}
+	return nil
+}
+
+func sortSilences(silences []Silence) {
+	sort.Slice(silences, func(i, j int) bool {
+		if !silences[i].Until.Equal(silences[j].Until) {
+			return silences[i].Until.Before(silences[j].Until)
+		}
+		return silences[i].ID < silences[j].ID
+	})
+}
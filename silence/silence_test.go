@@ -0,0 +1,143 @@
+package silence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	dir, err := ioutil.TempDir("", "silences")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "state", "silences.json")
+	s, err := NewStore(path)
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+	return s, path
+}
+
+func TestSilenceMatches(t *testing.T) {
+	node := Silence{Node: "10.0.0.1"}
+	assert.True(t, node.Matches("10.0.0.1", ""))
+	assert.True(t, node.Matches("10.0.0.1", "dcos-mesos-slave.service"))
+	assert.False(t, node.Matches("10.0.0.2", ""))
+
+	unit := Silence{Unit: "dcos-mesos-slave.service"}
+	assert.True(t, unit.Matches("10.0.0.1", "dcos-mesos-slave.service"))
+	assert.True(t, unit.Matches("10.0.0.2", "dcos-mesos-slave.service"))
+	assert.False(t, unit.Matches("10.0.0.1", "dcos-net.service"))
+	assert.False(t, unit.Matches("10.0.0.1", ""))
+
+	unitAtNode := Silence{Node: "10.0.0.1", Unit: "dcos-mesos-slave.service"}
+	assert.True(t, unitAtNode.Matches("10.0.0.1", "dcos-mesos-slave.service"))
+	assert.False(t, unitAtNode.Matches("10.0.0.2", "dcos-mesos-slave.service"))
+	assert.False(t, unitAtNode.Matches("10.0.0.1", ""))
+}
+
+func TestSilenceValidate(t *testing.T) {
+	valid := Silence{Node: "10.0.0.1", Until: now.Add(time.Hour), Author: "ops", Reason: "drain"}
+	assert.NoError(t, valid.Validate(now))
+
+	for expected, s := range map[string]Silence{
+		"node or unit must be set":                              {Until: now.Add(time.Hour), Author: "ops", Reason: "drain"},
+		"until must be in the future, got 2020-03-01T12:00:00Z": {Node: "10.0.0.1", Until: now, Author: "ops", Reason: "drain"},
+		"author must be set":                                    {Node: "10.0.0.1", Until: now.Add(time.Hour), Reason: "drain"},
+		"reason must be set":                                    {Node: "10.0.0.1", Until: now.Add(time.Hour), Author: "ops"},
+	} {
+		assert.EqualError(t, s.Validate(now), expected)
+	}
+}
+
+func TestStoreAddDeleteAndPersist(t *testing.T) {
+	s, path := newTestStore(t)
+
+	added, err := s.Add(Silence{Node: "10.0.0.1", Until: now.Add(time.Hour), Author: "ops", Reason: "drain"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, added.ID)
+	assert.Equal(t, now, added.CreatedAt)
+	assert.Equal(t, []Silence{added}, s.Active())
+	assert.True(t, s.Silenced("10.0.0.1", "dcos-mesos-slave.service"))
+	assert.False(t, s.Silenced("10.0.0.2", ""))
+
+	_, err = s.Add(Silence{Until: now.Add(time.Hour)})
+	assert.EqualError(t, err, "node or unit must be set")
+
+	loaded, err := NewStore(path)
+	require.NoError(t, err)
+	loaded.now = s.now
+	assert.Equal(t, []Silence{added}, loaded.Active())
+
+	require.NoError(t, s.Delete(added.ID))
+	assert.Empty(t, s.Active())
+	assert.False(t, s.Silenced("10.0.0.1", ""))
+	assert.Equal(t, ErrNotFound, s.Delete(added.ID))
+	assert.Equal(t, ErrNotFound, s.Delete("unknown"))
+
+	all := s.All()
+	require.Len(t, all, 1)
+	assert.True(t, all[0].Deleted, "deleted silence is kept for other masters")
+}
+
+func TestStoreExpires(t *testing.T) {
+	s, _ := newTestStore(t)
+	_, err := s.Add(Silence{Unit: "dcos-net.service", Until: now.Add(time.Hour), Author: "ops", Reason: "upgrade"})
+	require.NoError(t, err)
+
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	assert.Empty(t, s.Active())
+	assert.False(t, s.Silenced("10.0.0.1", "dcos-net.service"))
+	assert.Len(t, s.All(), 1)
+
+	// silences are pruned a day after they ended
+	s.now = func() time.Time { return now.Add(26 * time.Hour) }
+	_, err = s.Add(Silence{Unit: "dcos-net.service", Until: now.Add(27 * time.Hour), Author: "ops", Reason: "upgrade"})
+	require.NoError(t, err)
+	assert.Len(t, s.All(), 1)
+}
+
+func TestStoreMerge(t *testing.T) {
+	a, _ := newTestStore(t)
+	b, _ := newTestStore(t)
+
+	silence, err := a.Add(Silence{Node: "10.0.0.1", Until: now.Add(time.Hour), Author: "ops", Reason: "drain"})
+	require.NoError(t, err)
+	require.NoError(t, b.Merge(a.All()))
+	assert.True(t, b.Silenced("10.0.0.1", ""))
+
+	// deletion wins because it's newer
+	a.now = func() time.Time { return now.Add(time.Minute) }
+	require.NoError(t, a.Delete(silence.ID))
+	require.NoError(t, b.Merge(a.All()))
+	assert.False(t, b.Silenced("10.0.0.1", ""))
+
+	// older copy does not bring the silence back
+	require.NoError(t, a.Merge([]Silence{silence}))
+	assert.False(t, a.Silenced("10.0.0.1", ""))
+}
+
+func TestNewStoreWithBrokenFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "silences")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("not json")
+	require.NoError(t, err)
+	f.Close()
+
+	_, err = NewStore(f.Name())
+	assert.Error(t, err)
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	assert.False(t, s.Silenced("10.0.0.1", ""))
+	assert.Empty(t, s.Active())
+}
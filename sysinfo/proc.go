@@ -0,0 +1,422 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// clockTicks is USER_HZ, the unit of process times in /proc/[pid]/stat. It is 100 on all supported platforms.
+const clockTicks = 100
+
+// pseudoFileSystems have no meaningful disk usage
+var pseudoFileSystems = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true, "configfs": true,
+	"debugfs": true, "devpts": true, "fusectl": true, "hugetlbfs": true, "mqueue": true, "nsfs": true,
+	"proc": true, "pstore": true, "rpc_pipefs": true, "securityfs": true, "sysfs": true, "tracefs": true,
+}
+
+// tcpStates are names of states in /proc/net/tcp, see include/net/tcp_states.h
+var tcpStates = map[uint64]string{
+	0x01: "ESTABLISHED",
+	0x02: "SYN_SENT",
+	0x03: "SYN_RECV",
+	0x04: "FIN_WAIT1",
+	0x05: "FIN_WAIT2",
+	0x06: "TIME_WAIT",
+	0x07: "CLOSE",
+	0x08: "CLOSE_WAIT",
+	0x09: "LAST_ACK",
+	0x0A: "LISTEN",
+	0x0B: "CLOSING",
+}
+
+func readString(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readUint(file string) (uint64, error) {
+	s, err := readString(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func (r *Reader) load() (*Load, error) {
+	file := r.proc("loadavg")
+	s, err := readString(file)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, parseError(file, fmt.Errorf("expected at least 4 fields, got %d", len(fields)))
+	}
+	l := &Load{}
+	for i, v := range []*float64{&l.Load1, &l.Load5, &l.Load15} {
+		if *v, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, parseError(file, err)
+		}
+	}
+	entities := strings.SplitN(fields[3], "/", 2)
+	if len(entities) != 2 {
+		return nil, parseError(file, fmt.Errorf("invalid number of scheduling entities %q", fields[3]))
+	}
+	if l.Running, err = strconv.Atoi(entities[0]); err != nil {
+		return nil, parseError(file, err)
+	}
+	if l.Total, err = strconv.Atoi(entities[1]); err != nil {
+		return nil, parseError(file, err)
+	}
+	return l, nil
+}
+
+// memory returns /proc/meminfo values in bytes
+func (r *Reader) memory() (map[string]uint64, error) {
+	file := r.proc("meminfo")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	memory := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, parseError(file, err)
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			value *= 1024
+		}
+		memory[parts[0]] = value
+	}
+	return memory, nil
+}
+
+// processes returns all processes ordered by PID. Processes that exit while being read are skipped.
+func (r *Reader) processes(ctx context.Context) ([]Process, error) {
+	entries, err := ioutil.ReadDir(r.proc())
+	if err != nil {
+		return nil, err
+	}
+	var processes []Process
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		p, err := r.process(pid)
+		if processExited(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, *p)
+	}
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+	return processes, nil
+}
+
+// processExited tells if the error was caused by the process exiting while it was read. Files of the process
+// disappear once it's reaped, but reading them while it's a zombie fails with ESRCH.
+func processExited(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, syscall.ESRCH)
+}
+
+func (r *Reader) process(pid int) (*Process, error) {
+	dir := strconv.Itoa(pid)
+	file := r.proc(dir, "stat")
+	stat, err := readString(file)
+	if err != nil {
+		return nil, err
+	}
+	// the name is in parentheses and may contain spaces and parentheses itself
+	open, closing := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return nil, parseError(file, fmt.Errorf("no process name"))
+	}
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 22 {
+		return nil, parseError(file, fmt.Errorf("expected at least 22 fields after the name, got %d", len(fields)))
+	}
+	p := &Process{PID: pid, Name: stat[open+1 : closing], State: fields[0]}
+	values := make([]uint64, len(fields))
+	for _, i := range []int{1, 11, 12, 17, 20, 21} {
+		if values[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, parseError(file, err)
+		}
+	}
+	p.PPID = int(values[1])
+	p.CPUSeconds = float64(values[11]+values[12]) / clockTicks
+	p.Threads = int(values[17])
+	p.VMSBytes = values[20]
+	p.RSSBytes = values[21] * uint64(os.Getpagesize())
+
+	if status, err := ioutil.ReadFile(r.proc(dir, "status")); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(status))
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 1 && fields[0] == "Uid:" {
+				p.UID, _ = strconv.Atoi(fields[1])
+			}
+		}
+	}
+	if cmdline, err := ioutil.ReadFile(r.proc(dir, "cmdline")); err == nil {
+		if cmdline = bytes.TrimRight(cmdline, "\x00"); len(cmdline) > 0 {
+			p.Cmdline = strings.Split(string(cmdline), "\x00")
+		}
+	}
+	// file descriptors of processes of other users can not be listed without privileges
+	if fds, err := ioutil.ReadDir(r.proc(dir, "fd")); err == nil {
+		p.OpenFiles = len(fds)
+	} else {
+		p.OpenFiles = -1
+	}
+	return p, nil
+}
+
+func (r *Reader) mounts() ([]Mount, error) {
+	file := r.proc("mounts")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var mounts []Mount
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		m := Mount{
+			Device:     unescapeMount(fields[0]),
+			MountPoint: unescapeMount(fields[1]),
+			FSType:     fields[2],
+			Options:    fields[3],
+		}
+		if !pseudoFileSystems[m.FSType] && r.DiskUsage != nil {
+			if usage, err := r.DiskUsage(m.MountPoint); err != nil {
+				m.Error = err.Error()
+			} else {
+				m.Total = usage.Total
+				m.Used = usage.Used
+				m.Free = usage.Free
+				m.UsedPct = usage.UsedPercent
+				m.InodesUsed = usage.InodesUsed
+				m.InodesFree = usage.InodesFree
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// unescapeMount replaces octal escapes of white space in /proc/mounts e.g., \040 with the character
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// NetInterfaces returns network interfaces of the host ordered by name
+func (r *Reader) NetInterfaces() ([]NetInterface, error) {
+	entries, err := ioutil.ReadDir(r.sys("class", "net"))
+	if err != nil {
+		return nil, err
+	}
+	var interfaces []NetInterface
+	for _, entry := range entries {
+		dir := r.sys("class", "net", entry.Name())
+		i := NetInterface{Name: entry.Name()}
+		i.Address, _ = readString(filepath.Join(dir, "address"))
+		i.OperState, _ = readString(filepath.Join(dir, "operstate"))
+		if mtu, err := readUint(filepath.Join(dir, "mtu")); err == nil {
+			i.MTU = int(mtu)
+		}
+		for name, v := range map[string]*uint64{
+			"rx_bytes":   &i.RxBytes,
+			"tx_bytes":   &i.TxBytes,
+			"rx_errors":  &i.RxErrors,
+			"tx_errors":  &i.TxErrors,
+			"rx_dropped": &i.RxDropped,
+			"tx_dropped": &i.TxDropped,
+		} {
+			*v, _ = readUint(filepath.Join(dir, "statistics", name))
+		}
+		interfaces = append(interfaces, i)
+	}
+	return interfaces, nil
+}
+
+// sockets returns entries of TCP and UDP socket tables. Tables of protocols the kernel does not support are skipped.
+func (r *Reader) sockets() ([]Socket, error) {
+	var sockets []Socket
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		file := r.proc("net", protocol)
+		data, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		// skip the header
+		scanner.Scan()
+		for scanner.Scan() {
+			s, err := parseSocket(protocol, scanner.Text())
+			if err != nil {
+				return nil, parseError(file, err)
+			}
+			sockets = append(sockets, *s)
+		}
+	}
+	return sockets, nil
+}
+
+// parseSocket parses a line of the socket table e.g.,
+//
+//	0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 24817 ...
+func parseSocket(protocol, line string) (*Socket, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("expected at least 10 fields, got %d", len(fields))
+	}
+	s := &Socket{Protocol: protocol}
+	var err error
+	if s.LocalAddress, err = parseSocketAddress(fields[1]); err != nil {
+		return nil, err
+	}
+	if s.RemoteAddress, err = parseSocketAddress(fields[2]); err != nil {
+		return nil, err
+	}
+	state, err := strconv.ParseUint(fields[3], 16, 8)
+	if err != nil {
+		return nil, err
+	}
+	s.State = strconv.FormatUint(state, 16)
+	if strings.HasPrefix(protocol, "tcp") {
+		if name, ok := tcpStates[state]; ok {
+			s.State = name
+		}
+	} else if state == 0x07 {
+		s.State = "UNCONN"
+	} else if state == 0x01 {
+		s.State = "ESTABLISHED"
+	}
+	queues := strings.SplitN(fields[4], ":", 2)
+	if len(queues) != 2 {
+		return nil, fmt.Errorf("invalid queues %q", fields[4])
+	}
+	if s.TxQueue, err = strconv.ParseUint(queues[0], 16, 64); err != nil {
+		return nil, err
+	}
+	if s.RxQueue, err = strconv.ParseUint(queues[1], 16, 64); err != nil {
+		return nil, err
+	}
+	if s.UID, err = strconv.Atoi(fields[7]); err != nil {
+		return nil, err
+	}
+	if s.Inode, err = strconv.ParseUint(fields[9], 10, 64); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseSocketAddress parses hex encoded address and port. The address is stored as 32 bit words in the host
+// byte order, which is little endian on all supported platforms.
+func parseSocketAddress(s string) (string, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	ip, err := hex.DecodeString(parts[0])
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid port %q", s)
+	}
+	return net.JoinHostPort(net.IP(ip).String(), strconv.FormatUint(port, 10)), nil
+}
+
+func (r *Reader) openFiles() (*OpenFiles, error) {
+	file := r.proc("sys", "fs", "file-nr")
+	s, err := readString(file)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return nil, parseError(file, fmt.Errorf("expected 3 fields, got %d", len(fields)))
+	}
+	values := make([]uint64, 3)
+	for i, f := range fields {
+		if values[i], err = strconv.ParseUint(f, 10, 64); err != nil {
+			return nil, parseError(file, err)
+		}
+	}
+	return &OpenFiles{Allocated: values[0], Free: values[1], Max: values[2]}, nil
+}
+
+// kernelParameters returns sysctl values of the configured keys and the kernel command line as kernel.cmdline
+func (r *Reader) kernelParameters() (map[string]string, error) {
+	parameters := map[string]string{}
+	for _, key := range r.KernelParameters {
+		value, err := readString(r.proc(append([]string{"sys"}, strings.Split(key, ".")...)...))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		parameters[key] = strings.Join(strings.Fields(value), " ")
+	}
+	if cmdline, err := readString(r.proc("cmdline")); err == nil {
+		parameters["kernel.cmdline"] = cmdline
+	}
+	return parameters, nil
+}
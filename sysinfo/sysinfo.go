@@ -0,0 +1,186 @@
+// Package sysinfo reads a structured snapshot of the host state directly from procfs and sysfs,
+// so it does not depend on tools like ps, df or netstat being installed.
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+)
+
+// DefaultKernelParameters are sysctl keys read into the snapshot. Keys that do not exist are skipped.
+var DefaultKernelParameters = []string{
+	"kernel.hostname",
+	"kernel.osrelease",
+	"kernel.pid_max",
+	"kernel.threads-max",
+	"vm.swappiness",
+	"vm.overcommit_memory",
+	"vm.max_map_count",
+	"fs.file-max",
+	"fs.inotify.max_user_watches",
+	"fs.inotify.max_user_instances",
+	"net.core.somaxconn",
+	"net.ipv4.ip_forward",
+	"net.ipv4.ip_local_port_range",
+	"net.ipv4.tcp_max_syn_backlog",
+	"net.netfilter.nf_conntrack_count",
+	"net.netfilter.nf_conntrack_max",
+	"net.bridge.bridge-nf-call-iptables",
+}
+
+// Reader reads snapshots from procfs and sysfs mounted at the given paths.
+type Reader struct {
+	// ProcRoot is where procfs is mounted, usually /proc
+	ProcRoot string
+	// SysRoot is where sysfs is mounted, usually /sys
+	SysRoot string
+	// KernelParameters are sysctl keys to read
+	KernelParameters []string
+	// DiskUsage returns usage of the file system mounted at the path
+	DiskUsage func(path string) (*disk.UsageStat, error)
+}
+
+// NewReader returns Reader of the host procfs and sysfs
+func NewReader() *Reader {
+	return &Reader{
+		ProcRoot:         "/proc",
+		SysRoot:          "/sys",
+		KernelParameters: DefaultKernelParameters,
+		DiskUsage:        disk.Usage,
+	}
+}
+
+// Snapshot is the state of the host. Sections that could not be read are reported in Errors
+// with the section name as a key.
+type Snapshot struct {
+	Time             time.Time         `json:"time"`
+	Load             *Load             `json:"load,omitempty"`
+	Memory           map[string]uint64 `json:"memory,omitempty"`
+	Processes        []Process         `json:"processes,omitempty"`
+	Mounts           []Mount           `json:"mounts,omitempty"`
+	NetInterfaces    []NetInterface    `json:"net_interfaces,omitempty"`
+	Sockets          []Socket          `json:"sockets,omitempty"`
+	OpenFiles        *OpenFiles        `json:"open_files,omitempty"`
+	KernelParameters map[string]string `json:"kernel_parameters,omitempty"`
+	Errors           map[string]string `json:"errors,omitempty"`
+}
+
+// Load is the system load average and the number of scheduling entities
+type Load struct {
+	Load1   float64 `json:"load1"`
+	Load5   float64 `json:"load5"`
+	Load15  float64 `json:"load15"`
+	Running int     `json:"running"`
+	Total   int     `json:"total"`
+}
+
+// Process is a single process read from /proc/[pid]
+type Process struct {
+	PID        int      `json:"pid"`
+	PPID       int      `json:"ppid"`
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	UID        int      `json:"uid"`
+	Threads    int      `json:"threads"`
+	RSSBytes   uint64   `json:"rss_bytes"`
+	VMSBytes   uint64   `json:"vms_bytes"`
+	CPUSeconds float64  `json:"cpu_seconds"`
+	OpenFiles  int      `json:"open_files"`
+	Cmdline    []string `json:"cmdline,omitempty"`
+}
+
+// Mount is a mounted file system with its usage
+type Mount struct {
+	Device     string  `json:"device"`
+	MountPoint string  `json:"mount_point"`
+	FSType     string  `json:"fs_type"`
+	Options    string  `json:"options"`
+	Total      uint64  `json:"total_bytes,omitempty"`
+	Used       uint64  `json:"used_bytes,omitempty"`
+	Free       uint64  `json:"free_bytes,omitempty"`
+	UsedPct    float64 `json:"used_percent,omitempty"`
+	InodesUsed uint64  `json:"inodes_used,omitempty"`
+	InodesFree uint64  `json:"inodes_free,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// NetInterface is a network interface read from /sys/class/net
+type NetInterface struct {
+	Name      string `json:"name"`
+	Address   string `json:"address,omitempty"`
+	MTU       int    `json:"mtu"`
+	OperState string `json:"oper_state,omitempty"`
+	RxBytes   uint64 `json:"rx_bytes"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	RxErrors  uint64 `json:"rx_errors"`
+	TxErrors  uint64 `json:"tx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+// Socket is an entry of /proc/net/{tcp,tcp6,udp,udp6} socket tables
+type Socket struct {
+	Protocol      string `json:"protocol"`
+	LocalAddress  string `json:"local_address"`
+	RemoteAddress string `json:"remote_address"`
+	State         string `json:"state"`
+	UID           int    `json:"uid"`
+	Inode         uint64 `json:"inode"`
+	TxQueue       uint64 `json:"tx_queue"`
+	RxQueue       uint64 `json:"rx_queue"`
+}
+
+// OpenFiles is the number of file handles of the kernel, see /proc/sys/fs/file-nr
+type OpenFiles struct {
+	Allocated uint64 `json:"allocated"`
+	Free      uint64 `json:"free"`
+	Max       uint64 `json:"max"`
+}
+
+// Read returns the snapshot of the host. It fails only if the context is done, sections that could not be
+// read are reported in the snapshot.
+func (r *Reader) Read(ctx context.Context) (*Snapshot, error) {
+	s := &Snapshot{Time: time.Now().UTC(), Errors: map[string]string{}}
+
+	sections := []struct {
+		name string
+		read func() error
+	}{
+		{"load", func() (err error) { s.Load, err = r.load(); return }},
+		{"memory", func() (err error) { s.Memory, err = r.memory(); return }},
+		{"processes", func() (err error) { s.Processes, err = r.processes(ctx); return }},
+		{"mounts", func() (err error) { s.Mounts, err = r.mounts(); return }},
+		{"net_interfaces", func() (err error) { s.NetInterfaces, err = r.NetInterfaces(); return }},
+		{"sockets", func() (err error) { s.Sockets, err = r.sockets(); return }},
+		{"open_files", func() (err error) { s.OpenFiles, err = r.openFiles(); return }},
+		{"kernel_parameters", func() (err error) { s.KernelParameters, err = r.kernelParameters(); return }},
+	}
+	for _, section := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := section.read(); err != nil {
+			s.Errors[section.name] = err.Error()
+		}
+	}
+	if len(s.Errors) == 0 {
+		s.Errors = nil
+	}
+	return s, nil
+}
+
+func (r *Reader) proc(elem ...string) string {
+	return filepath.Join(append([]string{r.ProcRoot}, elem...)...)
+}
+
+func (r *Reader) sys(elem ...string) string {
+	return filepath.Join(append([]string{r.SysRoot}, elem...)...)
+}
+
+func parseError(file string, err error) error {
+	return fmt.Errorf("could not parse %s: %s", file, err)
+}
@@ -0,0 +1,177 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/shirou/gopsutil/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReader() *Reader {
+	return &Reader{
+		ProcRoot:         filepath.Join("testdata", "proc"),
+		SysRoot:          filepath.Join("testdata", "sys"),
+		KernelParameters: []string{"fs.file-max", "kernel.pid_max", "vm.swappiness", "net.core.somaxconn", "not.existing"},
+		DiskUsage: func(path string) (*disk.UsageStat, error) {
+			if path == "/" {
+				return &disk.UsageStat{Path: path, Total: 100, Used: 40, Free: 60, UsedPercent: 40, InodesUsed: 5, InodesFree: 95}, nil
+			}
+			return nil, fmt.Errorf("no such file system %s", path)
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	s, err := testReader().Read(context.TODO())
+	require.NoError(t, err)
+
+	assert.Empty(t, s.Errors)
+	assert.Equal(t, &Load{Load1: 0.52, Load5: 0.58, Load15: 0.59, Running: 2, Total: 467}, s.Load)
+	assert.Equal(t, map[string]uint64{
+		"MemTotal":        16318412 * 1024,
+		"MemFree":         1023496 * 1024,
+		"MemAvailable":    9527544 * 1024,
+		"HugePages_Total": 0,
+	}, s.Memory)
+	assert.Equal(t, &OpenFiles{Allocated: 2048, Free: 0, Max: 1616232}, s.OpenFiles)
+	assert.Equal(t, map[string]string{
+		"fs.file-max":        "1616232",
+		"kernel.pid_max":     "4194304",
+		"vm.swappiness":      "60",
+		"net.core.somaxconn": "4096",
+		"kernel.cmdline":     "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro",
+	}, s.KernelParameters)
+}
+
+func TestReadProcesses(t *testing.T) {
+	s, err := testReader().Read(context.TODO())
+	require.NoError(t, err)
+
+	pageSize := uint64(os.Getpagesize())
+	assert.Equal(t, []Process{
+		{
+			PID:        1,
+			PPID:       0,
+			Name:       "systemd",
+			State:      "S",
+			Threads:    1,
+			RSSBytes:   3186 * pageSize,
+			VMSBytes:   173461504,
+			CPUSeconds: 4,
+			OpenFiles:  3,
+			Cmdline:    []string{"/usr/lib/systemd/systemd", "--switched-root", "--system"},
+		},
+		{
+			PID:        4242,
+			PPID:       1,
+			Name:       "mesos agent",
+			State:      "R",
+			UID:        1000,
+			Threads:    12,
+			RSSBytes:   256 * pageSize,
+			VMSBytes:   1048576,
+			CPUSeconds: 15,
+			OpenFiles:  1,
+		},
+	}, s.Processes)
+}
+
+func TestReadMounts(t *testing.T) {
+	s, err := testReader().Read(context.TODO())
+	require.NoError(t, err)
+
+	assert.Equal(t, []Mount{
+		{Device: "sysfs", MountPoint: "/sys", FSType: "sysfs", Options: "rw,nosuid,nodev,noexec,relatime"},
+		{Device: "proc", MountPoint: "/proc", FSType: "proc", Options: "rw,nosuid,nodev,noexec,relatime"},
+		{Device: "/dev/sda1", MountPoint: "/", FSType: "xfs", Options: "rw,relatime",
+			Total: 100, Used: 40, Free: 60, UsedPct: 40, InodesUsed: 5, InodesFree: 95},
+		{Device: "/dev/sdb1", MountPoint: "/var/lib/mesos slave", FSType: "xfs", Options: "rw,relatime",
+			Error: "no such file system /var/lib/mesos slave"},
+	}, s.Mounts)
+}
+
+func TestReadNetwork(t *testing.T) {
+	s, err := testReader().Read(context.TODO())
+	require.NoError(t, err)
+
+	assert.Equal(t, []NetInterface{
+		{Name: "eth0", Address: "00:0c:29:3e:5f:01", MTU: 9001, OperState: "up",
+			RxBytes: 1000, TxBytes: 2000, RxErrors: 1, RxDropped: 3},
+		{Name: "lo", Address: "00:00:00:00:00:00", MTU: 65536, OperState: "unknown", RxBytes: 42},
+	}, s.NetInterfaces)
+
+	assert.Equal(t, []Socket{
+		{Protocol: "tcp", LocalAddress: "127.0.0.1:3306", RemoteAddress: "0.0.0.0:0", State: "LISTEN", Inode: 24817},
+		{Protocol: "tcp", LocalAddress: "10.0.2.15:5050", RemoteAddress: "10.0.2.10:50850", State: "ESTABLISHED",
+			UID: 1000, Inode: 31337, TxQueue: 16, RxQueue: 2},
+		{Protocol: "tcp6", LocalAddress: "[::1]:53", RemoteAddress: "[::]:0", State: "LISTEN", UID: 101, Inode: 1234},
+		{Protocol: "udp", LocalAddress: "0.0.0.0:68", RemoteAddress: "0.0.0.0:0", State: "UNCONN", Inode: 19283},
+	}, s.Sockets)
+}
+
+func TestReadReportsSectionErrors(t *testing.T) {
+	proc, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(proc)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(proc, "loadavg"), []byte("0.1 0.2\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(proc, "7"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(proc, "7", "stat"), []byte("7 broken"), 0644))
+
+	r := &Reader{ProcRoot: proc, SysRoot: filepath.Join(proc, "sys")}
+	s, err := r.Read(context.TODO())
+	require.NoError(t, err)
+
+	assert.Nil(t, s.Load)
+	assert.Equal(t, "could not parse "+filepath.Join(proc, "loadavg")+": expected at least 4 fields, got 2", s.Errors["load"])
+	assert.Equal(t, "could not parse "+filepath.Join(proc, "7", "stat")+": no process name", s.Errors["processes"])
+	assert.Contains(t, s.Errors, "memory")
+	assert.Contains(t, s.Errors, "mounts")
+	assert.Contains(t, s.Errors, "net_interfaces")
+	assert.Contains(t, s.Errors, "open_files")
+	assert.NotContains(t, s.Errors, "sockets")
+	assert.NotContains(t, s.Errors, "kernel_parameters")
+}
+
+func TestReadStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := testReader().Read(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err string
+	}{
+		{in: "0100007F:0016", out: "127.0.0.1:22"},
+		{in: "0000000000000000FFFF00000100007F:1F90", out: "127.0.0.1:8080"},
+		{in: "B80D01200000000000000000010000000:0050", err: `invalid address "B80D01200000000000000000010000000:0050"`},
+		{in: "0100007F", err: `invalid address "0100007F"`},
+		{in: "0100007F:XYZ", err: `invalid port "0100007F:XYZ"`},
+	}
+	for _, tt := range tests {
+		out, err := parseSocketAddress(tt.in)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.in)
+			continue
+		}
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.out, out, tt.in)
+	}
+}
+
+func TestProcessExited(t *testing.T) {
+	assert.True(t, processExited(&os.PathError{Op: "open", Path: "/proc/7/stat", Err: syscall.ENOENT}))
+	assert.True(t, processExited(&os.PathError{Op: "read", Path: "/proc/7/stat", Err: syscall.ESRCH}))
+	assert.False(t, processExited(&os.PathError{Op: "open", Path: "/proc/7/stat", Err: syscall.EACCES}))
+	assert.False(t, processExited(nil))
+}
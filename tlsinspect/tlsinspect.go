@@ -0,0 +1,232 @@
+// Package tlsinspect connects to TLS endpoints and reports their certificate chains, whether the chains verify
+// against the configured CA and how soon the certificates expire.
+package tlsinspect
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// Defaults of the Inspector
+const (
+	DefaultTimeout       = 5 * time.Second
+	DefaultExpiryWarning = 30 * 24 * time.Hour
+)
+
+// Endpoint is a TLS service to inspect
+type Endpoint struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// ServerName is sent in SNI and verified against certificate SANs, the host of Address if empty
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Certificate describes a certificate of the chain presented by the endpoint
+type Certificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	IPAddresses  []string  `json:"ip_addresses,omitempty"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	DaysLeft     int       `json:"days_left"`
+	IsCA         bool      `json:"is_ca"`
+	SHA256       string    `json:"sha256"`
+}
+
+// Result is the outcome of inspecting the endpoint. Problems make the endpoint unhealthy.
+type Result struct {
+	Endpoint
+	Time        time.Time     `json:"time"`
+	Version     string        `json:"version,omitempty"`
+	CipherSuite string        `json:"cipher_suite,omitempty"`
+	Chain       []Certificate `json:"chain,omitempty"`
+	Verified    bool          `json:"verified"`
+	VerifyError string        `json:"verify_error,omitempty"`
+	// ExpiresAt is when the first certificate of the chain expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Problems  []string   `json:"problems,omitempty"`
+}
+
+// Healthy returns true if the endpoint has no problems
+func (r Result) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// Inspector inspects TLS endpoints
+type Inspector struct {
+	// Roots verify presented chains, system roots are used if nil
+	Roots *x509.CertPool
+	// Timeout limits connecting and the TLS handshake
+	Timeout time.Duration
+	// ExpiryWarning makes certificates expiring within it a problem
+	ExpiryWarning time.Duration
+	// Now returns the current time
+	Now func() time.Time
+}
+
+// NewInspector returns Inspector verifying chains against roots with default settings
+func NewInspector(roots *x509.CertPool) *Inspector {
+	return &Inspector{
+		Roots:         roots,
+		Timeout:       DefaultTimeout,
+		ExpiryWarning: DefaultExpiryWarning,
+		Now:           time.Now,
+	}
+}
+
+// LoadRoots returns a pool of PEM encoded CA certificates read from the file
+func LoadRoots(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate %s: %s", path, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// Inspect connects to the endpoint, records the presented chain and verifies it
+func (i *Inspector) Inspect(ctx context.Context, e Endpoint) Result {
+	now := i.Now()
+	r := Result{Endpoint: e, Time: now.UTC()}
+
+	serverName := e.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(e.Address)
+		if err != nil {
+			r.Error = fmt.Sprintf("invalid address %q: %s", e.Address, err)
+			r.Problems = append(r.Problems, r.Error)
+			return r
+		}
+		serverName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, i.Timeout)
+	defer cancel()
+	state, err := handshake(ctx, e.Address, serverName)
+	if err != nil {
+		r.Error = err.Error()
+		r.Problems = append(r.Problems, fmt.Sprintf("could not connect: %s", err))
+		return r
+	}
+
+	r.Version = tlsVersion(state.Version)
+	r.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	for _, c := range state.PeerCertificates {
+		r.Chain = append(r.Chain, describe(c, now))
+		if r.ExpiresAt == nil || c.NotAfter.Before(*r.ExpiresAt) {
+			notAfter := c.NotAfter.UTC()
+			r.ExpiresAt = &notAfter
+		}
+	}
+
+	if err := i.verify(state.PeerCertificates, serverName, now); err != nil {
+		r.VerifyError = err.Error()
+		r.Problems = append(r.Problems, fmt.Sprintf("certificate does not verify: %s", err))
+	} else {
+		r.Verified = true
+	}
+	for _, c := range state.PeerCertificates {
+		left := c.NotAfter.Sub(now)
+		switch {
+		case left <= 0:
+			r.Problems = append(r.Problems, fmt.Sprintf("certificate %q expired on %s", c.Subject.String(), c.NotAfter.UTC().Format(time.RFC3339)))
+		case left <= i.ExpiryWarning:
+			r.Problems = append(r.Problems, fmt.Sprintf("certificate %q expires in %d days on %s",
+				c.Subject.String(), daysLeft(c, now), c.NotAfter.UTC().Format(time.RFC3339)))
+		}
+	}
+	return r
+}
+
+// handshake returns the state of the TLS connection to the address. Certificates are verified separately,
+// so chains that do not verify are still reported.
+func handshake(ctx context.Context, address, serverName string) (tls.ConnectionState, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) //nolint:errcheck
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}) //nolint:gosec // chain is verified by Inspect
+	if err := tlsConn.Handshake(); err != nil {
+		return tls.ConnectionState{}, fmt.Errorf("TLS handshake with %s failed: %s", address, err)
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+func (i *Inspector) verify(chain []*x509.Certificate, serverName string, now time.Time) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         i.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+func describe(c *x509.Certificate, now time.Time) Certificate {
+	sum := sha256.Sum256(c.Raw)
+	certificate := Certificate{
+		Subject:      c.Subject.String(),
+		Issuer:       c.Issuer.String(),
+		SerialNumber: c.SerialNumber.String(),
+		DNSNames:     c.DNSNames,
+		NotBefore:    c.NotBefore.UTC(),
+		NotAfter:     c.NotAfter.UTC(),
+		DaysLeft:     daysLeft(c, now),
+		IsCA:         c.IsCA,
+		SHA256:       hex.EncodeToString(sum[:]),
+	}
+	for _, ip := range c.IPAddresses {
+		certificate.IPAddresses = append(certificate.IPAddresses, ip.String())
+	}
+	return certificate
+}
+
+// daysLeft returns the number of whole days until the certificate expires, negative if it has expired
+func daysLeft(c *x509.Certificate, now time.Time) int {
+	left := c.NotAfter.Sub(now)
+	days := int(left / (24 * time.Hour))
+	if left < 0 {
+		days--
+	}
+	return days
+}
+
+func tlsVersion(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
@@ -0,0 +1,190 @@
+package tlsinspect
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2020, 10, 19, 12, 0, 0, 0, time.UTC)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "DC/OS Root CA"},
+		NotBefore:             now.Add(-24 * time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+func (ca testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// serve starts a TLS server presenting a certificate signed by the CA valid until notAfter
+func (ca testCA) serve(t *testing.T, notAfter time.Time) (string, func()) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "master.mesos"},
+		DNSNames:     []string{"master.mesos"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}},
+	})
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake() //nolint:errcheck
+			conn.Close()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func testInspector(roots *x509.CertPool) *Inspector {
+	i := NewInspector(roots)
+	i.Now = func() time.Time { return now }
+	return i
+}
+
+func TestInspectHealthyEndpoint(t *testing.T) {
+	ca := newCA(t)
+	address, stop := ca.serve(t, now.Add(90*24*time.Hour))
+	defer stop()
+
+	r := testInspector(ca.pool()).Inspect(context.TODO(), Endpoint{Name: "adminrouter", Address: address})
+
+	assert.True(t, r.Healthy(), r.Problems)
+	assert.True(t, r.Verified)
+	assert.Empty(t, r.Error)
+	assert.Equal(t, "TLS 1.3", r.Version)
+	require.Len(t, r.Chain, 2)
+	assert.Equal(t, "CN=master.mesos", r.Chain[0].Subject)
+	assert.Equal(t, "CN=DC/OS Root CA", r.Chain[0].Issuer)
+	assert.Equal(t, []string{"master.mesos"}, r.Chain[0].DNSNames)
+	assert.Equal(t, []string{"127.0.0.1"}, r.Chain[0].IPAddresses)
+	assert.Equal(t, 90, r.Chain[0].DaysLeft)
+	assert.False(t, r.Chain[0].IsCA)
+	assert.True(t, r.Chain[1].IsCA)
+	assert.Len(t, r.Chain[0].SHA256, 64)
+	assert.Equal(t, now.Add(90*24*time.Hour), *r.ExpiresAt)
+}
+
+func TestInspectExpiringCertificate(t *testing.T) {
+	ca := newCA(t)
+	address, stop := ca.serve(t, now.Add(10*24*time.Hour+time.Hour))
+	defer stop()
+
+	r := testInspector(ca.pool()).Inspect(context.TODO(), Endpoint{Name: "mesos", Address: address})
+
+	assert.False(t, r.Healthy())
+	assert.True(t, r.Verified)
+	assert.Equal(t, []string{`certificate "CN=master.mesos" expires in 10 days on 2020-10-29T13:00:00Z`}, r.Problems)
+}
+
+func TestInspectExpiredCertificate(t *testing.T) {
+	ca := newCA(t)
+	address, stop := ca.serve(t, now.Add(-time.Minute))
+	defer stop()
+
+	r := testInspector(ca.pool()).Inspect(context.TODO(), Endpoint{Name: "mesos", Address: address})
+
+	assert.False(t, r.Verified)
+	assert.Contains(t, r.VerifyError, "expired")
+	require.Len(t, r.Problems, 2)
+	assert.Equal(t, `certificate "CN=master.mesos" expired on 2020-10-19T11:59:00Z`, r.Problems[1])
+	assert.Equal(t, -1, r.Chain[0].DaysLeft)
+}
+
+func TestInspectUnknownAuthorityAndName(t *testing.T) {
+	ca := newCA(t)
+	address, stop := ca.serve(t, now.Add(90*24*time.Hour))
+	defer stop()
+
+	r := testInspector(newCA(t).pool()).Inspect(context.TODO(), Endpoint{Name: "zk", Address: address})
+	assert.False(t, r.Verified)
+	assert.Contains(t, r.VerifyError, "unknown authority")
+	assert.Len(t, r.Chain, 2, "chain is recorded even if it does not verify")
+
+	r = testInspector(ca.pool()).Inspect(context.TODO(), Endpoint{Name: "zk", Address: address, ServerName: "leader.mesos"})
+	assert.False(t, r.Verified)
+	assert.Contains(t, r.VerifyError, "leader.mesos")
+}
+
+func TestInspectConnectionError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+
+	r := testInspector(nil).Inspect(context.TODO(), Endpoint{Name: "diagnostics", Address: address})
+	assert.False(t, r.Healthy())
+	assert.NotEmpty(t, r.Error)
+	assert.Empty(t, r.Chain)
+
+	r = testInspector(nil).Inspect(context.TODO(), Endpoint{Name: "diagnostics", Address: "localhost"})
+	assert.Contains(t, r.Error, "invalid address")
+}
+
+func TestLoadRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roots")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newCA(t)
+	path := filepath.Join(dir, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0644))
+	pool, err := LoadRoots(path)
+	require.NoError(t, err)
+	assert.Len(t, pool.Subjects(), 1)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a certificate"), 0644))
+	_, err = LoadRoots(path)
+	assert.EqualError(t, err, "no PEM encoded certificates found in "+path)
+}
@@ -0,0 +1,423 @@
+package ui
+
+// appJS must not contain backquotes, strings are concatenated instead of using template literals
+const appJS = `(function () {
+  "use strict";
+
+  var levels = ["ok", "warning", "critical", "unknown"];
+  var healthLevels = {0: "ok", 1: "critical", 3: "unknown"};
+  var bundlePoll = null;
+  var healthPoll = null;
+  var browsedBundle = null;
+  var viewedFile = null;
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  // el creates an element with the given attributes and children, strings are added as text
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      if (k === "onclick") {
+        e.addEventListener("click", attrs[k]);
+      } else if (k === "className") {
+        e.className = attrs[k];
+      } else {
+        e.setAttribute(k, attrs[k]);
+      }
+    });
+    (children || []).forEach(function (c) {
+      e.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+    });
+    return e;
+  }
+
+  function clear(e) {
+    while (e.firstChild) {
+      e.removeChild(e.firstChild);
+    }
+    return e;
+  }
+
+  function showError(message) {
+    var e = $("error");
+    e.textContent = message;
+    e.hidden = !message;
+  }
+
+  // request calls the API and rejects with the error reported by the daemon
+  function request(method, url, body) {
+    var options = {method: method, headers: {}};
+    if (body !== undefined) {
+      options.body = JSON.stringify(body);
+      options.headers["Content-Type"] = "application/json";
+    }
+    return fetch(url, options).then(function (resp) {
+      return resp.text().then(function (text) {
+        if (!resp.ok) {
+          var message = text;
+          try {
+            message = JSON.parse(text).error || text;
+          } catch (e) {
+            // not a JSON error, report the body as is
+          }
+          throw new Error(method + " " + url + ": " + resp.status + " " + message);
+        }
+        return text;
+      });
+    });
+  }
+
+  function getJSON(url) {
+    return request("GET", url).then(JSON.parse);
+  }
+
+  function levelOf(item) {
+    if (item.SuppressedHealth !== undefined) {
+      return item.SuppressedLevel || healthLevels[item.SuppressedHealth] || "unknown";
+    }
+    if (item.Level) {
+      return item.Level;
+    }
+    return healthLevels[item.Health] || "unknown";
+  }
+
+  function formatBytes(n) {
+    if (!n) {
+      return "-";
+    }
+    var units = ["B", "KiB", "MiB", "GiB", "TiB"];
+    var i = 0;
+    while (n >= 1024 && i < units.length - 1) {
+      n /= 1024;
+      i++;
+    }
+    return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+  }
+
+  function isZeroTime(t) {
+    return !t || t.indexOf("0001-01-01") === 0;
+  }
+
+  function formatTime(t) {
+    return isZeroTime(t) ? "-" : new Date(t).toLocaleString();
+  }
+
+  function formatDuration(start, stop) {
+    if (isZeroTime(start)) {
+      return "-";
+    }
+    var end = isZeroTime(stop) ? new Date() : new Date(stop);
+    var seconds = Math.max(0, Math.round((end - new Date(start)) / 1000));
+    if (seconds < 60) {
+      return seconds + "s";
+    }
+    return Math.floor(seconds / 60) + "m " + (seconds % 60) + "s";
+  }
+
+  function pathURL(path) {
+    return path.split("/").map(encodeURIComponent).join("/");
+  }
+
+  // ---- Health
+
+  function loadHealth() {
+    return getJSON("report").then(function (report) {
+      showError("");
+      renderHealth(report);
+    }).catch(function (e) {
+      showError(e.message);
+    });
+  }
+
+  function renderHealth(report) {
+    var nodes = report.Nodes || {};
+    var ips = Object.keys(nodes).sort();
+    var table = clear($("health-matrix"));
+    $("health-updated").textContent = isZeroTime(report.UpdatedTime) ? "" : "Updated " + formatTime(report.UpdatedTime);
+    if (ips.length === 0) {
+      table.appendChild(el("tr", {}, [el("td", {}, ["No nodes reported yet. Health is aggregated only by masters started with --pull."])]));
+      return;
+    }
+
+    var unitNames = {};
+    var cells = {};
+    ips.forEach(function (ip) {
+      cells[ip] = {};
+      (nodes[ip].Units || []).forEach(function (u) {
+        unitNames[u.UnitName] = u.PrettyName || u.UnitName;
+        cells[ip][u.UnitName] = u;
+      });
+    });
+    var onlyProblems = $("health-problems").checked;
+    var units = Object.keys(unitNames).sort().filter(function (name) {
+      return !onlyProblems || ips.some(function (ip) {
+        var u = cells[ip][name];
+        return u && levelOf(u) !== "ok";
+      });
+    });
+
+    var head = el("tr", {}, [el("th", {}, ["Unit"])]);
+    var nodeRow = el("tr", {}, [el("th", {}, ["Node"])]);
+    ips.forEach(function (ip) {
+      var n = nodes[ip];
+      head.appendChild(el("th", {title: n.Host || ""}, [ip, el("br"), el("span", {className: "muted"}, [n.Role || ""])]));
+      nodeRow.appendChild(el("td", {}, [cell(levelOf(n), n.Silenced, function () {
+        showDetails(ip, nodeOutput(n));
+      })]));
+    });
+    table.appendChild(el("thead", {}, [head]));
+
+    var body = el("tbody", {}, [nodeRow]);
+    units.forEach(function (name) {
+      var row = el("tr", {}, [el("td", {title: name}, [unitNames[name]])]);
+      ips.forEach(function (ip) {
+        var u = cells[ip][name];
+        if (!u) {
+          row.appendChild(el("td", {}, []));
+          return;
+        }
+        row.appendChild(el("td", {}, [cell(levelOf(u), u.Silenced, function () {
+          showDetails(name + " on " + ip, [u.Output || ""].concat(u.Reasons || []).join("\n"));
+        })]));
+      });
+      body.appendChild(row);
+    });
+    table.appendChild(body);
+  }
+
+  function cell(level, silenced, onclick) {
+    var classes = "cell " + (levels.indexOf(level) >= 0 ? level : "unknown") + (silenced ? " silenced" : "");
+    return el("span", {className: classes, title: level + (silenced ? " (silenced)" : ""), onclick: onclick}, [level.charAt(0).toUpperCase()]);
+  }
+
+  function nodeOutput(node) {
+    var output = node.Output || {};
+    return Object.keys(output).filter(function (k) {
+      return output[k];
+    }).map(function (k) {
+      return k + ": " + output[k];
+    }).join("\n");
+  }
+
+  function showDetails(title, text) {
+    var e = $("health-details");
+    e.textContent = title + "\n\n" + (text || "no output");
+    e.hidden = false;
+  }
+
+  // ---- Bundles
+
+  function loadBundles() {
+    return getJSON("diagnostics").then(function (bundles) {
+      renderBundles(bundles || []);
+      var running = bundles && bundles.some(function (b) {
+        return b.status === "Started" || b.status === "InProgress";
+      });
+      clearTimeout(bundlePoll);
+      if (running && location.hash === "#bundles") {
+        bundlePoll = setTimeout(loadBundles, 2000);
+      }
+    }).catch(function (e) {
+      showError(e.message);
+    });
+  }
+
+  function renderBundles(bundles) {
+    bundles.sort(function (a, b) {
+      return (b.started_at || "").localeCompare(a.started_at || "");
+    });
+    var body = clear($("bundles-table").tBodies[0]);
+    bundles.forEach(function (b) {
+      var actions = el("td", {}, []);
+      var done = b.status === "Done";
+      if (done) {
+        actions.appendChild(el("a", {href: "diagnostics/" + encodeURIComponent(b.id) + "/file"}, ["Download"]));
+        actions.appendChild(document.createTextNode(" "));
+        if (!b.encrypted) {
+          actions.appendChild(el("span", {className: "link", onclick: function () {
+            browse(b.id);
+          }}, ["Browse"]));
+          actions.appendChild(document.createTextNode(" "));
+        }
+      }
+      // running bundles could not be canceled yet
+      if (done || b.status === "Failed") {
+        actions.appendChild(el("button", {onclick: function () {
+          deleteBundle(b.id);
+        }}, ["Delete"]));
+      }
+      var status = b.status + (b.encrypted ? " (encrypted)" : "");
+      body.appendChild(el("tr", {}, [
+        el("td", {}, [b.id]),
+        el("td", {title: (b.errors || []).join("\n")}, [status + ((b.errors || []).length ? " (" + b.errors.length + " errors)" : "")]),
+        el("td", {}, [formatTime(b.started_at)]),
+        el("td", {}, [formatDuration(b.started_at, b.stopped_at)]),
+        el("td", {}, [formatBytes(b.size)]),
+        el("td", {}, [b.format || "zip"]),
+        actions
+      ]));
+    });
+    if (bundles.length === 0) {
+      body.appendChild(el("tr", {}, [el("td", {colspan: "7", className: "muted"}, ["No bundles"])]));
+    }
+  }
+
+  function generateID() {
+    return "bundle-" + new Date().toISOString().replace(/\..*$/, "").replace(/:/g, "-");
+  }
+
+  function createBundle(event) {
+    event.preventDefault();
+    var id = $("bundle-id").value.trim() || generateID();
+    var options = {
+      masters: $("bundle-masters").checked,
+      agents: $("bundle-agents").checked,
+      network_matrix: $("bundle-network-matrix").checked,
+      format: $("bundle-format").value
+    };
+    var key = $("bundle-key").value.trim();
+    if (key) {
+      options.encryption_key = key;
+    }
+    request("PUT", "diagnostics/" + encodeURIComponent(id), options).then(function () {
+      showError("");
+      $("bundle-id").value = "";
+      return loadBundles();
+    }).catch(function (e) {
+      showError(e.message);
+    });
+  }
+
+  function deleteBundle(id) {
+    if (!confirm("Delete bundle " + id + "?")) {
+      return;
+    }
+    request("DELETE", "diagnostics/" + encodeURIComponent(id)).then(function () {
+      showError("");
+      if (browsedBundle === id) {
+        closeBrowser();
+      }
+      return loadBundles();
+    }).catch(function (e) {
+      showError(e.message);
+    });
+  }
+
+  // ---- File browser
+
+  function browse(id) {
+    getJSON("diagnostics/" + encodeURIComponent(id) + "/files").then(function (files) {
+      showError("");
+      browsedBundle = id;
+      viewedFile = null;
+      $("browser-bundle").textContent = id;
+      $("viewer-name").textContent = "";
+      $("viewer-content").textContent = "Select a file";
+      $("viewer-raw").removeAttribute("href");
+      renderFiles(files || []);
+      $("browser").hidden = false;
+    }).catch(function (e) {
+      showError(e.message);
+    });
+  }
+
+  function renderFiles(files) {
+    files.sort(function (a, b) {
+      return a.name.localeCompare(b.name);
+    });
+    var table = clear($("browser-files"));
+    table.appendChild(el("thead", {}, [el("tr", {}, [
+      el("th", {}, ["Name"]), el("th", {}, ["Size"]), el("th", {}, ["Compressed"]), el("th", {}, ["Modified"])
+    ])]));
+    var body = el("tbody", {}, []);
+    files.forEach(function (f) {
+      body.appendChild(el("tr", {}, [
+        el("td", {}, [el("span", {className: "link", onclick: function () {
+          viewFile(f.name);
+        }}, [f.name])]),
+        el("td", {}, [formatBytes(f.size)]),
+        el("td", {}, [formatBytes(f.compressed_size)]),
+        el("td", {}, [formatTime(f.mtime)])
+      ]));
+    });
+    table.appendChild(body);
+  }
+
+  function fileURL(name, filtered) {
+    var url = "diagnostics/" + encodeURIComponent(browsedBundle) + "/files/" + pathURL(name);
+    if (!filtered) {
+      return url;
+    }
+    var params = [];
+    var grep = $("viewer-grep").value;
+    if (grep) {
+      params.push("grep=" + encodeURIComponent(grep));
+    }
+    var mode = $("viewer-mode").value;
+    if (mode) {
+      params.push(mode + "=" + encodeURIComponent($("viewer-lines").value));
+    }
+    return params.length ? url + "?" + params.join("&") : url;
+  }
+
+  function viewFile(name) {
+    viewedFile = name;
+    $("viewer-name").textContent = name;
+    $("viewer-raw").href = fileURL(name, false);
+    if (/\.(gz|zst)$/.test(name)) {
+      $("viewer-content").textContent = "Compressed file, use the raw link to download it";
+      return;
+    }
+    $("viewer-content").textContent = "Loading...";
+    request("GET", fileURL(name, true)).then(function (text) {
+      showError("");
+      $("viewer-content").textContent = text;
+    }).catch(function (e) {
+      $("viewer-content").textContent = "";
+      showError(e.message);
+    });
+  }
+
+  function closeBrowser() {
+    browsedBundle = null;
+    $("browser").hidden = true;
+  }
+
+  // ---- Navigation
+
+  function showTab() {
+    var tab = location.hash === "#bundles" ? "bundles" : "health";
+    ["health", "bundles"].forEach(function (t) {
+      $(t).hidden = t !== tab;
+    });
+    Array.prototype.forEach.call(document.querySelectorAll("nav a"), function (a) {
+      a.className = a.getAttribute("data-tab") === tab ? "active" : "";
+    });
+    clearTimeout(bundlePoll);
+    clearInterval(healthPoll);
+    showError("");
+    if (tab === "health") {
+      loadHealth();
+      healthPoll = setInterval(loadHealth, 30000);
+    } else {
+      loadBundles();
+    }
+  }
+
+  $("health-refresh").addEventListener("click", loadHealth);
+  $("health-problems").addEventListener("change", loadHealth);
+  $("bundle-form").addEventListener("submit", createBundle);
+  $("bundles-refresh").addEventListener("click", loadBundles);
+  $("browser-close").addEventListener("click", closeBrowser);
+  $("viewer-form").addEventListener("submit", function (event) {
+    event.preventDefault();
+    if (viewedFile) {
+      viewFile(viewedFile);
+    }
+  });
+  window.addEventListener("hashchange", showTab);
+  showTab();
+})();
+`
@@ -0,0 +1,96 @@
+package ui
+
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>DC/OS Diagnostics</title>
+<link rel="stylesheet" href="ui/style.css">
+</head>
+<body>
+<header>
+  <h1>DC/OS Diagnostics</h1>
+  <nav>
+    <a href="#health" data-tab="health">Health</a>
+    <a href="#bundles" data-tab="bundles">Bundles</a>
+  </nav>
+</header>
+<div id="error" class="error" hidden></div>
+
+<main>
+<section id="health" hidden>
+  <div class="toolbar">
+    <button id="health-refresh">Refresh</button>
+    <label><input type="checkbox" id="health-problems"> Only problems</label>
+    <span id="health-updated" class="muted"></span>
+  </div>
+  <div class="legend">
+    <span class="cell ok">ok</span>
+    <span class="cell warning">warning</span>
+    <span class="cell critical">critical</span>
+    <span class="cell unknown">unknown</span>
+    <span class="cell silenced">silenced</span>
+  </div>
+  <div class="scroll"><table id="health-matrix"></table></div>
+  <pre id="health-details" class="output" hidden></pre>
+</section>
+
+<section id="bundles" hidden>
+  <form id="bundle-form">
+    <h2>Create bundle</h2>
+    <label>ID <input type="text" id="bundle-id" placeholder="generated when empty"></label>
+    <label><input type="checkbox" id="bundle-masters" checked> Masters</label>
+    <label><input type="checkbox" id="bundle-agents" checked> Agents</label>
+    <label><input type="checkbox" id="bundle-network-matrix"> Network matrix</label>
+    <label>Format
+      <select id="bundle-format">
+        <option value="zip">zip</option>
+        <option value="tar.gz">tar.gz</option>
+        <option value="tar.zst">tar.zst</option>
+      </select>
+    </label>
+    <details>
+      <summary>Encryption key</summary>
+      <textarea id="bundle-key" rows="6" placeholder="PEM encoded RSA public key, the configured key is used when empty"></textarea>
+    </details>
+    <button type="submit">Create</button>
+  </form>
+
+  <h2>Bundles <button id="bundles-refresh">Refresh</button></h2>
+  <table id="bundles-table">
+    <thead>
+      <tr><th>ID</th><th>Status</th><th>Started</th><th>Duration</th><th>Size</th><th>Format</th><th></th></tr>
+    </thead>
+    <tbody></tbody>
+  </table>
+
+  <div id="browser" hidden>
+    <h2>Files of <span id="browser-bundle"></span> <button id="browser-close">Close</button></h2>
+    <div class="browser">
+      <div class="scroll files"><table id="browser-files"></table></div>
+      <div class="viewer">
+        <form id="viewer-form">
+          <strong id="viewer-name"></strong>
+          <label>grep <input type="text" id="viewer-grep" placeholder="regular expression"></label>
+          <label>
+            <select id="viewer-mode">
+              <option value="">all lines</option>
+              <option value="head">head</option>
+              <option value="tail">tail</option>
+            </select>
+            <input type="number" id="viewer-lines" min="1" value="100">
+          </label>
+          <button type="submit">Show</button>
+          <a id="viewer-raw" target="_blank">raw</a>
+        </form>
+        <pre id="viewer-content" class="output"></pre>
+      </div>
+    </div>
+  </div>
+</section>
+</main>
+<script src="ui/app.js"></script>
+</body>
+</html>
+`
@@ -0,0 +1,48 @@
+package ui
+
+const styleCSS = `body {
+  margin: 0;
+  font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif;
+  font-size: 14px;
+  color: #1b2029;
+}
+header {
+  display: flex;
+  align-items: center;
+  gap: 24px;
+  padding: 8px 16px;
+  background: #1b2029;
+  color: #fff;
+}
+header h1 { font-size: 18px; margin: 0; }
+nav a { color: #b8c0cc; margin-right: 16px; text-decoration: none; }
+nav a.active { color: #fff; border-bottom: 2px solid #fff; }
+main { padding: 16px; }
+h2 { font-size: 16px; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 8px; border-bottom: 1px solid #e1e4e8; text-align: left; white-space: nowrap; }
+th { background: #f6f8fa; }
+button { cursor: pointer; }
+label { margin-right: 12px; }
+.toolbar, .legend { margin-bottom: 12px; }
+.muted { color: #6a737d; }
+.error { margin: 16px; padding: 8px; background: #ffe3e3; color: #86181d; white-space: pre-wrap; }
+.scroll { overflow: auto; }
+.output { background: #f6f8fa; padding: 8px; max-height: 60vh; overflow: auto; white-space: pre-wrap; word-break: break-all; }
+.cell { display: inline-block; min-width: 16px; padding: 2px 6px; border-radius: 3px; text-align: center; cursor: pointer; }
+.ok { background: #dcffe4; }
+.warning { background: #fff5b1; }
+.critical { background: #ffdce0; }
+.unknown { background: #e1e4e8; }
+.silenced { background: repeating-linear-gradient(45deg, #e1e4e8, #e1e4e8 4px, #fff 4px, #fff 8px); }
+.link { color: #0366d6; cursor: pointer; text-decoration: underline; }
+#bundle-form { padding: 8px 12px; border: 1px solid #e1e4e8; border-radius: 4px; max-width: 900px; }
+#bundle-form h2 { margin-top: 0; }
+#bundle-form details { margin: 8px 0; }
+#bundle-form textarea { width: 100%; font-family: monospace; }
+.browser { display: flex; gap: 16px; align-items: flex-start; }
+.browser .files { max-height: 70vh; flex: 0 0 auto; }
+.browser .viewer { flex: 1 1 auto; min-width: 0; }
+#viewer-form { margin-bottom: 8px; }
+#viewer-lines { width: 70px; }
+`
@@ -0,0 +1,42 @@
+// Package ui serves the web UI showing health of the cluster and managing diagnostics bundles. Assets are
+// kept in Go sources so they are compiled into the binary and the UI works without access to the internet.
+// The UI talks to the same REST API as other clients, all its requests are relative to the health API root.
+package ui
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// indexName is the asset served when no asset is requested
+const indexName = "index.html"
+
+type asset struct {
+	contentType string
+	content     string
+}
+
+var assets = map[string]asset{
+	indexName:   {"text/html; charset=utf-8", indexHTML},
+	"app.js":    {"application/javascript; charset=utf-8", appJS},
+	"style.css": {"text/css; charset=utf-8", styleCSS},
+}
+
+// Handler serves the asset named by the asset path variable, the index page when there is no variable
+func Handler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["asset"]
+	if name == "" {
+		name = indexName
+	}
+	a, ok := assets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", a.contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, name, time.Time{}, strings.NewReader(a.content))
+}
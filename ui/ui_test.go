@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/ui", Handler)
+	router.HandleFunc("/ui/{asset}", Handler)
+	return router
+}
+
+func TestHandlerServesAssets(t *testing.T) {
+	router := newRouter()
+
+	for _, tc := range []struct {
+		url         string
+		contentType string
+		content     string
+	}{
+		{"/ui", "text/html; charset=utf-8", indexHTML},
+		{"/ui/index.html", "text/html; charset=utf-8", indexHTML},
+		{"/ui/app.js", "application/javascript; charset=utf-8", appJS},
+		{"/ui/style.css", "text/css; charset=utf-8", styleCSS},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tc.content, rr.Body.String())
+		})
+	}
+}
+
+func TestHandlerReturns404ForUnknownAsset(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/ui/missing.js", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestIndexReferencesOnlyEmbeddedAssets ensures the UI works without access to the internet
+func TestIndexReferencesOnlyEmbeddedAssets(t *testing.T) {
+	refs := regexp.MustCompile(`(?:src|href)="([^"#]+)"`).FindAllStringSubmatch(indexHTML, -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		name := regexp.MustCompile(`^ui/`).ReplaceAllString(ref[1], "")
+		_, ok := assets[name]
+		assert.True(t, ok, "%s is not an asset", ref[1])
+	}
+}
@@ -11,3 +11,8 @@ import (
 func ReadJournalOutputSince(ctx context.Context, unit string, duration time.Duration) (io.ReadCloser, error) {
 	return nil, errors.New("does not work on darwin")
 }
+
+// ReadJournal returns error since darwin does not support journal
+func ReadJournal(ctx context.Context, unit string, options JournalOptions) (io.ReadCloser, error) {
+	return nil, errors.New("does not work on darwin")
+}
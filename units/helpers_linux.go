@@ -2,6 +2,7 @@ package units
 
 import (
 	"context"
+	"fmt"
 	goio "io"
 	"time"
 
@@ -10,30 +11,148 @@ import (
 	"github.com/dcos/dcos-diagnostics/io"
 )
 
+// followWaitTimeout is the longest time a followed journal waits for new entries before checking
+// whether the reader was cancelled.
+const followWaitTimeout = time.Second
+
 // ReadJournalOutputSince returns logs since given duration from journal
 func ReadJournalOutputSince(ctx context.Context, unit string, duration time.Duration) (goio.ReadCloser, error) {
-	return readJournalOutput(ctx, unit, duration, 0)
+	options := DefaultJournalOptions()
+	options.Since = duration
+	return ReadJournal(ctx, unit, options)
 }
 
 // ReadJournalTail returns numFromTail log lines from the end of the log
 func ReadJournalTail(ctx context.Context, unit string, numFromTail uint64) (goio.ReadCloser, error) {
-	return readJournalOutput(ctx, unit, 0, numFromTail)
+	options := DefaultJournalOptions()
+	options.NumFromTail = numFromTail
+	return ReadJournal(ctx, unit, options)
 }
 
-func readJournalOutput(ctx context.Context, unit string, d time.Duration, n uint64) (goio.ReadCloser, error) {
-	// We need to pass d for the past not future. So it need to negative
-	if d > 0 {
-		d = -d
+// ReadJournal returns logs of the given unit read from journal with the given options.
+// Empty unit reads logs of all units.
+func ReadJournal(ctx context.Context, unit string, options JournalOptions) (goio.ReadCloser, error) {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, fmt.Errorf("could not open journal: %s", err)
+	}
+
+	bootID, err := resolveBoot(j, options.Boot)
+	if err != nil {
+		j.Close()
+		return nil, err
+	}
+
+	matches := options.matches(unit, bootID)
+	for _, m := range matches {
+		if err := j.AddMatch(m.String()); err != nil {
+			j.Close()
+			return nil, fmt.Errorf("could not add journal match %s: %s", m, err)
+		}
 	}
-	config := sdjournal.JournalReaderConfig{
-		Since:       d,
-		NumFromTail: n,
-		Matches: []sdjournal.Match{
-			{Field: sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT, Value: unit},
+
+	if err := seek(j, options); err != nil {
+		j.Close()
+		return nil, fmt.Errorf("could not seek journal: %s", err)
+	}
+
+	r := &journalReader{
+		next: func() (*JournalEntry, error) {
+			for {
+				n, err := j.Next()
+				if err != nil {
+					return nil, err
+				}
+				if n > 0 {
+					break
+				}
+				if !options.Follow {
+					return nil, goio.EOF
+				}
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				j.Wait(followWaitTimeout)
+			}
+			e, err := j.GetEntry()
+			if err != nil {
+				return nil, err
+			}
+			return &JournalEntry{
+				Fields:             e.Fields,
+				Cursor:             e.Cursor,
+				RealtimeTimestamp:  e.RealtimeTimestamp,
+				MonotonicTimestamp: e.MonotonicTimestamp,
+			}, nil
 		},
+		close:   j.Close,
+		format:  options.Format,
+		matches: matches,
+	}
+
+	return io.ReadCloserWithContext(ctx, r), nil
+}
+
+// seek moves journal read pointer to the first entry that should be read. It works like sdjournal.JournalReader
+// except that followed journal without Since and NumFromTail starts at its end.
+func seek(j *sdjournal.Journal, options JournalOptions) error {
+	if options.Since != 0 {
+		// We need to pass d for the past not future. So it need to negative
+		d := options.Since
+		if d > 0 {
+			d = -d
+		}
+		start := time.Now().Add(d)
+		return j.SeekRealtimeUsec(uint64(start.UnixNano() / 1000))
+	}
+
+	if options.NumFromTail != 0 || options.Follow {
+		if err := j.SeekTail(); err != nil {
+			return err
+		}
+		// Go one further than the option so the first Next call positions us at the correct entry
+		skip, err := j.PreviousSkip(options.NumFromTail + 1)
+		if err != nil {
+			return err
+		}
+		// If we skipped fewer entries than expected, we have reached journal start
+		if skip != options.NumFromTail+1 {
+			return j.SeekHead()
+		}
+	}
+	return nil
+}
+
+// resolveBoot returns boot ID selected by boot option. Boots are listed only when boot is given as an offset.
+func resolveBoot(j *sdjournal.Journal, boot string) (string, error) {
+	if boot == "" || bootIDRegexp.MatchString(boot) {
+		return selectBoot(nil, boot)
 	}
 
-	src, err := sdjournal.NewJournalReader(config)
+	ids, err := j.GetUniqueValues("_BOOT_ID")
+	if err != nil {
+		return "", fmt.Errorf("could not list boots: %s", err)
+	}
+	defer j.FlushMatches()
+
+	boots := make([]journalBoot, 0, len(ids))
+	for _, id := range ids {
+		j.FlushMatches()
+		if err := j.AddMatch("_BOOT_ID=" + id); err != nil {
+			return "", fmt.Errorf("could not list boots: %s", err)
+		}
+		if err := j.SeekHead(); err != nil {
+			return "", fmt.Errorf("could not list boots: %s", err)
+		}
+		if n, err := j.Next(); err != nil || n == 0 {
+			continue
+		}
+		first, err := j.GetRealtimeUsec()
+		if err != nil {
+			return "", fmt.Errorf("could not list boots: %s", err)
+		}
+		boots = append(boots, journalBoot{id: id, first: first})
+	}
 
-	return io.ReadCloserWithContext(ctx, src), err
+	return selectBoot(boots, boot)
 }
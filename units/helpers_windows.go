@@ -11,3 +11,8 @@ import (
 func ReadJournalOutputSince(ctx context.Context, unit string, duration time.Duration) (io.ReadCloser, error) {
 	return nil, errors.New("there is no journal on Windows")
 }
+
+// ReadJournal returns error since windows does not support journal
+func ReadJournal(ctx context.Context, unit string, options JournalOptions) (io.ReadCloser, error) {
+	return nil, errors.New("there is no journal on Windows")
+}
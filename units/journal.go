@@ -0,0 +1,404 @@
+package units
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JournalFormat is an output format of journal entries. Formats follow `journalctl --output` modes.
+type JournalFormat string
+
+const (
+	// Short prints entry timestamp and message. It's the default format.
+	Short JournalFormat = "short"
+	// ShortISO prints ISO 8601 timestamp, hostname, identifier with PID and message like `journalctl -o short-iso`
+	ShortISO JournalFormat = "short-iso"
+	// JSON prints every entry as a single line JSON object with all fields like `journalctl -o json`
+	JSON JournalFormat = "json"
+	// Export prints entries in the journal export format that could be imported with systemd-journal-remote
+	Export JournalFormat = "export"
+)
+
+// ContentType returns MIME type of the output in the given format.
+func (f JournalFormat) ContentType() string {
+	switch f {
+	case JSON:
+		return "application/x-ndjson"
+	case Export:
+		return "application/vnd.fdo.journal"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+var priorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// journal field names consist of uppercase letters, digits and underscores
+var fieldNameRegexp = regexp.MustCompile(`^_{0,2}[A-Z0-9][A-Z0-9_]*$`)
+
+// bootIDRegexp matches 128 bit boot ID with or without dashes
+var bootIDRegexp = regexp.MustCompile(`^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$`)
+
+// JournalMatch selects entries with the field equal to the value
+type JournalMatch struct {
+	Field string
+	Value string
+}
+
+func (m JournalMatch) String() string {
+	return m.Field + "=" + m.Value
+}
+
+// JournalOptions drive which journal entries are read and how they are printed.
+type JournalOptions struct {
+	// Format of the output, empty means Short
+	Format JournalFormat
+	// Since limits entries to ones logged in the given duration before now. Zero means all entries.
+	Since time.Duration
+	// NumFromTail limits entries to the given number of the most recent ones. Used only when Since is zero.
+	NumFromTail uint64
+	// Follow keeps reading new entries when the end of the journal is reached, like `journalctl -f`.
+	// When neither Since nor NumFromTail is set only new entries are read.
+	Follow bool
+	// MaxPriority shows only entries with priority less or equal to it (0 is emerg, 7 is debug). -1 disables filtering.
+	MaxPriority int
+	// Boot selects entries from a single boot. It's a boot ID or an offset like in `journalctl -b`:
+	// 0 is the last boot, -1 is the boot before, 1 is the first boot. Empty means all boots.
+	Boot string
+	// Matches show only entries with given field values. Values of the same field are alternatives.
+	Matches []JournalMatch
+}
+
+// DefaultJournalOptions returns options that read all entries in Short format.
+func DefaultJournalOptions() JournalOptions {
+	return JournalOptions{Format: Short, MaxPriority: -1}
+}
+
+// ParseJournalOptions reads options from URL query parameters: format, priority, boot, lines, follow and
+// journal field matches (e.g., _PID=1&SYSLOG_IDENTIFIER=dcos-diagnostics). Since is not set.
+func ParseJournalOptions(query url.Values) (JournalOptions, error) {
+	options := DefaultJournalOptions()
+
+	if l := query.Get("lines"); l != "" {
+		n, err := strconv.ParseUint(l, 10, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid lines %q, expected a non-negative number", l)
+		}
+		options.NumFromTail = n
+	}
+
+	if f, ok := query["follow"]; ok && f[0] != "" { // follow without a value enables following
+		follow, err := strconv.ParseBool(f[0])
+		if err != nil {
+			return options, fmt.Errorf("invalid follow %q, expected true or false", f[0])
+		}
+		options.Follow = follow
+	} else if ok {
+		options.Follow = true
+	}
+
+	format, err := ParseJournalFormat(query.Get("format"))
+	if err != nil {
+		return options, err
+	}
+	options.Format = format
+
+	if p := query.Get("priority"); p != "" {
+		options.MaxPriority, err = ParsePriority(p)
+		if err != nil {
+			return options, err
+		}
+	}
+
+	if b, ok := query["boot"]; ok {
+		options.Boot = b[0]
+		if options.Boot == "" { // boot without a value is the current boot like `journalctl -b`
+			options.Boot = "0"
+		}
+		if err := validateBoot(options.Boot); err != nil {
+			return options, err
+		}
+	}
+
+	fields := make([]string, 0, len(query))
+	for field := range query {
+		if fieldNameRegexp.MatchString(field) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, value := range query[field] {
+			options.Matches = append(options.Matches, JournalMatch{Field: field, Value: value})
+		}
+	}
+
+	return options, nil
+}
+
+// ParseJournalFormat returns format with the given name. Empty name means Short.
+func ParseJournalFormat(name string) (JournalFormat, error) {
+	switch f := JournalFormat(name); f {
+	case "":
+		return Short, nil
+	case Short, ShortISO, JSON, Export:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown journal format %q, supported formats are: short, short-iso, json, export", name)
+}
+
+// ParsePriority parses syslog priority given as a number (0-7) or a name (emerg, alert, crit, err,
+// warning, notice, info, debug).
+func ParsePriority(p string) (int, error) {
+	for i, name := range priorities {
+		if p == name {
+			return i, nil
+		}
+	}
+	i, err := strconv.Atoi(p)
+	if err != nil || i < 0 || i >= len(priorities) {
+		return 0, fmt.Errorf("invalid priority %q, expected 0-7 or one of: %s", p, strings.Join(priorities, ", "))
+	}
+	return i, nil
+}
+
+func validateBoot(boot string) error {
+	if bootIDRegexp.MatchString(boot) {
+		return nil
+	}
+	if _, err := strconv.Atoi(boot); err != nil {
+		return fmt.Errorf("invalid boot %q, expected boot ID or offset", boot)
+	}
+	return nil
+}
+
+// journalBoot is a boot recorded in the journal with the time of its first entry.
+type journalBoot struct {
+	id    string
+	first uint64
+}
+
+// selectBoot returns ID of the boot described by boot option. Boots are ordered by the time of their first entry.
+func selectBoot(boots []journalBoot, boot string) (string, error) {
+	if boot == "" {
+		return "", nil
+	}
+	if bootIDRegexp.MatchString(boot) {
+		return strings.Replace(boot, "-", "", -1), nil
+	}
+	offset, err := strconv.Atoi(boot)
+	if err != nil {
+		return "", fmt.Errorf("invalid boot %q, expected boot ID or offset", boot)
+	}
+
+	sorted := append([]journalBoot{}, boots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].first < sorted[j].first })
+
+	index := offset - 1
+	if offset <= 0 {
+		index = len(sorted) - 1 + offset
+	}
+	if index < 0 || index >= len(sorted) {
+		return "", fmt.Errorf("boot %s not found, journal contains %d boot(s)", boot, len(sorted))
+	}
+	return sorted[index].id, nil
+}
+
+// matches returns journal matches for the given unit and resolved boot ID.
+// Journal ORs matches of the same field and ANDs matches of different fields.
+func (o JournalOptions) matches(unit, bootID string) []JournalMatch {
+	var matches []JournalMatch
+	if unit != "" {
+		matches = append(matches, JournalMatch{Field: "_SYSTEMD_UNIT", Value: unit})
+	}
+	if bootID != "" {
+		matches = append(matches, JournalMatch{Field: "_BOOT_ID", Value: bootID})
+	}
+	for p := 0; p <= o.MaxPriority && p < len(priorities); p++ {
+		matches = append(matches, JournalMatch{Field: "PRIORITY", Value: strconv.Itoa(p)})
+	}
+	return append(matches, o.Matches...)
+}
+
+// JournalEntry is a single journal entry with all its fields.
+type JournalEntry struct {
+	Fields             map[string]string
+	Cursor             string
+	RealtimeTimestamp  uint64
+	MonotonicTimestamp uint64
+}
+
+// entryMatches checks the entry against matches the same way journal does.
+func entryMatches(e *JournalEntry, matches []JournalMatch) bool {
+	byField := make(map[string]bool)
+	for _, m := range matches {
+		if _, ok := byField[m.Field]; !ok {
+			byField[m.Field] = false
+		}
+		if v, ok := e.Fields[m.Field]; ok && v == m.Value {
+			byField[m.Field] = true
+		}
+	}
+	for _, matched := range byField {
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteJournalEntry writes the entry to w in the given format.
+func WriteJournalEntry(w io.Writer, e *JournalEntry, format JournalFormat) error {
+	var err error
+	switch format {
+	case ShortISO:
+		err = writeShortISO(w, e)
+	case JSON:
+		err = writeJSON(w, e)
+	case Export:
+		err = writeExport(w, e)
+	default:
+		_, err = fmt.Fprintf(w, "%s %s\n", timestamp(e), e.Fields["MESSAGE"])
+	}
+	return err
+}
+
+func timestamp(e *JournalEntry) time.Time {
+	return time.Unix(0, int64(e.RealtimeTimestamp)*int64(time.Microsecond))
+}
+
+// writeShortISO prints the entry like `journalctl -o short-iso`. Timestamps are in UTC so output
+// does not depend on node time zone.
+func writeShortISO(w io.Writer, e *JournalEntry) error {
+	identifier := e.Fields["SYSLOG_IDENTIFIER"]
+	if identifier == "" {
+		identifier = e.Fields["_COMM"]
+	}
+	pid := e.Fields["_PID"]
+	if pid == "" {
+		pid = e.Fields["SYSLOG_PID"]
+	}
+	if pid != "" {
+		identifier += "[" + pid + "]"
+	}
+	_, err := fmt.Fprintf(w, "%s %s %s: %s\n",
+		timestamp(e).UTC().Format("2006-01-02T15:04:05-0700"), e.Fields["_HOSTNAME"], identifier, e.Fields["MESSAGE"])
+	return err
+}
+
+// writeJSON prints the entry like `journalctl -o json`. Values that are not valid UTF-8 are printed
+// as arrays of bytes.
+func writeJSON(w io.Writer, e *JournalEntry) error {
+	fields := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		if utf8.ValidString(v) {
+			fields[k] = v
+			continue
+		}
+		raw := make([]int, len(v))
+		for i := 0; i < len(v); i++ {
+			raw[i] = int(v[i])
+		}
+		fields[k] = raw
+	}
+	fields["__CURSOR"] = e.Cursor
+	fields["__REALTIME_TIMESTAMP"] = strconv.FormatUint(e.RealtimeTimestamp, 10)
+	fields["__MONOTONIC_TIMESTAMP"] = strconv.FormatUint(e.MonotonicTimestamp, 10)
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("could not marshal journal entry: %s", err)
+	}
+	_, err = w.Write(append(raw, '\n'))
+	return err
+}
+
+// writeExport prints the entry in the journal export format.
+// See: https://systemd.io/JOURNAL_EXPORT_FORMATS/
+func writeExport(w io.Writer, e *JournalEntry) error {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "__CURSOR=%s\n__REALTIME_TIMESTAMP=%d\n__MONOTONIC_TIMESTAMP=%d\n",
+		e.Cursor, e.RealtimeTimestamp, e.MonotonicTimestamp)
+
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		if name != "_BOOT_ID" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := e.Fields["_BOOT_ID"]; ok {
+		names = append([]string{"_BOOT_ID"}, names...)
+	}
+
+	for _, name := range names {
+		value := e.Fields[name]
+		if isPrintable(value) {
+			fmt.Fprintf(buf, "%s=%s\n", name, value)
+			continue
+		}
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value))) // nolint:errcheck
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// journalReader reads entries from the source and formats them. Entries that do not match are skipped.
+type journalReader struct {
+	next    func() (*JournalEntry, error)
+	close   func() error
+	format  JournalFormat
+	matches []JournalMatch
+	buf     bytes.Buffer
+}
+
+func (r *journalReader) Read(b []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		e, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		if !entryMatches(e, r.matches) {
+			continue
+		}
+		if err := WriteJournalEntry(&r.buf, e, r.format); err != nil {
+			return 0, err
+		}
+	}
+	return r.buf.Read(b)
+}
+
+func (r *journalReader) Close() error {
+	if r.close == nil {
+		return nil
+	}
+	return r.close()
+}
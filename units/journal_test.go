@@ -0,0 +1,277 @@
+package units
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	firstBoot  = "5d6a4f2a1c8e4b0f9e7d3c2b1a0f9e8d"
+	secondBoot = "9f8e7d6c5b4a39281706f5e4d3c2b1a0"
+)
+
+// loadJournalFixture reads entries saved with `journalctl -o json`
+func loadJournalFixture(t *testing.T) []*JournalEntry {
+	f, err := os.Open(filepath.Join("testdata", "journal", "entries.json"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []*JournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &raw))
+
+		e := &JournalEntry{Fields: map[string]string{}}
+		for k, v := range raw {
+			var value string
+			switch v := v.(type) {
+			case string:
+				value = v
+			case []interface{}: // binary fields are arrays of bytes
+				b := make([]byte, len(v))
+				for i := range v {
+					b[i] = byte(v[i].(float64))
+				}
+				value = string(b)
+			}
+			switch k {
+			case "__CURSOR":
+				e.Cursor = value
+			case "__REALTIME_TIMESTAMP":
+				e.RealtimeTimestamp, err = strconv.ParseUint(value, 10, 64)
+				require.NoError(t, err)
+			case "__MONOTONIC_TIMESTAMP":
+				e.MonotonicTimestamp, err = strconv.ParseUint(value, 10, 64)
+				require.NoError(t, err)
+			default:
+				e.Fields[k] = value
+			}
+		}
+		entries = append(entries, e)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func fixtureBoots(entries []*JournalEntry) []journalBoot {
+	first := map[string]uint64{}
+	for _, e := range entries {
+		id := e.Fields["_BOOT_ID"]
+		if ts, ok := first[id]; !ok || e.RealtimeTimestamp < ts {
+			first[id] = e.RealtimeTimestamp
+		}
+	}
+	var boots []journalBoot
+	for id, ts := range first {
+		boots = append(boots, journalBoot{id: id, first: ts})
+	}
+	return boots
+}
+
+// readFixture reads fixture entries the same way ReadJournal reads journal
+func readFixture(t *testing.T, unit string, options JournalOptions) string {
+	entries := loadJournalFixture(t)
+	bootID, err := selectBoot(fixtureBoots(entries), options.Boot)
+	require.NoError(t, err)
+
+	r := &journalReader{
+		next: func() (*JournalEntry, error) {
+			if len(entries) == 0 {
+				return nil, io.EOF
+			}
+			e := entries[0]
+			entries = entries[1:]
+			return e, nil
+		},
+		format:  options.Format,
+		matches: options.matches(unit, bootID),
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestJournalFormats(t *testing.T) {
+	for _, format := range []JournalFormat{ShortISO, JSON, Export} {
+		t.Run(string(format), func(t *testing.T) {
+			options := DefaultJournalOptions()
+			options.Format = format
+
+			expected, err := ioutil.ReadFile(filepath.Join("testdata", "journal", "expected."+string(format)))
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), readFixture(t, "", options))
+		})
+	}
+}
+
+func TestJournalJSONFormatRoundTrip(t *testing.T) {
+	entries := loadJournalFixture(t)
+	buf := bytes.NewBuffer(nil)
+	for _, e := range entries {
+		require.NoError(t, WriteJournalEntry(buf, e, JSON))
+	}
+
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", "journal", "entries.json"))
+	require.NoError(t, err)
+	expectedLines := bytes.Split(bytes.TrimSpace(expected), []byte("\n"))
+	actualLines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, actualLines, len(expectedLines))
+	for i := range expectedLines {
+		assert.JSONEq(t, string(expectedLines[i]), string(actualLines[i]))
+	}
+}
+
+func TestJournalFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     string
+		query    string
+		expected string
+	}{
+		{
+			name:  "unit",
+			unit:  "dcos-mesos-master.service",
+			query: "format=short-iso",
+			expected: "2020-06-08T07:06:41+0000 master-1 mesos-master[2345]: Failed to connect to ZooKeeper\n" +
+				"2020-06-08T08:06:42+0000 master-1 mesos-master[876]: Elected as leading master\n",
+		},
+		{
+			name:  "priority",
+			query: "format=short-iso&priority=warning",
+			expected: "2020-06-08T07:06:41+0000 master-1 mesos-master[2345]: Failed to connect to ZooKeeper\n" +
+				"2020-06-08T07:06:42+0000 master-1 dcos-diagnostics[1234]: Slow response from node\n" +
+				"2020-06-08T08:06:41+0000 master-1 dcos-diagnostics[987]: could not get unit names\n",
+		},
+		{
+			name:  "current boot",
+			unit:  "dcos-diagnostics.service",
+			query: "format=short-iso&boot",
+			expected: "2020-06-08T08:06:40+0000 master-1 dcos-diagnostics[987]: Starting dcos-diagnostics\n" +
+				"2020-06-08T08:06:41+0000 master-1 dcos-diagnostics[987]: could not get unit names\n",
+		},
+		{
+			name:     "previous boot and priority",
+			query:    "format=short-iso&boot=-1&priority=3",
+			expected: "2020-06-08T07:06:41+0000 master-1 mesos-master[2345]: Failed to connect to ZooKeeper\n",
+		},
+		{
+			name:     "boot ID",
+			unit:     "dcos-mesos-master.service",
+			query:    "format=short-iso&boot=" + secondBoot,
+			expected: "2020-06-08T08:06:42+0000 master-1 mesos-master[876]: Elected as leading master\n",
+		},
+		{
+			name:  "field matches",
+			query: "format=short-iso&_PID=1234&_PID=876",
+			expected: "2020-06-08T07:06:40+0000 master-1 dcos-diagnostics[1234]: Starting dcos-diagnostics\n" +
+				"2020-06-08T07:06:42+0000 master-1 dcos-diagnostics[1234]: Slow response from node\n" +
+				"2020-06-08T08:06:42+0000 master-1 mesos-master[876]: Elected as leading master\n",
+		},
+		{
+			name:     "different fields",
+			query:    "format=short-iso&SYSLOG_IDENTIFIER=dcos-diagnostics&PRIORITY=3",
+			expected: "2020-06-08T08:06:41+0000 master-1 dcos-diagnostics[987]: could not get unit names\n",
+		},
+		{
+			name:     "no match",
+			query:    "SYSLOG_IDENTIFIER=not-existing",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			options, err := ParseJournalOptions(query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, readFixture(t, tt.unit, options))
+		})
+	}
+}
+
+func TestParseJournalOptions(t *testing.T) {
+	query, err := url.ParseQuery("format=json&priority=4&boot=-2&lines=10&follow=true&_PID=1&SYSLOG_IDENTIFIER=a&lowercase=ignored&_PID=2")
+	require.NoError(t, err)
+
+	options, err := ParseJournalOptions(query)
+	require.NoError(t, err)
+	assert.Equal(t, JournalOptions{
+		Format:      JSON,
+		NumFromTail: 10,
+		Follow:      true,
+		MaxPriority: 4,
+		Boot:        "-2",
+		Matches: []JournalMatch{
+			{Field: "SYSLOG_IDENTIFIER", Value: "a"},
+			{Field: "_PID", Value: "1"},
+			{Field: "_PID", Value: "2"},
+		},
+	}, options)
+
+	options, err = ParseJournalOptions(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultJournalOptions(), options)
+
+	options, err = ParseJournalOptions(url.Values{"follow": {""}})
+	require.NoError(t, err)
+	assert.True(t, options.Follow)
+
+	options, err = ParseJournalOptions(url.Values{"follow": {"false"}})
+	require.NoError(t, err)
+	assert.False(t, options.Follow)
+}
+
+func TestParseJournalOptionsErrors(t *testing.T) {
+	tests := map[string]string{
+		"format=cat":  `unknown journal format "cat", supported formats are: short, short-iso, json, export`,
+		"priority=8":  `invalid priority "8", expected 0-7 or one of: emerg, alert, crit, err, warning, notice, info, debug`,
+		"priority=no": `invalid priority "no", expected 0-7 or one of: emerg, alert, crit, err, warning, notice, info, debug`,
+		"boot=last":   `invalid boot "last", expected boot ID or offset`,
+		"lines=-1":    `invalid lines "-1", expected a non-negative number`,
+		"follow=yes":  `invalid follow "yes", expected true or false`,
+	}
+
+	for raw, expected := range tests {
+		query, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+		_, err = ParseJournalOptions(query)
+		assert.EqualError(t, err, expected, raw)
+	}
+}
+
+func TestSelectBoot(t *testing.T) {
+	boots := fixtureBoots(loadJournalFixture(t))
+
+	tests := map[string]string{
+		"":                                     "",
+		"0":                                    secondBoot,
+		"-1":                                   firstBoot,
+		"1":                                    firstBoot,
+		"2":                                    secondBoot,
+		"9f8e7d6c-5b4a-3928-1706-f5e4d3c2b1a0": secondBoot,
+	}
+	for boot, expected := range tests {
+		id, err := selectBoot(boots, boot)
+		require.NoError(t, err, boot)
+		assert.Equal(t, expected, id, boot)
+	}
+
+	_, err := selectBoot(boots, "-2")
+	assert.EqualError(t, err, "boot -2 not found, journal contains 2 boot(s)")
+}
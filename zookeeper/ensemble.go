@@ -0,0 +1,216 @@
+package zookeeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout limits querying a single server
+const DefaultTimeout = 5 * time.Second
+
+// exhibitorServing is the Exhibitor code of a server that is serving
+const exhibitorServing = 3
+
+// ExhibitorServer is a server of the ensemble as seen by Exhibitor
+type ExhibitorServer struct {
+	Hostname    string `json:"hostname"`
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+	IsLeader    bool   `json:"is_leader"`
+}
+
+// Ensemble is the state of the ZooKeeper ensemble. Problems make the ensemble unhealthy.
+type Ensemble struct {
+	Time      time.Time `json:"time"`
+	Servers   []Server  `json:"servers"`
+	Leader    string    `json:"leader,omitempty"`
+	Followers []string  `json:"followers,omitempty"`
+	// Size is the number of servers of the ensemble
+	Size int `json:"size"`
+	// Serving is the number of servers that are part of the quorum
+	Serving int `json:"serving"`
+	// Quorum is the number of servers needed to serve requests
+	Quorum         int               `json:"quorum"`
+	HasQuorum      bool              `json:"has_quorum"`
+	Exhibitor      []ExhibitorServer `json:"exhibitor,omitempty"`
+	ExhibitorError string            `json:"exhibitor_error,omitempty"`
+	Problems       []string          `json:"problems,omitempty"`
+}
+
+// Healthy returns true if the ensemble has no problems
+func (e Ensemble) Healthy() bool {
+	return len(e.Problems) == 0
+}
+
+// Servers returns addresses of servers of the ensemble
+type Servers func() ([]string, error)
+
+// Inspector queries servers of the ensemble and Exhibitor
+type Inspector struct {
+	// Servers returns addresses of servers
+	Servers Servers
+	// ExhibitorURL is the Exhibitor cluster status endpoint, Exhibitor is not queried if empty
+	ExhibitorURL string
+	// Client fetches Exhibitor cluster status
+	Client *http.Client
+	// Dial connects to servers
+	Dial DialFunc
+	// Timeout limits querying a single server
+	Timeout time.Duration
+}
+
+// NewInspector returns Inspector of servers with default settings
+func NewInspector(servers Servers, exhibitorURL string, client *http.Client) *Inspector {
+	return &Inspector{
+		Servers:      servers,
+		ExhibitorURL: exhibitorURL,
+		Client:       client,
+		Dial:         newDialer(DefaultTimeout),
+		Timeout:      DefaultTimeout,
+	}
+}
+
+// HostServers returns Servers listening on DefaultPort of the hosts
+func HostServers(hosts func() ([]string, error)) Servers {
+	return func() ([]string, error) {
+		h, err := hosts()
+		if err != nil {
+			return nil, err
+		}
+		addresses := make([]string, 0, len(h))
+		for _, host := range h {
+			addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(DefaultPort)))
+		}
+		return addresses, nil
+	}
+}
+
+// Inspect returns the state of the ensemble. It fails only if the servers could not be listed.
+func (i *Inspector) Inspect(ctx context.Context) (*Ensemble, error) {
+	addresses, err := i.Servers()
+	if err != nil {
+		return nil, fmt.Errorf("could not list ZooKeeper servers: %s", err)
+	}
+	sort.Strings(addresses)
+
+	e := &Ensemble{Time: time.Now().UTC(), Servers: make([]Server, len(addresses))}
+	var wg sync.WaitGroup
+	for n, address := range addresses {
+		wg.Add(1)
+		go func(n int, address string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, i.Timeout)
+			defer cancel()
+			e.Servers[n] = Query(ctx, i.Dial, address)
+		}(n, address)
+	}
+	wg.Wait()
+
+	if i.ExhibitorURL != "" {
+		e.Exhibitor, err = i.exhibitorStatus(ctx)
+		if err != nil {
+			e.ExhibitorError = err.Error()
+		}
+	}
+	e.evaluate()
+	return e, nil
+}
+
+// evaluate finds the leader, computes zxid lag and quorum and records problems
+func (e *Ensemble) evaluate() {
+	e.Size = len(e.Servers)
+	e.Quorum = e.Size/2 + 1
+
+	var leader *Server
+	for n := range e.Servers {
+		s := &e.Servers[n]
+		if s.Error != "" {
+			e.Problems = append(e.Problems, fmt.Sprintf("server %s is not serving: %s", s.Address, s.Error))
+			continue
+		}
+		switch s.Mode {
+		case "leader", "standalone":
+			if leader != nil {
+				e.Problems = append(e.Problems, fmt.Sprintf("servers %s and %s both report to be the leader", leader.Address, s.Address))
+			}
+			leader = s
+			e.Leader = s.Address
+			e.Serving++
+		case "follower", "observer":
+			e.Followers = append(e.Followers, s.Address)
+			e.Serving++
+		}
+	}
+	if leader == nil && e.Size > 0 {
+		e.Problems = append(e.Problems, "no leader elected")
+	}
+	if leader != nil {
+		for n := range e.Servers {
+			s := &e.Servers[n]
+			if s.Serving() && s.Zxid != "" && leader.Zxid != "" {
+				lag := int64(leader.zxid) - int64(s.zxid)
+				s.ZxidLag = &lag
+			}
+		}
+		if leader.Followers != nil && leader.SyncedFollowers != nil && *leader.SyncedFollowers < *leader.Followers {
+			e.Problems = append(e.Problems, fmt.Sprintf("only %d of %d followers are synced with the leader",
+				*leader.SyncedFollowers, *leader.Followers))
+		}
+	}
+
+	e.HasQuorum = e.Size > 0 && e.Serving >= e.Quorum
+	if e.Size == 0 {
+		e.Problems = append(e.Problems, "no ZooKeeper servers found")
+	} else if !e.HasQuorum {
+		e.Problems = append(e.Problems, fmt.Sprintf("ensemble lost quorum: %d of %d servers serving, %d needed", e.Serving, e.Size, e.Quorum))
+	} else if e.Serving < e.Size {
+		e.Problems = append(e.Problems, fmt.Sprintf("quorum is degraded: %d of %d servers serving, %d more failures lose quorum",
+			e.Serving, e.Size, e.Serving-e.Quorum+1))
+	}
+	for _, s := range e.Exhibitor {
+		if s.Code != exhibitorServing {
+			e.Problems = append(e.Problems, fmt.Sprintf("Exhibitor reports %s as %s", s.Hostname, s.Description))
+		}
+	}
+}
+
+func (i *Inspector) exhibitorStatus(ctx context.Context) ([]ExhibitorServer, error) {
+	request, err := http.NewRequest(http.MethodGet, i.ExhibitorURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new HTTP request: %s", err)
+	}
+	resp, err := i.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %s", i.ExhibitorURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unable to fetch %s. Return code %d. Body: %s", i.ExhibitorURL, resp.StatusCode, body)
+	}
+
+	var status []struct {
+		Code        int
+		Description string
+		Hostname    string
+		IsLeader    bool
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("could not decode Exhibitor cluster status: %s", err)
+	}
+	servers := make([]ExhibitorServer, 0, len(status))
+	for _, s := range status {
+		servers = append(servers, ExhibitorServer{Hostname: s.Hostname, Code: s.Code, Description: s.Description, IsLeader: s.IsLeader})
+	}
+	sort.Slice(servers, func(a, b int) bool { return servers[a].Hostname < servers[b].Hostname })
+	return servers, nil
+}
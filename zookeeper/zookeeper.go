@@ -0,0 +1,180 @@
+// Package zookeeper reports health of the ZooKeeper ensemble run on DC/OS masters. Every server is queried with
+// the mntr, srvr and cons four letter words and the ensemble view of Exhibitor is fetched from its cluster status.
+package zookeeper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the ZooKeeper client port on DC/OS masters
+const DefaultPort = 2181
+
+// DialFunc connects to the address
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Command sends the four letter word to the server and returns its response. The server closes
+// the connection once the response is written.
+func Command(ctx context.Context, dial DialFunc, address, word string) (string, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("could not connect to %s: %s", address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) //nolint:errcheck
+	}
+
+	if _, err := conn.Write([]byte(word)); err != nil {
+		return "", fmt.Errorf("could not send %s to %s: %s", word, address, err)
+	}
+	response, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s response from %s: %s", word, address, err)
+	}
+	if strings.HasSuffix(strings.TrimSpace(string(response)), "is not executed because it is not in the whitelist.") {
+		return "", fmt.Errorf("%s is not allowed by 4lw.commands.whitelist of %s", word, address)
+	}
+	return string(response), nil
+}
+
+// Server is the state of a single ZooKeeper server
+type Server struct {
+	Address string `json:"address"`
+	// Mode is leader, follower, observer or standalone
+	Mode string `json:"mode,omitempty"`
+	// Zxid is the last transaction ID processed by the server
+	Zxid string `json:"zxid,omitempty"`
+	// ZxidLag is the number of transactions the server is behind the leader
+	ZxidLag             *int64  `json:"zxid_lag,omitempty"`
+	OutstandingRequests int64   `json:"outstanding_requests"`
+	AvgLatencyMs        float64 `json:"avg_latency_ms"`
+	MaxLatencyMs        float64 `json:"max_latency_ms"`
+	Connections         int64   `json:"connections"`
+	ZnodeCount          int64   `json:"znode_count"`
+	// Followers and SyncedFollowers are reported by the leader only
+	Followers       *int64 `json:"followers,omitempty"`
+	SyncedFollowers *int64 `json:"synced_followers,omitempty"`
+	// Clients are connections listed by cons
+	Clients []string          `json:"clients,omitempty"`
+	Mntr    map[string]string `json:"mntr,omitempty"`
+	Error   string            `json:"error,omitempty"`
+
+	zxid uint64
+}
+
+// Serving returns true if the server is part of a working ensemble
+func (s Server) Serving() bool {
+	return s.Error == "" && s.Mode != ""
+}
+
+// Query returns the state of the server at the address
+func Query(ctx context.Context, dial DialFunc, address string) Server {
+	s := Server{Address: address}
+
+	srvr, err := Command(ctx, dial, address, "srvr")
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	if err := s.parseSrvr(srvr); err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	// mntr and cons add details, the server state is known from srvr already
+	if mntr, err := Command(ctx, dial, address, "mntr"); err == nil {
+		s.parseMntr(mntr)
+	}
+	if cons, err := Command(ctx, dial, address, "cons"); err == nil {
+		s.Clients = parseCons(cons)
+	}
+	return s
+}
+
+// parseSrvr reads the srvr response:
+//
+// Zookeeper version: 3.4.14-4c25d480e66aadd371de8bd2fd8da255ac140bcf, built on 03/06/2019 16:18 GMT
+// Latency min/avg/max: 0/0/24
+// Received: 1234
+// Sent: 1233
+// Connections: 5
+// Outstanding: 0
+// Zxid: 0x100000a3c
+// Mode: follower
+// Node count: 154
+func (s *Server) parseSrvr(response string) error {
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 {
+			fields[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	if strings.Contains(response, "This ZooKeeper instance is not currently serving requests") {
+		return fmt.Errorf("server is not currently serving requests")
+	}
+
+	s.Mode = fields["Mode"]
+	if s.Mode == "" {
+		return fmt.Errorf("could not parse srvr response: no mode")
+	}
+	if zxid, ok := fields["Zxid"]; ok {
+		v, err := strconv.ParseUint(strings.TrimPrefix(zxid, "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("could not parse zxid %q: %s", zxid, err)
+		}
+		s.zxid = v
+		s.Zxid = zxid
+	}
+	s.OutstandingRequests, _ = strconv.ParseInt(fields["Outstanding"], 10, 64)
+	s.Connections, _ = strconv.ParseInt(fields["Connections"], 10, 64)
+	s.ZnodeCount, _ = strconv.ParseInt(fields["Node count"], 10, 64)
+	if latency := strings.Split(fields["Latency min/avg/max"], "/"); len(latency) == 3 {
+		s.AvgLatencyMs, _ = strconv.ParseFloat(latency[1], 64)
+		s.MaxLatencyMs, _ = strconv.ParseFloat(latency[2], 64)
+	}
+	return nil
+}
+
+// parseMntr reads tab separated key value pairs of the mntr response
+func (s *Server) parseMntr(response string) {
+	s.Mntr = map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) == 2 {
+			s.Mntr[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	if v, err := strconv.ParseInt(s.Mntr["zk_followers"], 10, 64); err == nil {
+		s.Followers = &v
+	}
+	if v, err := strconv.ParseInt(s.Mntr["zk_synced_followers"], 10, 64); err == nil {
+		s.SyncedFollowers = &v
+	}
+}
+
+// parseCons returns connections listed by cons, one per line
+func parseCons(response string) []string {
+	var clients []string
+	scanner := bufio.NewScanner(strings.NewReader(response))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			clients = append(clients, line)
+		}
+	}
+	return clients
+}
+
+// newDialer returns DialFunc connecting with the timeout
+func newDialer(timeout time.Duration) DialFunc {
+	return (&net.Dialer{Timeout: timeout}).DialContext
+}
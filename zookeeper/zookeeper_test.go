@@ -0,0 +1,238 @@
+package zookeeper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const followerSrvr = `Zookeeper version: 3.4.14-4c25d480e66aadd371de8bd2fd8da255ac140bcf, built on 03/06/2019 16:18 GMT
+Latency min/avg/max: 0/1/24
+Received: 1234
+Sent: 1233
+Connections: 5
+Outstanding: 2
+Zxid: 0x100000a3c
+Mode: follower
+Node count: 154
+`
+
+const leaderSrvr = `Zookeeper version: 3.4.14-4c25d480e66aadd371de8bd2fd8da255ac140bcf, built on 03/06/2019 16:18 GMT
+Latency min/avg/max: 0/0/12
+Received: 4321
+Sent: 4320
+Connections: 3
+Outstanding: 0
+Zxid: 0x100000a40
+Mode: leader
+Node count: 154
+`
+
+const leaderMntr = "zk_version\t3.4.14\nzk_server_state\tleader\nzk_followers\t2\nzk_synced_followers\t2\n"
+
+const cons = ` /10.0.0.2:51234[1](queued=0,recved=10,sent=10,sid=0x1,lop=PING)
+ /10.0.0.3:51235[1](queued=0,recved=4,sent=4,sid=0x2,lop=PING)
+`
+
+// fakeServer is a fake ZooKeeper server answering four letter words with canned responses
+func fakeServer(t *testing.T, responses map[string]string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				word := make([]byte, 4)
+				if _, err := io.ReadFull(conn, word); err != nil {
+					return
+				}
+				response, ok := responses[string(word)]
+				if !ok {
+					response = fmt.Sprintf("%s is not executed because it is not in the whitelist.\n", word)
+				}
+				conn.Write([]byte(response)) //nolint:errcheck
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+// closedAddress returns an address nothing listens on
+func closedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	l.Close()
+	return address
+}
+
+func newTestInspector(addresses ...string) *Inspector {
+	i := NewInspector(func() ([]string, error) { return addresses, nil }, "", http.DefaultClient)
+	i.Timeout = time.Second
+	return i
+}
+
+func TestQuery(t *testing.T) {
+	address := fakeServer(t, map[string]string{"srvr": leaderSrvr, "mntr": leaderMntr, "cons": cons})
+
+	s := Query(context.Background(), newDialer(time.Second), address)
+
+	assert.Empty(t, s.Error)
+	assert.True(t, s.Serving())
+	assert.Equal(t, "leader", s.Mode)
+	assert.Equal(t, "0x100000a40", s.Zxid)
+	assert.Equal(t, int64(3), s.Connections)
+	assert.Equal(t, int64(154), s.ZnodeCount)
+	assert.Equal(t, 12.0, s.MaxLatencyMs)
+	require.NotNil(t, s.Followers)
+	assert.Equal(t, int64(2), *s.Followers)
+	require.NotNil(t, s.SyncedFollowers)
+	assert.Equal(t, int64(2), *s.SyncedFollowers)
+	assert.Equal(t, "3.4.14", s.Mntr["zk_version"])
+	assert.Len(t, s.Clients, 2)
+}
+
+func TestQueryNotWhitelisted(t *testing.T) {
+	address := fakeServer(t, map[string]string{"srvr": followerSrvr})
+
+	s := Query(context.Background(), newDialer(time.Second), address)
+
+	assert.Empty(t, s.Error)
+	assert.Equal(t, "follower", s.Mode)
+	assert.Equal(t, int64(2), s.OutstandingRequests)
+	assert.Empty(t, s.Mntr)
+	assert.Empty(t, s.Clients)
+
+	_, err := Command(context.Background(), newDialer(time.Second), address, "mntr")
+	assert.EqualError(t, err, "mntr is not allowed by 4lw.commands.whitelist of "+address)
+}
+
+func TestQueryNotServing(t *testing.T) {
+	address := fakeServer(t, map[string]string{"srvr": "This ZooKeeper instance is not currently serving requests\n"})
+
+	s := Query(context.Background(), newDialer(time.Second), address)
+
+	assert.False(t, s.Serving())
+	assert.Equal(t, "server is not currently serving requests", s.Error)
+}
+
+func TestInspectHealthyEnsemble(t *testing.T) {
+	leader := fakeServer(t, map[string]string{"srvr": leaderSrvr, "mntr": leaderMntr})
+	follower1 := fakeServer(t, map[string]string{"srvr": followerSrvr})
+	follower2 := fakeServer(t, map[string]string{"srvr": followerSrvr})
+
+	e, err := newTestInspector(leader, follower1, follower2).Inspect(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, e.Healthy(), e.Problems)
+	assert.True(t, e.HasQuorum)
+	assert.Equal(t, 3, e.Size)
+	assert.Equal(t, 3, e.Serving)
+	assert.Equal(t, 2, e.Quorum)
+	assert.Equal(t, leader, e.Leader)
+	assert.ElementsMatch(t, []string{follower1, follower2}, e.Followers)
+	for _, s := range e.Servers {
+		require.NotNil(t, s.ZxidLag, s.Address)
+		if s.Address == leader {
+			assert.Equal(t, int64(0), *s.ZxidLag)
+		} else {
+			assert.Equal(t, int64(4), *s.ZxidLag)
+		}
+	}
+}
+
+func TestInspectDegradedQuorum(t *testing.T) {
+	leader := fakeServer(t, map[string]string{"srvr": leaderSrvr})
+	follower := fakeServer(t, map[string]string{"srvr": followerSrvr})
+	down := closedAddress(t)
+
+	e, err := newTestInspector(leader, follower, down).Inspect(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, e.Healthy())
+	assert.True(t, e.HasQuorum)
+	assert.Equal(t, 2, e.Serving)
+	require.Len(t, e.Problems, 2)
+	assert.Contains(t, e.Problems[0], "server "+down+" is not serving")
+	assert.Equal(t, "quorum is degraded: 2 of 3 servers serving, 1 more failures lose quorum", e.Problems[1])
+}
+
+func TestInspectLostQuorum(t *testing.T) {
+	follower := fakeServer(t, map[string]string{"srvr": followerSrvr})
+
+	e, err := newTestInspector(follower, closedAddress(t), closedAddress(t)).Inspect(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, e.HasQuorum)
+	assert.Empty(t, e.Leader)
+	assert.Contains(t, e.Problems, "no leader elected")
+	assert.Contains(t, e.Problems, "ensemble lost quorum: 1 of 3 servers serving, 2 needed")
+}
+
+func TestInspectServersError(t *testing.T) {
+	i := NewInspector(func() ([]string, error) { return nil, fmt.Errorf("no masters") }, "", http.DefaultClient)
+
+	_, err := i.Inspect(context.Background())
+	assert.EqualError(t, err, "could not list ZooKeeper servers: no masters")
+}
+
+func TestInspectExhibitor(t *testing.T) {
+	leader := fakeServer(t, map[string]string{"srvr": leaderSrvr})
+	exhibitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"code": 3, "description": "serving", "hostname": "10.0.0.1", "isLeader": true},
+			{"code": 1, "description": "latent", "hostname": "10.0.0.2", "isLeader": false}
+		]`)
+	}))
+	defer exhibitor.Close()
+
+	i := newTestInspector(leader)
+	i.ExhibitorURL = exhibitor.URL
+	e, err := i.Inspect(context.Background())
+	require.NoError(t, err)
+
+	assert.Empty(t, e.ExhibitorError)
+	assert.Equal(t, []ExhibitorServer{
+		{Hostname: "10.0.0.1", Code: 3, Description: "serving", IsLeader: true},
+		{Hostname: "10.0.0.2", Code: 1, Description: "latent"},
+	}, e.Exhibitor)
+	assert.Equal(t, []string{"Exhibitor reports 10.0.0.2 as latent"}, e.Problems)
+}
+
+func TestInspectExhibitorError(t *testing.T) {
+	leader := fakeServer(t, map[string]string{"srvr": leaderSrvr})
+	exhibitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer exhibitor.Close()
+
+	i := newTestInspector(leader)
+	i.ExhibitorURL = exhibitor.URL
+	e, err := i.Inspect(context.Background())
+	require.NoError(t, err)
+
+	assert.Contains(t, e.ExhibitorError, "Return code 500")
+	assert.True(t, e.Healthy())
+}
+
+func TestHostServers(t *testing.T) {
+	servers := HostServers(func() ([]string, error) { return []string{"10.0.0.1", "10.0.0.2"}, nil })
+
+	addresses, err := servers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:2181", "10.0.0.2:2181"}, addresses)
+}